@@ -8,18 +8,15 @@ import (
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
 )
 
-type Seasonality string
+// Seasonality is shared with the forecast function of the query builder
+type Seasonality = v3.Seasonality
 
 const (
-	SeasonalityHourly Seasonality = "hourly"
-	SeasonalityDaily  Seasonality = "daily"
-	SeasonalityWeekly Seasonality = "weekly"
+	SeasonalityHourly = v3.SeasonalityHourly
+	SeasonalityDaily  = v3.SeasonalityDaily
+	SeasonalityWeekly = v3.SeasonalityWeekly
 )
 
-func (s Seasonality) String() string {
-	return string(s)
-}
-
 var (
 	oneWeekOffset = 24 * 7 * time.Hour.Milliseconds()
 	oneDayOffset  = 24 * time.Hour.Milliseconds()
@@ -27,15 +24,6 @@ var (
 	fiveMinOffset = 5 * time.Minute.Milliseconds()
 )
 
-func (s Seasonality) IsValid() bool {
-	switch s {
-	case SeasonalityHourly, SeasonalityDaily, SeasonalityWeekly:
-		return true
-	default:
-		return false
-	}
-}
-
 type GetAnomaliesRequest struct {
 	Params      *v3.QueryRangeParamsV3
 	Seasonality Seasonality
@@ -21,6 +21,7 @@ import (
 
 	"github.com/rs/cors"
 	"github.com/soheilhy/cmux"
+	"go.opentelemetry.io/otel/metric"
 	"go.signoz.io/signoz/ee/query-service/app/api"
 	"go.signoz.io/signoz/ee/query-service/app/db"
 	"go.signoz.io/signoz/ee/query-service/auth"
@@ -39,6 +40,7 @@ import (
 
 	"go.signoz.io/signoz/pkg/query-service/agentConf"
 	baseapp "go.signoz.io/signoz/pkg/query-service/app"
+	queryprogress "go.signoz.io/signoz/pkg/query-service/app/clickhouseReader/query_progress"
 	"go.signoz.io/signoz/pkg/query-service/app/dashboards"
 	baseexplorer "go.signoz.io/signoz/pkg/query-service/app/explorer"
 	"go.signoz.io/signoz/pkg/query-service/app/integrations"
@@ -53,6 +55,7 @@ import (
 	baseint "go.signoz.io/signoz/pkg/query-service/interfaces"
 	basemodel "go.signoz.io/signoz/pkg/query-service/model"
 	pqle "go.signoz.io/signoz/pkg/query-service/pqlEngine"
+	"go.signoz.io/signoz/pkg/query-service/querylimits"
 	baserules "go.signoz.io/signoz/pkg/query-service/rules"
 	"go.signoz.io/signoz/pkg/query-service/telemetry"
 	"go.signoz.io/signoz/pkg/query-service/utils"
@@ -78,6 +81,11 @@ type ServerOptions struct {
 	Cluster           string
 	GatewayUrl        string
 	UseLogsNewSchema  bool
+	QueryLimitsPath   string
+	// tracks the progress of the queries in the redis of the cache so that it can be followed from any replica
+	ProgressInRedis bool
+	// MeterProvider provides the meters of the query service metrics
+	MeterProvider metric.MeterProvider
 }
 
 // Server runs HTTP api service
@@ -141,6 +149,23 @@ func NewServer(serverOptions *ServerOptions) (*Server, error) {
 
 	// set license manager as feature flag provider in dao
 	modelDao.SetFlagProvider(lm)
+
+	var c cache.Cache
+	var cacheOpts *cache.Options
+	if serverOptions.CacheConfigPath != "" {
+		cacheOpts, err = cache.LoadFromYAMLCacheConfigFile(serverOptions.CacheConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		cacheOpts.MeterProvider = serverOptions.MeterProvider
+		c = cache.NewCache(cacheOpts)
+		if c != nil {
+			if err := c.Connect(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	readerReady := make(chan bool)
 
 	var reader interfaces.DataConnector
@@ -157,6 +182,14 @@ func NewServer(serverOptions *ServerOptions) (*Server, error) {
 			serverOptions.Cluster,
 			serverOptions.UseLogsNewSchema,
 		)
+		// the progress of the queries is tracked in redis when enabled, so that it can be
+		// followed from any replica
+		if serverOptions.ProgressInRedis {
+			if !cacheOpts.UsesRedis() {
+				return nil, fmt.Errorf("tracking the query progress in redis needs a redis or tiered cache")
+			}
+			qb.SetQueryProgressTracker(queryprogress.NewRedisQueryProgressTracker(cacheOpts.Redis))
+		}
 		go qb.Start(readerReady)
 		reader = qb
 	} else {
@@ -170,13 +203,12 @@ func NewServer(serverOptions *ServerOptions) (*Server, error) {
 			return nil, err
 		}
 	}
-	var c cache.Cache
-	if serverOptions.CacheConfigPath != "" {
-		cacheOpts, err := cache.LoadFromYAMLCacheConfigFile(serverOptions.CacheConfigPath)
+	var queryLimits *querylimits.Config
+	if serverOptions.QueryLimitsPath != "" {
+		queryLimits, err = querylimits.LoadFromYAMLConfigFile(serverOptions.QueryLimitsPath)
 		if err != nil {
 			return nil, err
 		}
-		c = cache.NewCache(cacheOpts)
 	}
 
 	<-readerReady
@@ -270,6 +302,7 @@ func NewServer(serverOptions *ServerOptions) (*Server, error) {
 		FluxInterval:                  fluxInterval,
 		Gateway:                       gatewayProxy,
 		UseLogsNewSchema:              serverOptions.UseLogsNewSchema,
+		QueryLimits:                   queryLimits,
 	}
 
 	apiHandler, err := api.NewAPIHandler(apiOpts)
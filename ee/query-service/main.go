@@ -10,13 +10,18 @@ import (
 	"syscall"
 	"time"
 
+	"go.opentelemetry.io/collector/confmap"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
 	"go.signoz.io/signoz/ee/query-service/app"
+	"go.signoz.io/signoz/pkg/config"
+	"go.signoz.io/signoz/pkg/confmap/provider/signozenvprovider"
+	"go.signoz.io/signoz/pkg/instrumentation"
 	"go.signoz.io/signoz/pkg/query-service/auth"
 	baseconst "go.signoz.io/signoz/pkg/query-service/constants"
 	"go.signoz.io/signoz/pkg/query-service/migrate"
 	"go.signoz.io/signoz/pkg/query-service/version"
+	signozversion "go.signoz.io/signoz/pkg/version"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
@@ -88,7 +93,8 @@ func main() {
 	var cluster string
 
 	var useLogsNewSchema bool
-	var cacheConfigPath, fluxInterval string
+	var progressInRedis bool
+	var cacheConfigPath, fluxInterval, queryLimitsPath string
 	var enableQueryServiceLogOTLPExport bool
 	var preferSpanMetrics bool
 
@@ -98,6 +104,7 @@ func main() {
 	var gatewayUrl string
 
 	flag.BoolVar(&useLogsNewSchema, "use-logs-new-schema", false, "use logs_v2 schema for logs")
+	flag.BoolVar(&progressInRedis, "experimental.query-progress-redis", false, "(track the query progress in the redis of the cache config so it can be followed from any replica)")
 	flag.StringVar(&promConfigPath, "config", "./config/prometheus.yml", "(prometheus config to read metrics)")
 	flag.StringVar(&skipTopLvlOpsPath, "skip-top-level-ops", "", "(config file to skip top level operations)")
 	flag.BoolVar(&disableRules, "rules.disable", false, "(disable rule evaluation)")
@@ -107,6 +114,7 @@ func main() {
 	flag.DurationVar(&dialTimeout, "dial-timeout", 5*time.Second, "(the maximum time to establish a connection.)")
 	flag.StringVar(&ruleRepoURL, "rules.repo-url", baseconst.AlertHelpPage, "(host address used to build rule link in alert messages)")
 	flag.StringVar(&cacheConfigPath, "experimental.cache-config", "", "(cache config to use)")
+	flag.StringVar(&queryLimitsPath, "query-limits", "", "(query limits config of each role)")
 	flag.StringVar(&fluxInterval, "flux-interval", "5m", "(the interval to exclude data from being cached to avoid incorrect cache for data in motion)")
 	flag.BoolVar(&enableQueryServiceLogOTLPExport, "enable.query.service.log.otlp.export", false, "(enable query service log otlp export)")
 	flag.StringVar(&cluster, "cluster", "cluster", "(cluster name - defaults to 'cluster')")
@@ -121,6 +129,21 @@ func main() {
 
 	version.PrintVersion()
 
+	// the instrumentation config is read from the SIGNOZ__INSTRUMENTATION__* environment variables
+	signozConfig, err := config.New(context.Background(), config.ProviderSettings{
+		ResolverSettings: confmap.ResolverSettings{
+			URIs:              []string{"signozenv:"},
+			ProviderFactories: []confmap.ProviderFactory{signozenvprovider.NewFactory()},
+		},
+	})
+	if err != nil {
+		zap.L().Fatal("Failed to load the config", zap.Error(err))
+	}
+	instr, err := instrumentation.New(context.Background(), signozversion.Build{Name: "query-service", Version: version.GetVersion()}, signozConfig.Instrumentation)
+	if err != nil {
+		zap.L().Fatal("Failed to create the instrumentation", zap.Error(err))
+	}
+
 	serverOptions := &app.ServerOptions{
 		HTTPHostPort:      baseconst.HTTPHostPort,
 		PromConfigPath:    promConfigPath,
@@ -137,6 +160,9 @@ func main() {
 		Cluster:           cluster,
 		GatewayUrl:        gatewayUrl,
 		UseLogsNewSchema:  useLogsNewSchema,
+		QueryLimitsPath:   queryLimitsPath,
+		ProgressInRedis:   progressInRedis,
+		MeterProvider:     instr.MeterProvider,
 	}
 
 	// Read the jwt secret key
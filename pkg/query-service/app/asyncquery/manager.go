@@ -0,0 +1,270 @@
+package asyncquery
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.signoz.io/signoz/pkg/query-service/cache"
+	"go.signoz.io/signoz/pkg/query-service/cache/status"
+	"go.signoz.io/signoz/pkg/query-service/common"
+	"go.signoz.io/signoz/pkg/query-service/model"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.uber.org/zap"
+)
+
+// DefaultTTL is the time for which the async queries and their results are kept in the cache
+const DefaultTTL = 24 * time.Hour
+
+// cancelCheckInterval is how often the replica running a query checks whether the query was
+// cancelled from another replica
+const cancelCheckInterval = time.Second
+
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// Query is an async query, the result is set once the query succeeds
+type Query struct {
+	ID          string                 `json:"id"`
+	Status      Status                 `json:"status"`
+	Error       string                 `json:"error,omitempty"`
+	SubmittedBy string                 `json:"submittedBy,omitempty"`
+	SubmittedAt time.Time              `json:"submittedAt"`
+	FinishedAt  *time.Time             `json:"finishedAt,omitempty"`
+	Progress    *model.QueryProgress   `json:"progress,omitempty"`
+	Result      *v3.QueryRangeResponse `json:"result,omitempty"`
+}
+
+// RunFunc runs the query range request of an async query
+type RunFunc func(ctx context.Context) (*v3.QueryRangeResponse, error)
+
+// ProgressTracker reports the progress of the clickhouse queries run for a query id
+type ProgressTracker interface {
+	ReportQueryStartForProgressTracking(queryId string) (reportQueryFinished func(), err *model.ApiError)
+	GetQueryProgress(queryId string) (*model.QueryProgress, *model.ApiError)
+}
+
+// Manager runs the async queries and keeps their state and results in the cache so they
+// can be fetched from any replica until the TTL expires
+type Manager struct {
+	cache   cache.Cache
+	tracker ProgressTracker
+	ttl     time.Duration
+
+	// cancel functions of the queries running in this replica
+	cancels map[string]context.CancelFunc
+	mu      sync.Mutex
+
+	cancelCheckInterval time.Duration
+}
+
+func NewManager(c cache.Cache, tracker ProgressTracker, ttl time.Duration) *Manager {
+	return &Manager{
+		cache:   c,
+		tracker: tracker,
+		ttl:     ttl,
+		cancels: make(map[string]context.CancelFunc),
+
+		cancelCheckInterval: cancelCheckInterval,
+	}
+}
+
+func cacheKey(id string) string {
+	return "async_query:" + id
+}
+
+// cancelKey is the key of the cancel request of a query, the replica running the query
+// stores the cancelled status once it sees it
+func cancelKey(id string) string {
+	return "async_query_cancel:" + id
+}
+
+func (m *Manager) store(query *Query) error {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return err
+	}
+	// the copies of the query kept by the replicas are dropped so that they see the new status
+	if replacer, ok := m.cache.(cache.Replacer); ok {
+		return replacer.Replace(cacheKey(query.ID), data, m.ttl)
+	}
+	return m.cache.Store(cacheKey(query.ID), data, m.ttl)
+}
+
+func (m *Manager) retrieve(id string) (*Query, *model.ApiError) {
+	data, retrieveStatus, err := m.cache.Retrieve(cacheKey(id), false)
+	if err != nil {
+		return nil, model.InternalError(err)
+	}
+	if retrieveStatus != status.RetrieveStatusHit {
+		return nil, model.NotFoundError(fmt.Errorf("async query %s doesn't exist or has expired", id))
+	}
+	var query Query
+	if err := json.Unmarshal(data, &query); err != nil {
+		return nil, model.InternalError(err)
+	}
+	return &query, nil
+}
+
+// retrieveOwned returns the query if it was submitted by the user of ctx, the queries of
+// the other users are not found
+func (m *Manager) retrieveOwned(ctx context.Context, id string) (*Query, *model.ApiError) {
+	query, apiErr := m.retrieve(id)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if query.SubmittedBy != "" {
+		if user := common.GetUserFromContext(ctx); user == nil || user.Id != query.SubmittedBy {
+			return nil, model.NotFoundError(fmt.Errorf("async query %s doesn't exist or has expired", id))
+		}
+	}
+	return query, nil
+}
+
+// cancelRequested returns whether the query was cancelled from any replica
+func (m *Manager) cancelRequested(id string) bool {
+	_, retrieveStatus, err := m.cache.Retrieve(cancelKey(id), false)
+	return err == nil && retrieveStatus == status.RetrieveStatusHit
+}
+
+// Submit starts running the query in the background and returns the async query. The
+// query keeps running after ctx is done, only the values of ctx e.g the user are used.
+func (m *Manager) Submit(ctx context.Context, run RunFunc) (*Query, *model.ApiError) {
+	query := &Query{
+		ID:          uuid.NewString(),
+		Status:      StatusRunning,
+		SubmittedAt: time.Now().UTC(),
+	}
+	if user := common.GetUserFromContext(ctx); user != nil {
+		query.SubmittedBy = user.Id
+	}
+	if err := m.store(query); err != nil {
+		return nil, model.InternalError(fmt.Errorf("error storing async query: %w", err))
+	}
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	reportQueryFinished, apiErr := m.tracker.ReportQueryStartForProgressTracking(query.ID)
+	if apiErr != nil {
+		zap.L().Error("couldn't report async query start for progress tracking", zap.String("queryId", query.ID), zap.Error(apiErr))
+	} else {
+		// Adding queryId to the context signals clickhouse queries to report progress
+		//lint:ignore SA1029 ignore for now
+		runCtx = context.WithValue(runCtx, "queryId", query.ID)
+	}
+
+	m.mu.Lock()
+	m.cancels[query.ID] = cancel
+	m.mu.Unlock()
+
+	go m.watchCancel(runCtx, query.ID, cancel)
+	go func() {
+		defer func() {
+			m.mu.Lock()
+			delete(m.cancels, query.ID)
+			m.mu.Unlock()
+			cancel()
+		}()
+
+		result, err := run(runCtx)
+
+		finished := *query
+		if reportQueryFinished != nil {
+			finished.Progress, _ = m.tracker.GetQueryProgress(query.ID)
+			reportQueryFinished()
+		}
+		finishedAt := time.Now().UTC()
+		finished.FinishedAt = &finishedAt
+		switch {
+		case errors.Is(runCtx.Err(), context.Canceled) || m.cancelRequested(query.ID):
+			finished.Status = StatusCancelled
+		case err != nil:
+			finished.Status = StatusFailed
+			finished.Error = err.Error()
+		default:
+			finished.Status = StatusSucceeded
+			finished.Result = result
+		}
+
+		if err := m.store(&finished); err != nil {
+			zap.L().Error("error storing async query result", zap.String("queryId", query.ID), zap.Error(err))
+			// the result may be too large for the cache, the query is failed without it
+			// so that it doesn't stay running until it expires
+			finished.Status = StatusFailed
+			finished.Error = fmt.Sprintf("error storing the result of the query: %v", err)
+			finished.Result = nil
+			if err := m.store(&finished); err != nil {
+				zap.L().Error("error storing async query status", zap.String("queryId", query.ID), zap.Error(err))
+			}
+		}
+	}()
+
+	return query, nil
+}
+
+// watchCancel cancels the query running in this replica once it's cancelled from another
+// replica, until the query is done
+func (m *Manager) watchCancel(ctx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(m.cancelCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if m.cancelRequested(id) {
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// Get returns the async query submitted by the user of ctx with the result if it succeeded,
+// or the progress if it's running
+func (m *Manager) Get(ctx context.Context, id string) (*Query, *model.ApiError) {
+	query, apiErr := m.retrieveOwned(ctx, id)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if query.Status == StatusRunning {
+		// the progress is only available in the replica running the query
+		if progress, err := m.tracker.GetQueryProgress(id); err == nil {
+			query.Progress = progress
+		}
+	}
+	return query, nil
+}
+
+// Cancel cancels the running async query submitted by the user of ctx, the finished queries
+// are not changed. Only the replica running the query stores the cancelled status, once it
+// stops the query, so that it's not replaced by the result of a query finishing at the same time
+func (m *Manager) Cancel(ctx context.Context, id string) (*Query, *model.ApiError) {
+	query, apiErr := m.retrieveOwned(ctx, id)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if query.Status != StatusRunning {
+		return query, nil
+	}
+
+	if err := m.cache.Store(cancelKey(id), []byte(id), m.ttl); err != nil {
+		return nil, model.InternalError(fmt.Errorf("error cancelling async query: %w", err))
+	}
+	m.mu.Lock()
+	cancel, ok := m.cancels[id]
+	m.mu.Unlock()
+	if ok {
+		cancel()
+	}
+	return query, nil
+}
@@ -0,0 +1,216 @@
+package asyncquery
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.signoz.io/signoz/pkg/query-service/cache"
+	"go.signoz.io/signoz/pkg/query-service/cache/inmemory"
+	"go.signoz.io/signoz/pkg/query-service/constants"
+	"go.signoz.io/signoz/pkg/query-service/model"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+type fakeTracker struct{}
+
+func (fakeTracker) ReportQueryStartForProgressTracking(string) (func(), *model.ApiError) {
+	return func() {}, nil
+}
+
+func (fakeTracker) GetQueryProgress(string) (*model.QueryProgress, *model.ApiError) {
+	return &model.QueryProgress{ReadRows: 10}, nil
+}
+
+func waitForStatus(t *testing.T, manager *Manager, id string, want Status) *Query {
+	var query *Query
+	require.Eventually(t, func() bool {
+		var apiErr *model.ApiError
+		query, apiErr = manager.Get(context.Background(), id)
+		require.Nil(t, apiErr)
+		return query.Status == want
+	}, time.Second, 5*time.Millisecond)
+	return query
+}
+
+func TestManager(t *testing.T) {
+	manager := NewManager(inmemory.New(nil), fakeTracker{}, time.Minute)
+
+	t.Run("succeeded", func(t *testing.T) {
+		release := make(chan struct{})
+		query, apiErr := manager.Submit(context.Background(), func(ctx context.Context) (*v3.QueryRangeResponse, error) {
+			<-release
+			// the clickhouse queries report their progress for the query id
+			assert.NotNil(t, ctx.Value("queryId"))
+			return &v3.QueryRangeResponse{Result: []*v3.Result{{QueryName: "A"}}}, nil
+		})
+		require.Nil(t, apiErr)
+		assert.Equal(t, StatusRunning, query.Status)
+
+		running, apiErr := manager.Get(context.Background(), query.ID)
+		require.Nil(t, apiErr)
+		assert.Equal(t, StatusRunning, running.Status)
+		assert.Equal(t, uint64(10), running.Progress.ReadRows)
+
+		close(release)
+		succeeded := waitForStatus(t, manager, query.ID, StatusSucceeded)
+		assert.Equal(t, "A", succeeded.Result.Result[0].QueryName)
+		assert.NotNil(t, succeeded.FinishedAt)
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		query, apiErr := manager.Submit(context.Background(), func(ctx context.Context) (*v3.QueryRangeResponse, error) {
+			return nil, fmt.Errorf("code: 159, message: Timeout exceeded")
+		})
+		require.Nil(t, apiErr)
+		failed := waitForStatus(t, manager, query.ID, StatusFailed)
+		assert.Equal(t, "code: 159, message: Timeout exceeded", failed.Error)
+		assert.Nil(t, failed.Result)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		submitCtx, cancelSubmit := context.WithCancel(context.Background())
+		query, apiErr := manager.Submit(submitCtx, func(ctx context.Context) (*v3.QueryRangeResponse, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+		require.Nil(t, apiErr)
+		// the query keeps running after the request that submitted it is done
+		cancelSubmit()
+		running, apiErr := manager.Get(context.Background(), query.ID)
+		require.Nil(t, apiErr)
+		assert.Equal(t, StatusRunning, running.Status)
+
+		_, apiErr = manager.Cancel(context.Background(), query.ID)
+		require.Nil(t, apiErr)
+		cancelled := waitForStatus(t, manager, query.ID, StatusCancelled)
+		assert.NotNil(t, cancelled.FinishedAt)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, apiErr := manager.Get(context.Background(), "unknown")
+		require.NotNil(t, apiErr)
+		assert.Equal(t, model.ErrorNotFound, apiErr.Type())
+	})
+}
+
+func TestManagerCancelFromAnotherReplica(t *testing.T) {
+	// the replicas share the cache
+	c := inmemory.New(nil)
+	running := NewManager(c, fakeTracker{}, time.Minute)
+	running.cancelCheckInterval = 5 * time.Millisecond
+	other := NewManager(c, fakeTracker{}, time.Minute)
+
+	cancelled := make(chan struct{})
+	query, apiErr := running.Submit(context.Background(), func(ctx context.Context) (*v3.QueryRangeResponse, error) {
+		<-ctx.Done()
+		close(cancelled)
+		// the result of the query finishing after the cancel doesn't replace the cancelled status
+		return &v3.QueryRangeResponse{Result: []*v3.Result{{QueryName: "A"}}}, nil
+	})
+	require.Nil(t, apiErr)
+
+	_, apiErr = other.Cancel(context.Background(), query.ID)
+	require.Nil(t, apiErr)
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		require.Fail(t, "the query should be cancelled in the replica running it")
+	}
+	require.Eventually(t, func() bool {
+		running.mu.Lock()
+		defer running.mu.Unlock()
+		return len(running.cancels) == 0
+	}, time.Second, 5*time.Millisecond)
+
+	for _, manager := range []*Manager{running, other} {
+		stored, apiErr := manager.Get(context.Background(), query.ID)
+		require.Nil(t, apiErr)
+		assert.Equal(t, StatusCancelled, stored.Status)
+		assert.Nil(t, stored.Result)
+	}
+}
+
+// limitedCache rejects the entries larger than maxBytes
+type limitedCache struct {
+	cache.Cache
+	maxBytes int
+}
+
+func (c *limitedCache) Store(cacheKey string, data []byte, ttl time.Duration) error {
+	if len(data) > c.maxBytes {
+		return fmt.Errorf("entry of %d bytes is larger than %d bytes", len(data), c.maxBytes)
+	}
+	return c.Cache.Store(cacheKey, data, ttl)
+}
+
+func TestManagerResultNotStored(t *testing.T) {
+	manager := NewManager(&limitedCache{Cache: inmemory.New(nil), maxBytes: 1000}, fakeTracker{}, time.Minute)
+
+	query, apiErr := manager.Submit(context.Background(), func(ctx context.Context) (*v3.QueryRangeResponse, error) {
+		series := &v3.Series{}
+		for ts := int64(0); ts < 100; ts++ {
+			series.Points = append(series.Points, v3.Point{Timestamp: ts, Value: float64(ts)})
+		}
+		return &v3.QueryRangeResponse{Result: []*v3.Result{{QueryName: "A", Series: []*v3.Series{series}}}}, nil
+	})
+	require.Nil(t, apiErr)
+
+	// the query doesn't stay running when its result can't be stored
+	failed := waitForStatus(t, manager, query.ID, StatusFailed)
+	assert.Contains(t, failed.Error, "error storing the result of the query")
+	assert.Nil(t, failed.Result)
+}
+
+func TestManagerCancelBeforeFinished(t *testing.T) {
+	manager := NewManager(inmemory.New(nil), fakeTracker{}, time.Minute)
+
+	release := make(chan struct{})
+	query, apiErr := manager.Submit(context.Background(), func(ctx context.Context) (*v3.QueryRangeResponse, error) {
+		<-release
+		// the query finishes without seeing the cancel
+		return &v3.QueryRangeResponse{Result: []*v3.Result{{QueryName: "A"}}}, nil
+	})
+	require.Nil(t, apiErr)
+
+	_, apiErr = manager.Cancel(context.Background(), query.ID)
+	require.Nil(t, apiErr)
+	close(release)
+
+	cancelled := waitForStatus(t, manager, query.ID, StatusCancelled)
+	assert.Nil(t, cancelled.Result)
+}
+
+func TestManagerOtherUser(t *testing.T) {
+	manager := NewManager(inmemory.New(nil), fakeTracker{}, time.Minute)
+	userCtx := func(id string) context.Context {
+		return context.WithValue(context.Background(), constants.ContextUserKey, &model.UserPayload{User: model.User{Id: id}})
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	query, apiErr := manager.Submit(userCtx("submitter"), func(ctx context.Context) (*v3.QueryRangeResponse, error) {
+		<-release
+		return &v3.QueryRangeResponse{}, nil
+	})
+	require.Nil(t, apiErr)
+	assert.Equal(t, "submitter", query.SubmittedBy)
+
+	running, apiErr := manager.Get(userCtx("submitter"), query.ID)
+	require.Nil(t, apiErr)
+	assert.Equal(t, StatusRunning, running.Status)
+
+	// the queries of the other users are not found
+	_, apiErr = manager.Get(userCtx("other"), query.ID)
+	require.NotNil(t, apiErr)
+	assert.Equal(t, model.ErrorNotFound, apiErr.Type())
+	_, apiErr = manager.Cancel(userCtx("other"), query.ID)
+	require.NotNil(t, apiErr)
+	assert.Equal(t, model.ErrorNotFound, apiErr.Type())
+	_, apiErr = manager.Get(context.Background(), query.ID)
+	require.NotNil(t, apiErr)
+}
@@ -0,0 +1,424 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.signoz.io/signoz/pkg/query-service/app/dashboards"
+	"go.signoz.io/signoz/pkg/query-service/app/queryBuilder"
+	"go.signoz.io/signoz/pkg/query-service/cache"
+	"go.signoz.io/signoz/pkg/query-service/model"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.signoz.io/signoz/pkg/query-service/querycache"
+	"go.uber.org/zap"
+)
+
+// cacheEntry is a cached query result with the time ranges it covers
+type cacheEntry struct {
+	Key    string                   `json:"key"`
+	Hash   string                   `json:"hash"`
+	Ranges []querycache.CachedRange `json:"ranges"`
+}
+
+// stepKeyPart matches the step of the cache keys, the step depends on the time range
+// of the dashboard so the keys of a dashboard are matched regardless of it
+var stepKeyPart = regexp.MustCompile(`(^|&)step=\d+`)
+
+func withoutStep(cacheKey string) string {
+	return stepKeyPart.ReplaceAllString(cacheKey, "")
+}
+
+// cacheKeyLister returns the configured cache if its keys can be listed
+func (aH *APIHandler) cacheKeyLister() (cache.KeyLister, *model.ApiError) {
+	if aH.cache == nil {
+		return nil, &model.ApiError{Typ: model.ErrorBadData, Err: fmt.Errorf("query cache is not configured")}
+	}
+	lister, ok := aH.cache.(cache.KeyLister)
+	if !ok {
+		return nil, &model.ApiError{Typ: model.ErrorNotImplemented, Err: fmt.Errorf("the keys of the configured cache can not be listed")}
+	}
+	return lister, nil
+}
+
+func (aH *APIHandler) listCacheEntries(w http.ResponseWriter, r *http.Request) {
+	lister, apiErr := aH.cacheKeyLister()
+	if apiErr != nil {
+		RespondError(w, apiErr, nil)
+		return
+	}
+
+	keys, err := lister.Keys(r.URL.Query().Get("prefix"))
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorInternal, Err: err}, nil)
+		return
+	}
+	sort.Strings(keys)
+
+	qc := querycache.NewQueryCache(querycache.WithCache(aH.cache))
+	entries := make([]cacheEntry, 0, len(keys))
+	for _, key := range keys {
+		// the entries other than the query results, such as the async queries, are not listed
+		ranges, ok := qc.CachedRanges(key)
+		if !ok {
+			continue
+		}
+		entries = append(entries, cacheEntry{Key: key, Hash: querycache.KeyHash(key), Ranges: ranges})
+	}
+
+	aH.WriteJSON(w, r, entries)
+}
+
+func (aH *APIHandler) invalidateCache(w http.ResponseWriter, r *http.Request) {
+	req, err := parseInvalidateCacheRequest(r)
+	if aH.HandleError(w, err, http.StatusBadRequest) {
+		return
+	}
+
+	lister, apiErr := aH.cacheKeyLister()
+	if apiErr != nil {
+		RespondError(w, apiErr, nil)
+		return
+	}
+
+	var queryKeys *dashboardKeys
+	if req.DashboardID != "" {
+		dashboard, apiErr := dashboards.GetDashboard(r.Context(), req.DashboardID)
+		if apiErr != nil {
+			RespondError(w, apiErr, nil)
+			return
+		}
+		queryKeys = dashboardCacheKeys(dashboard.Data)
+	}
+
+	hashes := make(map[string]struct{}, len(req.Hashes))
+	for _, hash := range req.Hashes {
+		hashes[hash] = struct{}{}
+	}
+
+	keys, err := lister.Keys(req.Prefix)
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorInternal, Err: err}, nil)
+		return
+	}
+
+	qc := querycache.NewQueryCache(querycache.WithCache(aH.cache), querycache.WithFluxInterval(aH.fluxInterval))
+	invalidated := 0
+	for _, key := range keys {
+		if len(hashes) > 0 {
+			if _, ok := hashes[querycache.KeyHash(key)]; !ok {
+				continue
+			}
+		}
+		if queryKeys != nil {
+			if !queryKeys.matches(withoutStep(key)) {
+				continue
+			}
+		}
+		if _, ok := qc.CachedRanges(key); !ok {
+			continue
+		}
+
+		if req.Start > 0 || req.End > 0 {
+			if qc.InvalidateTimeRange(key, req.Start, req.End) {
+				invalidated++
+			}
+			continue
+		}
+		aH.cache.Remove(key)
+		invalidated++
+	}
+
+	zap.L().Info("invalidated cache entries", zap.Int("count", invalidated), zap.Any("request", req))
+	aH.WriteJSON(w, r, map[string]int{"invalidated": invalidated})
+}
+
+// variablePlaceholder replaces the dashboard variables in the widget queries, the
+// cache keys are matched with any value in its place
+const variablePlaceholder = "__signoz_dashboard_variable__"
+
+// dashboardKeys matches the cache keys, without the step, of the queries of the dashboard widgets.
+// The queries are cached with the values of the dashboard variables substituted, so the keys of the
+// queries with variables match the keys with any value of the variables
+type dashboardKeys struct {
+	keys     map[string]struct{}
+	patterns []*regexp.Regexp
+}
+
+func (d *dashboardKeys) add(key string) {
+	if !strings.Contains(key, variablePlaceholder) {
+		d.keys[key] = struct{}{}
+		return
+	}
+	parts := strings.Split(key, variablePlaceholder)
+	for idx := range parts {
+		parts[idx] = regexp.QuoteMeta(parts[idx])
+	}
+	d.patterns = append(d.patterns, regexp.MustCompile("^(?s)"+strings.Join(parts, ".*")+"$"))
+}
+
+// matches returns whether the cache key, without the step, is the key of a dashboard query
+func (d *dashboardKeys) matches(key string) bool {
+	if _, ok := d.keys[key]; ok {
+		return true
+	}
+	for _, pattern := range d.patterns {
+		if pattern.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
+
+// dashboardCacheKeys returns the cache keys of the queries of the dashboard widgets
+func dashboardCacheKeys(data map[string]interface{}) *dashboardKeys {
+	keys := &dashboardKeys{keys: make(map[string]struct{})}
+	widgets, ok := data["widgets"].([]interface{})
+	if !ok {
+		return keys
+	}
+	variables := dashboardVariables(data)
+
+	for _, widget := range widgets {
+		widgetData, ok := widget.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		query, ok := widgetData["query"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch query["queryType"] {
+		case string(v3.QueryTypeBuilder):
+			panelType := v3.PanelTypeGraph
+			if widgetData["panelTypes"] == string(v3.PanelTypeList) {
+				panelType = v3.PanelTypeList
+			}
+			for _, allSelected := range allSelections(widgetAllVariables(widgetBuilderQueries(query), variables)) {
+				for _, key := range widgetCacheKeys(widgetBuilderQueries(query), panelType, variables, allSelected) {
+					keys.add(key)
+				}
+			}
+		case string(v3.QueryTypePromQL):
+			promQueries, ok := query["promql"].([]interface{})
+			if !ok {
+				continue
+			}
+			for _, promQuery := range promQueries {
+				promQueryData, ok := promQuery.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if queryString, ok := promQueryData["query"].(string); ok && queryString != "" {
+					keys.add(promQLWithVariablePlaceholders(queryString, variables))
+				}
+			}
+		}
+	}
+	return keys
+}
+
+// widgetCacheKeys returns the cache keys, without the step, of the builder queries of a widget. The
+// queries are prepared by the query range params parsing, the same as the queries of the dashboard
+// requests, so that the shift of the time shift function and the substituted variables are in the keys
+func widgetCacheKeys(builderQueries map[string]*v3.BuilderQuery, panelType v3.PanelType, variables map[string]bool, allSelected map[string]struct{}) []string {
+	if len(builderQueries) == 0 {
+		return nil
+	}
+	// the filter items of the variables with all the values selected are not sent by the dashboard
+	for _, builderQuery := range builderQueries {
+		builderQuery.Filters = withoutVariableItems(builderQuery.Filters, allSelected)
+	}
+
+	placeholders := make(map[string]interface{}, len(variables))
+	for name := range variables {
+		placeholders[name] = variablePlaceholder
+	}
+	end := time.Now().UnixMilli()
+	params, apiErr := prepareQueryRangeParams(&v3.QueryRangeParamsV3{
+		Start:     end - time.Hour.Milliseconds(),
+		End:       end,
+		Version:   "v4",
+		Variables: placeholders,
+		CompositeQuery: &v3.CompositeQuery{
+			QueryType:      v3.QueryTypeBuilder,
+			PanelType:      panelType,
+			BuilderQueries: builderQueries,
+		},
+	})
+	if apiErr != nil {
+		zap.L().Debug("error preparing the dashboard widget query", zap.Error(apiErr.Err))
+		return nil
+	}
+
+	var keys []string
+	if panelType == v3.PanelTypeList {
+		for _, builderQuery := range params.CompositeQuery.BuilderQueries {
+			keys = append(keys, queryBuilder.ListCacheKey(builderQuery))
+		}
+		return keys
+	}
+	for _, key := range queryBuilder.NewKeyGenerator().GenerateKeys(params) {
+		keys = append(keys, withoutStep(key))
+	}
+	return keys
+}
+
+// maxAllVariables is the number of the variables of a widget whose all option is matched, the
+// widget keys are generated for every combination of them
+const maxAllVariables = 5
+
+// allSelections returns every combination of the variables with all the values selected
+func allSelections(names []string) []map[string]struct{} {
+	if len(names) > maxAllVariables {
+		names = names[:maxAllVariables]
+	}
+	selections := make([]map[string]struct{}, 0, 1<<len(names))
+	for mask := 0; mask < 1<<len(names); mask++ {
+		selection := make(map[string]struct{})
+		for idx, name := range names {
+			if mask&(1<<idx) != 0 {
+				selection[name] = struct{}{}
+			}
+		}
+		selections = append(selections, selection)
+	}
+	return selections
+}
+
+// widgetAllVariables returns the sorted names of the variables with the all option
+// that are used by the filters of the builder queries
+func widgetAllVariables(builderQueries map[string]*v3.BuilderQuery, variables map[string]bool) []string {
+	used := make(map[string]struct{})
+	for _, builderQuery := range builderQueries {
+		_ = builderQuery.Filters.ForEachItem(func(item *v3.FilterItem) error {
+			if name, ok := filterVariable(item.Value); ok && variables[name] {
+				used[name] = struct{}{}
+			}
+			return nil
+		})
+	}
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// withoutVariableItems returns the filters without the items of the given variables,
+// the groups left without items are removed as well
+func withoutVariableItems(filters *v3.FilterSet, names map[string]struct{}) *v3.FilterSet {
+	if filters == nil || len(names) == 0 {
+		return filters
+	}
+	items := make([]v3.FilterItem, 0, len(filters.Items))
+	for _, item := range filters.Items {
+		if name, ok := filterVariable(item.Value); ok {
+			if _, ok := names[name]; ok {
+				continue
+			}
+		}
+		items = append(items, item)
+	}
+	groups := make([]*v3.FilterSet, 0, len(filters.Groups))
+	for _, group := range filters.Groups {
+		group = withoutVariableItems(group, names)
+		if group == nil || (len(group.Items) == 0 && len(group.Groups) == 0) {
+			continue
+		}
+		groups = append(groups, group)
+	}
+	result := *filters
+	result.Items = items
+	result.Groups = groups
+	if len(groups) == 0 {
+		result.Groups = nil
+	}
+	return &result
+}
+
+// filterVariable returns the name of the variable referenced by the value of a filter item,
+// the same way the query range params parsing substitutes it
+func filterVariable(value interface{}) (string, bool) {
+	switch x := value.(type) {
+	case string:
+		return strings.Trim(x, "{[.$]}"), true
+	case []interface{}:
+		if len(x) > 0 {
+			if first, ok := x[0].(string); ok {
+				return strings.Trim(first, "{[.$]}"), true
+			}
+		}
+	}
+	return "", false
+}
+
+// dashboardVariables returns the names of the variables of the dashboard and
+// whether the all option can be selected for them
+func dashboardVariables(data map[string]interface{}) map[string]bool {
+	names := make(map[string]bool)
+	variables, ok := data["variables"].(map[string]interface{})
+	if !ok {
+		return names
+	}
+	for _, variable := range variables {
+		variableData, ok := variable.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := variableData["name"].(string)
+		if !ok || name == "" {
+			continue
+		}
+		showAll, _ := variableData["showALLOption"].(bool)
+		allSelected, _ := variableData["allSelected"].(bool)
+		names[name] = showAll || allSelected
+	}
+	return names
+}
+
+// promQLWithVariablePlaceholders replaces the references of the dashboard variables in the promql query
+func promQLWithVariablePlaceholders(query string, variables map[string]bool) string {
+	for name := range variables {
+		quotedName := regexp.QuoteMeta(name)
+		reference := regexp.MustCompile(`\{\{\s*\.?` + quotedName + `\s*\}\}|\[\[` + quotedName + `\]\]|\$` + quotedName + `\b`)
+		query = reference.ReplaceAllLiteralString(query, variablePlaceholder)
+	}
+	return query
+}
+
+// widgetBuilderQueries returns the builder queries of the widget query, the queries
+// that can not be parsed are skipped
+func widgetBuilderQueries(query map[string]interface{}) map[string]*v3.BuilderQuery {
+	builderQueries := make(map[string]*v3.BuilderQuery)
+	builder, ok := query["builder"].(map[string]interface{})
+	if !ok {
+		return builderQueries
+	}
+	queryData, ok := builder["queryData"].([]interface{})
+	if !ok {
+		return builderQueries
+	}
+	for _, item := range queryData {
+		itemJSON, err := json.Marshal(item)
+		if err != nil {
+			continue
+		}
+		var builderQuery v3.BuilderQuery
+		if err := json.Unmarshal(itemJSON, &builderQuery); err != nil {
+			zap.L().Debug("error parsing the dashboard widget query", zap.Error(err))
+			continue
+		}
+		if builderQuery.QueryName == "" {
+			continue
+		}
+		builderQueries[builderQuery.QueryName] = &builderQuery
+	}
+	return builderQueries
+}
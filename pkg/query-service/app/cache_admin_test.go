@@ -0,0 +1,271 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.signoz.io/signoz/pkg/query-service/app/queryBuilder"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+func TestDashboardCacheKeys(t *testing.T) {
+	dashboardJSON := `{
+		"widgets": [
+			{
+				"panelTypes": "graph",
+				"query": {
+					"queryType": "builder",
+					"builder": {
+						"queryData": [
+							{
+								"queryName": "A",
+								"expression": "A",
+								"dataSource": "metrics",
+								"aggregateOperator": "rate",
+								"aggregateAttribute": {"key": "signoz_calls_total", "dataType": "float64", "type": "Sum", "isColumn": true},
+								"timeAggregation": "rate",
+								"spaceAggregation": "sum",
+								"filters": {"op": "AND", "items": []},
+								"groupBy": [{"key": "service_name", "dataType": "string", "type": "tag", "isColumn": false}],
+								"stepInterval": 60
+							}
+						]
+					}
+				}
+			},
+			{
+				"panelTypes": "list",
+				"query": {
+					"queryType": "builder",
+					"builder": {
+						"queryData": [
+							{
+								"queryName": "A",
+								"expression": "A",
+								"dataSource": "logs",
+								"aggregateOperator": "noop",
+								"filters": {"op": "AND", "items": []},
+								"orderBy": [{"columnName": "timestamp", "order": "desc"}],
+								"pageSize": 10
+							}
+						]
+					}
+				}
+			},
+			{
+				"panelTypes": "graph",
+				"query": {
+					"queryType": "promql",
+					"promql": [{"name": "A", "query": "up"}]
+				}
+			}
+		]
+	}`
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(dashboardJSON), &data))
+	keys := dashboardCacheKeys(data)
+
+	// the keys of the dashboard queries match regardless of the step of the time range
+	metricsQuery := &v3.BuilderQuery{
+		QueryName:          "A",
+		Expression:         "A",
+		DataSource:         v3.DataSourceMetrics,
+		AggregateOperator:  v3.AggregateOperatorRate,
+		AggregateAttribute: v3.AttributeKey{Key: "signoz_calls_total", DataType: v3.AttributeKeyDataTypeFloat64, Type: "Sum", IsColumn: true},
+		TimeAggregation:    v3.TimeAggregationRate,
+		SpaceAggregation:   v3.SpaceAggregationSum,
+		Filters:            &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{}},
+		GroupBy:            []v3.AttributeKey{{Key: "service_name", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}},
+		StepInterval:       300,
+	}
+	params := &v3.QueryRangeParamsV3{
+		Version: "v4",
+		CompositeQuery: &v3.CompositeQuery{
+			QueryType:      v3.QueryTypeBuilder,
+			PanelType:      v3.PanelTypeGraph,
+			BuilderQueries: map[string]*v3.BuilderQuery{"A": metricsQuery},
+		},
+	}
+	metricsKey := queryBuilder.NewKeyGenerator().GenerateKeys(params)["A"]
+	require.NotEmpty(t, metricsKey)
+	assert.True(t, keys.matches(withoutStep(metricsKey)))
+
+	logsQuery := &v3.BuilderQuery{
+		QueryName:         "A",
+		Expression:        "A",
+		DataSource:        v3.DataSourceLogs,
+		AggregateOperator: v3.AggregateOperatorNoOp,
+		Filters:           &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{}},
+		OrderBy:           []v3.OrderBy{{ColumnName: "timestamp", Order: "desc"}},
+	}
+	assert.True(t, keys.matches(queryBuilder.ListCacheKey(logsQuery)))
+
+	assert.True(t, keys.matches("up"))
+	assert.Len(t, keys.keys, 3)
+	assert.Empty(t, keys.patterns)
+}
+
+func TestDashboardCacheKeysWithVariables(t *testing.T) {
+	dashboardJSON := `{
+		"variables": {
+			"b2c7a0b8-4d6c-4e0e-9a51-4f1b0d5c8d1e": {"name": "service", "multiSelect": true},
+			"6f0c6c3e-2a5b-4c43-8f5b-7a4e8d2c1b3f": {"name": "env"}
+		},
+		"widgets": [
+			{
+				"panelTypes": "graph",
+				"query": {
+					"queryType": "builder",
+					"builder": {
+						"queryData": [
+							{
+								"queryName": "A",
+								"expression": "A",
+								"dataSource": "metrics",
+								"aggregateOperator": "rate",
+								"aggregateAttribute": {"key": "signoz_calls_total", "dataType": "float64", "type": "Sum", "isColumn": true},
+								"timeAggregation": "rate",
+								"spaceAggregation": "sum",
+								"filters": {"op": "AND", "items": [
+									{"key": {"key": "service_name", "dataType": "string", "type": "tag"}, "op": "in", "value": ["{{.service}}"]},
+									{"key": {"key": "deployment_environment", "dataType": "string", "type": "tag"}, "op": "=", "value": "$env"}
+								]},
+								"stepInterval": 60
+							}
+						]
+					}
+				}
+			},
+			{
+				"panelTypes": "graph",
+				"query": {
+					"queryType": "promql",
+					"promql": [{"name": "A", "query": "sum(rate(signoz_calls_total{service_name=~\"{{.service}}\"}[5m]))"}]
+				}
+			}
+		]
+	}`
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(dashboardJSON), &data))
+	keys := dashboardCacheKeys(data)
+
+	// the query as it is cached after the query range params substituted the variables
+	metricsQuery := func(services []interface{}, env string) *v3.BuilderQuery {
+		return &v3.BuilderQuery{
+			QueryName:          "A",
+			Expression:         "A",
+			DataSource:         v3.DataSourceMetrics,
+			AggregateOperator:  v3.AggregateOperatorRate,
+			AggregateAttribute: v3.AttributeKey{Key: "signoz_calls_total", DataType: v3.AttributeKeyDataTypeFloat64, Type: "Sum", IsColumn: true},
+			TimeAggregation:    v3.TimeAggregationRate,
+			SpaceAggregation:   v3.SpaceAggregationSum,
+			Filters: &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{
+				{Key: v3.AttributeKey{Key: "service_name", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}, Operator: "in", Value: services},
+				{Key: v3.AttributeKey{Key: "deployment_environment", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}, Operator: "=", Value: env},
+			}},
+			StepInterval: 120,
+		}
+	}
+	metricsKey := func(query *v3.BuilderQuery) string {
+		params := &v3.QueryRangeParamsV3{
+			Version: "v4",
+			CompositeQuery: &v3.CompositeQuery{
+				QueryType:      v3.QueryTypeBuilder,
+				PanelType:      v3.PanelTypeGraph,
+				BuilderQueries: map[string]*v3.BuilderQuery{"A": query},
+			},
+		}
+		return withoutStep(queryBuilder.NewKeyGenerator().GenerateKeys(params)["A"])
+	}
+
+	assert.True(t, keys.matches(metricsKey(metricsQuery([]interface{}{"frontend", "checkout"}, "production"))))
+	assert.True(t, keys.matches(metricsKey(metricsQuery([]interface{}{"redis"}, "staging"))))
+
+	// the parts of the query other than the variables have to match
+	otherQuery := metricsQuery([]interface{}{"frontend"}, "production")
+	otherQuery.SpaceAggregation = v3.SpaceAggregationMax
+	assert.False(t, keys.matches(metricsKey(otherQuery)))
+
+	assert.True(t, keys.matches(`sum(rate(signoz_calls_total{service_name=~"frontend|checkout"}[5m]))`))
+	assert.False(t, keys.matches(`sum(rate(signoz_calls_total{service_name=~"frontend"}[1m]))`))
+	assert.Empty(t, keys.keys)
+	assert.Len(t, keys.patterns, 2)
+}
+
+func TestDashboardCacheKeysShiftAndAllVariables(t *testing.T) {
+	dashboardJSON := `{
+		"variables": {
+			"b2c7a0b8-4d6c-4e0e-9a51-4f1b0d5c8d1e": {"name": "service", "multiSelect": true, "showALLOption": true},
+			"6f0c6c3e-2a5b-4c43-8f5b-7a4e8d2c1b3f": {"name": "env"}
+		},
+		"widgets": [
+			{
+				"panelTypes": "graph",
+				"query": {
+					"queryType": "builder",
+					"builder": {
+						"queryData": [
+							{
+								"queryName": "A",
+								"expression": "A",
+								"dataSource": "metrics",
+								"aggregateOperator": "rate",
+								"aggregateAttribute": {"key": "signoz_calls_total", "dataType": "float64", "type": "Sum", "isColumn": true},
+								"timeAggregation": "rate",
+								"spaceAggregation": "sum",
+								"filters": {"op": "AND", "items": [
+									{"key": {"key": "service_name", "dataType": "string", "type": "tag"}, "op": "in", "value": ["{{.service}}"]},
+									{"key": {"key": "deployment_environment", "dataType": "string", "type": "tag"}, "op": "=", "value": "$env"}
+								]},
+								"functions": [{"name": "timeShift", "args": [86400]}],
+								"stepInterval": 60
+							}
+						]
+					}
+				}
+			}
+		]
+	}`
+	var data map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(dashboardJSON), &data))
+	keys := dashboardCacheKeys(data)
+
+	serviceItem := v3.FilterItem{Key: v3.AttributeKey{Key: "service_name", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}, Operator: "in", Value: []interface{}{"frontend"}}
+	envItem := v3.FilterItem{Key: v3.AttributeKey{Key: "deployment_environment", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}, Operator: "=", Value: "production"}
+	metricsKey := func(shiftBy int64, items ...v3.FilterItem) string {
+		query := &v3.BuilderQuery{
+			QueryName:          "A",
+			Expression:         "A",
+			DataSource:         v3.DataSourceMetrics,
+			AggregateOperator:  v3.AggregateOperatorRate,
+			AggregateAttribute: v3.AttributeKey{Key: "signoz_calls_total", DataType: v3.AttributeKeyDataTypeFloat64, Type: "Sum", IsColumn: true},
+			TimeAggregation:    v3.TimeAggregationRate,
+			SpaceAggregation:   v3.SpaceAggregationSum,
+			Filters:            &v3.FilterSet{Operator: "AND", Items: items},
+			Functions:          []v3.Function{{Name: v3.FunctionNameTimeShift, Args: []interface{}{float64(86400)}}},
+			StepInterval:       120,
+			ShiftBy:            shiftBy,
+		}
+		params := &v3.QueryRangeParamsV3{
+			Version: "v4",
+			CompositeQuery: &v3.CompositeQuery{
+				QueryType:      v3.QueryTypeBuilder,
+				PanelType:      v3.PanelTypeGraph,
+				BuilderQueries: map[string]*v3.BuilderQuery{"A": query},
+			},
+		}
+		return withoutStep(queryBuilder.NewKeyGenerator().GenerateKeys(params)["A"])
+	}
+
+	// the shift of the time shift function is a part of the key
+	assert.True(t, keys.matches(metricsKey(86400, serviceItem, envItem)))
+	assert.False(t, keys.matches(metricsKey(0, serviceItem, envItem)))
+
+	// the filter item of a variable with all the values selected is not sent by the dashboard
+	assert.True(t, keys.matches(metricsKey(86400, envItem)))
+	// the variable without the all option is always sent
+	assert.False(t, keys.matches(metricsKey(86400, serviceItem)))
+	assert.Len(t, keys.patterns, 2)
+}
@@ -60,6 +60,17 @@ func (tracker *inMemoryQueryProgressTracker) SubscribeToQueryProgress(
 	return queryTracker.subscribe()
 }
 
+func (tracker *inMemoryQueryProgressTracker) GetQueryProgress(
+	queryId string,
+) (*model.QueryProgress, *model.ApiError) {
+	queryTracker, err := tracker.getQueryTracker(queryId)
+	if err != nil {
+		return nil, err
+	}
+
+	return queryTracker.getProgress(), nil
+}
+
 func (tracker *inMemoryQueryProgressTracker) onQueryFinished(
 	queryId string,
 ) {
@@ -133,6 +144,18 @@ func (qt *queryTracker) handleProgressUpdate(p *clickhouse.Progress) {
 	}
 }
 
+// returns a copy of the latest progress state, zero valued if no update was received yet
+func (qt *queryTracker) getProgress() *model.QueryProgress {
+	qt.lock.Lock()
+	defer qt.lock.Unlock()
+
+	progress := model.QueryProgress{}
+	if qt.progress != nil {
+		progress = *qt.progress
+	}
+	return &progress
+}
+
 func (qt *queryTracker) subscribe() (
 	<-chan model.QueryProgress, func(), *model.ApiError,
 ) {
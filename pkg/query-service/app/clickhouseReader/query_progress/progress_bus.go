@@ -0,0 +1,81 @@
+package queryprogress
+
+import (
+	"context"
+	"encoding/json"
+
+	goredis "github.com/go-redis/redis/v8"
+	"go.signoz.io/signoz/pkg/query-service/model"
+	"go.uber.org/zap"
+)
+
+const queryProgressChannelPrefix = "query_progress_updates:"
+
+// message broadcast to the subscribers of a query progress
+type progressMessage struct {
+	Progress *model.QueryProgress `json:"progress,omitempty"`
+	// number of the progress updates received, used to order the messages
+	Updates  int64 `json:"updates,omitempty"`
+	Finished bool  `json:"finished,omitempty"`
+}
+
+// broadcasts the progress messages of the queries to the replicas
+type progressBus interface {
+	publish(queryId string, msg progressMessage) error
+	// calls onMessage with the progress messages of the query until unsubscribed
+	subscribe(queryId string, onMessage func(msg progressMessage)) (unsubscribe func(), err error)
+}
+
+// broadcasts the progress messages over a redis channel per query
+type redisProgressBus struct {
+	client *goredis.Client
+}
+
+func newRedisProgressBus(client *goredis.Client) *redisProgressBus {
+	return &redisProgressBus{client: client}
+}
+
+func queryProgressChannel(queryId string) string {
+	return queryProgressChannelPrefix + queryId
+}
+
+func (bus *redisProgressBus) publish(queryId string, msg progressMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return bus.client.Publish(context.Background(), queryProgressChannel(queryId), payload).Err()
+}
+
+func (bus *redisProgressBus) subscribe(
+	queryId string, onMessage func(msg progressMessage),
+) (func(), error) {
+	ctx := context.Background()
+	pubsub := bus.client.Subscribe(ctx, queryProgressChannel(queryId))
+	// wait for the subscription to be confirmed so that no message published afterwards is missed
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	messages := pubsub.Channel()
+	go func() {
+		for message := range messages {
+			var msg progressMessage
+			if err := json.Unmarshal([]byte(message.Payload), &msg); err != nil {
+				zap.L().Error(
+					"couldn't decode query progress message",
+					zap.String("queryId", queryId), zap.String("payload", message.Payload), zap.Error(err),
+				)
+				continue
+			}
+			onMessage(msg)
+		}
+	}()
+
+	return func() {
+		if err := pubsub.Close(); err != nil {
+			zap.L().Debug("couldn't close query progress subscription", zap.String("queryId", queryId), zap.Error(err))
+		}
+	}, nil
+}
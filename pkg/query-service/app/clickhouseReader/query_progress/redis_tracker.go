@@ -0,0 +1,245 @@
+package queryprogress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	goredis "github.com/go-redis/redis/v8"
+	"go.signoz.io/signoz/pkg/query-service/cache/redis"
+	"go.signoz.io/signoz/pkg/query-service/model"
+	"go.uber.org/zap"
+)
+
+const (
+	queryProgressKeyPrefix = "query_progress:"
+
+	// queries running for longer are not tracked anymore, the progress of the queries
+	// of the replicas that went away without reporting them finished expires too
+	queryProgressTTL = 1 * time.Hour
+
+	fieldReadRows  = "read_rows"
+	fieldReadBytes = "read_bytes"
+	fieldElapsedMs = "elapsed_ms"
+	fieldUpdates   = "updates"
+)
+
+// reportProgressScript adds the progress to the query progress if the query is still tracked, in
+// one step so that a progress reported after the query finished doesn't track the query again.
+// It returns the totals of read_rows, read_bytes, elapsed_ms and updates, nil for a query not tracked
+var reportProgressScript = goredis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return nil
+end
+return {
+	redis.call("HINCRBY", KEYS[1], "` + fieldReadRows + `", ARGV[1]),
+	redis.call("HINCRBY", KEYS[1], "` + fieldReadBytes + `", ARGV[2]),
+	redis.call("HINCRBY", KEYS[1], "` + fieldElapsedMs + `", ARGV[3]),
+	redis.call("HINCRBY", KEYS[1], "` + fieldUpdates + `", 1)
+}
+`)
+
+// startQueryScript starts tracking the query with its expiry in one step, so that a query
+// started is never tracked without expiring. It returns 1 if the query is started, 0 if the
+// query is already tracked
+var startQueryScript = goredis.NewScript(`
+if redis.call("HSETNX", KEYS[1], "` + fieldUpdates + `", 0) == 0 then
+	return 0
+end
+redis.call("PEXPIRE", KEYS[1], ARGV[1])
+return 1
+`)
+
+// tracks progress of the queries in redis and broadcasts it to the subscribers of
+// all the replicas, so that the progress can be followed from any replica
+type redisQueryProgressTracker struct {
+	client *goredis.Client
+	bus    progressBus
+}
+
+func NewRedisQueryProgressTracker(opts *redis.Options) QueryProgressTracker {
+	client := redis.NewClient(opts)
+	return newRedisQueryProgressTracker(client, newRedisProgressBus(client))
+}
+
+func newRedisQueryProgressTracker(client *goredis.Client, bus progressBus) *redisQueryProgressTracker {
+	return &redisQueryProgressTracker{
+		client: client,
+		bus:    bus,
+	}
+}
+
+func queryProgressKey(queryId string) string {
+	return queryProgressKeyPrefix + queryId
+}
+
+func (tracker *redisQueryProgressTracker) ReportQueryStarted(
+	queryId string,
+) (postQueryCleanup func(), err *model.ApiError) {
+	ctx := context.Background()
+	key := queryProgressKey(queryId)
+
+	created, redisErr := startQueryScript.Run(
+		ctx, tracker.client, []string{key}, queryProgressTTL.Milliseconds(),
+	).Int64()
+	if redisErr != nil {
+		return nil, model.InternalError(fmt.Errorf(
+			"couldn't start tracking query %s: %w", queryId, redisErr,
+		))
+	}
+	if created == 0 {
+		return nil, model.BadRequest(fmt.Errorf(
+			"query %s already started", queryId,
+		))
+	}
+
+	return func() {
+		tracker.onQueryFinished(queryId)
+	}, nil
+}
+
+func (tracker *redisQueryProgressTracker) ReportQueryProgress(
+	queryId string, chProgress *clickhouse.Progress,
+) *model.ApiError {
+	ctx := context.Background()
+	key := queryProgressKey(queryId)
+
+	totals, redisErr := reportProgressScript.Run(
+		ctx, tracker.client, []string{key},
+		int64(chProgress.Rows), int64(chProgress.Bytes), chProgress.Elapsed.Milliseconds(),
+	).Int64Slice()
+	if errors.Is(redisErr, goredis.Nil) {
+		return model.NotFoundError(fmt.Errorf(
+			"query %s doesn't exist", queryId,
+		))
+	}
+	if redisErr != nil {
+		return model.InternalError(redisErr)
+	}
+	if len(totals) != 4 {
+		return model.InternalError(fmt.Errorf(
+			"unexpected query %s progress totals %v", queryId, totals,
+		))
+	}
+
+	// broadcast latest state to all subscribers.
+	msg := progressMessage{
+		Progress: &model.QueryProgress{
+			ReadRows:  uint64(totals[0]),
+			ReadBytes: uint64(totals[1]),
+			ElapsedMs: uint64(totals[2]),
+		},
+		Updates: totals[3],
+	}
+	if err := tracker.bus.publish(queryId, msg); err != nil {
+		zap.L().Error("couldn't publish query progress", zap.String("queryId", queryId), zap.Error(err))
+	}
+	return nil
+}
+
+func (tracker *redisQueryProgressTracker) SubscribeToQueryProgress(
+	queryId string,
+) (<-chan model.QueryProgress, func(), *model.ApiError) {
+	subscription := newQueryProgressSubscription()
+
+	// updates older than the latest state sent are skipped, they might be
+	// received after the state read below
+	var lock sync.Mutex
+	var lastUpdates int64
+	var isClosed bool
+	closeSubscription := func() {
+		lock.Lock()
+		defer lock.Unlock()
+		isClosed = true
+		subscription.close()
+	}
+
+	unsubscribe, err := tracker.bus.subscribe(queryId, func(msg progressMessage) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		if isClosed {
+			return
+		}
+		if msg.Finished {
+			isClosed = true
+			subscription.close()
+			return
+		}
+		if msg.Progress == nil || msg.Updates <= lastUpdates {
+			return
+		}
+		lastUpdates = msg.Updates
+		subscription.send(*msg.Progress)
+	})
+	if err != nil {
+		return nil, nil, model.InternalError(fmt.Errorf(
+			"couldn't subscribe to query %s progress: %w", queryId, err,
+		))
+	}
+
+	// the state is read after subscribing so that no update is missed in between
+	progress, updates, apiErr := tracker.getProgress(queryId)
+	if apiErr != nil {
+		unsubscribe()
+		return nil, nil, apiErr
+	}
+
+	lock.Lock()
+	if !isClosed && updates > lastUpdates {
+		lastUpdates = updates
+		subscription.send(*progress)
+	}
+	lock.Unlock()
+
+	return subscription.ch, func() {
+		unsubscribe()
+		closeSubscription()
+	}, nil
+}
+
+func (tracker *redisQueryProgressTracker) GetQueryProgress(
+	queryId string,
+) (*model.QueryProgress, *model.ApiError) {
+	progress, _, err := tracker.getProgress(queryId)
+	return progress, err
+}
+
+// returns the latest progress state and the number of updates received
+func (tracker *redisQueryProgressTracker) getProgress(
+	queryId string,
+) (*model.QueryProgress, int64, *model.ApiError) {
+	fields, err := tracker.client.HGetAll(context.Background(), queryProgressKey(queryId)).Result()
+	if err != nil {
+		return nil, 0, model.InternalError(err)
+	}
+	if len(fields) == 0 {
+		return nil, 0, model.NotFoundError(fmt.Errorf(
+			"query %s doesn't exist", queryId,
+		))
+	}
+
+	field := func(name string) uint64 {
+		value, _ := strconv.ParseUint(fields[name], 10, 64)
+		return value
+	}
+	progress := &model.QueryProgress{
+		ReadRows:  field(fieldReadRows),
+		ReadBytes: field(fieldReadBytes),
+		ElapsedMs: field(fieldElapsedMs),
+	}
+	return progress, int64(field(fieldUpdates)), nil
+}
+
+func (tracker *redisQueryProgressTracker) onQueryFinished(queryId string) {
+	if err := tracker.client.Del(context.Background(), queryProgressKey(queryId)).Err(); err != nil {
+		zap.L().Error("couldn't remove query progress", zap.String("queryId", queryId), zap.Error(err))
+	}
+	if err := tracker.bus.publish(queryId, progressMessage{Finished: true}); err != nil {
+		zap.L().Error("couldn't publish query finish", zap.String("queryId", queryId), zap.Error(err))
+	}
+}
@@ -0,0 +1,153 @@
+package queryprogress
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/require"
+	"go.signoz.io/signoz/pkg/query-service/model"
+)
+
+// delivers the progress messages to the subscribers in memory, as redis pub/sub would
+type fakeProgressBus struct {
+	lock        sync.Mutex
+	subscribers map[string]map[int]func(msg progressMessage)
+	nextId      int
+}
+
+func newFakeProgressBus() *fakeProgressBus {
+	return &fakeProgressBus{subscribers: map[string]map[int]func(msg progressMessage){}}
+}
+
+func (bus *fakeProgressBus) publish(queryId string, msg progressMessage) error {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	for _, onMessage := range bus.subscribers[queryId] {
+		onMessage(msg)
+	}
+	return nil
+}
+
+func (bus *fakeProgressBus) subscribe(queryId string, onMessage func(msg progressMessage)) (func(), error) {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	if bus.subscribers[queryId] == nil {
+		bus.subscribers[queryId] = map[int]func(msg progressMessage){}
+	}
+	id := bus.nextId
+	bus.nextId++
+	bus.subscribers[queryId][id] = onMessage
+	return func() {
+		bus.lock.Lock()
+		defer bus.lock.Unlock()
+		delete(bus.subscribers[queryId], id)
+	}, nil
+}
+
+func TestRedisQueryProgressTracking(t *testing.T) {
+	require := require.New(t)
+
+	db, mock := redismock.NewClientMock()
+	bus := newFakeProgressBus()
+	// the trackers of two replicas sharing the redis server
+	runningReplica := newRedisQueryProgressTracker(db, bus)
+	otherReplica := newRedisQueryProgressTracker(db, bus)
+
+	testQueryId := "test-query"
+	key := queryProgressKey(testQueryId)
+
+	mock.ExpectEvalSha(reportProgressScript.Hash(), []string{key}, int64(0), int64(0), int64(0)).RedisNil()
+	err := runningReplica.ReportQueryProgress(testQueryId, &clickhouse.Progress{})
+	require.NotNil(err, "shouldn't be able to report query progress before query has been started")
+	require.Equal(model.ErrorNotFound, err.Type())
+
+	mock.ExpectHGetAll(key).SetVal(map[string]string{})
+	ch, unsubscribe, err := otherReplica.SubscribeToQueryProgress(testQueryId)
+	require.NotNil(err, "shouldn't be able to subscribe for progress updates before query has been started")
+	require.Equal(model.ErrorNotFound, err.Type())
+	require.Nil(ch)
+	require.Nil(unsubscribe)
+
+	mock.ExpectEvalSha(startQueryScript.Hash(), []string{key}, queryProgressTTL.Milliseconds()).SetVal(int64(1))
+	reportQueryFinished, err := runningReplica.ReportQueryStarted(testQueryId)
+	require.Nil(err, "should be able to report start of a query to be tracked")
+
+	mock.ExpectEvalSha(startQueryScript.Hash(), []string{key}, queryProgressTTL.Milliseconds()).SetVal(int64(0))
+	_, err = otherReplica.ReportQueryStarted(testQueryId)
+	require.NotNil(err, "shouldn't be able to start a query already started by another replica")
+	require.Equal(model.ErrorBadData, err.Type())
+
+	testProgress1 := &clickhouse.Progress{Rows: 10, Bytes: 20, TotalRows: 100, Elapsed: 20 * time.Millisecond}
+	mock.ExpectEvalSha(reportProgressScript.Hash(), []string{key}, int64(10), int64(20), int64(20)).
+		SetVal([]interface{}{int64(10), int64(20), int64(20), int64(1)})
+	err = runningReplica.ReportQueryProgress(testQueryId, testProgress1)
+	require.Nil(err, "should be able to report progress after query has started")
+
+	// the other replica subscribes to the progress of the query
+	mock.ExpectHGetAll(key).SetVal(map[string]string{
+		fieldReadRows: "10", fieldReadBytes: "20", fieldElapsedMs: "20", fieldUpdates: "1",
+	})
+	ch, unsubscribe, err = otherReplica.SubscribeToQueryProgress(testQueryId)
+	require.Nil(err, "should be able to subscribe to query progress updates from another replica")
+	require.NotNil(ch)
+	require.NotNil(unsubscribe)
+
+	expectedProgress := model.QueryProgress{}
+	updateQueryProgress(&expectedProgress, testProgress1)
+	select {
+	case qp := <-ch:
+		require.Equal(expectedProgress, qp)
+	default:
+		require.Fail("should receive latest query progress state immediately after subscription")
+	}
+
+	testProgress2 := &clickhouse.Progress{Rows: 20, Bytes: 40, TotalRows: 100, Elapsed: 40 * time.Millisecond}
+	mock.ExpectEvalSha(reportProgressScript.Hash(), []string{key}, int64(20), int64(40), int64(40)).
+		SetVal([]interface{}{int64(30), int64(60), int64(60), int64(2)})
+	err = runningReplica.ReportQueryProgress(testQueryId, testProgress2)
+	require.Nil(err, "should be able to report progress multiple times while query is in progress")
+
+	updateQueryProgress(&expectedProgress, testProgress2)
+	select {
+	case qp := <-ch:
+		require.Equal(expectedProgress, qp)
+	default:
+		require.Fail("should receive updates reported on another replica")
+	}
+
+	// a stale update is not sent after a newer state
+	require.NoError(bus.publish(testQueryId, progressMessage{Progress: &model.QueryProgress{ReadRows: 10}, Updates: 1}))
+	select {
+	case <-ch:
+		require.Fail("should have had no pending update at this point")
+	default:
+	}
+
+	mock.ExpectHGetAll(key).SetVal(map[string]string{
+		fieldReadRows: "30", fieldReadBytes: "60", fieldElapsedMs: "60", fieldUpdates: "2",
+	})
+	qp, err := otherReplica.GetQueryProgress(testQueryId)
+	require.Nil(err, "should be able to get query progress from another replica")
+	require.Equal(expectedProgress, *qp)
+
+	mock.ExpectDel(key).SetVal(1)
+	reportQueryFinished()
+	select {
+	case _, isSubscriptionChannelOpen := <-ch:
+		require.False(isSubscriptionChannelOpen, "subscription channels should get closed after query finishes")
+	default:
+		require.Fail("subscription channels should get closed after query finishes")
+	}
+	unsubscribe()
+
+	// the progress reported after the query finished doesn't track the query again
+	mock.ExpectEvalSha(reportProgressScript.Hash(), []string{key}, int64(10), int64(20), int64(20)).RedisNil()
+	err = runningReplica.ReportQueryProgress(testQueryId, testProgress1)
+	require.NotNil(err, "shouldn't be able to report query progress after query has finished")
+	require.Equal(model.ErrorNotFound, err.Type())
+
+	require.NoError(mock.ExpectationsWereMet())
+}
@@ -19,11 +19,16 @@ type QueryProgressTracker interface {
 	// the latest state of query progress stats. Also returns a function that
 	// can be called to unsubscribe before the query finishes, if needed.
 	SubscribeToQueryProgress(queryId string) (ch <-chan model.QueryProgress, unsubscribe func(), err *model.ApiError)
+
+	// Returns the latest state of query progress stats for `queryId`, useful for
+	// polling the progress of a query instead of subscribing to it.
+	GetQueryProgress(queryId string) (*model.QueryProgress, *model.ApiError)
 }
 
 func NewQueryProgressTracker() QueryProgressTracker {
 	// InMemory tracker is useful only for single replica query service setups.
-	// Multi replica setups must use a centralized store for tracking and subscribing to query progress
+	// Multi replica setups must use a centralized store for tracking and subscribing to query progress,
+	// see NewRedisQueryProgressTracker
 	return &inMemoryQueryProgressTracker{
 		queries: map[string]*queryTracker{},
 	}
@@ -81,6 +81,10 @@ func TestQueryProgressTracking(t *testing.T) {
 	default:
 	}
 
+	qp, err := tracker.GetQueryProgress(testQueryId)
+	require.Nil(err, "should be able to get query progress while query is in progress")
+	require.Equal(*qp, expectedProgress)
+
 	reportQueryFinished()
 	select {
 	case _, isSubscriptionChannelOpen := <-ch:
@@ -98,4 +102,9 @@ func TestQueryProgressTracking(t *testing.T) {
 	require.Equal(err.Type(), model.ErrorNotFound)
 	require.Nil(ch)
 	require.Nil(unsubscribe)
+
+	qp, err = tracker.GetQueryProgress(testQueryId)
+	require.NotNil(err, "shouldn't be able to get query progress after query has finished")
+	require.Equal(err.Type(), model.ErrorNotFound)
+	require.Nil(qp)
 }
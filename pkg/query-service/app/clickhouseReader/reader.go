@@ -3879,6 +3879,7 @@ func (r *ClickHouseReader) GetLogAggregateAttributes(ctx context.Context, req *v
 		v3.AggregateOperatorP90,
 		v3.AggregateOperatorP95,
 		v3.AggregateOperatorP99,
+		v3.AggregateOperatorPercentile,
 		v3.AggregateOperatorAvg,
 		v3.AggregateOperatorSum,
 		v3.AggregateOperatorMin,
@@ -4438,6 +4439,28 @@ func (r *ClickHouseReader) GetListResultV3(ctx context.Context, query string) ([
 
 }
 
+// ExplainQueryV3 returns the lines of the clickhouse EXPLAIN output of the query, the query is not run
+func (r *ClickHouseReader) ExplainQueryV3(ctx context.Context, query string) ([]string, error) {
+	defer utils.Elapsed("ExplainQueryV3", map[string]interface{}{"query": query})()
+
+	rows, err := r.db.Query(ctx, "EXPLAIN "+query)
+	if err != nil {
+		zap.L().Error("error while explaining query", zap.Error(err))
+		return nil, errors.New(err.Error())
+	}
+	defer rows.Close()
+
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
 func getPersonalisedError(err error) error {
 	if err == nil {
 		return nil
@@ -4450,6 +4473,10 @@ func getPersonalisedError(err error) error {
 	if strings.Contains(err.Error(), "code: 159") {
 		return chErrors.ErrResourceTimeLimitExceeded
 	}
+
+	if strings.Contains(err.Error(), "code: 158") {
+		return chErrors.ErrResourceRowsLimitExceeded
+	}
 	return err
 }
 
@@ -4535,6 +4562,7 @@ func (r *ClickHouseReader) GetTraceAggregateAttributes(ctx context.Context, req
 		v3.AggregateOperatorP90,
 		v3.AggregateOperatorP95,
 		v3.AggregateOperatorP99,
+		v3.AggregateOperatorPercentile,
 		v3.AggregateOperatorAvg,
 		v3.AggregateOperatorSum,
 		v3.AggregateOperatorMin,
@@ -5293,6 +5321,12 @@ func (r *ClickHouseReader) GetMinAndMaxTimestampForTraceID(ctx context.Context,
 	return minTime.UnixNano(), maxTime.UnixNano(), nil
 }
 
+// SetQueryProgressTracker replaces the in-memory query progress tracker, it must be
+// called before any query is run
+func (r *ClickHouseReader) SetQueryProgressTracker(tracker queryprogress.QueryProgressTracker) {
+	r.queryProgressTracker = tracker
+}
+
 func (r *ClickHouseReader) ReportQueryStartForProgressTracking(
 	queryId string,
 ) (func(), *model.ApiError) {
@@ -5304,3 +5338,9 @@ func (r *ClickHouseReader) SubscribeToQueryProgress(
 ) (<-chan model.QueryProgress, func(), *model.ApiError) {
 	return r.queryProgressTracker.SubscribeToQueryProgress(queryId)
 }
+
+func (r *ClickHouseReader) GetQueryProgress(
+	queryId string,
+) (*model.QueryProgress, *model.ApiError) {
+	return r.queryProgressTracker.GetQueryProgress(queryId)
+}
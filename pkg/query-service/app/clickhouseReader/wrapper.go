@@ -8,6 +8,7 @@ import (
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"go.signoz.io/signoz/pkg/query-service/common"
+	"go.signoz.io/signoz/pkg/query-service/querylimits"
 )
 
 type ClickhouseQuerySettings struct {
@@ -60,6 +61,13 @@ func (c clickhouseConnWrapper) addClickHouseSettings(ctx context.Context, query
 		settings["timeout_before_checking_execution_speed"] = c.settings.TimeoutBeforeCheckingExecutionSpeed
 	}
 
+	// the query limits of the user take precedence over the server settings
+	if limits, ok := querylimits.FromContext(ctx); ok {
+		for name, value := range limits.ClickHouseSettings() {
+			settings[name] = value
+		}
+	}
+
 	// only list queries of
 	if c.settings.OptimizeReadInOrderRegex != "" && c.settings.OptimizeReadInOrderRegexCompiled.Match([]byte(query)) {
 		settings["optimize_read_in_order"] = 0
@@ -0,0 +1,277 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	logsV3 "go.signoz.io/signoz/pkg/query-service/app/logs/v3"
+	chErrors "go.signoz.io/signoz/pkg/query-service/errors"
+	"go.signoz.io/signoz/pkg/query-service/interfaces"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.signoz.io/signoz/pkg/query-service/postprocess"
+)
+
+// Format is the format of the exported rows
+type Format string
+
+const (
+	FormatNDJSON  Format = "ndjson"
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+func (f Format) Validate() error {
+	switch f {
+	case FormatNDJSON, FormatCSV, FormatParquet:
+		return nil
+	default:
+		return fmt.Errorf("invalid export format %s, supported formats are ndjson, csv and parquet", f)
+	}
+}
+
+// ContentType returns the content type of the response with the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "application/x-ndjson"
+	}
+}
+
+const (
+	// MaxRows is the hard cap on the number of rows of an export
+	MaxRows = 1000000
+	// pageSize is the number of list rows fetched by each query, only a page of rows is held in memory
+	pageSize = 10000
+)
+
+// Export runs the list or table query of the params and writes at most maxRows rows to w in the
+// format. The list rows are fetched page by page and each page is flushed to w before the next
+// one is fetched. It returns the number of rows written. Nothing is written to w in the NDJSON and
+// CSV formats when no row is written, the Parquet file has the schema of the columns and no rows.
+func Export(ctx context.Context, querier interfaces.Querier, params *v3.QueryRangeParamsV3, format Format, maxRows uint64, w io.Writer) (uint64, error) {
+	if err := format.Validate(); err != nil {
+		return 0, err
+	}
+	if params.CompositeQuery == nil {
+		return 0, fmt.Errorf("composite query is required")
+	}
+	if maxRows == 0 || maxRows > MaxRows {
+		maxRows = MaxRows
+	}
+
+	rw := newRowWriter(format, w)
+	var written uint64
+	var columns []string
+	var err error
+	switch params.CompositeQuery.PanelType {
+	case v3.PanelTypeList:
+		written, columns, err = exportList(ctx, querier, params, rw, w, maxRows)
+	case v3.PanelTypeTable:
+		written, columns, err = exportTable(ctx, querier, params, rw, w, maxRows)
+	default:
+		return 0, fmt.Errorf("export is only supported for the list and table panels")
+	}
+	if err != nil {
+		return written, err
+	}
+	return written, flushWith(func() error { return rw.close(columns) }, w)
+}
+
+func flush(rw rowWriter, w io.Writer) error {
+	return flushWith(rw.flush, w)
+}
+
+// flushWith flushes the rows with the flush function of the row writer and then w
+func flushWith(flushRows func() error, w io.Writer) error {
+	if err := flushRows(); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// listRowColumns returns the timestamp followed by the sorted columns of the row
+func listRowColumns(row map[string]interface{}) []string {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		if column != "timestamp" {
+			columns = append(columns, column)
+		}
+	}
+	sort.Strings(columns)
+	return append([]string{"timestamp"}, columns...)
+}
+
+// queryError returns the error of the query that exceeds the resource limits of clickhouse, if any,
+// so that it is reported as such instead of the generic error of the queries
+func queryError(err error, errQueriesByName map[string]error) error {
+	for _, queryErr := range errQueriesByName {
+		if chErrors.IsResourceLimitError(queryErr) {
+			return queryErr
+		}
+	}
+	return err
+}
+
+// exportList writes the list rows of the query, it returns the number of rows written and their columns
+func exportList(ctx context.Context, querier interfaces.Querier, params *v3.QueryRangeParamsV3, rw rowWriter, w io.Writer, maxRows uint64) (uint64, []string, error) {
+	if params.CompositeQuery.QueryType != v3.QueryTypeBuilder || len(params.CompositeQuery.BuilderQueries) != 1 {
+		return 0, nil, fmt.Errorf("list export is only supported for a single builder query")
+	}
+	var queryName string
+	var query *v3.BuilderQuery
+	for name, builderQuery := range params.CompositeQuery.BuilderQueries {
+		queryName = name
+		query = builderQuery
+	}
+	if query.Limit > 0 && query.Limit < maxRows {
+		maxRows = query.Limit
+	}
+
+	// the logs ordered by timestamp are paginated with the id of the last row, the same as
+	// the logs explorer, the offset is not added to their queries
+	paginateByID := query.DataSource == v3.DataSourceLogs && logsV3.IsOrderByTs(query.OrderBy)
+	idOperator := v3.FilterOperatorLessThan
+	if paginateByID && query.OrderBy[0].Order == "asc" {
+		idOperator = v3.FilterOperatorGreaterThan
+	}
+
+	var columns []string
+	var lastID interface{}
+	written := uint64(0)
+	for written < maxRows {
+		size := maxRows - written
+		if size > pageSize {
+			size = pageSize
+		}
+
+		pageParams := params.Clone()
+		page := pageParams.CompositeQuery.BuilderQueries[queryName]
+		page.Limit = 0
+		page.Offset = 0
+		page.PageSize = 0
+		switch {
+		case query.DataSource == v3.DataSourceTraces:
+			// the traces list queries are paginated with the limit and offset
+			page.Limit = size
+			page.Offset = written
+		case paginateByID:
+			page.PageSize = size
+			if page.Filters == nil {
+				page.Filters = &v3.FilterSet{Operator: "AND"}
+			}
+			if lastID != nil {
+				page.Filters.Items = append(page.Filters.Items, v3.FilterItem{
+					Key:      v3.AttributeKey{Key: "id", IsColumn: true, DataType: v3.AttributeKeyDataTypeString},
+					Operator: idOperator,
+					Value:    lastID,
+				})
+			}
+		default:
+			page.PageSize = size
+			page.Offset = written
+		}
+
+		results, errQueriesByName, err := querier.QueryRange(ctx, pageParams)
+		if err != nil {
+			return written, columns, queryError(err, errQueriesByName)
+		}
+		var rows []*v3.Row
+		for _, result := range results {
+			if result.QueryName == queryName {
+				rows = result.List
+			}
+		}
+
+		for _, row := range rows {
+			record := make(map[string]interface{}, len(row.Data)+1)
+			for key, value := range row.Data {
+				record[key] = value
+			}
+			record["timestamp"] = row.Timestamp
+			if columns == nil {
+				columns = listRowColumns(record)
+			}
+			if err := rw.write(columns, record); err != nil {
+				return written, columns, err
+			}
+			written++
+		}
+		if err := flush(rw, w); err != nil {
+			return written, columns, err
+		}
+
+		if uint64(len(rows)) < size {
+			break
+		}
+		if paginateByID {
+			lastID = rows[len(rows)-1].Data["id"]
+		}
+	}
+	if columns == nil {
+		columns = listQueryColumns(query)
+	}
+	return written, columns, nil
+}
+
+// listQueryColumns returns the timestamp followed by the sorted select columns of the query, they
+// are the columns of the export when the query returns no rows
+func listQueryColumns(query *v3.BuilderQuery) []string {
+	columns := make([]string, 0, len(query.SelectColumns))
+	for _, column := range query.SelectColumns {
+		if column.Key != "timestamp" {
+			columns = append(columns, column.Key)
+		}
+	}
+	sort.Strings(columns)
+	return append([]string{"timestamp"}, columns...)
+}
+
+// exportTable writes the rows of the table result, it returns the number of rows written and the
+// columns of the table
+func exportTable(ctx context.Context, querier interfaces.Querier, params *v3.QueryRangeParamsV3, rw rowWriter, w io.Writer, maxRows uint64) (uint64, []string, error) {
+	params.FormatForWeb = true
+	results, errQueriesByName, err := querier.QueryRange(ctx, params)
+	if err != nil {
+		return 0, nil, queryError(err, errQueriesByName)
+	}
+	switch params.CompositeQuery.QueryType {
+	case v3.QueryTypeBuilder:
+		results, err = postprocess.PostProcessResult(results, params)
+		if err != nil {
+			return 0, nil, err
+		}
+	case v3.QueryTypeClickHouseSQL:
+		postprocess.ApplyFunctions(results, params)
+		results = postprocess.TransformToTableForClickHouseQueries(results)
+	}
+	if len(results) == 0 || results[0].Table == nil {
+		return 0, nil, nil
+	}
+
+	table := results[0].Table
+	columns := make([]string, 0, len(table.Columns))
+	for _, column := range table.Columns {
+		columns = append(columns, column.Name)
+	}
+	written := uint64(0)
+	for _, row := range table.Rows {
+		if written >= maxRows {
+			break
+		}
+		if err := rw.write(columns, row.Data); err != nil {
+			return written, columns, err
+		}
+		written++
+	}
+	return written, columns, flush(rw, w)
+}
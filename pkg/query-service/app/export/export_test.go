@@ -0,0 +1,248 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+// fakeQuerier returns the rows of the page queried, the rows have decreasing ids
+type fakeQuerier struct {
+	totalRows int
+	table     *v3.Result
+	pages     []*v3.BuilderQuery
+}
+
+func (f *fakeQuerier) QueryRange(_ context.Context, params *v3.QueryRangeParamsV3) ([]*v3.Result, map[string]error, error) {
+	if params.CompositeQuery.PanelType == v3.PanelTypeTable {
+		return []*v3.Result{f.table}, nil, nil
+	}
+	query := params.CompositeQuery.BuilderQueries["A"]
+	f.pages = append(f.pages, query)
+
+	start := int(query.Offset)
+	if query.Filters != nil && len(query.Filters.Items) > 0 {
+		fmt.Sscanf(*query.Filters.Items[len(query.Filters.Items)-1].Value.(*string), "id-%d", &start)
+		start = f.totalRows - start + 1
+	}
+	size := int(query.PageSize)
+	if size == 0 {
+		size = int(query.Limit)
+	}
+	rows := []*v3.Row{}
+	for i := start; i < start+size && i < f.totalRows; i++ {
+		id := fmt.Sprintf("id-%d", f.totalRows-i)
+		rows = append(rows, &v3.Row{
+			Timestamp: time.Unix(0, int64(f.totalRows-i)).UTC(),
+			Data:      map[string]interface{}{"id": &id, "body": fmt.Sprintf("log %d", i)},
+		})
+	}
+	return []*v3.Result{{QueryName: "A", List: rows}}, nil, nil
+}
+
+func (f *fakeQuerier) Explain(context.Context, *v3.QueryRangeParamsV3, bool) ([]*v3.QueryExplanation, error) {
+	return nil, nil
+}
+
+func (f *fakeQuerier) QueriesExecuted() []string {
+	return nil
+}
+
+func (f *fakeQuerier) TimeRanges() [][]int {
+	return nil
+}
+
+func listParams(dataSource v3.DataSource, orderBy string) *v3.QueryRangeParamsV3 {
+	return &v3.QueryRangeParamsV3{
+		Start: 1675115596722,
+		End:   1675115596722 + 60*60*1000,
+		CompositeQuery: &v3.CompositeQuery{
+			QueryType: v3.QueryTypeBuilder,
+			PanelType: v3.PanelTypeList,
+			BuilderQueries: map[string]*v3.BuilderQuery{
+				"A": {
+					QueryName:  "A",
+					Expression: "A",
+					DataSource: dataSource,
+					PageSize:   100,
+					OrderBy:    []v3.OrderBy{{ColumnName: orderBy, Order: "desc"}},
+				},
+			},
+		},
+	}
+}
+
+func TestExportList(t *testing.T) {
+	t.Run("logs ordered by timestamp are paginated by id", func(t *testing.T) {
+		querier := &fakeQuerier{totalRows: 25000}
+		var out bytes.Buffer
+		rows, err := Export(context.Background(), querier, listParams(v3.DataSourceLogs, "timestamp"), FormatNDJSON, 15000, &out)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(15000), rows)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 15000)
+		assert.Equal(t, `{"body":"log 0","id":"id-25000","timestamp":"1970-01-01T00:00:00.000025Z"}`, lines[0])
+		assert.Equal(t, `{"body":"log 14999","id":"id-10001","timestamp":"1970-01-01T00:00:00.000010001Z"}`, lines[14999])
+
+		require.Len(t, querier.pages, 2)
+		assert.Equal(t, uint64(pageSize), querier.pages[0].PageSize)
+		assert.Empty(t, querier.pages[0].Filters.Items)
+		assert.Equal(t, uint64(5000), querier.pages[1].PageSize)
+		assert.Equal(t, v3.FilterOperatorLessThan, querier.pages[1].Filters.Items[0].Operator)
+	})
+
+	t.Run("traces are paginated by offset", func(t *testing.T) {
+		querier := &fakeQuerier{totalRows: 12000}
+		var out bytes.Buffer
+		rows, err := Export(context.Background(), querier, listParams(v3.DataSourceTraces, "durationNano"), FormatCSV, 0, &out)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(12000), rows)
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		require.Len(t, lines, 12001)
+		assert.Equal(t, "timestamp,body,id", lines[0])
+		assert.Equal(t, "1970-01-01T00:00:00.000012Z,log 0,id-12000", lines[1])
+
+		require.Len(t, querier.pages, 2)
+		assert.Equal(t, uint64(pageSize), querier.pages[0].Limit)
+		assert.Equal(t, uint64(pageSize), querier.pages[1].Offset)
+	})
+}
+
+func TestExportTable(t *testing.T) {
+	querier := &fakeQuerier{table: &v3.Result{
+		QueryName: "A",
+		Series: []*v3.Series{
+			{LabelsArray: []map[string]string{{"service.name": "frontend"}}, Points: []v3.Point{{Value: 10.5}}},
+			{LabelsArray: []map[string]string{{"service.name": "redis, cache"}}, Points: []v3.Point{{Value: 3}}},
+		},
+	}}
+	params := &v3.QueryRangeParamsV3{
+		CompositeQuery: &v3.CompositeQuery{QueryType: v3.QueryTypeClickHouseSQL, PanelType: v3.PanelTypeTable},
+	}
+
+	var out bytes.Buffer
+	rows, err := Export(context.Background(), querier, params, FormatCSV, 0, &out)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), rows)
+	assert.Equal(t, "service.name,A\nfrontend,10.5\n\"redis, cache\",3\n", out.String())
+}
+
+func TestExportErrors(t *testing.T) {
+	var out bytes.Buffer
+	_, err := Export(context.Background(), &fakeQuerier{}, listParams(v3.DataSourceLogs, "timestamp"), Format("xlsx"), 0, &out)
+	assert.EqualError(t, err, "invalid export format xlsx, supported formats are ndjson, csv and parquet")
+
+	params := listParams(v3.DataSourceLogs, "timestamp")
+	params.CompositeQuery.PanelType = v3.PanelTypeGraph
+	_, err = Export(context.Background(), &fakeQuerier{}, params, FormatCSV, 0, &out)
+	assert.EqualError(t, err, "export is only supported for the list and table panels")
+	assert.Empty(t, out.String())
+}
+
+// readParquet reads back the rows of the parquet file by column name and its number of row groups
+func readParquet(t *testing.T, data []byte) ([]map[string]interface{}, int) {
+	file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	columns := file.Schema().Columns()
+	reader := parquet.NewReader(file)
+	defer reader.Close()
+
+	records := make([]map[string]interface{}, 0, file.NumRows())
+	rows := make([]parquet.Row, 100)
+	for {
+		n, err := reader.ReadRows(rows)
+		for _, row := range rows[:n] {
+			record := map[string]interface{}{}
+			for _, value := range row {
+				name := columns[value.Column()][0]
+				switch {
+				case value.IsNull():
+					record[name] = nil
+				case value.Kind() == parquet.ByteArray:
+					record[name] = value.String()
+				case value.Kind() == parquet.Double:
+					record[name] = value.Double()
+				case value.Kind() == parquet.Int64:
+					record[name] = value.Int64()
+				}
+			}
+			records = append(records, record)
+		}
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+	}
+	return records, len(file.RowGroups())
+}
+
+func TestExportParquet(t *testing.T) {
+	t.Run("list rows are written a row group per page", func(t *testing.T) {
+		querier := &fakeQuerier{totalRows: 15000}
+		var out bytes.Buffer
+		rows, err := Export(context.Background(), querier, listParams(v3.DataSourceLogs, "timestamp"), FormatParquet, 0, &out)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(15000), rows)
+
+		records, rowGroups := readParquet(t, out.Bytes())
+		assert.Equal(t, 2, rowGroups)
+		require.Len(t, records, 15000)
+		assert.Equal(t, map[string]interface{}{"body": "log 0", "id": "id-15000", "timestamp": int64(15000)}, records[0])
+		assert.Equal(t, map[string]interface{}{"body": "log 14999", "id": "id-1", "timestamp": int64(1)}, records[14999])
+	})
+
+	t.Run("table rows keep the numbers and the missing values", func(t *testing.T) {
+		querier := &fakeQuerier{table: &v3.Result{
+			QueryName: "A",
+			Series: []*v3.Series{
+				{LabelsArray: []map[string]string{{"service.name": "frontend"}}, Points: []v3.Point{{Value: 10.5}}},
+				{LabelsArray: []map[string]string{{"service.name": "redis"}}, Points: []v3.Point{{Value: 3}}},
+			},
+		}}
+		params := &v3.QueryRangeParamsV3{
+			CompositeQuery: &v3.CompositeQuery{QueryType: v3.QueryTypeClickHouseSQL, PanelType: v3.PanelTypeTable},
+		}
+
+		var out bytes.Buffer
+		rows, err := Export(context.Background(), querier, params, FormatParquet, 0, &out)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), rows)
+
+		records, rowGroups := readParquet(t, out.Bytes())
+		assert.Equal(t, 1, rowGroups)
+		assert.Equal(t, []map[string]interface{}{
+			{"service.name": "frontend", "A": 10.5},
+			{"service.name": "redis", "A": 3.0},
+		}, records)
+	})
+
+	t.Run("the schema is written without rows", func(t *testing.T) {
+		var out bytes.Buffer
+		params := listParams(v3.DataSourceTraces, "timestamp")
+		params.CompositeQuery.BuilderQueries["A"].SelectColumns = []v3.AttributeKey{{Key: "serviceName"}, {Key: "name"}}
+		rows, err := Export(context.Background(), &fakeQuerier{}, params, FormatParquet, 0, &out)
+		require.NoError(t, err)
+		assert.Zero(t, rows)
+
+		file, err := parquet.OpenFile(bytes.NewReader(out.Bytes()), int64(out.Len()))
+		require.NoError(t, err)
+		assert.Zero(t, file.NumRows())
+		var fields []string
+		for _, field := range file.Schema().Fields() {
+			fields = append(fields, field.Name())
+		}
+		assert.Equal(t, []string{"name", "serviceName", "timestamp"}, fields)
+	})
+}
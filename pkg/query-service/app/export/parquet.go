@@ -0,0 +1,192 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetKind is the type of a parquet column, inferred from the first row
+type parquetKind int
+
+const (
+	parquetKindString parquetKind = iota
+	parquetKindTimestamp
+	parquetKindDouble
+	parquetKindInt64
+	parquetKindBoolean
+)
+
+func (k parquetKind) node() parquet.Node {
+	switch k {
+	case parquetKindTimestamp:
+		return parquet.Timestamp(parquet.Nanosecond)
+	case parquetKindDouble:
+		return parquet.Leaf(parquet.DoubleType)
+	case parquetKindInt64:
+		return parquet.Int(64)
+	case parquetKindBoolean:
+		return parquet.Leaf(parquet.BooleanType)
+	default:
+		return parquet.String()
+	}
+}
+
+// parquetWriter writes the rows as a parquet file with a row group per flush, the schema is
+// created from the columns and the values of the first row, or from the columns alone when
+// there are no rows. All the columns are optional.
+type parquetWriter struct {
+	w      io.Writer
+	writer *parquet.Writer
+	// the export columns in the order of the parquet columns and their kind
+	columns []string
+	kinds   []parquetKind
+	rows    []parquet.Row
+}
+
+func (p *parquetWriter) init(columns []string, row map[string]interface{}) {
+	group := make(parquet.Group, len(columns))
+	kinds := make(map[string]parquetKind, len(columns))
+	for _, column := range columns {
+		kinds[column] = inferParquetKind(row[column])
+		group[column] = parquet.Optional(kinds[column].node())
+	}
+	schema := parquet.NewSchema("export", group)
+
+	// the fields of the group are sorted by name
+	for _, field := range schema.Fields() {
+		p.columns = append(p.columns, field.Name())
+		p.kinds = append(p.kinds, kinds[field.Name()])
+	}
+	p.writer = parquet.NewWriter(p.w, schema)
+}
+
+func (p *parquetWriter) write(columns []string, row map[string]interface{}) error {
+	if p.writer == nil {
+		p.init(columns, row)
+	}
+	record := make(parquet.Row, len(p.columns))
+	for idx, column := range p.columns {
+		value, err := parquetValue(p.kinds[idx], row[column])
+		if err != nil {
+			return fmt.Errorf("error formatting column %s: %w", column, err)
+		}
+		definitionLevel := 1
+		if value.IsNull() {
+			definitionLevel = 0
+		}
+		record[idx] = value.Level(0, definitionLevel, idx)
+	}
+	p.rows = append(p.rows, record)
+	return nil
+}
+
+func (p *parquetWriter) flush() error {
+	if p.writer == nil || len(p.rows) == 0 {
+		return nil
+	}
+	if _, err := p.writer.WriteRows(p.rows); err != nil {
+		return err
+	}
+	p.rows = p.rows[:0]
+	return p.writer.Flush()
+}
+
+func (p *parquetWriter) close(columns []string) error {
+	if p.writer == nil {
+		if len(columns) == 0 {
+			// a parquet schema has at least one column, nothing is written without columns
+			return nil
+		}
+		// the file has the schema of the columns and no rows, all of them are strings
+		// since there is no value to infer their type from
+		p.init(columns, nil)
+	}
+	if err := p.flush(); err != nil {
+		return err
+	}
+	return p.writer.Close()
+}
+
+// deref returns the value the pointers of the list rows point to, nil for the nil pointers
+func deref(value interface{}) (reflect.Value, bool) {
+	if value == nil {
+		return reflect.Value{}, false
+	}
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+	return v, true
+}
+
+func inferParquetKind(value interface{}) parquetKind {
+	v, ok := deref(value)
+	if !ok {
+		return parquetKindString
+	}
+	if _, ok := v.Interface().(time.Time); ok {
+		return parquetKindTimestamp
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return parquetKindDouble
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return parquetKindInt64
+	case reflect.Bool:
+		return parquetKindBoolean
+	default:
+		return parquetKindString
+	}
+}
+
+// parquetValue returns the parquet value of the column kind, the values of the string
+// columns are formatted the same as the CSV fields
+func parquetValue(kind parquetKind, value interface{}) (parquet.Value, error) {
+	v, ok := deref(value)
+	if !ok {
+		return parquet.NullValue(), nil
+	}
+
+	switch kind {
+	case parquetKindTimestamp:
+		if t, ok := v.Interface().(time.Time); ok {
+			return parquet.Int64Value(t.UnixNano()), nil
+		}
+	case parquetKindDouble, parquetKindInt64:
+		var f float64
+		var i int64
+		switch v.Kind() {
+		case reflect.Float32, reflect.Float64:
+			f, i = v.Float(), int64(v.Float())
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			f, i = float64(v.Int()), v.Int()
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			f, i = float64(v.Uint()), int64(v.Uint())
+		default:
+			return parquet.Value{}, fmt.Errorf("expected a number, got %T", v.Interface())
+		}
+		if kind == parquetKindDouble {
+			return parquet.DoubleValue(f), nil
+		}
+		return parquet.Int64Value(i), nil
+	case parquetKindBoolean:
+		if v.Kind() == reflect.Bool {
+			return parquet.BooleanValue(v.Bool()), nil
+		}
+	default:
+		formatted, err := formatValue(v.Interface())
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.ByteArrayValue([]byte(formatted)), nil
+	}
+	return parquet.Value{}, fmt.Errorf("unexpected value of type %T", v.Interface())
+}
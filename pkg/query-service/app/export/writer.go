@@ -0,0 +1,118 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// rowWriter writes the exported rows, the columns are the same for all the rows of an export
+type rowWriter interface {
+	write(columns []string, row map[string]interface{}) error
+	// flush writes the buffered rows to the underlying writer
+	flush() error
+	// close writes the rest of the rows and the end of the export, the columns are those of
+	// the export even when no row is written
+	close(columns []string) error
+}
+
+func newRowWriter(format Format, w io.Writer) rowWriter {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w)}
+	case FormatParquet:
+		return &parquetWriter{w: w}
+	}
+	buffered := bufio.NewWriter(w)
+	return &ndjsonWriter{w: buffered, encoder: json.NewEncoder(buffered)}
+}
+
+// ndjsonWriter writes a JSON object per line
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	encoder *json.Encoder
+}
+
+func (n *ndjsonWriter) write(_ []string, row map[string]interface{}) error {
+	return n.encoder.Encode(row)
+}
+
+func (n *ndjsonWriter) flush() error {
+	return n.w.Flush()
+}
+
+func (n *ndjsonWriter) close(_ []string) error {
+	return n.flush()
+}
+
+// csvWriter writes the columns as the header followed by a record per row
+type csvWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+	record        []string
+}
+
+func (c *csvWriter) write(columns []string, row map[string]interface{}) error {
+	if !c.headerWritten {
+		if err := c.w.Write(columns); err != nil {
+			return err
+		}
+		c.headerWritten = true
+		c.record = make([]string, len(columns))
+	}
+	for idx, column := range columns {
+		value, err := formatValue(row[column])
+		if err != nil {
+			return fmt.Errorf("error formatting column %s: %w", column, err)
+		}
+		c.record[idx] = value
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) close(_ []string) error {
+	return c.flush()
+}
+
+// formatValue returns the CSV field of the value, the maps and slices e.g the attributes
+// of the logs are written as JSON
+func formatValue(value interface{}) (string, error) {
+	// the list rows have pointers to the scanned values
+	v, ok := deref(value)
+	if !ok {
+		return "", nil
+	}
+	value = v.Interface()
+
+	switch val := value.(type) {
+	case string:
+		return val, nil
+	case time.Time:
+		return val.UTC().Format(time.RFC3339Nano), nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct:
+		b, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
@@ -3,6 +3,7 @@ package app
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -22,11 +23,14 @@ import (
 	"github.com/gorilla/websocket"
 	jsoniter "github.com/json-iterator/go"
 	_ "github.com/mattn/go-sqlite3"
+	promModel "github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/promql"
 
 	"go.signoz.io/signoz/pkg/query-service/agentConf"
+	"go.signoz.io/signoz/pkg/query-service/app/asyncquery"
 	"go.signoz.io/signoz/pkg/query-service/app/dashboards"
 	"go.signoz.io/signoz/pkg/query-service/app/explorer"
+	"go.signoz.io/signoz/pkg/query-service/app/export"
 	"go.signoz.io/signoz/pkg/query-service/app/integrations"
 	"go.signoz.io/signoz/pkg/query-service/app/logs"
 	logsv3 "go.signoz.io/signoz/pkg/query-service/app/logs/v3"
@@ -40,17 +44,20 @@ import (
 	tracesV3 "go.signoz.io/signoz/pkg/query-service/app/traces/v3"
 	"go.signoz.io/signoz/pkg/query-service/auth"
 	"go.signoz.io/signoz/pkg/query-service/cache"
+	"go.signoz.io/signoz/pkg/query-service/cache/inmemory"
 	"go.signoz.io/signoz/pkg/query-service/common"
 	"go.signoz.io/signoz/pkg/query-service/constants"
 	"go.signoz.io/signoz/pkg/query-service/contextlinks"
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
 	"go.signoz.io/signoz/pkg/query-service/postprocess"
+	"go.signoz.io/signoz/pkg/query-service/querylimits"
 
 	"go.uber.org/zap"
 
 	mq "go.signoz.io/signoz/pkg/query-service/app/integrations/messagingQueues/kafka"
 	"go.signoz.io/signoz/pkg/query-service/app/logparsingpipeline"
 	"go.signoz.io/signoz/pkg/query-service/dao"
+	chErrors "go.signoz.io/signoz/pkg/query-service/errors"
 	am "go.signoz.io/signoz/pkg/query-service/integrations/alertManager"
 	signozio "go.signoz.io/signoz/pkg/query-service/integrations/signozio"
 	"go.signoz.io/signoz/pkg/query-service/interfaces"
@@ -110,6 +117,17 @@ type APIHandler struct {
 	Upgrader *websocket.Upgrader
 
 	UseLogsNewSchema bool
+
+	// query limits of each role, nil if the queries are not limited
+	queryLimits *querylimits.Config
+
+	asyncQueries *asyncquery.Manager
+
+	// query results cache, nil if no cache is configured
+	cache cache.Cache
+
+	// the interval of the recent data that is not cached
+	fluxInterval time.Duration
 }
 
 type APIHandlerOpts struct {
@@ -148,6 +166,9 @@ type APIHandlerOpts struct {
 
 	// Use Logs New schema
 	UseLogsNewSchema bool
+
+	// Query limits of each role, nil if the queries are not limited
+	QueryLimits *querylimits.Config
 }
 
 // NewAPIHandler returns an APIHandler
@@ -196,8 +217,18 @@ func NewAPIHandler(opts APIHandlerOpts) (*APIHandler, error) {
 		querier:                       querier,
 		querierV2:                     querierv2,
 		UseLogsNewSchema:              opts.UseLogsNewSchema,
+		queryLimits:                   opts.QueryLimits,
+		cache:                         opts.Cache,
+		fluxInterval:                  opts.FluxInterval,
 	}
 
+	// the async queries are kept in memory when no cache is configured
+	asyncQueriesCache := opts.Cache
+	if asyncQueriesCache == nil {
+		asyncQueriesCache = inmemory.New(nil)
+	}
+	aH.asyncQueries = asyncquery.NewManager(asyncQueriesCache, opts.Reader, asyncquery.DefaultTTL)
+
 	logsQueryBuilder := logsv3.PrepareLogsQuery
 	if opts.UseLogsNewSchema {
 		logsQueryBuilder = logsv4.PrepareLogsQuery
@@ -279,7 +310,7 @@ func RespondError(w http.ResponseWriter, apiErr model.BaseApiError, data interfa
 	switch apiErr.Type() {
 	case model.ErrorBadData:
 		code = http.StatusBadRequest
-	case model.ErrorExec:
+	case model.ErrorExec, model.ErrorQueryLimitExceeded:
 		code = 422
 	case model.ErrorCanceled, model.ErrorTimeout:
 		code = http.StatusServiceUnavailable
@@ -293,6 +324,8 @@ func RespondError(w http.ResponseWriter, apiErr model.BaseApiError, data interfa
 		code = http.StatusUnauthorized
 	case model.ErrorForbidden:
 		code = http.StatusForbidden
+	case model.ErrorConflict:
+		code = http.StatusConflict
 	default:
 		code = http.StatusInternalServerError
 	}
@@ -334,6 +367,7 @@ func (aH *APIHandler) RegisterQueryRangeV3Routes(router *mux.Router, am *AuthMid
 		withCacheControl(AutoCompleteCacheControlAge, aH.autoCompleteAttributeValues))).Methods(http.MethodGet)
 	subRouter.HandleFunc("/query_range", am.ViewAccess(aH.QueryRangeV3)).Methods(http.MethodPost)
 	subRouter.HandleFunc("/query_range/format", am.ViewAccess(aH.QueryRangeV3Format)).Methods(http.MethodPost)
+	subRouter.HandleFunc("/query_range/text", am.ViewAccess(aH.QueryRangeV3Text)).Methods(http.MethodPost)
 
 	subRouter.HandleFunc("/filter_suggestions", am.ViewAccess(aH.getQueryBuilderSuggestions)).Methods(http.MethodGet)
 
@@ -352,6 +386,11 @@ func (aH *APIHandler) RegisterWebSocketPaths(router *mux.Router, am *AuthMiddlew
 func (aH *APIHandler) RegisterQueryRangeV4Routes(router *mux.Router, am *AuthMiddleware) {
 	subRouter := router.PathPrefix("/api/v4").Subrouter()
 	subRouter.HandleFunc("/query_range", am.ViewAccess(aH.QueryRangeV4)).Methods(http.MethodPost)
+	subRouter.HandleFunc("/query_range/text", am.ViewAccess(aH.QueryRangeV4Text)).Methods(http.MethodPost)
+	subRouter.HandleFunc("/query_range/export", am.ViewAccess(aH.QueryRangeV4Export)).Methods(http.MethodPost)
+	subRouter.HandleFunc("/query_range/async", am.ViewAccess(aH.QueryRangeV4Async)).Methods(http.MethodPost)
+	subRouter.HandleFunc("/query_range/async/{id}", am.ViewAccess(aH.GetAsyncQueryRange)).Methods(http.MethodGet)
+	subRouter.HandleFunc("/query_range/async/{id}", am.ViewAccess(aH.CancelAsyncQueryRange)).Methods(http.MethodDelete)
 	subRouter.HandleFunc("/metric/metric_metadata", am.ViewAccess(aH.getMetricMetadata)).Methods(http.MethodGet)
 }
 
@@ -396,6 +435,13 @@ func (aH *APIHandler) RegisterRoutes(router *mux.Router, am *AuthMiddleware) {
 	router.HandleFunc("/api/v1/downtime_schedules/{id}", am.OpenAccess(aH.editDowntimeSchedule)).Methods(http.MethodPut)
 	router.HandleFunc("/api/v1/downtime_schedules/{id}", am.OpenAccess(aH.deleteDowntimeSchedule)).Methods(http.MethodDelete)
 
+	router.HandleFunc("/api/v1/slos", am.ViewAccess(aH.listSLOs)).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/slos/{id}", am.ViewAccess(aH.getSLO)).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/slos/{id}/status", am.ViewAccess(aH.getSLOStatus)).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/slos", am.EditAccess(aH.createSLO)).Methods(http.MethodPost)
+	router.HandleFunc("/api/v1/slos/{id}", am.EditAccess(aH.editSLO)).Methods(http.MethodPut)
+	router.HandleFunc("/api/v1/slos/{id}", am.EditAccess(aH.deleteSLO)).Methods(http.MethodDelete)
+
 	router.HandleFunc("/api/v1/dashboards", am.ViewAccess(aH.getDashboards)).Methods(http.MethodGet)
 	router.HandleFunc("/api/v1/dashboards", am.EditAccess(aH.createDashboards)).Methods(http.MethodPost)
 	router.HandleFunc("/api/v1/dashboards/{uuid}", am.ViewAccess(aH.getDashboard)).Methods(http.MethodGet)
@@ -428,6 +474,9 @@ func (aH *APIHandler) RegisterRoutes(router *mux.Router, am *AuthMiddleware) {
 	router.HandleFunc("/api/v1/settings/ingestion_key", am.AdminAccess(aH.insertIngestionKey)).Methods(http.MethodPost)
 	router.HandleFunc("/api/v1/settings/ingestion_key", am.ViewAccess(aH.getIngestionKeys)).Methods(http.MethodGet)
 
+	router.HandleFunc("/api/v1/cache/entries", am.AdminAccess(aH.listCacheEntries)).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/cache/invalidate", am.AdminAccess(aH.invalidateCache)).Methods(http.MethodPost)
+
 	router.HandleFunc("/api/v1/version", am.OpenAccess(aH.getVersion)).Methods(http.MethodGet)
 	router.HandleFunc("/api/v1/featureFlags", am.OpenAccess(aH.getFeatureFlags)).Methods(http.MethodGet)
 	router.HandleFunc("/api/v1/configs", am.OpenAccess(aH.getConfigs)).Methods(http.MethodGet)
@@ -669,6 +718,132 @@ func (aH *APIHandler) deleteDowntimeSchedule(w http.ResponseWriter, r *http.Requ
 	aH.Respond(w, nil)
 }
 
+func (aH *APIHandler) listSLOs(w http.ResponseWriter, r *http.Request) {
+	slos, err := aH.ruleManager.RuleDB().GetAllSLOs(r.Context())
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorInternal, Err: err}, nil)
+		return
+	}
+	aH.Respond(w, slos)
+}
+
+func (aH *APIHandler) getSLOByID(w http.ResponseWriter, r *http.Request) (*rules.SLO, bool) {
+	id := mux.Vars(r)["id"]
+	slo, err := aH.ruleManager.RuleDB().GetSLOByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			RespondError(w, &model.ApiError{Typ: model.ErrorNotFound, Err: fmt.Errorf("slo %s not found", id)}, nil)
+			return nil, false
+		}
+		RespondError(w, &model.ApiError{Typ: model.ErrorInternal, Err: err}, nil)
+		return nil, false
+	}
+	return slo, true
+}
+
+func (aH *APIHandler) getSLO(w http.ResponseWriter, r *http.Request) {
+	slo, ok := aH.getSLOByID(w, r)
+	if !ok {
+		return
+	}
+	aH.Respond(w, slo)
+}
+
+// getSLOStatus computes the indicator, the remaining error budget and the burn rates of the SLO.
+// The burn rate windows can be set with the comma separated windows parameter e.g. windows=5m,1h
+func (aH *APIHandler) getSLOStatus(w http.ResponseWriter, r *http.Request) {
+	windows := rules.DefaultBurnRateWindows
+	if param := r.URL.Query().Get("windows"); param != "" {
+		windows = make([]time.Duration, 0)
+		for _, value := range strings.Split(param, ",") {
+			window, err := promModel.ParseDuration(strings.TrimSpace(value))
+			if err != nil || window <= 0 {
+				RespondError(w, &model.ApiError{Typ: model.ErrorBadData, Err: fmt.Errorf("invalid window %s", value)}, nil)
+				return
+			}
+			windows = append(windows, time.Duration(window))
+		}
+	}
+
+	slo, ok := aH.getSLOByID(w, r)
+	if !ok {
+		return
+	}
+
+	evaluator := rules.NewSLOEvaluator(aH.querierV2, aH.reader, aH.PopulateTemporality)
+	status, err := evaluator.Status(r.Context(), slo, windows, time.Now())
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorInternal, Err: err}, nil)
+		return
+	}
+	aH.Respond(w, status)
+}
+
+func (aH *APIHandler) createSLO(w http.ResponseWriter, r *http.Request) {
+	var slo rules.SLO
+	err := json.NewDecoder(r.Body).Decode(&slo)
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorBadData, Err: err}, nil)
+		return
+	}
+	if err := slo.Validate(); err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorBadData, Err: err}, nil)
+		return
+	}
+
+	id, err := aH.ruleManager.RuleDB().CreateSLO(r.Context(), slo)
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorInternal, Err: err}, nil)
+		return
+	}
+	slo.Id = id
+	aH.Respond(w, slo)
+}
+
+func (aH *APIHandler) editSLO(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var slo rules.SLO
+	err := json.NewDecoder(r.Body).Decode(&slo)
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorBadData, Err: err}, nil)
+		return
+	}
+	if err := slo.Validate(); err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorBadData, Err: err}, nil)
+		return
+	}
+	err = aH.ruleManager.RuleDB().EditSLO(r.Context(), slo, id)
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorInternal, Err: err}, nil)
+		return
+	}
+	aH.Respond(w, nil)
+}
+
+func (aH *APIHandler) deleteSLO(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	// the burn rate rules of the SLO would fail on every evaluation without it
+	ruleIds, err := aH.ruleManager.RulesUsingSLO(r.Context(), id)
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorInternal, Err: err}, nil)
+		return
+	}
+	if len(ruleIds) > 0 {
+		RespondError(w, &model.ApiError{Typ: model.ErrorConflict, Err: fmt.Errorf(
+			"slo %s is used by the burn rate rules %s, delete the rules or change their slo first", id, strings.Join(ruleIds, ", "),
+		)}, nil)
+		return
+	}
+
+	err = aH.ruleManager.RuleDB().DeleteSLO(r.Context(), id)
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorInternal, Err: err}, nil)
+		return
+	}
+	aH.Respond(w, nil)
+}
+
 func (aH *APIHandler) getRuleStats(w http.ResponseWriter, r *http.Request) {
 	ruleID := mux.Vars(r)["id"]
 	params := model.QueryRuleStateHistory{}
@@ -769,8 +944,8 @@ func (aH *APIHandler) getOverallStateTransitions(w http.ResponseWriter, r *http.
 	aH.Respond(w, stateItems)
 }
 
-func (aH *APIHandler) metaForLinks(ctx context.Context, rule *rules.GettableRule) ([]v3.FilterItem, []v3.AttributeKey, map[string]v3.AttributeKey) {
-	filterItems := []v3.FilterItem{}
+func (aH *APIHandler) metaForLinks(ctx context.Context, rule *rules.GettableRule) (*v3.FilterSet, []v3.AttributeKey, map[string]v3.AttributeKey) {
+	var filters *v3.FilterSet
 	groupBy := []v3.AttributeKey{}
 	keys := make(map[string]v3.AttributeKey)
 
@@ -799,7 +974,7 @@ func (aH *APIHandler) metaForLinks(ctx context.Context, rule *rules.GettableRule
 				selectedQuery := rule.RuleCondition.GetSelectedQueryName()
 				if rule.RuleCondition.CompositeQuery.BuilderQueries[selectedQuery] != nil &&
 					rule.RuleCondition.CompositeQuery.BuilderQueries[selectedQuery].Filters != nil {
-					filterItems = rule.RuleCondition.CompositeQuery.BuilderQueries[selectedQuery].Filters.Items
+					filters = rule.RuleCondition.CompositeQuery.BuilderQueries[selectedQuery].Filters
 				}
 				if rule.RuleCondition.CompositeQuery.BuilderQueries[selectedQuery] != nil &&
 					rule.RuleCondition.CompositeQuery.BuilderQueries[selectedQuery].GroupBy != nil {
@@ -808,7 +983,7 @@ func (aH *APIHandler) metaForLinks(ctx context.Context, rule *rules.GettableRule
 			}
 		}
 	}
-	return filterItems, groupBy, keys
+	return filters, groupBy, keys
 }
 
 func (aH *APIHandler) getRuleStateHistory(w http.ResponseWriter, r *http.Request) {
@@ -838,8 +1013,8 @@ func (aH *APIHandler) getRuleStateHistory(w http.ResponseWriter, r *http.Request
 			if err != nil {
 				continue
 			}
-			filterItems, groupBy, keys := aH.metaForLinks(r.Context(), rule)
-			newFilters := contextlinks.PrepareFilters(lbls, filterItems, groupBy, keys)
+			filters, groupBy, keys := aH.metaForLinks(r.Context(), rule)
+			newFilters := contextlinks.PrepareFilters(lbls, filters, groupBy, keys)
 			end := time.Unix(res.Items[idx].UnixMilli/1000, 0)
 			// why are we subtracting 3 minutes?
 			// the query range is calculated based on the rule's evalWindow and evalDelay
@@ -880,8 +1055,8 @@ func (aH *APIHandler) getRuleStateHistoryTopContributors(w http.ResponseWriter,
 			if err != nil {
 				continue
 			}
-			filterItems, groupBy, keys := aH.metaForLinks(r.Context(), rule)
-			newFilters := contextlinks.PrepareFilters(lbls, filterItems, groupBy, keys)
+			filters, groupBy, keys := aH.metaForLinks(r.Context(), rule)
+			newFilters := contextlinks.PrepareFilters(lbls, filters, groupBy, keys)
 			end := time.Unix(params.End/1000, 0)
 			start := time.Unix(params.Start/1000, 0)
 			if rule.AlertType == rules.AlertTypeLogs {
@@ -3883,6 +4058,23 @@ func (aH *APIHandler) autoCompleteAttributeValues(w http.ResponseWriter, r *http
 	aH.Respond(w, response)
 }
 
+// enrichApdex sets the stored apdex thresholds of the services on the apdex queries
+func (aH *APIHandler) enrichApdex(ctx context.Context, queryRangeParams *v3.QueryRangeParamsV3) error {
+	if !tracesV3.ApdexRequired(queryRangeParams) {
+		return nil
+	}
+	apdexSettings, apiErr := dao.DB().GetAllApdexSettings(ctx)
+	if apiErr != nil {
+		return apiErr.Err
+	}
+	thresholds := make(map[string]float64, len(apdexSettings))
+	for _, settings := range apdexSettings {
+		thresholds[settings.ServiceName] = settings.Threshold
+	}
+	tracesV3.EnrichApdex(queryRangeParams, thresholds)
+	return nil
+}
+
 func (aH *APIHandler) getSpanKeysV3(ctx context.Context, queryRangeParams *v3.QueryRangeParamsV3) (map[string]v3.AttributeKey, error) {
 	data := map[string]v3.AttributeKey{}
 	for _, query := range queryRangeParams.CompositeQuery.BuilderQueries {
@@ -3915,7 +4107,61 @@ func (aH *APIHandler) QueryRangeV3Format(w http.ResponseWriter, r *http.Request)
 	aH.Respond(w, queryRangeParams)
 }
 
+// withQueryLimits returns the context with the query limits of the role of the user
+func (aH *APIHandler) withQueryLimits(ctx context.Context) context.Context {
+	if aH.queryLimits == nil {
+		return ctx
+	}
+	var role string
+	if user := common.GetUserFromContext(ctx); user != nil {
+		switch {
+		case auth.IsAdmin(user):
+			role = constants.AdminGroup
+		case auth.IsEditor(user):
+			role = constants.EditorGroup
+		case auth.IsViewer(user):
+			role = constants.ViewerGroup
+		}
+	}
+	return querylimits.NewContext(ctx, aH.queryLimits.ForRole(role))
+}
+
+// queryLimitError returns the api error if the error, or the error of any of the queries, is of a
+// query that exceeds the query limits or the resource limits of clickhouse e.g max_rows_to_read.
+// The resource limit errors are only reported as such when the query limits are configured, the
+// limits set on the clickhouse server are internal errors otherwise.
+func (aH *APIHandler) queryLimitError(err error, errQueriesByName map[string]error) *model.ApiError {
+	var apiErr *model.ApiError
+	if errors.As(err, &apiErr) && apiErr.Typ == model.ErrorQueryLimitExceeded {
+		return apiErr
+	}
+	if aH.queryLimits == nil {
+		return nil
+	}
+	if chErrors.IsResourceLimitError(err) {
+		return model.QueryLimitError(err)
+	}
+	names := make([]string, 0, len(errQueriesByName))
+	for name := range errQueriesByName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if chErrors.IsResourceLimitError(errQueriesByName[name]) {
+			return model.QueryLimitError(errQueriesByName[name])
+		}
+	}
+	return nil
+}
+
 func (aH *APIHandler) queryRangeV3(ctx context.Context, queryRangeParams *v3.QueryRangeParamsV3, w http.ResponseWriter, r *http.Request) {
+	ctx = aH.withQueryLimits(ctx)
+
+	// the formulas of the v3 api are evaluated in clickhouse, which can't join the shifted queries
+	if queryRangeParams.Compare != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorBadData, Err: fmt.Errorf("compare is only supported by the v4 query range API")}, nil)
+		return
+	}
 
 	var result []*v3.Result
 	var err error
@@ -3942,6 +4188,12 @@ func (aH *APIHandler) queryRangeV3(ctx context.Context, queryRangeParams *v3.Que
 			return
 		}
 		tracesV3.Enrich(queryRangeParams, spanKeys)
+
+		if err := aH.enrichApdex(ctx, queryRangeParams); err != nil {
+			apiErrObj := &model.ApiError{Typ: model.ErrorInternal, Err: err}
+			RespondError(w, apiErrObj, errQuriesByName)
+			return
+		}
 	}
 
 	// WARN: Only works for AND operator in traces query
@@ -3960,6 +4212,10 @@ func (aH *APIHandler) queryRangeV3(ctx context.Context, queryRangeParams *v3.Que
 		}
 	}
 
+	if aH.dryRunQueryRange(ctx, aH.querier, queryRangeParams, w, r) {
+		return
+	}
+
 	// Hook up query progress tracking if requested
 	queryIdHeader := r.Header.Get("X-SIGNOZ-QUERY-ID")
 	if len(queryIdHeader) > 0 {
@@ -3985,6 +4241,10 @@ func (aH *APIHandler) queryRangeV3(ctx context.Context, queryRangeParams *v3.Que
 	result, errQuriesByName, err = aH.querier.QueryRange(ctx, queryRangeParams)
 
 	if err != nil {
+		if apiErr := aH.queryLimitError(err, errQuriesByName); apiErr != nil {
+			RespondError(w, apiErr, apiErr.Err)
+			return
+		}
 		queryErrors := map[string]string{}
 		for name, err := range errQuriesByName {
 			queryErrors[fmt.Sprintf("Query-%s", name)] = err.Error()
@@ -4000,13 +4260,12 @@ func (aH *APIHandler) queryRangeV3(ctx context.Context, queryRangeParams *v3.Que
 	sendQueryResultEvents(r, result, queryRangeParams)
 	// only adding applyFunctions instead of postProcess since experssion are
 	// are executed in clickhouse directly and we wanted to add support for timeshift
+	postprocess.ApplyFunctions(result, queryRangeParams)
 	if queryRangeParams.CompositeQuery.QueryType == v3.QueryTypeBuilder {
-		postprocess.ApplyFunctions(result, queryRangeParams)
+		postprocess.ApplyTopK(result, queryRangeParams)
 	}
 
-	if queryRangeParams.CompositeQuery.FillGaps {
-		postprocess.FillGaps(result, queryRangeParams)
-	}
+	postprocess.FillGaps(result, queryRangeParams)
 
 	if queryRangeParams.CompositeQuery.PanelType == v3.PanelTypeTable && queryRangeParams.FormatForWeb {
 		if queryRangeParams.CompositeQuery.QueryType == v3.QueryTypeClickHouseSQL {
@@ -4033,6 +4292,24 @@ func (aH *APIHandler) queryRangeV3(ctx context.Context, queryRangeParams *v3.Que
 	aH.Respond(w, resp)
 }
 
+// dryRunQueryRange responds with the queries of the query range request without running them
+// when the request has dryRun=true, explain=true adds the EXPLAIN output of clickhouse
+func (aH *APIHandler) dryRunQueryRange(ctx context.Context, querier interfaces.Querier, queryRangeParams *v3.QueryRangeParamsV3, w http.ResponseWriter, r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	if !dryRun {
+		return false
+	}
+	explain, _ := strconv.ParseBool(r.URL.Query().Get("explain"))
+
+	queries, err := querier.Explain(ctx, queryRangeParams, explain)
+	if err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorBadData, Err: err}, nil)
+		return true
+	}
+	aH.Respond(w, v3.QueryRangeExplainResponse{Queries: queries})
+	return true
+}
+
 func sendQueryResultEvents(r *http.Request, result []*v3.Result, queryRangeParams *v3.QueryRangeParamsV3) {
 	referrer := r.Header.Get("Referer")
 
@@ -4135,6 +4412,27 @@ func (aH *APIHandler) QueryRangeV3(w http.ResponseWriter, r *http.Request) {
 	aH.queryRangeV3(r.Context(), queryRangeParams, w, r)
 }
 
+// QueryRangeV3Text is the same as QueryRangeV3 with the builder queries in the text form
+func (aH *APIHandler) QueryRangeV3Text(w http.ResponseWriter, r *http.Request) {
+	queryRangeParams, apiErrorObj := ParseQueryRangeTextParams(r)
+
+	if apiErrorObj != nil {
+		zap.L().Error("error parsing text query range params", zap.Error(apiErrorObj.Err))
+		RespondError(w, apiErrorObj, nil)
+		return
+	}
+
+	// add temporality for each metric
+	temporalityErr := aH.PopulateTemporality(r.Context(), queryRangeParams)
+	if temporalityErr != nil {
+		zap.L().Error("Error while adding temporality for metrics", zap.Error(temporalityErr))
+		RespondError(w, &model.ApiError{Typ: model.ErrorInternal, Err: temporalityErr}, nil)
+		return
+	}
+
+	aH.queryRangeV3(r.Context(), queryRangeParams, w, r)
+}
+
 func (aH *APIHandler) GetQueryProgressUpdates(w http.ResponseWriter, r *http.Request) {
 	// Upgrade connection to websocket, sending back the requested protocol
 	// value for sec-websocket-protocol
@@ -4385,33 +4683,29 @@ func (aH *APIHandler) getMetricMetadata(w http.ResponseWriter, r *http.Request)
 	aH.WriteJSON(w, r, metricMetadata)
 }
 
-func (aH *APIHandler) queryRangeV4(ctx context.Context, queryRangeParams *v3.QueryRangeParamsV3, w http.ResponseWriter, r *http.Request) {
-
-	var result []*v3.Result
-	var err error
-	var errQuriesByName map[string]error
-	var spanKeys map[string]v3.AttributeKey
+// enrichQueryRangeParamsV4 adds the types of the logs and traces attributes to the builder queries
+func (aH *APIHandler) enrichQueryRangeParamsV4(ctx context.Context, queryRangeParams *v3.QueryRangeParamsV3) *model.ApiError {
 	if queryRangeParams.CompositeQuery.QueryType == v3.QueryTypeBuilder {
 		// check if any enrichment is required for logs if yes then enrich them
 		if logsv3.EnrichmentRequired(queryRangeParams) {
 			// get the fields if any logs query is present
-			logsFields, err := aH.reader.GetLogFields(r.Context())
+			logsFields, err := aH.reader.GetLogFields(ctx)
 			if err != nil {
-				apiErrObj := &model.ApiError{Typ: model.ErrorInternal, Err: err}
-				RespondError(w, apiErrObj, nil)
-				return
+				return &model.ApiError{Typ: model.ErrorInternal, Err: err}
 			}
-			fields := model.GetLogFieldsV3(r.Context(), queryRangeParams, logsFields)
+			fields := model.GetLogFieldsV3(ctx, queryRangeParams, logsFields)
 			logsv3.Enrich(queryRangeParams, fields)
 		}
 
-		spanKeys, err = aH.getSpanKeysV3(ctx, queryRangeParams)
+		spanKeys, err := aH.getSpanKeysV3(ctx, queryRangeParams)
 		if err != nil {
-			apiErrObj := &model.ApiError{Typ: model.ErrorInternal, Err: err}
-			RespondError(w, apiErrObj, errQuriesByName)
-			return
+			return &model.ApiError{Typ: model.ErrorInternal, Err: err}
 		}
 		tracesV3.Enrich(queryRangeParams, spanKeys)
+
+		if err := aH.enrichApdex(ctx, queryRangeParams); err != nil {
+			return &model.ApiError{Typ: model.ErrorInternal, Err: err}
+		}
 	}
 
 	// WARN: Only works for AND operator in traces query
@@ -4429,10 +4723,28 @@ func (aH *APIHandler) queryRangeV4(ctx context.Context, queryRangeParams *v3.Que
 			}
 		}
 	}
+	return nil
+}
 
-	result, errQuriesByName, err = aH.querierV2.QueryRange(ctx, queryRangeParams)
+func (aH *APIHandler) queryRangeV4(ctx context.Context, queryRangeParams *v3.QueryRangeParamsV3, w http.ResponseWriter, r *http.Request) {
+	ctx = aH.withQueryLimits(ctx)
+
+	if apiErrObj := aH.enrichQueryRangeParamsV4(ctx, queryRangeParams); apiErrObj != nil {
+		RespondError(w, apiErrObj, nil)
+		return
+	}
+
+	if aH.dryRunQueryRange(ctx, aH.querierV2, queryRangeParams, w, r) {
+		return
+	}
+
+	result, errQuriesByName, err := aH.querierV2.QueryRange(ctx, queryRangeParams)
 
 	if err != nil {
+		if apiErr := aH.queryLimitError(err, errQuriesByName); apiErr != nil {
+			RespondError(w, apiErr, apiErr.Err)
+			return
+		}
 		queryErrors := map[string]string{}
 		for name, err := range errQuriesByName {
 			queryErrors[fmt.Sprintf("Query-%s", name)] = err.Error()
@@ -4442,13 +4754,7 @@ func (aH *APIHandler) queryRangeV4(ctx context.Context, queryRangeParams *v3.Que
 		return
 	}
 
-	if queryRangeParams.CompositeQuery.QueryType == v3.QueryTypeBuilder {
-		result, err = postprocess.PostProcessResult(result, queryRangeParams)
-	} else if queryRangeParams.CompositeQuery.QueryType == v3.QueryTypeClickHouseSQL &&
-		queryRangeParams.CompositeQuery.PanelType == v3.PanelTypeTable && queryRangeParams.FormatForWeb {
-		result = postprocess.TransformToTableForClickHouseQueries(result)
-	}
-
+	result, err = postProcessResultV4(result, queryRangeParams)
 	if err != nil {
 		apiErrObj := &model.ApiError{Typ: model.ErrorBadData, Err: err}
 		RespondError(w, apiErrObj, errQuriesByName)
@@ -4462,11 +4768,165 @@ func (aH *APIHandler) queryRangeV4(ctx context.Context, queryRangeParams *v3.Que
 	aH.Respond(w, resp)
 }
 
-func (aH *APIHandler) QueryRangeV4(w http.ResponseWriter, r *http.Request) {
-	queryRangeParams, apiErrorObj := ParseQueryRangeParams(r)
+// postProcessResultV4 evaluates the formulas and functions of the builder queries, applies the
+// functions of the promql and clickhouse queries and formats the tables of the clickhouse queries
+func postProcessResultV4(result []*v3.Result, queryRangeParams *v3.QueryRangeParamsV3) ([]*v3.Result, error) {
+	if queryRangeParams.CompositeQuery.QueryType == v3.QueryTypeBuilder {
+		return postprocess.PostProcessResult(result, queryRangeParams)
+	}
+	postprocess.ApplyFunctions(result, queryRangeParams)
+	if queryRangeParams.CompositeQuery.QueryType == v3.QueryTypeClickHouseSQL &&
+		queryRangeParams.CompositeQuery.PanelType == v3.PanelTypeTable && queryRangeParams.FormatForWeb {
+		return postprocess.TransformToTableForClickHouseQueries(result), nil
+	}
+	return result, nil
+}
 
+// parseQueryRangeParamsV4 parses the v4 query range request and adds the temporality of the metrics
+func (aH *APIHandler) parseQueryRangeParamsV4(r *http.Request) (*v3.QueryRangeParamsV3, *model.ApiError) {
+	queryRangeParams, apiErrorObj := ParseQueryRangeParams(r)
 	if apiErrorObj != nil {
 		zap.L().Error("error parsing metric query range params", zap.Error(apiErrorObj.Err))
+		return nil, apiErrorObj
+	}
+	queryRangeParams.Version = "v4"
+
+	// add temporality for each metric
+	temporalityErr := aH.PopulateTemporality(r.Context(), queryRangeParams)
+	if temporalityErr != nil {
+		zap.L().Error("Error while adding temporality for metrics", zap.Error(temporalityErr))
+		return nil, &model.ApiError{Typ: model.ErrorInternal, Err: temporalityErr}
+	}
+	return queryRangeParams, nil
+}
+
+// QueryRangeV4Async starts running the query range request in the background and responds with
+// the id of the async query. The status, progress and result of the query are fetched with
+// GetAsyncQueryRange until they expire from the cache
+func (aH *APIHandler) QueryRangeV4Async(w http.ResponseWriter, r *http.Request) {
+	queryRangeParams, apiErrorObj := aH.parseQueryRangeParamsV4(r)
+	if apiErrorObj != nil {
+		RespondError(w, apiErrorObj, nil)
+		return
+	}
+
+	ctx := aH.withQueryLimits(r.Context())
+	if apiErrObj := aH.enrichQueryRangeParamsV4(ctx, queryRangeParams); apiErrObj != nil {
+		RespondError(w, apiErrObj, nil)
+		return
+	}
+
+	query, apiErrObj := aH.asyncQueries.Submit(ctx, func(ctx context.Context) (*v3.QueryRangeResponse, error) {
+		result, _, err := aH.querierV2.QueryRange(ctx, queryRangeParams)
+		if err != nil {
+			return nil, err
+		}
+		result, err = postProcessResultV4(result, queryRangeParams)
+		if err != nil {
+			return nil, err
+		}
+		return &v3.QueryRangeResponse{Result: result}, nil
+	})
+	if apiErrObj != nil {
+		RespondError(w, apiErrObj, nil)
+		return
+	}
+	aH.Respond(w, query)
+}
+
+// GetAsyncQueryRange responds with the status of the async query, and the result once it succeeds
+func (aH *APIHandler) GetAsyncQueryRange(w http.ResponseWriter, r *http.Request) {
+	query, apiErrObj := aH.asyncQueries.Get(r.Context(), mux.Vars(r)["id"])
+	if apiErrObj != nil {
+		RespondError(w, apiErrObj, nil)
+		return
+	}
+	aH.Respond(w, query)
+}
+
+// CancelAsyncQueryRange cancels the running async query
+func (aH *APIHandler) CancelAsyncQueryRange(w http.ResponseWriter, r *http.Request) {
+	query, apiErrObj := aH.asyncQueries.Cancel(r.Context(), mux.Vars(r)["id"])
+	if apiErrObj != nil {
+		RespondError(w, apiErrObj, nil)
+		return
+	}
+	aH.Respond(w, query)
+}
+
+func (aH *APIHandler) QueryRangeV4(w http.ResponseWriter, r *http.Request) {
+	queryRangeParams, apiErrorObj := aH.parseQueryRangeParamsV4(r)
+	if apiErrorObj != nil {
+		RespondError(w, apiErrorObj, nil)
+		return
+	}
+
+	aH.queryRangeV4(r.Context(), queryRangeParams, w, r)
+}
+
+// QueryRangeV4Export streams the rows of the list or table query range request as NDJSON, CSV or Parquet
+// e.g. /api/v4/query_range/export?format=csv&limit=50000. The rows are capped at export.MaxRows,
+// the number of rows written and the error of an interrupted export are sent in the trailers
+func (aH *APIHandler) QueryRangeV4Export(w http.ResponseWriter, r *http.Request) {
+	format := export.Format(r.URL.Query().Get("format"))
+	if format == "" {
+		format = export.FormatNDJSON
+	}
+	if err := format.Validate(); err != nil {
+		RespondError(w, &model.ApiError{Typ: model.ErrorBadData, Err: err}, nil)
+		return
+	}
+	maxRows := uint64(export.MaxRows)
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		var err error
+		maxRows, err = strconv.ParseUint(limit, 10, 64)
+		if err != nil {
+			RespondError(w, &model.ApiError{Typ: model.ErrorBadData, Err: fmt.Errorf("invalid limit %s", limit)}, nil)
+			return
+		}
+	}
+
+	queryRangeParams, apiErrorObj := aH.parseQueryRangeParamsV4(r)
+	if apiErrorObj != nil {
+		RespondError(w, apiErrorObj, nil)
+		return
+	}
+
+	ctx := aH.withQueryLimits(r.Context())
+	if apiErrObj := aH.enrichQueryRangeParamsV4(ctx, queryRangeParams); apiErrObj != nil {
+		RespondError(w, apiErrObj, nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"export.%s\"", format))
+	w.Header().Set("Trailer", "X-Export-Rows, X-Export-Error")
+
+	rows, err := export.Export(ctx, aH.querierV2, queryRangeParams, format, maxRows, w)
+	if err != nil && rows == 0 {
+		// nothing is written yet, respond with the error
+		w.Header().Del("Content-Disposition")
+		w.Header().Del("Trailer")
+		if apiErr := aH.queryLimitError(err, nil); apiErr != nil {
+			RespondError(w, apiErr, apiErr.Err)
+			return
+		}
+		RespondError(w, &model.ApiError{Typ: model.ErrorBadData, Err: err}, nil)
+		return
+	}
+	if err != nil {
+		zap.L().Error("error exporting query range result", zap.Uint64("rows", rows), zap.Error(err))
+		w.Header().Set("X-Export-Error", err.Error())
+	}
+	w.Header().Set("X-Export-Rows", strconv.FormatUint(rows, 10))
+}
+
+// QueryRangeV4Text is the same as QueryRangeV4 with the builder queries in the text form
+func (aH *APIHandler) QueryRangeV4Text(w http.ResponseWriter, r *http.Request) {
+	queryRangeParams, apiErrorObj := ParseQueryRangeTextParams(r)
+
+	if apiErrorObj != nil {
+		zap.L().Error("error parsing text query range params", zap.Error(apiErrorObj.Err))
 		RespondError(w, apiErrorObj, nil)
 		return
 	}
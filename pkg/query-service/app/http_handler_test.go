@@ -2,13 +2,20 @@ package app
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	chErrors "go.signoz.io/signoz/pkg/query-service/errors"
 	"go.signoz.io/signoz/pkg/query-service/model"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.signoz.io/signoz/pkg/query-service/querylimits"
 )
 
 func TestPrepareQuery(t *testing.T) {
@@ -130,3 +137,108 @@ func TestPrepareQuery(t *testing.T) {
 		})
 	}
 }
+
+// errQuerier fails the queries with the errors of the queries, the same as the querier
+type errQuerier struct {
+	errQueriesByName map[string]error
+}
+
+func (q *errQuerier) QueryRange(context.Context, *v3.QueryRangeParamsV3) ([]*v3.Result, map[string]error, error) {
+	return nil, q.errQueriesByName, fmt.Errorf("error in builder queries")
+}
+
+func (q *errQuerier) Explain(context.Context, *v3.QueryRangeParamsV3, bool) ([]*v3.QueryExplanation, error) {
+	return nil, nil
+}
+
+func (q *errQuerier) QueriesExecuted() []string {
+	return nil
+}
+
+func (q *errQuerier) TimeRanges() [][]int {
+	return nil
+}
+
+func TestQueryRangeResourceLimitErrors(t *testing.T) {
+	testCases := []struct {
+		name         string
+		err          error
+		noLimits     bool
+		expectedCode int
+		expectedType model.ErrorType
+	}{
+		{
+			name:         "rows limit exceeded, code 158",
+			err:          chErrors.ErrResourceRowsLimitExceeded,
+			expectedCode: 422,
+			expectedType: model.ErrorQueryLimitExceeded,
+		},
+		{
+			name:         "time limit exceeded, code 159",
+			err:          chErrors.ErrResourceTimeLimitExceeded,
+			expectedCode: 422,
+			expectedType: model.ErrorQueryLimitExceeded,
+		},
+		{
+			name:         "bytes limit exceeded, code 307",
+			err:          chErrors.ErrResourceBytesLimitExceeded,
+			expectedCode: 422,
+			expectedType: model.ErrorQueryLimitExceeded,
+		},
+		{
+			name:         "rows limit exceeded without query limits",
+			err:          chErrors.ErrResourceRowsLimitExceeded,
+			noLimits:     true,
+			expectedCode: http.StatusInternalServerError,
+			expectedType: model.ErrorInternal,
+		},
+		{
+			name:         "other errors",
+			err:          fmt.Errorf("code: 60, table doesn't exist"),
+			expectedCode: http.StatusInternalServerError,
+			expectedType: model.ErrorInternal,
+		},
+	}
+
+	for _, tc := range testCases {
+		for _, version := range []string{"v3", "v4"} {
+			t.Run(version+" "+tc.name, func(t *testing.T) {
+				querier := &errQuerier{errQueriesByName: map[string]error{"A": tc.err}}
+				aH := &APIHandler{querier: querier, querierV2: querier}
+				if !tc.noLimits {
+					aH.queryLimits = &querylimits.Config{}
+				}
+				params := &v3.QueryRangeParamsV3{
+					Start: 1675115596722,
+					End:   1675115596722 + 60*60*1000,
+					CompositeQuery: &v3.CompositeQuery{
+						QueryType: v3.QueryTypeClickHouseSQL,
+						PanelType: v3.PanelTypeGraph,
+						ClickHouseQueries: map[string]*v3.ClickHouseQuery{
+							"A": {Query: "SELECT 1"},
+						},
+					},
+				}
+
+				r := httptest.NewRequest(http.MethodPost, "/api/"+version+"/query_range", nil)
+				w := httptest.NewRecorder()
+				if version == "v3" {
+					aH.queryRangeV3(r.Context(), params, w, r)
+				} else {
+					aH.queryRangeV4(r.Context(), params, w, r)
+				}
+
+				assert.Equal(t, tc.expectedCode, w.Code)
+				var resp struct {
+					ErrorType model.ErrorType `json:"errorType"`
+					Error     string          `json:"error"`
+				}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, tc.expectedType, resp.ErrorType)
+				if tc.expectedType == model.ErrorQueryLimitExceeded {
+					assert.Equal(t, tc.err.Error(), resp.Error)
+				}
+			})
+		}
+	}
+}
@@ -30,12 +30,15 @@ func EnrichmentRequired(params *v3.QueryRangeParamsV3) bool {
 		}
 
 		// check filter attribute
-		if query.Filters != nil && len(query.Filters.Items) != 0 {
-			for _, item := range query.Filters.Items {
-				if !isEnriched(item.Key) {
-					return true
-				}
+		enriched := true
+		_ = query.Filters.ForEachItem(func(item *v3.FilterItem) error {
+			if !isEnriched(item.Key) {
+				enriched = false
 			}
+			return nil
+		})
+		if !enriched {
+			return true
 		}
 
 		groupByLookup := map[string]struct{}{}
@@ -102,17 +105,16 @@ func EnrichLogsQuery(query *v3.BuilderQuery, fields map[string]v3.AttributeKey)
 		query.AggregateAttribute = enrichFieldWithMetadata(query.AggregateAttribute, fields)
 	}
 
-	// enrich filter attribute
-	if query.Filters != nil && len(query.Filters.Items) != 0 {
-		for i := 0; i < len(query.Filters.Items); i++ {
-			query.Filters.Items[i] = jsonFilterEnrich(query.Filters.Items[i])
-			if query.Filters.Items[i].Key.IsJSON {
-				query.Filters.Items[i] = jsonReplaceField(query.Filters.Items[i], fields)
-				continue
-			}
-			query.Filters.Items[i].Key = enrichFieldWithMetadata(query.Filters.Items[i].Key, fields)
+	// enrich filter attribute, including the ones in nested groups
+	_ = query.Filters.ForEachItem(func(item *v3.FilterItem) error {
+		*item = jsonFilterEnrich(*item)
+		if item.Key.IsJSON {
+			*item = jsonReplaceField(*item, fields)
+			return nil
 		}
-	}
+		item.Key = enrichFieldWithMetadata(item.Key, fields)
+		return nil
+	})
 
 	// enrich groupby
 	for i := 0; i < len(query.GroupBy); i++ {
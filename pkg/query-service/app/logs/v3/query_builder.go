@@ -162,6 +162,10 @@ func GetExistsNexistsFilter(op v3.FilterOperator, item v3.FilterItem) string {
 func buildLogsTimeSeriesFilterQuery(fs *v3.FilterSet, groupBy []v3.AttributeKey, aggregateAttribute v3.AttributeKey) (string, error) {
 	var conditions []string
 
+	if fs != nil && len(fs.Groups) != 0 {
+		return "", fmt.Errorf("filter groups are not supported with the old logs schema")
+	}
+
 	if fs != nil && len(fs.Items) != 0 {
 		for _, item := range fs.Items {
 			if item.Key.IsJSON {
@@ -366,6 +370,29 @@ func buildLogsQuery(panelType v3.PanelType, start, end, step int64, mq *v3.Build
 	}
 }
 
+// buildLogsHeatmapQuery returns the number of logs in each bucket of the aggregate attribute for every step
+func buildLogsHeatmapQuery(start, end, step int64, mq *v3.BuilderQuery) (string, error) {
+	filterSubQuery, err := buildLogsTimeSeriesFilterQuery(mq.Filters, mq.GroupBy, mq.AggregateAttribute)
+	if err != nil {
+		return "", err
+	}
+	if len(filterSubQuery) > 0 {
+		filterSubQuery = " AND " + filterSubQuery
+	}
+
+	// timerange will be sent in epoch millisecond
+	timeFilter := fmt.Sprintf("(timestamp >= %d AND timestamp <= %d)", utils.GetEpochNanoSecs(start), utils.GetEpochNanoSecs(end))
+
+	// the labels are selected irrespective of the aggregate operator, the logs are always counted
+	selectLabels := getSelectLabels(v3.AggregateOperatorCount, mq.GroupBy)
+	groupBy := GroupByAttributeKeyTags(v3.PanelTypeHeatmap, "", mq.GroupBy...)
+
+	query := fmt.Sprintf("SELECT toStartOfInterval(fromUnixTimestamp64Nano(timestamp), INTERVAL %d SECOND) AS ts,%s %s as le, toFloat64(count(*)) as value "+
+		"from signoz_logs.distributed_logs where "+timeFilter+"%s group by %s",
+		step, selectLabels, utils.HeatmapBucket(getClickhouseColumnName(mq.AggregateAttribute), mq.Buckets), filterSubQuery, groupBy)
+	return query, nil
+}
+
 func buildLogsLiveTailQuery(mq *v3.BuilderQuery) (string, error) {
 	filterSubQuery, err := buildLogsTimeSeriesFilterQuery(mq.Filters, mq.GroupBy, v3.AttributeKey{})
 	if err != nil {
@@ -391,6 +418,9 @@ func groupBy(panelType v3.PanelType, graphLimitQtype string, tags ...string) str
 	if (graphLimitQtype != constants.FirstQueryGraphLimit) && (panelType == v3.PanelTypeGraph || panelType == v3.PanelTypeValue) {
 		tags = append(tags, "ts")
 	}
+	if panelType == v3.PanelTypeHeatmap {
+		tags = append(tags, "le", "ts")
+	}
 	return strings.Join(tags, ",")
 }
 
@@ -511,6 +541,8 @@ func PrepareLogsQuery(start, end int64, queryType v3.QueryType, panelType v3.Pan
 			return "", err
 		}
 		return query, nil
+	} else if panelType == v3.PanelTypeHeatmap {
+		return buildLogsHeatmapQuery(start, end, mq.StepInterval, mq)
 	} else if options.GraphLimitQtype == constants.FirstQueryGraphLimit {
 		// give me just the groupby names
 		query, err := buildLogsQuery(panelType, start, end, mq.StepInterval, mq, options.GraphLimitQtype, options.PreferRPM)
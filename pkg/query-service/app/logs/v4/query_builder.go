@@ -172,10 +172,64 @@ func buildAttributeFilter(item v3.FilterItem) (string, error) {
 	}
 }
 
+// buildFilterItemConditions builds the conditions for a single filter item
+func buildFilterItemConditions(item v3.FilterItem) ([]string, error) {
+	// if the filter is json filter
+	if item.Key.IsJSON {
+		filter, err := GetJSONFilter(item)
+		if err != nil {
+			return nil, err
+		}
+		return []string{filter}, nil
+	}
+
+	// generate the filter
+	filter, err := buildAttributeFilter(item)
+	if err != nil {
+		return nil, err
+	}
+	conditions := []string{filter}
+
+	// add extra condition for map contains
+	// by default clickhouse is not able to utilize indexes for keys with all operators.
+	// mapContains forces the use of index.
+	op := v3.FilterOperator(strings.ToLower(string(item.Operator)))
+	if item.Key.IsColumn == false && op != v3.FilterOperatorExists && op != v3.FilterOperatorNotExists {
+		conditions = append(conditions, getExistsNexistsFilter(v3.FilterOperatorExists, item))
+	}
+	return conditions, nil
+}
+
+// buildFilterGroup builds the condition for a nested filter group
+// unlike the top level filter items, the resource attributes of a group are filtered
+// on the logs table itself as the resource sub query can't express OR and NOT
+func buildFilterGroup(fs *v3.FilterSet) (string, error) {
+	var conditions []string
+	for _, item := range fs.Items {
+		itemConditions, err := buildFilterItemConditions(item)
+		if err != nil {
+			return "", err
+		}
+		if len(itemConditions) > 1 {
+			conditions = append(conditions, "("+strings.Join(itemConditions, " AND ")+")")
+		} else {
+			conditions = append(conditions, itemConditions...)
+		}
+	}
+	for _, group := range fs.Groups {
+		condition, err := buildFilterGroup(group)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, condition)
+	}
+	return fs.JoinConditions(conditions), nil
+}
+
 func buildLogsTimeSeriesFilterQuery(fs *v3.FilterSet, groupBy []v3.AttributeKey, aggregateAttribute v3.AttributeKey) (string, error) {
 	var conditions []string
 
-	if fs == nil || len(fs.Items) == 0 {
+	if fs == nil || (len(fs.Items) == 0 && len(fs.Groups) == 0) {
 		return "", nil
 	}
 
@@ -185,30 +239,19 @@ func buildLogsTimeSeriesFilterQuery(fs *v3.FilterSet, groupBy []v3.AttributeKey,
 			continue
 		}
 
-		// if the filter is json filter
-		if item.Key.IsJSON {
-			filter, err := GetJSONFilter(item)
-			if err != nil {
-				return "", err
-			}
-			conditions = append(conditions, filter)
-			continue
+		itemConditions, err := buildFilterItemConditions(item)
+		if err != nil {
+			return "", err
 		}
+		conditions = append(conditions, itemConditions...)
+	}
 
-		// generate the filter
-		filter, err := buildAttributeFilter(item)
+	for _, group := range fs.Groups {
+		condition, err := buildFilterGroup(group)
 		if err != nil {
 			return "", err
 		}
-		conditions = append(conditions, filter)
-
-		// add extra condition for map contains
-		// by default clickhouse is not able to utilize indexes for keys with all operators.
-		// mapContains forces the use of index.
-		op := v3.FilterOperator(strings.ToLower(string(item.Operator)))
-		if item.Key.IsColumn == false && op != v3.FilterOperatorExists && op != v3.FilterOperatorNotExists {
-			conditions = append(conditions, getExistsNexistsFilter(v3.FilterOperatorExists, item))
-		}
+		conditions = append(conditions, condition)
 	}
 
 	// add group by conditions to filter out log lines which doesn't have the key
@@ -285,6 +328,7 @@ func orderByAttributeKeyTags(panelType v3.PanelType, items []v3.OrderBy, tags []
 
 func generateAggregateClause(aggOp v3.AggregateOperator,
 	aggKey string,
+	quantile float64,
 	step int64,
 	preferRPM bool,
 	timeFilter string,
@@ -333,6 +377,10 @@ func generateAggregateClause(aggOp v3.AggregateOperator,
 		op := fmt.Sprintf("quantile(%v)(%s)", logsV3.AggregateOperatorToPercentile[aggOp], aggKey)
 		query := fmt.Sprintf(queryTmpl, op, whereClause, groupBy, having, orderBy)
 		return query, nil
+	case v3.AggregateOperatorPercentile:
+		op := fmt.Sprintf("quantile(%v)(%s)", quantile, aggKey)
+		query := fmt.Sprintf(queryTmpl, op, whereClause, groupBy, having, orderBy)
+		return query, nil
 	case v3.AggregateOperatorAvg, v3.AggregateOperatorSum, v3.AggregateOperatorMin, v3.AggregateOperatorMax:
 		op := fmt.Sprintf("%s(%s)", logsV3.AggregateOperatorToSQLFunc[aggOp], aggKey)
 		query := fmt.Sprintf(queryTmpl, op, whereClause, groupBy, having, orderBy)
@@ -426,7 +474,7 @@ func buildLogsQuery(panelType v3.PanelType, start, end, step int64, mq *v3.Build
 		filterSubQuery = filterSubQuery + " AND " + fmt.Sprintf("(%s) GLOBAL IN (", logsV3.GetSelectKeys(mq.AggregateOperator, mq.GroupBy)) + "#LIMIT_PLACEHOLDER)"
 	}
 
-	aggClause, err := generateAggregateClause(mq.AggregateOperator, aggregationKey, step, preferRPM, timeFilter, filterSubQuery, groupBy, having, orderBy)
+	aggClause, err := generateAggregateClause(mq.AggregateOperator, aggregationKey, mq.Quantile, step, preferRPM, timeFilter, filterSubQuery, groupBy, having, orderBy)
 	if err != nil {
 		return "", err
 	}
@@ -456,6 +504,45 @@ func buildLogsQuery(panelType v3.PanelType, start, end, step int64, mq *v3.Build
 	return query, nil
 }
 
+// buildLogsHeatmapQuery returns the number of logs in each bucket of the aggregate attribute for every step
+func buildLogsHeatmapQuery(start, end, step int64, mq *v3.BuilderQuery) (string, error) {
+	// timerange will be sent in epoch millisecond
+	logsStart := utils.GetEpochNanoSecs(start)
+	logsEnd := utils.GetEpochNanoSecs(end)
+
+	// -1800 this is added so that the bucket start considers all the fingerprints.
+	bucketStart := logsStart/NANOSECOND - 1800
+	bucketEnd := logsEnd / NANOSECOND
+
+	// timestamp filter , bucket_start filter is added for primary key
+	timeFilter := fmt.Sprintf("(timestamp >= %d AND timestamp <= %d) AND (ts_bucket_start >= %d AND ts_bucket_start <= %d)", logsStart, logsEnd, bucketStart, bucketEnd)
+
+	filterSubQuery, err := buildLogsTimeSeriesFilterQuery(mq.Filters, mq.GroupBy, mq.AggregateAttribute)
+	if err != nil {
+		return "", err
+	}
+	if filterSubQuery != "" {
+		filterSubQuery = " AND " + filterSubQuery
+	}
+
+	resourceSubQuery, err := buildResourceSubQuery(bucketStart, bucketEnd, mq.Filters, mq.GroupBy, mq.AggregateAttribute, false)
+	if err != nil {
+		return "", err
+	}
+	if resourceSubQuery != "" {
+		filterSubQuery = filterSubQuery + " AND (resource_fingerprint GLOBAL IN " + resourceSubQuery + ")"
+	}
+
+	// the labels are selected irrespective of the aggregate operator, the logs are always counted
+	selectLabels := getSelectLabels(v3.AggregateOperatorCount, mq.GroupBy)
+	groupBy := logsV3.GroupByAttributeKeyTags(v3.PanelTypeHeatmap, "", mq.GroupBy...)
+
+	query := fmt.Sprintf("SELECT toStartOfInterval(fromUnixTimestamp64Nano(timestamp), INTERVAL %d SECOND) AS ts,%s %s as le, toFloat64(count(*)) as value "+
+		"from signoz_logs."+DISTRIBUTED_LOGS_V2+" where "+timeFilter+"%s group by %s",
+		step, selectLabels, utils.HeatmapBucket(getClickhouseKey(mq.AggregateAttribute), mq.Buckets), filterSubQuery, groupBy)
+	return query, nil
+}
+
 func buildLogsLiveTailQuery(mq *v3.BuilderQuery) (string, error) {
 	filterSubQuery, err := buildLogsTimeSeriesFilterQuery(mq.Filters, mq.GroupBy, v3.AttributeKey{})
 	if err != nil {
@@ -506,6 +593,8 @@ func PrepareLogsQuery(start, end int64, queryType v3.QueryType, panelType v3.Pan
 			return "", err
 		}
 		return query, nil
+	} else if panelType == v3.PanelTypeHeatmap {
+		return buildLogsHeatmapQuery(start, end, mq.StepInterval, mq)
 	} else if options.GraphLimitQtype == constants.FirstQueryGraphLimit {
 		// give me just the group_by names (no values)
 		query, err := buildLogsQuery(panelType, start, end, mq.StepInterval, mq, options.GraphLimitQtype, options.PreferRPM)
@@ -433,6 +433,70 @@ func Test_buildLogsTimeSeriesFilterQuery(t *testing.T) {
 			want: "attributes_string['service.name'] = 'test' AND mapContains(attributes_string, 'service.name') " +
 				"AND mapContains(attributes_string, 'user_name') AND `attribute_string_method_exists`=true AND mapContains(attributes_string, 'test')",
 		},
+		{
+			name: "build logs time series filter query with nested groups",
+			args: args{
+				fs: &v3.FilterSet{
+					Operator: "AND",
+					Items: []v3.FilterItem{
+						{
+							Key: v3.AttributeKey{
+								Key:      "method",
+								DataType: v3.AttributeKeyDataTypeString,
+								Type:     v3.AttributeKeyTypeTag,
+							},
+							Operator: v3.FilterOperatorEqual,
+							Value:    "GET",
+						},
+					},
+					Groups: []*v3.FilterSet{
+						{
+							Operator: "OR",
+							Items: []v3.FilterItem{
+								{
+									Key: v3.AttributeKey{
+										Key:      "service.name",
+										DataType: v3.AttributeKeyDataTypeString,
+										Type:     v3.AttributeKeyTypeResource,
+									},
+									Operator: v3.FilterOperatorEqual,
+									Value:    "cartservice",
+								},
+								{
+									Key: v3.AttributeKey{
+										Key:      "status",
+										DataType: v3.AttributeKeyDataTypeInt64,
+										Type:     v3.AttributeKeyTypeTag,
+									},
+									Operator: v3.FilterOperatorGreaterThanOrEq,
+									Value:    500,
+								},
+							},
+							Groups: []*v3.FilterSet{
+								{
+									Not: true,
+									Items: []v3.FilterItem{
+										{
+											Key: v3.AttributeKey{
+												Key:      "body",
+												DataType: v3.AttributeKeyDataTypeString,
+												IsColumn: true,
+											},
+											Operator: v3.FilterOperatorContains,
+											Value:    "healthcheck",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			want: "attributes_string['method'] = 'GET' AND mapContains(attributes_string, 'method') " +
+				"AND ((resources_string['service.name'] = 'cartservice' AND mapContains(resources_string, 'service.name')) " +
+				"OR (attributes_number['status'] >= 500 AND mapContains(attributes_number, 'status')) " +
+				"OR NOT (lower(body) LIKE lower('%healthcheck%')))",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -571,6 +635,7 @@ func Test_generateAggregateClause(t *testing.T) {
 	type args struct {
 		op          v3.AggregateOperator
 		aggKey      string
+		quantile    float64
 		step        int64
 		preferRPM   bool
 		timeFilter  string
@@ -619,10 +684,25 @@ func Test_generateAggregateClause(t *testing.T) {
 				"(ts_bucket_start >= 1680064560 AND ts_bucket_start <= 1680066458) AND attributes_string['service.name'] = 'test' group by `user_name` having value > 10 order by " +
 				"`user_name` desc",
 		},
+		{
+			name: "test percentile",
+			args: args{
+				op:          v3.AggregateOperatorPercentile,
+				aggKey:      "attributes_number['duration']",
+				quantile:    0.999,
+				step:        60,
+				timeFilter:  "(timestamp >= 1680066360726210000 AND timestamp <= 1680066458000000000) AND (ts_bucket_start >= 1680064560 AND ts_bucket_start <= 1680066458)",
+				whereClause: " AND attributes_string['service.name'] = 'test'",
+				groupBy:     " group by `user_name`",
+				orderBy:     " order by `user_name` desc",
+			},
+			want: " quantile(0.999)(attributes_number['duration']) as value from signoz_logs.distributed_logs_v2 where (timestamp >= 1680066360726210000 AND timestamp <= 1680066458000000000) AND " +
+				"(ts_bucket_start >= 1680064560 AND ts_bucket_start <= 1680066458) AND attributes_string['service.name'] = 'test' group by `user_name` order by `user_name` desc",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := generateAggregateClause(tt.args.op, tt.args.aggKey, tt.args.step, tt.args.preferRPM, tt.args.timeFilter, tt.args.whereClause, tt.args.groupBy, tt.args.having, tt.args.orderBy)
+			got, err := generateAggregateClause(tt.args.op, tt.args.aggKey, tt.args.quantile, tt.args.step, tt.args.preferRPM, tt.args.timeFilter, tt.args.whereClause, tt.args.groupBy, tt.args.having, tt.args.orderBy)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("generateAggreagteClause() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -854,6 +934,33 @@ func TestPrepareLogsQuery(t *testing.T) {
 				"(resource_fingerprint GLOBAL IN (SELECT fingerprint FROM signoz_logs.distributed_logs_v2_resource WHERE (seen_at_ts_bucket_start >= 1680064560) AND (seen_at_ts_bucket_start <= 1680066458) AND " +
 				"( (simpleJSONHas(labels, 'host') AND labels like '%host%') ))) group by `name`,`host` order by `name` DESC",
 		},
+		{
+			name: "HEATMAP: Test numeric attribute with group by",
+			args: args{
+				start:     1680066360726,
+				end:       1680066458000,
+				queryType: v3.QueryTypeBuilder,
+				panelType: v3.PanelTypeHeatmap,
+				mq: &v3.BuilderQuery{
+					QueryName:          "A",
+					StepInterval:       60,
+					AggregateAttribute: v3.AttributeKey{Key: "duration_ms", DataType: v3.AttributeKeyDataTypeFloat64, Type: v3.AttributeKeyTypeTag},
+					AggregateOperator:  v3.AggregateOperatorAvg,
+					Expression:         "A",
+					Filters: &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{
+						{Key: v3.AttributeKey{Key: "service.name", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeResource}, Value: "app", Operator: "="},
+					},
+					},
+					GroupBy: []v3.AttributeKey{{Key: "method", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}},
+				},
+			},
+			want: "SELECT toStartOfInterval(fromUnixTimestamp64Nano(timestamp), INTERVAL 60 SECOND) AS ts, attributes_string['method'] as `method`, " +
+				"if(attributes_number['duration_ms'] <= 0, 0, exp2(ceil(log2(attributes_number['duration_ms'])))) as le, toFloat64(count(*)) as value from signoz_logs.distributed_logs_v2 where " +
+				"(timestamp >= 1680066360726000000 AND timestamp <= 1680066458000000000) AND (ts_bucket_start >= 1680064560 AND ts_bucket_start <= 1680066458) AND " +
+				"mapContains(attributes_string, 'method') AND mapContains(attributes_number, 'duration_ms') AND " +
+				"(resource_fingerprint GLOBAL IN (SELECT fingerprint FROM signoz_logs.distributed_logs_v2_resource WHERE (seen_at_ts_bucket_start >= 1680064560) AND (seen_at_ts_bucket_start <= 1680066458) AND " +
+				"simpleJSONExtractString(labels, 'service.name') = 'app' AND labels like '%service.name%app%')) group by `method`,le,ts",
+		},
 		{
 			name: "Test TS with limit- first",
 			args: args{
@@ -335,13 +335,21 @@ func PrepareMetricQuery(start, end int64, queryType v3.QueryType, panelType v3.P
 
 	start, end = common.AdjustedMetricTimeRange(start, end, mq.StepInterval, *mq)
 
-	// if the aggregate operator is a histogram quantile, and user has not forgotten
-	// the le tag in the group by then add the le tag to the group by
-	if mq.AggregateOperator == v3.AggregateOperatorHistQuant50 ||
+	isHistogramQuantile := mq.AggregateOperator == v3.AggregateOperatorHistQuant50 ||
 		mq.AggregateOperator == v3.AggregateOperatorHistQuant75 ||
 		mq.AggregateOperator == v3.AggregateOperatorHistQuant90 ||
 		mq.AggregateOperator == v3.AggregateOperatorHistQuant95 ||
-		mq.AggregateOperator == v3.AggregateOperatorHistQuant99 {
+		mq.AggregateOperator == v3.AggregateOperatorHistQuant99
+
+	// heatmap shows the rate of each bucket of the histogram
+	// instead of the quantile
+	if panelType == v3.PanelTypeHeatmap && isHistogramQuantile {
+		mq.AggregateOperator = v3.AggregateOperatorSumRate
+	}
+
+	// if the aggregate operator is a histogram quantile, and user has not forgotten
+	// the le tag in the group by then add the le tag to the group by
+	if isHistogramQuantile || panelType == v3.PanelTypeHeatmap {
 		found := false
 		for _, tag := range mq.GroupBy {
 			if tag.Key == "le" {
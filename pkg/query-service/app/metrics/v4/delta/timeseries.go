@@ -123,8 +123,9 @@ func prepareQueryOptimized(start, end, step int64, mq *v3.BuilderQuery) (string,
 		v3.SpaceAggregationPercentile75,
 		v3.SpaceAggregationPercentile90,
 		v3.SpaceAggregationPercentile95,
-		v3.SpaceAggregationPercentile99:
-		op := fmt.Sprintf(sketchFmt, v3.GetPercentileFromOperator(mq.SpaceAggregation))
+		v3.SpaceAggregationPercentile99,
+		v3.SpaceAggregationPercentile:
+		op := fmt.Sprintf(sketchFmt, helpers.GetPercentile(mq))
 		query = fmt.Sprintf(queryTmpl, selectLabels, step, op, timeSeriesSubQuery, groupBy, orderBy)
 	}
 	return query, nil
@@ -2,9 +2,12 @@ package helpers
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.signoz.io/signoz/pkg/query-service/utils"
 )
 
 // groupingSets returns a string of comma separated tags for group by clause
@@ -71,3 +74,115 @@ func SelectLabels(tags []v3.AttributeKey) string {
 	}
 	return strings.Join(selectLabels, " ")
 }
+
+// GetPercentile returns the quantile for the percentile space aggregation of the query
+// The parameterised `percentile` space aggregation reads the quantile from the query
+// while the fixed ones (p50, p75 etc) derive it from the operator
+func GetPercentile(mq *v3.BuilderQuery) float64 {
+	if mq.SpaceAggregation == v3.SpaceAggregationPercentile {
+		return mq.Quantile
+	}
+	return v3.GetPercentileFromOperator(mq.SpaceAggregation)
+}
+
+// FormatQuantile formats the quantile with at least three decimal places
+// and as many more as needed to represent it exactly, e.g 0.990, 0.999, 0.9999
+func FormatQuantile(quantile float64) string {
+	precision := 3
+	for precision < 9 {
+		scale := math.Pow(10, float64(precision))
+		if math.Abs(math.Round(quantile*scale)/scale-quantile) < 1e-12 {
+			break
+		}
+		precision++
+	}
+	return strconv.FormatFloat(quantile, 'f', precision, 64)
+}
+
+// HistogramQuantile wraps the query, which should return the `le` buckets
+// with their values, to calculate the quantile from the fixed-bucket histogram
+func HistogramQuantile(query string, quantile float64, groupBy, orderBy string) string {
+	return fmt.Sprintf(
+		`SELECT %s, histogramQuantile(arrayMap(x -> toFloat64(x), groupArray(le)), groupArray(value), %s) as value FROM (%s) GROUP BY %s ORDER BY %s`,
+		groupBy, FormatQuantile(quantile), query, groupBy, orderBy,
+	)
+}
+
+// filterItemClause returns the clause for a single filter item on the time series labels
+func filterItemClause(item v3.FilterItem) (string, error) {
+	toFormat := item.Value
+	op := v3.FilterOperator(strings.ToLower(strings.TrimSpace(string(item.Operator))))
+	if op == v3.FilterOperatorContains || op == v3.FilterOperatorNotContains {
+		toFormat = fmt.Sprintf("%%%s%%", toFormat)
+	}
+	fmtVal := utils.ClickHouseFormattedValue(toFormat)
+	switch op {
+	case v3.FilterOperatorEqual:
+		return fmt.Sprintf("JSONExtractString(labels, '%s') = %s", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorNotEqual:
+		return fmt.Sprintf("JSONExtractString(labels, '%s') != %s", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorIn:
+		return fmt.Sprintf("JSONExtractString(labels, '%s') IN %s", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorNotIn:
+		return fmt.Sprintf("JSONExtractString(labels, '%s') NOT IN %s", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorLike:
+		return fmt.Sprintf("like(JSONExtractString(labels, '%s'), %s)", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorNotLike:
+		return fmt.Sprintf("notLike(JSONExtractString(labels, '%s'), %s)", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorRegex:
+		return fmt.Sprintf("match(JSONExtractString(labels, '%s'), %s)", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorNotRegex:
+		return fmt.Sprintf("not match(JSONExtractString(labels, '%s'), %s)", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorGreaterThan:
+		return fmt.Sprintf("JSONExtractString(labels, '%s') > %s", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorGreaterThanOrEq:
+		return fmt.Sprintf("JSONExtractString(labels, '%s') >= %s", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorLessThan:
+		return fmt.Sprintf("JSONExtractString(labels, '%s') < %s", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorLessThanOrEq:
+		return fmt.Sprintf("JSONExtractString(labels, '%s') <= %s", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorContains:
+		return fmt.Sprintf("like(JSONExtractString(labels, '%s'), %s)", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorNotContains:
+		return fmt.Sprintf("notLike(JSONExtractString(labels, '%s'), %s)", item.Key.Key, fmtVal), nil
+	case v3.FilterOperatorExists:
+		return fmt.Sprintf("has(JSONExtractKeys(labels), '%s')", item.Key.Key), nil
+	case v3.FilterOperatorNotExists:
+		return fmt.Sprintf("not has(JSONExtractKeys(labels), '%s')", item.Key.Key), nil
+	default:
+		return "", fmt.Errorf("unsupported filter operator")
+	}
+}
+
+// filterGroupClause returns the clause for a nested filter group
+func filterGroupClause(fs *v3.FilterSet) (string, error) {
+	clauses, err := filterSetClauses(fs)
+	if err != nil {
+		return "", err
+	}
+	return fs.JoinConditions(clauses), nil
+}
+
+// filterSetClauses returns the clauses for the items and the nested groups of the filter set
+// the clauses of the top level filter set are joined with AND and the ones of a group with its operator
+func filterSetClauses(fs *v3.FilterSet) ([]string, error) {
+	var clauses []string
+	if fs == nil {
+		return clauses, nil
+	}
+	for _, item := range fs.Items {
+		clause, err := filterItemClause(item)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	for _, group := range fs.Groups {
+		clause, err := filterGroupClause(group)
+		if err != nil {
+			return nil, err
+		}
+		clauses = append(clauses, clause)
+	}
+	return clauses, nil
+}
@@ -25,29 +25,29 @@ func PrepareMetricQuery(start, end int64, queryType v3.QueryType, panelType v3.P
 
 	percentileOperator := mq.SpaceAggregation
 
-	if v3.IsPercentileOperator(mq.SpaceAggregation) &&
+	if panelType == v3.PanelTypeHeatmap {
+		if mq.AggregateAttribute.Type == v3.AttributeKeyType(v3.MetricTypeExponentialHistogram) {
+			return "", fmt.Errorf("heatmap panel type is not supported for exponential histogram metrics")
+		}
+		// heatmap shows the rate of each bucket of the histogram, the time and space
+		// aggregations are kept if they are set, the buckets are rebuilt from le
+		if mq.TimeAggregation == v3.TimeAggregationUnspecified || v3.IsPercentileOperator(mq.SpaceAggregation) {
+			mq.TimeAggregation = v3.TimeAggregationRate
+		}
+		if mq.SpaceAggregation == v3.SpaceAggregationUnspecified || v3.IsPercentileOperator(mq.SpaceAggregation) {
+			mq.SpaceAggregation = v3.SpaceAggregationSum
+		}
+		addLeToGroupBy(mq)
+	} else if v3.IsPercentileOperator(mq.SpaceAggregation) &&
 		mq.AggregateAttribute.Type != v3.AttributeKeyType(v3.MetricTypeExponentialHistogram) {
-		quantile = v3.GetPercentileFromOperator(mq.SpaceAggregation)
+		quantile = helpers.GetPercentile(mq)
 		// If quantile is set, we need to group by le
 		// and set the space aggregation to sum
 		// and time aggregation to rate
 		mq.TimeAggregation = v3.TimeAggregationRate
 		mq.SpaceAggregation = v3.SpaceAggregationSum
 		// If le is not present in group by for quantile, add it
-		leFound := false
-		for _, groupBy := range mq.GroupBy {
-			if groupBy.Key == "le" {
-				leFound = true
-				break
-			}
-		}
-		if !leFound {
-			mq.GroupBy = append(mq.GroupBy, v3.AttributeKey{
-				Key:      "le",
-				Type:     v3.AttributeKeyTypeTag,
-				DataType: v3.AttributeKeyDataTypeString,
-			})
-		}
+		addLeToGroupBy(mq)
 	}
 
 	var query string
@@ -81,13 +81,26 @@ func PrepareMetricQuery(start, end int64, queryType v3.QueryType, panelType v3.P
 
 	// fixed-bucket histogram quantiles are calculated with UDF
 	if quantile != 0 && mq.AggregateAttribute.Type != v3.AttributeKeyType(v3.MetricTypeExponentialHistogram) {
-		query = fmt.Sprintf(`SELECT %s, histogramQuantile(arrayMap(x -> toFloat64(x), groupArray(le)), groupArray(value), %.3f) as value FROM (%s) GROUP BY %s ORDER BY %s`, groupBy, quantile, query, groupBy, orderBy)
+		query = helpers.HistogramQuantile(query, quantile, groupBy, orderBy)
 		mq.SpaceAggregation = percentileOperator
 	}
 
 	return query, nil
 }
 
+func addLeToGroupBy(mq *v3.BuilderQuery) {
+	for _, groupBy := range mq.GroupBy {
+		if groupBy.Key == "le" {
+			return
+		}
+	}
+	mq.GroupBy = append(mq.GroupBy, v3.AttributeKey{
+		Key:      "le",
+		Type:     v3.AttributeKeyTypeTag,
+		DataType: v3.AttributeKeyDataTypeString,
+	})
+}
+
 func BuildPromQuery(promQuery *v3.PromQuery, step, start, end int64) *model.QueryRangeParams {
 	return &model.QueryRangeParams{
 		Query: promQuery.Query,
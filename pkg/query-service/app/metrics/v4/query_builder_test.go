@@ -140,6 +140,81 @@ func TestPrepareTimeseriesFilterQuery(t *testing.T) {
 			},
 			expectedQueryContains: "SELECT DISTINCT JSONExtractString(labels, 'service_name') as service_name, fingerprint FROM signoz_metrics.time_series_v4 WHERE metric_name = 'http_requests' AND temporality = 'Cumulative' AND unix_milli >= 1706428800000 AND unix_milli < 1706434026000 AND JSONExtractString(labels, 'service_name') != 'payment_service' AND JSONExtractString(labels, 'endpoint') IN ['/paycallback','/payme','/paypal']",
 		},
+		{
+			name: "test prepare time series with nested filter groups",
+			builderQuery: &v3.BuilderQuery{
+				QueryName:    "A",
+				StepInterval: 60,
+				DataSource:   v3.DataSourceMetrics,
+				AggregateAttribute: v3.AttributeKey{
+					Key:      "http_requests",
+					DataType: v3.AttributeKeyDataTypeFloat64,
+					Type:     v3.AttributeKeyTypeUnspecified,
+					IsColumn: true,
+					IsJSON:   false,
+				},
+				Temporality: v3.Cumulative,
+				Filters: &v3.FilterSet{
+					Operator: "AND",
+					Items: []v3.FilterItem{
+						{
+							Key: v3.AttributeKey{
+								Key:      "service_name",
+								Type:     v3.AttributeKeyTypeTag,
+								DataType: v3.AttributeKeyDataTypeString,
+							},
+							Operator: v3.FilterOperatorEqual,
+							Value:    "payment_service",
+						},
+					},
+					Groups: []*v3.FilterSet{
+						{
+							Operator: "OR",
+							Items: []v3.FilterItem{
+								{
+									Key: v3.AttributeKey{
+										Key:      "endpoint",
+										Type:     v3.AttributeKeyTypeTag,
+										DataType: v3.AttributeKeyDataTypeString,
+									},
+									Operator: v3.FilterOperatorEqual,
+									Value:    "/paypal",
+								},
+								{
+									Key: v3.AttributeKey{
+										Key:      "status_code",
+										Type:     v3.AttributeKeyTypeTag,
+										DataType: v3.AttributeKeyDataTypeString,
+									},
+									Operator: v3.FilterOperatorLike,
+									Value:    "5%",
+								},
+							},
+							Groups: []*v3.FilterSet{
+								{
+									Not: true,
+									Items: []v3.FilterItem{
+										{
+											Key: v3.AttributeKey{
+												Key:      "env",
+												Type:     v3.AttributeKeyTypeTag,
+												DataType: v3.AttributeKeyDataTypeString,
+											},
+											Operator: v3.FilterOperatorEqual,
+											Value:    "dev",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+				Expression: "A",
+				Disabled:   false,
+				// remaining struct fields are not needed here
+			},
+			expectedQueryContains: "SELECT DISTINCT fingerprint FROM signoz_metrics.time_series_v4 WHERE metric_name = 'http_requests' AND temporality = 'Cumulative' AND unix_milli >= 1706428800000 AND unix_milli < 1706434026000 AND JSONExtractString(labels, 'service_name') = 'payment_service' AND (JSONExtractString(labels, 'endpoint') = '/paypal' OR like(JSONExtractString(labels, 'status_code'), '5%') OR NOT (JSONExtractString(labels, 'env') = 'dev'))",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -387,6 +462,36 @@ func TestPrepreMetricQueryCumulativeQuantile(t *testing.T) {
 	}
 }
 
+func TestPrepareMetricQueryCumulativeHeatmap(t *testing.T) {
+	builderQuery := &v3.BuilderQuery{
+		QueryName:    "A",
+		StepInterval: 60,
+		DataSource:   v3.DataSourceMetrics,
+		AggregateAttribute: v3.AttributeKey{
+			Key: "signoz_latency_bucket",
+		},
+		Temporality: v3.Cumulative,
+		GroupBy: []v3.AttributeKey{{
+			Key:      "service_name",
+			DataType: v3.AttributeKeyDataTypeString,
+			Type:     v3.AttributeKeyTypeTag,
+		}},
+		Expression:       "A",
+		SpaceAggregation: v3.SpaceAggregationPercentile99,
+	}
+
+	query, err := PrepareMetricQuery(1650991982000, 1651078382000, v3.QueryTypeBuilder, v3.PanelTypeHeatmap, builderQuery, metricsV3.Options{})
+	assert.Nil(t, err)
+	// the rate of each bucket is returned instead of the quantile
+	assert.Equal(t, "SELECT service_name, le, ts, sum(per_series_value) as value FROM (SELECT service_name, le, ts, If((per_series_value - lagInFrame(per_series_value, 1, 0) OVER rate_window) < 0, nan, If((ts - lagInFrame(ts, 1, toDate('1970-01-01')) OVER rate_window) >= 86400, nan, (per_series_value - lagInFrame(per_series_value, 1, 0) OVER rate_window) / (ts - lagInFrame(ts, 1, toDate('1970-01-01')) OVER rate_window))) as per_series_value FROM (SELECT fingerprint, any(service_name) as service_name, any(le) as le, toStartOfInterval(toDateTime(intDiv(unix_milli, 1000)), INTERVAL 60 SECOND) as ts, max(value) as per_series_value FROM signoz_metrics.distributed_samples_v4 INNER JOIN (SELECT DISTINCT JSONExtractString(labels, 'service_name') as service_name, JSONExtractString(labels, 'le') as le, fingerprint FROM signoz_metrics.time_series_v4_6hrs WHERE metric_name = 'signoz_latency_bucket' AND temporality = 'Cumulative' AND unix_milli >= 1650974400000 AND unix_milli < 1651078380000) as filtered_time_series USING fingerprint WHERE metric_name = 'signoz_latency_bucket' AND unix_milli >= 1650991980000 AND unix_milli < 1651078380000 GROUP BY fingerprint, ts ORDER BY fingerprint, ts) WINDOW rate_window as (PARTITION BY fingerprint ORDER BY fingerprint, ts)) WHERE isNaN(per_series_value) = 0 GROUP BY service_name, le, ts ORDER BY service_name ASC, le ASC, ts ASC", query)
+	assert.Equal(t, v3.TimeAggregationRate, builderQuery.TimeAggregation)
+	assert.Equal(t, v3.SpaceAggregationSum, builderQuery.SpaceAggregation)
+
+	builderQuery.AggregateAttribute.Type = v3.AttributeKeyType(v3.MetricTypeExponentialHistogram)
+	_, err = PrepareMetricQuery(1650991982000, 1651078382000, v3.QueryTypeBuilder, v3.PanelTypeHeatmap, builderQuery, metricsV3.Options{})
+	assert.EqualError(t, err, "heatmap panel type is not supported for exponential histogram metrics")
+}
+
 func TestPrepreMetricQueryDeltaQuantile(t *testing.T) {
 	testCases := []struct {
 		name                  string
@@ -458,6 +563,37 @@ func TestPrepreMetricQueryDeltaQuantile(t *testing.T) {
 			},
 			expectedQueryContains: "SELECT ts, histogramQuantile(arrayMap(x -> toFloat64(x), groupArray(le)), groupArray(value), 0.990) as value FROM (SELECT le, toStartOfInterval(toDateTime(intDiv(unix_milli, 1000)), INTERVAL 60 SECOND) as ts, sum(value)/60 as value FROM signoz_metrics.distributed_samples_v4 INNER JOIN (SELECT DISTINCT JSONExtractString(labels, 'le') as le, fingerprint FROM signoz_metrics.time_series_v4_6hrs WHERE metric_name = 'signoz_latency_bucket' AND temporality = 'Delta' AND unix_milli >= 1650974400000 AND unix_milli < 1651078380000 AND like(JSONExtractString(labels, 'service_name'), '%frontend%')) as filtered_time_series USING fingerprint WHERE metric_name = 'signoz_latency_bucket' AND unix_milli >= 1650991980000 AND unix_milli < 1651078380000 GROUP BY le, ts ORDER BY le ASC, ts ASC) GROUP BY ts ORDER BY ts ASC",
 		},
+		{
+			name: "test temporality = delta, quantile = 0.9999 no group by",
+			builderQuery: &v3.BuilderQuery{
+				QueryName:    "A",
+				StepInterval: 60,
+				DataSource:   v3.DataSourceMetrics,
+				AggregateAttribute: v3.AttributeKey{
+					Key: "signoz_latency_bucket",
+				},
+				Temporality: v3.Delta,
+				Filters: &v3.FilterSet{
+					Operator: "AND",
+					Items: []v3.FilterItem{
+						{
+							Key: v3.AttributeKey{
+								Key:      "service_name",
+								Type:     v3.AttributeKeyTypeTag,
+								DataType: v3.AttributeKeyDataTypeString,
+							},
+							Operator: v3.FilterOperatorContains,
+							Value:    "frontend",
+						},
+					},
+				},
+				Expression:       "A",
+				Disabled:         false,
+				SpaceAggregation: v3.SpaceAggregationPercentile,
+				Quantile:         0.9999,
+			},
+			expectedQueryContains: "SELECT ts, histogramQuantile(arrayMap(x -> toFloat64(x), groupArray(le)), groupArray(value), 0.9999) as value FROM (SELECT le, toStartOfInterval(toDateTime(intDiv(unix_milli, 1000)), INTERVAL 60 SECOND) as ts, sum(value)/60 as value FROM signoz_metrics.distributed_samples_v4 INNER JOIN (SELECT DISTINCT JSONExtractString(labels, 'le') as le, fingerprint FROM signoz_metrics.time_series_v4_6hrs WHERE metric_name = 'signoz_latency_bucket' AND temporality = 'Delta' AND unix_milli >= 1650974400000 AND unix_milli < 1651078380000 AND like(JSONExtractString(labels, 'service_name'), '%frontend%')) as filtered_time_series USING fingerprint WHERE metric_name = 'signoz_latency_bucket' AND unix_milli >= 1650991980000 AND unix_milli < 1651078380000 GROUP BY le, ts ORDER BY le ASC, ts ASC) GROUP BY ts ORDER BY ts ASC",
+		},
 	}
 
 	for _, testCase := range testCases {
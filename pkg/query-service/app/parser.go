@@ -20,6 +20,7 @@ import (
 
 	"go.signoz.io/signoz/pkg/query-service/app/metrics"
 	"go.signoz.io/signoz/pkg/query-service/app/queryBuilder"
+	"go.signoz.io/signoz/pkg/query-service/app/querylang"
 	"go.signoz.io/signoz/pkg/query-service/auth"
 	"go.signoz.io/signoz/pkg/query-service/common"
 	baseconstants "go.signoz.io/signoz/pkg/query-service/constants"
@@ -732,6 +733,26 @@ func parseSetApdexScoreRequest(r *http.Request) (*model.ApdexSettings, error) {
 	return &req, nil
 }
 
+func parseInvalidateCacheRequest(r *http.Request) (*model.InvalidateCacheRequest, error) {
+	var req model.InvalidateCacheRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return nil, err
+	}
+	if len(req.Hashes) == 0 && req.Prefix == "" && req.DashboardID == "" {
+		return nil, fmt.Errorf("one of hashes, prefix or dashboardId is required")
+	}
+	if req.Start < 0 || req.End < 0 {
+		return nil, fmt.Errorf("start and end must not be negative")
+	}
+	if req.Start > 0 && req.End == 0 {
+		req.End = time.Now().UnixMilli()
+	}
+	if req.Start > req.End {
+		return nil, fmt.Errorf("start must not be after end")
+	}
+	return &req, nil
+}
+
 func parseInsertIngestionKeyRequest(r *http.Request) (*model.IngestionKey, error) {
 	var req model.IngestionKey
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -983,6 +1004,15 @@ func validateQueryRangeParamsV3(qp *v3.QueryRangeParamsV3) error {
 		return err
 	}
 
+	if qp.Compare != nil {
+		if qp.CompositeQuery.QueryType != v3.QueryTypeBuilder {
+			return fmt.Errorf("compare is only supported for builder queries")
+		}
+		if err := qp.Compare.Validate(); err != nil {
+			return err
+		}
+	}
+
 	var expressions []string
 	for _, q := range qp.CompositeQuery.BuilderQueries {
 		expressions = append(expressions, q.Expression)
@@ -1004,6 +1034,10 @@ func validateExpressions(expressions []string, funcs map[string]govaluate.Expres
 			errs = append(errs, fmt.Errorf("invalid expression %s: %v", exp, err))
 			continue
 		}
+		if err := postprocess.ValidateFormula(evalExp); err != nil {
+			errs = append(errs, fmt.Errorf("invalid expression %s: %v", exp, err))
+			continue
+		}
 		for _, v := range evalExp.Vars() {
 			var hasVariable bool
 			for _, q := range cq.BuilderQueries {
@@ -1029,6 +1063,45 @@ func ParseQueryRangeParams(r *http.Request) (*v3.QueryRangeParamsV3, *model.ApiE
 		return nil, &model.ApiError{Typ: model.ErrorBadData, Err: fmt.Errorf("cannot parse the request body: %v", err)}
 	}
 
+	return prepareQueryRangeParams(queryRangeParams)
+}
+
+// ParseQueryRangeTextParams parses the query range request with the builder queries in the text form
+func ParseQueryRangeTextParams(r *http.Request) (*v3.QueryRangeParamsV3, *model.ApiError) {
+
+	var textParams v3.QueryRangeTextParams
+
+	// parse the request body
+	if err := json.NewDecoder(r.Body).Decode(&textParams); err != nil {
+		return nil, &model.ApiError{Typ: model.ErrorBadData, Err: fmt.Errorf("cannot parse the request body: %v", err)}
+	}
+
+	compositeQuery, err := querylang.ParseCompositeQuery(textParams.PanelType, textParams.Queries)
+	if err != nil {
+		return nil, &model.ApiError{Typ: model.ErrorBadData, Err: err}
+	}
+
+	for _, query := range compositeQuery.BuilderQueries {
+		// the step interval is optional in the text form, default to a minute
+		if query.QueryName == query.Expression && query.StepInterval == 0 {
+			query.StepInterval = 60
+		}
+	}
+
+	return prepareQueryRangeParams(&v3.QueryRangeParamsV3{
+		Start:          textParams.Start,
+		End:            textParams.End,
+		Step:           textParams.Step,
+		CompositeQuery: compositeQuery,
+		Variables:      textParams.Variables,
+		NoCache:        textParams.NoCache,
+		FormatForWeb:   textParams.FormatForWeb,
+	})
+}
+
+// prepareQueryRangeParams validates the query range params and replaces the variables in the queries
+func prepareQueryRangeParams(queryRangeParams *v3.QueryRangeParamsV3) (*v3.QueryRangeParamsV3, *model.ApiError) {
+
 	// sanitize the request body
 	queryRangeParams.CompositeQuery.Sanitize()
 
@@ -1037,6 +1110,11 @@ func ParseQueryRangeParams(r *http.Request) (*v3.QueryRangeParamsV3, *model.ApiE
 		return nil, &model.ApiError{Typ: model.ErrorBadData, Err: err}
 	}
 
+	// add the shifted queries and the formulas of the compare option
+	if err := postprocess.ExpandCompareQueries(queryRangeParams); err != nil {
+		return nil, &model.ApiError{Typ: model.ErrorBadData, Err: err}
+	}
+
 	// prepare the variables for the corresponding query type
 	formattedVars := make(map[string]interface{})
 	for name, value := range queryRangeParams.Variables {
@@ -1061,6 +1139,15 @@ func ParseQueryRangeParams(r *http.Request) (*v3.QueryRangeParamsV3, *model.ApiE
 					return nil, &model.ApiError{Typ: model.ErrorBadData, Err: err}
 				}
 
+				// the queries reduced across their series e.g sum(A) and timestamp() have no group keys
+				expression, reduced, err := postprocess.ExpandFormula(expression)
+				if err != nil {
+					return nil, &model.ApiError{Typ: model.ErrorBadData, Err: err}
+				}
+				if query.Join != nil && len(reduced) > 0 {
+					return nil, &model.ApiError{Typ: model.ErrorBadData, Err: fmt.Errorf("cross series reducers are not supported in the formulas with join")}
+				}
+
 				// get the group keys for the vars
 				groupKeys := make(map[string][]string)
 				for _, v := range expression.Vars() {
@@ -1069,6 +1156,8 @@ func ParseQueryRangeParams(r *http.Request) (*v3.QueryRangeParamsV3, *model.ApiE
 						for _, key := range varQuery.GroupBy {
 							groupKeys[v] = append(groupKeys[v], key.Key)
 						}
+					} else if _, ok := reduced[v]; ok || v == postprocess.TimestampVar {
+						groupKeys[v] = []string{}
 					} else {
 						return nil, &model.ApiError{Typ: model.ErrorBadData, Err: fmt.Errorf("unknown variable %s", v)}
 					}
@@ -1114,12 +1203,12 @@ func ParseQueryRangeParams(r *http.Request) (*v3.QueryRangeParamsV3, *model.ApiE
 			}
 			query.ShiftBy = timeShiftBy
 
-			if query.Filters == nil || len(query.Filters.Items) == 0 {
+			if query.Filters == nil || (len(query.Filters.Items) == 0 && len(query.Filters.Groups) == 0) {
 				continue
 			}
 
-			for idx := range query.Filters.Items {
-				item := &query.Filters.Items[idx]
+			// substitute the variables in the nested filter groups as well
+			err := query.Filters.ForEachItem(func(item *v3.FilterItem) error {
 				value := item.Value
 				if value != nil {
 					switch x := value.(type) {
@@ -1144,9 +1233,13 @@ func ParseQueryRangeParams(r *http.Request) (*v3.QueryRangeParamsV3, *model.ApiE
 				if v3.FilterOperator(strings.ToLower((string(item.Operator)))) != v3.FilterOperatorIn && v3.FilterOperator(strings.ToLower((string(item.Operator)))) != v3.FilterOperatorNotIn {
 					// the value type should not be multiple values
 					if _, ok := item.Value.([]interface{}); ok {
-						return nil, &model.ApiError{Typ: model.ErrorBadData, Err: fmt.Errorf("multiple values %s are not allowed for operator `%s` for key `%s`", item.Value, item.Operator, item.Key.Key)}
+						return fmt.Errorf("multiple values %s are not allowed for operator `%s` for key `%s`", item.Value, item.Operator, item.Key.Key)
 					}
 				}
+				return nil
+			})
+			if err != nil {
+				return nil, &model.ApiError{Typ: model.ErrorBadData, Err: err}
 			}
 		}
 	}
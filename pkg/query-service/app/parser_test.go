@@ -12,6 +12,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.signoz.io/signoz/pkg/query-service/common"
+	"go.signoz.io/signoz/pkg/query-service/model"
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
 )
 
@@ -594,6 +595,58 @@ func TestParseQueryRangeParamsExpressions(t *testing.T) {
 			expectErr: true,
 			errMsg:    "unknown variable B; unknown variable C",
 		},
+		{
+			desc: "invalid number of function arguments",
+			compositeQuery: v3.CompositeQuery{
+				PanelType: v3.PanelTypeGraph,
+				QueryType: v3.QueryTypeBuilder,
+				BuilderQueries: map[string]*v3.BuilderQuery{
+					"A": {
+						QueryName:          "A",
+						DataSource:         v3.DataSourceLogs,
+						AggregateOperator:  v3.AggregateOperatorSum,
+						AggregateAttribute: v3.AttributeKey{Key: "attribute_logs"},
+						Expression:         "A",
+					},
+					"F1": {
+						QueryName:  "F1",
+						Expression: "if(A > 10, A)",
+					},
+				},
+			},
+			expectErr: true,
+			errMsg:    "function if takes 3 arguments, got 2",
+		},
+		{
+			desc: "cross series reducer joins with any group by",
+			compositeQuery: v3.CompositeQuery{
+				PanelType: v3.PanelTypeGraph,
+				QueryType: v3.QueryTypeBuilder,
+				BuilderQueries: map[string]*v3.BuilderQuery{
+					"A": {
+						QueryName:          "A",
+						DataSource:         v3.DataSourceLogs,
+						AggregateOperator:  v3.AggregateOperatorCount,
+						AggregateAttribute: v3.AttributeKey{Key: "attribute_logs"},
+						GroupBy:            []v3.AttributeKey{{Key: "service_name"}},
+						Expression:         "A",
+					},
+					"B": {
+						QueryName:          "B",
+						DataSource:         v3.DataSourceLogs,
+						AggregateOperator:  v3.AggregateOperatorCount,
+						AggregateAttribute: v3.AttributeKey{Key: "attribute_logs"},
+						GroupBy:            []v3.AttributeKey{{Key: "host_name"}},
+						Expression:         "B",
+					},
+					"F1": {
+						QueryName:  "F1",
+						Expression: "default(A, 0) * 100 / sum(B)",
+					},
+				},
+			},
+			expectErr: false,
+		},
 	}
 
 	for _, tc := range reqCases {
@@ -1498,3 +1551,89 @@ func TestParseQueryRangeParamsStepIntervalAdjustment(t *testing.T) {
 		})
 	}
 }
+
+func TestParseQueryRangeTextParams(t *testing.T) {
+	reqCases := []struct {
+		desc        string
+		queries     map[string]string
+		variables   map[string]interface{}
+		expectErr   bool
+		errMsg      string
+		checkParams func(t *testing.T, params *v3.QueryRangeParamsV3)
+	}{
+		{
+			desc: "builder query with the default step and variables",
+			queries: map[string]string{
+				"A": `logs | where service.name = $service and severity_text = "ERROR" | count() by k8s.pod.name`,
+			},
+			variables: map[string]interface{}{"service": "api"},
+			checkParams: func(t *testing.T, params *v3.QueryRangeParamsV3) {
+				query := params.CompositeQuery.BuilderQueries["A"]
+				require.NotNil(t, query)
+				assert.Equal(t, v3.QueryTypeBuilder, params.CompositeQuery.QueryType)
+				assert.Equal(t, v3.DataSourceLogs, query.DataSource)
+				assert.Equal(t, v3.AggregateOperatorCount, query.AggregateOperator)
+				assert.Equal(t, int64(60), query.StepInterval)
+				assert.Equal(t, "api", query.Filters.Items[0].Value)
+			},
+		},
+		{
+			desc: "formula",
+			queries: map[string]string{
+				"A":  `logs | where severity_text = "ERROR" | count() | every 5m`,
+				"B":  `logs | count() | every 5m`,
+				"F1": `A / B * 100`,
+			},
+			checkParams: func(t *testing.T, params *v3.QueryRangeParamsV3) {
+				assert.Equal(t, int64(300), params.CompositeQuery.BuilderQueries["A"].StepInterval)
+				assert.Equal(t, "A / B * 100", params.CompositeQuery.BuilderQueries["F1"].Expression)
+			},
+		},
+		{
+			desc:      "syntax error",
+			queries:   map[string]string{"A": `logs | where service.name = | count()`},
+			expectErr: true,
+			errMsg:    "query A: line 1, column 29: expected value, got '|'",
+		},
+		{
+			desc:      "unknown variable in formula",
+			queries:   map[string]string{"A": `logs | count()`, "F1": `A / C`},
+			expectErr: true,
+			errMsg:    "unknown variable C",
+		},
+	}
+
+	for _, tc := range reqCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			textParams := &v3.QueryRangeTextParams{
+				Start:     time.Now().Add(-time.Hour).UnixMilli(),
+				End:       time.Now().UnixMilli(),
+				PanelType: v3.PanelTypeGraph,
+				Queries:   tc.queries,
+				Variables: tc.variables,
+			}
+
+			body := &bytes.Buffer{}
+			err := json.NewEncoder(body).Encode(textParams)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/api/v3/query_range/text", body)
+
+			params, apiErr := ParseQueryRangeTextParams(req)
+			if tc.expectErr {
+				require.NotNil(t, apiErr)
+				assert.Contains(t, apiErr.Err.Error(), tc.errMsg)
+				return
+			}
+			require.Nil(t, apiErr)
+			tc.checkParams(t, params)
+		})
+	}
+}
+
+func TestParseQueryRangeTextParamsNullBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/v3/query_range/text", strings.NewReader("null"))
+
+	_, apiErr := ParseQueryRangeTextParams(req)
+	require.NotNil(t, apiErr)
+	assert.Equal(t, model.ErrorBadData, apiErr.Typ)
+}
@@ -6,9 +6,11 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/SigNoz/govaluate"
 	logsV3 "go.signoz.io/signoz/pkg/query-service/app/logs/v3"
 	logsV4 "go.signoz.io/signoz/pkg/query-service/app/logs/v4"
 	metricsV3 "go.signoz.io/signoz/pkg/query-service/app/metrics/v3"
+	"go.signoz.io/signoz/pkg/query-service/app/queryBuilder"
 	tracesV3 "go.signoz.io/signoz/pkg/query-service/app/traces/v3"
 	"go.signoz.io/signoz/pkg/query-service/common"
 	"go.signoz.io/signoz/pkg/query-service/constants"
@@ -280,6 +282,17 @@ func (q *querier) runBuilderExpression(
 
 	queryName := builderQuery.QueryName
 
+	expression, err := govaluate.NewEvaluableExpressionWithFunctions(builderQuery.Expression, queryBuilder.EvalFuncs)
+	if err != nil {
+		ch <- channelResult{Err: err, Name: queryName, Query: "", Series: nil}
+		return
+	}
+	if function, ok := queryBuilder.FormulaOnlyFunction(expression); ok {
+		err := fmt.Errorf("function %s of formula %s is only supported by the v4 query range API", function, queryName)
+		ch <- channelResult{Err: err, Name: queryName, Query: "", Series: nil}
+		return
+	}
+
 	queries, err := q.builder.PrepareQueries(params)
 	if err != nil {
 		ch <- channelResult{Err: err, Name: queryName, Query: "", Series: nil}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/SigNoz/govaluate"
 	logsV3 "go.signoz.io/signoz/pkg/query-service/app/logs/v3"
 	logsV4 "go.signoz.io/signoz/pkg/query-service/app/logs/v4"
 	metricsV3 "go.signoz.io/signoz/pkg/query-service/app/metrics/v3"
@@ -13,7 +14,9 @@ import (
 	tracesV3 "go.signoz.io/signoz/pkg/query-service/app/traces/v3"
 	"go.signoz.io/signoz/pkg/query-service/common"
 	chErrors "go.signoz.io/signoz/pkg/query-service/errors"
+	"go.signoz.io/signoz/pkg/query-service/postprocess"
 	"go.signoz.io/signoz/pkg/query-service/querycache"
+	"go.signoz.io/signoz/pkg/query-service/querylimits"
 	"go.signoz.io/signoz/pkg/query-service/utils"
 
 	"go.signoz.io/signoz/pkg/query-service/cache"
@@ -152,6 +155,24 @@ func (q *querier) execPromQuery(ctx context.Context, params *model.QueryRangePar
 	return seriesList, nil
 }
 
+// joinOperands returns the queries used in the formulas with join, the formulas with join
+// are evaluated on the results of the queries, so they are run even when they are disabled
+func joinOperands(params *v3.QueryRangeParamsV3) (map[string]struct{}, error) {
+	operands := make(map[string]struct{})
+	for _, builderQuery := range params.CompositeQuery.BuilderQueries {
+		if builderQuery.Join != nil && !builderQuery.Disabled {
+			expression, err := govaluate.NewEvaluableExpressionWithFunctions(builderQuery.Expression, queryBuilder.EvalFuncs)
+			if err != nil {
+				return nil, err
+			}
+			for _, queryName := range expression.Vars() {
+				operands[queryName] = struct{}{}
+			}
+		}
+	}
+	return operands, nil
+}
+
 func (q *querier) runBuilderQueries(ctx context.Context, params *v3.QueryRangeParamsV3) ([]*v3.Result, map[string]error, error) {
 
 	cacheKeys := q.keyGenerator.GenerateKeys(params)
@@ -159,8 +180,16 @@ func (q *querier) runBuilderQueries(ctx context.Context, params *v3.QueryRangePa
 	ch := make(chan channelResult, len(params.CompositeQuery.BuilderQueries))
 	var wg sync.WaitGroup
 
+	joinOperands, err := joinOperands(params)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	for queryName, builderQuery := range params.CompositeQuery.BuilderQueries {
-		if builderQuery.Disabled {
+		if _, ok := joinOperands[queryName]; builderQuery.Disabled && !ok {
+			continue
+		}
+		if builderQuery.Join != nil {
 			continue
 		}
 		wg.Add(1)
@@ -184,13 +213,41 @@ func (q *querier) runBuilderQueries(ctx context.Context, params *v3.QueryRangePa
 			errQueriesByName[result.Name] = result.Err
 			continue
 		}
-		results = append(results, &v3.Result{
+		res := &v3.Result{
 			QueryName: result.Name,
 			Series:    result.Series,
-		})
+		}
+		if params.CompositeQuery.PanelType == v3.PanelTypeHeatmap {
+			if err := postprocess.TransformToHeatmap(res, params); err != nil {
+				errs = append(errs, err)
+				errQueriesByName[result.Name] = err
+				continue
+			}
+		}
+		results = append(results, res)
 	}
 
-	var err error
+	for queryName, builderQuery := range params.CompositeQuery.BuilderQueries {
+		if builderQuery.Join == nil || builderQuery.Disabled {
+			continue
+		}
+		res, err := postprocess.ProcessJoinFormula(results, builderQuery, params)
+		if err != nil {
+			errs = append(errs, err)
+			errQueriesByName[queryName] = err
+			continue
+		}
+		results = append(results, res)
+	}
+
+	enabledResults := make([]*v3.Result, 0, len(results))
+	for _, res := range results {
+		if !params.CompositeQuery.BuilderQueries[res.QueryName].Disabled {
+			enabledResults = append(enabledResults, res)
+		}
+	}
+	results = enabledResults
+
 	if len(errs) > 0 {
 		err = fmt.Errorf("error in builder queries")
 	}
@@ -368,6 +425,12 @@ func (q *querier) runLogsListQuery(ctx context.Context, params *v3.QueryRangePar
 }
 
 func (q *querier) runBuilderListQueries(ctx context.Context, params *v3.QueryRangeParamsV3) ([]*v3.Result, map[string]error, error) {
+	if q.cache != nil {
+		if cq, ok := queryBuilder.NewCachedListQuery(q.queryCache, params, q.UseLogsNewSchema); ok {
+			return cq.Run(ctx, q.builder, q.reader, params)
+		}
+	}
+
 	// List query has support for only one query.
 	if q.UseLogsNewSchema && params.CompositeQuery != nil && len(params.CompositeQuery.BuilderQueries) == 1 {
 		for _, v := range params.CompositeQuery.BuilderQueries {
@@ -429,6 +492,15 @@ func (q *querier) runBuilderListQueries(ctx context.Context, params *v3.QueryRan
 }
 
 func (q *querier) QueryRange(ctx context.Context, params *v3.QueryRangeParamsV3) ([]*v3.Result, map[string]error, error) {
+	// the limits of the user are checked before the queries are run, the clickhouse
+	// settings of the limits are added to the queries by the reader
+	limits, hasLimits := querylimits.FromContext(ctx)
+	if hasLimits {
+		if err := limits.Validate(params); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	var results []*v3.Result
 	var err error
 	var errQueriesByName map[string]error
@@ -465,6 +537,12 @@ func (q *querier) QueryRange(ctx context.Context, params *v3.QueryRangeParamsV3)
 		}
 	}
 
+	if hasLimits && err == nil {
+		if err := limits.ValidateResults(results); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	return results, errQueriesByName, err
 }
 
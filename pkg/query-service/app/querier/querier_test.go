@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -12,6 +15,8 @@ import (
 	"go.signoz.io/signoz/pkg/query-service/app/queryBuilder"
 	tracesV3 "go.signoz.io/signoz/pkg/query-service/app/traces/v3"
 	"go.signoz.io/signoz/pkg/query-service/cache/inmemory"
+	"go.signoz.io/signoz/pkg/query-service/featureManager"
+	"go.signoz.io/signoz/pkg/query-service/interfaces"
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
 	"go.signoz.io/signoz/pkg/query-service/querycache"
 )
@@ -1124,3 +1129,129 @@ func TestQueryRangeValueTypePromQL(t *testing.T) {
 		}
 	}
 }
+
+// listReader returns the latest logs in the time range of the list query
+type listReader struct {
+	interfaces.Reader
+	rows    []*v3.Row
+	queries []string
+}
+
+var (
+	listTimeRangeRegex = regexp.MustCompile(`timestamp >= (\d+) AND timestamp <= (\d+)`)
+	listLimitRegex     = regexp.MustCompile(`LIMIT (\d+)`)
+)
+
+func (r *listReader) GetListResultV3(ctx context.Context, query string) ([]*v3.Row, error) {
+	r.queries = append(r.queries, query)
+	timeRange := listTimeRangeRegex.FindStringSubmatch(query)
+	start, _ := strconv.ParseInt(timeRange[1], 10, 64)
+	end, _ := strconv.ParseInt(timeRange[2], 10, 64)
+	limit, _ := strconv.Atoi(listLimitRegex.FindStringSubmatch(query)[1])
+
+	rows := make([]*v3.Row, 0)
+	for _, row := range r.rows {
+		ts := row.Timestamp.UnixNano()
+		if ts >= start && ts <= end && len(rows) < limit {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func TestQueryRangeLogsListWithCache(t *testing.T) {
+	// a log every minute, latest first
+	end := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	reader := &listReader{}
+	for i := 0; i < 30; i++ {
+		ts := end.Add(-time.Duration(i) * time.Minute)
+		id := fmt.Sprintf("%d", ts.Unix())
+		reader.rows = append(reader.rows, &v3.Row{Timestamp: ts, Data: map[string]interface{}{"id": &id}})
+	}
+
+	params := func(cursor string) *v3.QueryRangeParamsV3 {
+		filters := &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{
+			{Key: v3.AttributeKey{Key: "service_name", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeResource}, Operator: v3.FilterOperatorEqual, Value: "frontend"},
+		}}
+		if cursor != "" {
+			filters.Items = append(filters.Items, v3.FilterItem{Key: v3.AttributeKey{Key: "id", DataType: v3.AttributeKeyDataTypeString, IsColumn: true}, Operator: v3.FilterOperatorLessThan, Value: cursor})
+		}
+		return &v3.QueryRangeParamsV3{
+			Start: end.Add(-30 * time.Minute).UnixMilli(),
+			End:   end.UnixMilli(),
+			CompositeQuery: &v3.CompositeQuery{
+				QueryType: v3.QueryTypeBuilder,
+				PanelType: v3.PanelTypeList,
+				BuilderQueries: map[string]*v3.BuilderQuery{
+					"A": {
+						QueryName:         "A",
+						DataSource:        v3.DataSourceLogs,
+						AggregateOperator: v3.AggregateOperatorNoOp,
+						Filters:           filters,
+						OrderBy:           []v3.OrderBy{{ColumnName: "timestamp", Order: "desc"}},
+						PageSize:          10,
+						Expression:        "A",
+					},
+				},
+			},
+		}
+	}
+
+	q := NewQuerier(QuerierOptions{
+		Reader:           reader,
+		Cache:            inmemory.New(&inmemory.Options{TTL: 5 * time.Minute, CleanupInterval: 10 * time.Minute}),
+		KeyGenerator:     queryBuilder.NewKeyGenerator(),
+		FluxInterval:     5 * time.Minute,
+		FeatureLookup:    featureManager.StartManager(),
+		UseLogsNewSchema: true,
+	})
+
+	rowIDs := func(results []*v3.Result) []string {
+		ids := make([]string, 0)
+		for _, row := range results[0].List {
+			ids = append(ids, queryBuilder.RowID(row))
+		}
+		return ids
+	}
+	expectedIDs := func(from, to int) []string {
+		ids := make([]string, 0)
+		for _, row := range reader.rows[from:to] {
+			ids = append(ids, queryBuilder.RowID(row))
+		}
+		return ids
+	}
+
+	firstPage, _, err := q.QueryRange(context.Background(), params(""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids := rowIDs(firstPage); !reflect.DeepEqual(ids, expectedIDs(0, 10)) {
+		t.Errorf("expected the first page %v, got %v", expectedIDs(0, 10), ids)
+	}
+	if len(reader.queries) != 1 {
+		t.Errorf("expected 1 query for the first page, got %d", len(reader.queries))
+	}
+
+	// the next page starts after the last log of the first page
+	cursor := queryBuilder.RowID(firstPage[0].List[9])
+	secondPage, _, err := q.QueryRange(context.Background(), params(cursor))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids := rowIDs(secondPage); !reflect.DeepEqual(ids, expectedIDs(10, 20)) {
+		t.Errorf("expected the second page %v, got %v", expectedIDs(10, 20), ids)
+	}
+	if len(reader.queries) != 2 {
+		t.Errorf("expected 1 query for the second page, got %d", len(reader.queries)-1)
+	}
+
+	// paging back is served from the cache
+	for _, cursor := range []string{"", cursor} {
+		if _, _, err := q.QueryRange(context.Background(), params(cursor)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(reader.queries) != 2 {
+		t.Errorf("expected no queries for the cached pages, got %v", reader.queries[2:])
+	}
+}
@@ -0,0 +1,132 @@
+package v2
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+// cacheMisses returns the time ranges for which the query with the cache key is run
+func (q *querier) cacheMisses(start, end, step int64, cacheKeys map[string]string, queryName string, noCache bool) []v3.CacheMiss {
+	if _, ok := cacheKeys[queryName]; !ok || noCache {
+		return []v3.CacheMiss{{Start: start, End: end}}
+	}
+	misses := make([]v3.CacheMiss, 0)
+	for _, miss := range q.queryCache.FindMissingTimeRanges(start, end, step, cacheKeys[queryName]) {
+		misses = append(misses, v3.CacheMiss{Start: miss.Start, End: miss.End})
+	}
+	return misses
+}
+
+func (q *querier) explainBuilderQueries(params *v3.QueryRangeParamsV3) ([]*v3.QueryExplanation, error) {
+	cacheKeys := q.keyGenerator.GenerateKeys(params)
+
+	// the disabled queries are run for the formulas, but PrepareQueries leaves them out
+	enabledParams := params.Clone()
+	for _, builderQuery := range enabledParams.CompositeQuery.BuilderQueries {
+		builderQuery.Disabled = false
+	}
+	queries, err := q.builder.PrepareQueries(enabledParams)
+	if err != nil {
+		return nil, err
+	}
+
+	explanations := make([]*v3.QueryExplanation, 0, len(params.CompositeQuery.BuilderQueries))
+	for queryName, builderQuery := range params.CompositeQuery.BuilderQueries {
+		// the formulas are evaluated on the results of the queries
+		if queryName != builderQuery.Expression {
+			if !builderQuery.Disabled {
+				explanations = append(explanations, &v3.QueryExplanation{
+					QueryName:  queryName,
+					Expression: builderQuery.Expression,
+					Misses:     []v3.CacheMiss{},
+				})
+			}
+			continue
+		}
+
+		start := params.Start
+		end := params.End
+		if builderQuery.ShiftBy != 0 {
+			start = start - builderQuery.ShiftBy*1000
+			end = end - builderQuery.ShiftBy*1000
+		}
+		explanation := &v3.QueryExplanation{
+			QueryName: queryName,
+			Query:     queries[queryName],
+			Misses:    []v3.CacheMiss{{Start: start, End: end}},
+		}
+		// the traces queries are not cached
+		if builderQuery.DataSource != v3.DataSourceTraces {
+			explanation.Misses = q.cacheMisses(start, end, builderQuery.StepInterval, cacheKeys, queryName, params.NoCache)
+		}
+		explanations = append(explanations, explanation)
+	}
+	return explanations, nil
+}
+
+// Explain returns the queries that QueryRange runs for the params and the time ranges missing
+// in the cache for which they are run. Nothing is run except the EXPLAIN of the clickhouse
+// queries when withExplain is set
+func (q *querier) Explain(ctx context.Context, params *v3.QueryRangeParamsV3, withExplain bool) ([]*v3.QueryExplanation, error) {
+	if params.CompositeQuery == nil {
+		return nil, fmt.Errorf("composite query is required")
+	}
+
+	var explanations []*v3.QueryExplanation
+	switch params.CompositeQuery.QueryType {
+	case v3.QueryTypeBuilder:
+		var err error
+		explanations, err = q.explainBuilderQueries(params)
+		if err != nil {
+			return nil, err
+		}
+	case v3.QueryTypePromQL:
+		cacheKeys := q.keyGenerator.GenerateKeys(params)
+		for queryName, promQuery := range params.CompositeQuery.PromQueries {
+			if promQuery.Disabled {
+				continue
+			}
+			explanations = append(explanations, &v3.QueryExplanation{
+				QueryName: queryName,
+				Query:     promQuery.Query,
+				Misses:    q.cacheMisses(params.Start, params.End, params.Step, cacheKeys, queryName, params.NoCache),
+			})
+		}
+		// the promql queries are not run on clickhouse
+		withExplain = false
+	case v3.QueryTypeClickHouseSQL:
+		for queryName, clickHouseQuery := range params.CompositeQuery.ClickHouseQueries {
+			if clickHouseQuery.Disabled {
+				continue
+			}
+			explanations = append(explanations, &v3.QueryExplanation{
+				QueryName: queryName,
+				Query:     clickHouseQuery.Query,
+				Misses:    []v3.CacheMiss{{Start: params.Start, End: params.End}},
+			})
+		}
+	default:
+		return nil, fmt.Errorf("invalid query type")
+	}
+
+	sort.Slice(explanations, func(i, j int) bool {
+		return explanations[i].QueryName < explanations[j].QueryName
+	})
+
+	if withExplain {
+		for _, explanation := range explanations {
+			if explanation.Query == "" {
+				continue
+			}
+			explain, err := q.reader.ExplainQueryV3(ctx, explanation.Query)
+			if err != nil {
+				return nil, fmt.Errorf("error explaining query %s: %w", explanation.QueryName, err)
+			}
+			explanation.Explain = explain
+		}
+	}
+	return explanations, nil
+}
@@ -13,7 +13,9 @@ import (
 	tracesV3 "go.signoz.io/signoz/pkg/query-service/app/traces/v3"
 	"go.signoz.io/signoz/pkg/query-service/common"
 	chErrors "go.signoz.io/signoz/pkg/query-service/errors"
+	"go.signoz.io/signoz/pkg/query-service/postprocess"
 	"go.signoz.io/signoz/pkg/query-service/querycache"
+	"go.signoz.io/signoz/pkg/query-service/querylimits"
 	"go.signoz.io/signoz/pkg/query-service/utils"
 
 	"go.signoz.io/signoz/pkg/query-service/cache"
@@ -182,10 +184,18 @@ func (q *querier) runBuilderQueries(ctx context.Context, params *v3.QueryRangePa
 			errQueriesByName[result.Name] = result.Err
 			continue
 		}
-		results = append(results, &v3.Result{
+		res := &v3.Result{
 			QueryName: result.Name,
 			Series:    result.Series,
-		})
+		}
+		if params.CompositeQuery.PanelType == v3.PanelTypeHeatmap {
+			if err := postprocess.TransformToHeatmap(res, params); err != nil {
+				errs = append(errs, err)
+				errQueriesByName[result.Name] = err
+				continue
+			}
+		}
+		results = append(results, res)
 	}
 
 	var err error
@@ -365,6 +375,12 @@ func (q *querier) runLogsListQuery(ctx context.Context, params *v3.QueryRangePar
 }
 
 func (q *querier) runBuilderListQueries(ctx context.Context, params *v3.QueryRangeParamsV3) ([]*v3.Result, map[string]error, error) {
+	if q.cache != nil {
+		if cq, ok := queryBuilder.NewCachedListQuery(q.queryCache, params, q.UseLogsNewSchema); ok {
+			return cq.Run(ctx, q.builder, q.reader, params)
+		}
+	}
+
 	// List query has support for only one query.
 	if q.UseLogsNewSchema && params.CompositeQuery != nil && len(params.CompositeQuery.BuilderQueries) == 1 {
 		for _, v := range params.CompositeQuery.BuilderQueries {
@@ -428,6 +444,15 @@ func (q *querier) runBuilderListQueries(ctx context.Context, params *v3.QueryRan
 // QueryRange is the main function that runs the queries
 // and returns the results
 func (q *querier) QueryRange(ctx context.Context, params *v3.QueryRangeParamsV3) ([]*v3.Result, map[string]error, error) {
+	// the limits of the user are checked before the queries are run, the clickhouse
+	// settings of the limits are added to the queries by the reader
+	limits, hasLimits := querylimits.FromContext(ctx)
+	if hasLimits {
+		if err := limits.Validate(params); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	var results []*v3.Result
 	var err error
 	var errQueriesByName map[string]error
@@ -464,6 +489,12 @@ func (q *querier) QueryRange(ctx context.Context, params *v3.QueryRangeParamsV3)
 		}
 	}
 
+	if hasLimits && err == nil {
+		if err := limits.ValidateResults(results); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	return results, errQueriesByName, err
 }
 
@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
+	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -12,8 +15,11 @@ import (
 	"go.signoz.io/signoz/pkg/query-service/app/queryBuilder"
 	tracesV3 "go.signoz.io/signoz/pkg/query-service/app/traces/v3"
 	"go.signoz.io/signoz/pkg/query-service/cache/inmemory"
+	"go.signoz.io/signoz/pkg/query-service/featureManager"
+	"go.signoz.io/signoz/pkg/query-service/interfaces"
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
 	"go.signoz.io/signoz/pkg/query-service/querycache"
+	"go.signoz.io/signoz/pkg/query-service/querylimits"
 )
 
 func minTimestamp(series []*v3.Series) int64 {
@@ -1178,3 +1184,283 @@ func TestV2QueryRangeValueTypePromQL(t *testing.T) {
 		}
 	}
 }
+
+func TestV2Explain(t *testing.T) {
+	logsQuery := func(disabled bool) *v3.BuilderQuery {
+		return &v3.BuilderQuery{
+			QueryName:         "A",
+			StepInterval:      60,
+			DataSource:        v3.DataSourceLogs,
+			Filters:           &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{}},
+			AggregateOperator: v3.AggregateOperatorCount,
+			Expression:        "A",
+			Disabled:          disabled,
+			GroupBy:           []v3.AttributeKey{{Key: "service_name", IsColumn: false}},
+		}
+	}
+	cache := inmemory.New(&inmemory.Options{TTL: 60 * time.Minute, CleanupInterval: 10 * time.Minute})
+	opts := QuerierOptions{
+		Cache:         cache,
+		Reader:        nil,
+		FluxInterval:  5 * time.Minute,
+		KeyGenerator:  queryBuilder.NewKeyGenerator(),
+		TestingMode:   true,
+		FeatureLookup: featureManager.StartManager(),
+		ReturnedSeries: []*v3.Series{
+			{
+				Labels: map[string]string{"service_name": "frontend"},
+				Points: []v3.Point{{Timestamp: 1675115596722 + 60*60*1000, Value: 1}},
+			},
+		},
+	}
+	q := NewQuerier(opts)
+
+	// 31st Jan, 4:23 to 5:23 is cached
+	cached := &v3.QueryRangeParamsV3{
+		Start:   1675115596722 + 60*60*1000,
+		End:     1675115596722 + 120*60*1000,
+		Step:    60,
+		Version: "v4",
+		CompositeQuery: &v3.CompositeQuery{
+			QueryType:      v3.QueryTypeBuilder,
+			PanelType:      v3.PanelTypeGraph,
+			BuilderQueries: map[string]*v3.BuilderQuery{"A": logsQuery(false)},
+		},
+	}
+	_, _, err := q.QueryRange(context.Background(), cached)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	params := &v3.QueryRangeParamsV3{
+		Start:   1675115596722,
+		End:     1675115596722 + 120*60*1000,
+		Step:    60,
+		Version: "v4",
+		CompositeQuery: &v3.CompositeQuery{
+			QueryType: v3.QueryTypeBuilder,
+			PanelType: v3.PanelTypeGraph,
+			BuilderQueries: map[string]*v3.BuilderQuery{
+				"A": logsQuery(true),
+				"B": {
+					QueryName:          "B",
+					StepInterval:       60,
+					DataSource:         v3.DataSourceTraces,
+					AggregateAttribute: v3.AttributeKey{Key: "durationNano", IsColumn: true},
+					AggregateOperator:  v3.AggregateOperatorP99,
+					Filters:            &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{}},
+					Expression:         "B",
+				},
+				"F1": {QueryName: "F1", Expression: "A / B"},
+			},
+		},
+	}
+	tracesV3.Enrich(params, map[string]v3.AttributeKey{})
+	explanations, err := q.Explain(context.Background(), params, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	if len(q.QueriesExecuted()) != 1 {
+		t.Errorf("expected no queries to be executed, got %v", q.QueriesExecuted()[1:])
+	}
+	if len(explanations) != 3 {
+		t.Fatalf("expected 3 explanations, got %d", len(explanations))
+	}
+
+	// the disabled query is run for the formula
+	if explanations[0].QueryName != "A" || !strings.Contains(explanations[0].Query, "signoz_logs") {
+		t.Errorf("expected logs query for A, got %+v", explanations[0])
+	}
+	expectedMisses := []v3.CacheMiss{{Start: 1675115596722, End: 1675115596722 + 60*60*1000}}
+	if fmt.Sprint(explanations[0].Misses) != fmt.Sprint(expectedMisses) {
+		t.Errorf("expected misses %v, got %v", expectedMisses, explanations[0].Misses)
+	}
+
+	// the traces queries are not cached
+	expectedMisses = []v3.CacheMiss{{Start: params.Start, End: params.End}}
+	if explanations[1].QueryName != "B" || !strings.Contains(explanations[1].Query, "signoz_traces") {
+		t.Errorf("expected traces query for B, got %+v", explanations[1])
+	}
+	if fmt.Sprint(explanations[1].Misses) != fmt.Sprint(expectedMisses) {
+		t.Errorf("expected misses %v, got %v", expectedMisses, explanations[1].Misses)
+	}
+
+	if explanations[2].QueryName != "F1" || explanations[2].Expression != "A / B" || explanations[2].Query != "" {
+		t.Errorf("expected the expression of F1, got %+v", explanations[2])
+	}
+}
+
+func TestV2QueryRangeWithQueryLimits(t *testing.T) {
+	params := &v3.QueryRangeParamsV3{
+		Start:   1675115596722,
+		End:     1675115596722 + 120*60*1000,
+		Step:    60,
+		Version: "v4",
+		CompositeQuery: &v3.CompositeQuery{
+			QueryType: v3.QueryTypeBuilder,
+			PanelType: v3.PanelTypeGraph,
+			BuilderQueries: map[string]*v3.BuilderQuery{
+				"A": {
+					QueryName:         "A",
+					StepInterval:      60,
+					DataSource:        v3.DataSourceLogs,
+					Filters:           &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{}},
+					AggregateOperator: v3.AggregateOperatorCount,
+					Expression:        "A",
+				},
+			},
+		},
+	}
+	opts := QuerierOptions{
+		Reader:       nil,
+		FluxInterval: 5 * time.Minute,
+		KeyGenerator: queryBuilder.NewKeyGenerator(),
+		TestingMode:  true,
+		ReturnedSeries: []*v3.Series{
+			{Labels: map[string]string{"service_name": "frontend"}},
+			{Labels: map[string]string{"service_name": "route"}},
+		},
+	}
+	q := NewQuerier(opts)
+
+	ctx := querylimits.NewContext(context.Background(), querylimits.Limits{MaxTimeRange: time.Hour})
+	_, _, err := q.QueryRange(ctx, params)
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit of 1h0m0s") {
+		t.Errorf("expected time range limit error, got %v", err)
+	}
+	if len(q.QueriesExecuted()) != 0 {
+		t.Errorf("expected no queries to be executed, got %v", q.QueriesExecuted())
+	}
+
+	ctx = querylimits.NewContext(context.Background(), querylimits.Limits{MaxSeries: 1})
+	_, _, err = q.QueryRange(ctx, params)
+	if err == nil || !strings.Contains(err.Error(), "returned 2 series") {
+		t.Errorf("expected max series limit error, got %v", err)
+	}
+}
+
+// listReader returns the latest logs in the time range of the list query
+type listReader struct {
+	interfaces.Reader
+	rows    []*v3.Row
+	queries []string
+}
+
+var (
+	listTimeRangeRegex = regexp.MustCompile(`timestamp >= (\d+) AND timestamp <= (\d+)`)
+	listLimitRegex     = regexp.MustCompile(`LIMIT (\d+)`)
+)
+
+func (r *listReader) GetListResultV3(ctx context.Context, query string) ([]*v3.Row, error) {
+	r.queries = append(r.queries, query)
+	timeRange := listTimeRangeRegex.FindStringSubmatch(query)
+	start, _ := strconv.ParseInt(timeRange[1], 10, 64)
+	end, _ := strconv.ParseInt(timeRange[2], 10, 64)
+	limit, _ := strconv.Atoi(listLimitRegex.FindStringSubmatch(query)[1])
+
+	rows := make([]*v3.Row, 0)
+	for _, row := range r.rows {
+		ts := row.Timestamp.UnixNano()
+		if ts >= start && ts <= end && len(rows) < limit {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func TestV2QueryRangeLogsListWithCache(t *testing.T) {
+	// a log every minute, latest first
+	end := time.Now().Add(-time.Hour).Truncate(time.Minute)
+	reader := &listReader{}
+	for i := 0; i < 30; i++ {
+		ts := end.Add(-time.Duration(i) * time.Minute)
+		id := fmt.Sprintf("%d", ts.Unix())
+		reader.rows = append(reader.rows, &v3.Row{Timestamp: ts, Data: map[string]interface{}{"id": &id}})
+	}
+
+	params := func(cursor string) *v3.QueryRangeParamsV3 {
+		filters := &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{
+			{Key: v3.AttributeKey{Key: "service_name", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeResource}, Operator: v3.FilterOperatorEqual, Value: "frontend"},
+		}}
+		if cursor != "" {
+			filters.Items = append(filters.Items, v3.FilterItem{Key: v3.AttributeKey{Key: "id", DataType: v3.AttributeKeyDataTypeString, IsColumn: true}, Operator: v3.FilterOperatorLessThan, Value: cursor})
+		}
+		return &v3.QueryRangeParamsV3{
+			Start: end.Add(-30 * time.Minute).UnixMilli(),
+			End:   end.UnixMilli(),
+			CompositeQuery: &v3.CompositeQuery{
+				QueryType: v3.QueryTypeBuilder,
+				PanelType: v3.PanelTypeList,
+				BuilderQueries: map[string]*v3.BuilderQuery{
+					"A": {
+						QueryName:         "A",
+						DataSource:        v3.DataSourceLogs,
+						AggregateOperator: v3.AggregateOperatorNoOp,
+						Filters:           filters,
+						OrderBy:           []v3.OrderBy{{ColumnName: "timestamp", Order: "desc"}},
+						PageSize:          10,
+						Expression:        "A",
+					},
+				},
+			},
+		}
+	}
+
+	q := NewQuerier(QuerierOptions{
+		Reader:           reader,
+		Cache:            inmemory.New(&inmemory.Options{TTL: 5 * time.Minute, CleanupInterval: 10 * time.Minute}),
+		KeyGenerator:     queryBuilder.NewKeyGenerator(),
+		FluxInterval:     5 * time.Minute,
+		FeatureLookup:    featureManager.StartManager(),
+		UseLogsNewSchema: true,
+	})
+
+	rowIDs := func(results []*v3.Result) []string {
+		ids := make([]string, 0)
+		for _, row := range results[0].List {
+			ids = append(ids, queryBuilder.RowID(row))
+		}
+		return ids
+	}
+	expectedIDs := func(from, to int) []string {
+		ids := make([]string, 0)
+		for _, row := range reader.rows[from:to] {
+			ids = append(ids, queryBuilder.RowID(row))
+		}
+		return ids
+	}
+
+	firstPage, _, err := q.QueryRange(context.Background(), params(""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids := rowIDs(firstPage); !reflect.DeepEqual(ids, expectedIDs(0, 10)) {
+		t.Errorf("expected the first page %v, got %v", expectedIDs(0, 10), ids)
+	}
+	if len(reader.queries) != 1 {
+		t.Errorf("expected 1 query for the first page, got %d", len(reader.queries))
+	}
+
+	// the next page starts after the last log of the first page
+	cursor := queryBuilder.RowID(firstPage[0].List[9])
+	secondPage, _, err := q.QueryRange(context.Background(), params(cursor))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids := rowIDs(secondPage); !reflect.DeepEqual(ids, expectedIDs(10, 20)) {
+		t.Errorf("expected the second page %v, got %v", expectedIDs(10, 20), ids)
+	}
+	if len(reader.queries) != 2 {
+		t.Errorf("expected 1 query for the second page, got %d", len(reader.queries)-1)
+	}
+
+	// paging back is served from the cache
+	for _, cursor := range []string{"", cursor} {
+		if _, _, err := q.QueryRange(context.Background(), params(cursor)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(reader.queries) != 2 {
+		t.Errorf("expected no queries for the cached pages, got %v", reader.queries[2:])
+	}
+}
@@ -3,7 +3,11 @@ package queryBuilder
 import (
 	"math"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"go.signoz.io/signoz/pkg/query-service/constants"
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
 )
 
@@ -252,6 +256,738 @@ func median(values []float64) float64 {
 	return values[medianIndex]
 }
 
+// windowStart returns the index of the first point of the window ending at the point at idx,
+// the window is a number of points or a duration e.g 5m
+func windowStart(points []v3.Point, idx int, window interface{}) int {
+	switch w := window.(type) {
+	case float64:
+		start := idx - int(w) + 1
+		if start < 0 {
+			start = 0
+		}
+		return start
+	case string:
+		duration, err := time.ParseDuration(w)
+		if err != nil {
+			return idx
+		}
+		from := points[idx].Timestamp - duration.Milliseconds()
+		start := idx
+		for start > 0 && points[start-1].Timestamp > from {
+			start--
+		}
+		return start
+	}
+	return idx
+}
+
+// funcMovingWindow replaces each point with the aggregate of the non-NaN values of the window
+// ending at the point, or NaN if the window has no values
+func funcMovingWindow(result *v3.Result, window interface{}, aggregate func(values []float64) float64) *v3.Result {
+	for _, series := range result.Series {
+		aggregated := make([]float64, len(series.Points))
+		values := make([]float64, 0)
+		for i := range series.Points {
+			values = values[:0]
+			for j := windowStart(series.Points, i, window); j <= i; j++ {
+				if !math.IsNaN(series.Points[j].Value) {
+					values = append(values, series.Points[j].Value)
+				}
+			}
+			if len(values) == 0 {
+				aggregated[i] = math.NaN()
+				continue
+			}
+			aggregated[i] = aggregate(values)
+		}
+		for i := range series.Points {
+			series.Points[i].Value = aggregated[i]
+		}
+	}
+	return result
+}
+
+func sum(values []float64) float64 {
+	var total float64
+	for _, value := range values {
+		total += value
+	}
+	return total
+}
+
+func avg(values []float64) float64 {
+	return sum(values) / float64(len(values))
+}
+
+func minimum(values []float64) float64 {
+	result := values[0]
+	for _, value := range values[1:] {
+		result = math.Min(result, value)
+	}
+	return result
+}
+
+func maximum(values []float64) float64 {
+	result := values[0]
+	for _, value := range values[1:] {
+		result = math.Max(result, value)
+	}
+	return result
+}
+
+// stdDev returns the population standard deviation of the values
+func stdDev(values []float64) float64 {
+	mean := avg(values)
+	var variance float64
+	for _, value := range values {
+		variance += (value - mean) * (value - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)))
+}
+
+// percentile returns the percentile (0-100) of the values with linear interpolation
+// between the closest ranks, the values are sorted in place
+func percentile(values []float64, p float64) float64 {
+	sort.Float64s(values)
+	rank := p / 100 * float64(len(values)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return values[lower] + (values[upper]-values[lower])*(rank-float64(lower))
+}
+
+// funcZScore returns the number of standard deviations of each point from the mean of the
+// series, or from the mean of the window ending at the point if the window is set
+func funcZScore(result *v3.Result, window interface{}) *v3.Result {
+	zScore := func(value float64, values []float64) float64 {
+		deviation := stdDev(values)
+		if deviation == 0 {
+			return 0
+		}
+		return (value - avg(values)) / deviation
+	}
+
+	for _, series := range result.Series {
+		values := make([]float64, 0, len(series.Points))
+		if window == nil {
+			for _, point := range series.Points {
+				if !math.IsNaN(point.Value) {
+					values = append(values, point.Value)
+				}
+			}
+		}
+
+		scores := make([]float64, len(series.Points))
+		for i, point := range series.Points {
+			if window != nil {
+				values = values[:0]
+				for j := windowStart(series.Points, i, window); j <= i; j++ {
+					if !math.IsNaN(series.Points[j].Value) {
+						values = append(values, series.Points[j].Value)
+					}
+				}
+			}
+			if math.IsNaN(point.Value) {
+				scores[i] = math.NaN()
+				continue
+			}
+			scores[i] = zScore(point.Value, values)
+		}
+		for i := range series.Points {
+			series.Points[i].Value = scores[i]
+		}
+	}
+	return result
+}
+
+// funcRateOfChange returns the change per second between each point and the previous point
+func funcRateOfChange(result *v3.Result) *v3.Result {
+	for _, series := range result.Series {
+		if len(series.Points) == 0 {
+			continue
+		}
+		// iterate over the point in reverse order
+		for idx := len(series.Points) - 1; idx > 0; idx-- {
+			elapsed := float64(series.Points[idx].Timestamp-series.Points[idx-1].Timestamp) / 1000
+			if elapsed <= 0 {
+				series.Points[idx].Value = math.NaN()
+				continue
+			}
+			series.Points[idx].Value = (series.Points[idx].Value - series.Points[idx-1].Value) / elapsed
+		}
+		// remove the first point
+		// the timerange is already adjusted in the query range
+		series.Points = series.Points[1:]
+	}
+	return result
+}
+
+func count(values []float64) float64 {
+	return float64(len(values))
+}
+
+// funcAggregateBy aggregates the series with the same values of the labels into one series,
+// the points with the same timestamp are aggregated. The series without a label have an
+// empty value for it, and all the series are aggregated into one if there are no labels.
+func funcAggregateBy(result *v3.Result, labels []string, aggregate func(values []float64) float64) *v3.Result {
+	type group struct {
+		labels      map[string]string
+		labelsArray []map[string]string
+		values      map[int64][]float64
+	}
+	groups := make(map[string]*group)
+	keys := make([]string, 0)
+
+	for _, series := range result.Series {
+		groupLabels := make(map[string]string, len(labels))
+		groupLabelsArray := make([]map[string]string, 0, len(labels))
+		keyParts := make([]string, 0, len(labels))
+		for _, label := range labels {
+			value := series.Labels[label]
+			if value != "" {
+				groupLabels[label] = value
+				groupLabelsArray = append(groupLabelsArray, map[string]string{label: value})
+			}
+			keyParts = append(keyParts, label+"="+value)
+		}
+		key := strings.Join(keyParts, ",")
+
+		g, ok := groups[key]
+		if !ok {
+			g = &group{labels: groupLabels, labelsArray: groupLabelsArray, values: make(map[int64][]float64)}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+		for _, point := range series.Points {
+			if !math.IsNaN(point.Value) {
+				g.values[point.Timestamp] = append(g.values[point.Timestamp], point.Value)
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	aggregated := make([]*v3.Series, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+		timestamps := make([]int64, 0, len(g.values))
+		for timestamp := range g.values {
+			timestamps = append(timestamps, timestamp)
+		}
+		sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+		points := make([]v3.Point, 0, len(timestamps))
+		for _, timestamp := range timestamps {
+			points = append(points, v3.Point{Timestamp: timestamp, Value: aggregate(g.values[timestamp])})
+		}
+		aggregated = append(aggregated, &v3.Series{Labels: g.labels, LabelsArray: g.labelsArray, Points: points})
+	}
+	result.Series = aggregated
+	return result
+}
+
+// setLabel sets the label of the series, the label is removed if the value is empty
+func setLabel(series *v3.Series, label, value string) {
+	if series.Labels == nil {
+		series.Labels = make(map[string]string)
+	}
+	labelsArray := make([]map[string]string, 0, len(series.LabelsArray)+1)
+	for _, labels := range series.LabelsArray {
+		if _, ok := labels[label]; !ok {
+			labelsArray = append(labelsArray, labels)
+		}
+	}
+	if value == "" {
+		delete(series.Labels, label)
+	} else {
+		series.Labels[label] = value
+		labelsArray = append(labelsArray, map[string]string{label: value})
+	}
+	series.LabelsArray = labelsArray
+}
+
+// funcLabelReplace sets the destination label to the replacement for the series with the
+// source label matching the regex, the replacement can refer to the regex groups e.g $1
+func funcLabelReplace(result *v3.Result, destination, replacement, source, regex string) *v3.Result {
+	re, err := v3.LabelReplaceRegex(regex)
+	if err != nil {
+		return result
+	}
+	for _, series := range result.Series {
+		value := series.Labels[source]
+		match := re.FindStringSubmatchIndex(value)
+		if match == nil {
+			continue
+		}
+		setLabel(series, destination, string(re.ExpandString(nil, replacement, value, match)))
+	}
+	return result
+}
+
+// funcLabelJoin sets the destination label to the values of the source labels joined with the separator
+func funcLabelJoin(result *v3.Result, destination, separator string, sources []string) *v3.Result {
+	for _, series := range result.Series {
+		values := make([]string, 0, len(sources))
+		for _, source := range sources {
+			values = append(values, series.Labels[source])
+		}
+		setLabel(series, destination, strings.Join(values, separator))
+	}
+	return result
+}
+
+// stringArgs returns the arguments of the function as strings, the arguments are validated
+// to be strings in the query validation
+func stringArgs(fn v3.Function) []string {
+	args := make([]string, 0, len(fn.Args))
+	for _, arg := range fn.Args {
+		if str, ok := arg.(string); ok {
+			args = append(args, str)
+		}
+	}
+	return args
+}
+
+// seriesStep returns the median interval between the points of the series in milliseconds
+func seriesStep(points []v3.Point) int64 {
+	intervals := make([]int64, 0, len(points))
+	for idx := 1; idx < len(points); idx++ {
+		if interval := points[idx].Timestamp - points[idx-1].Timestamp; interval > 0 {
+			intervals = append(intervals, interval)
+		}
+	}
+	if len(intervals) == 0 {
+		return 0
+	}
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+	return intervals[len(intervals)/2]
+}
+
+// forecastTimestamps returns the timestamps of the points after the last point up to the horizon,
+// the step is increased if there are more points than allowed in a series
+func forecastTimestamps(last, step int64, horizon time.Duration) []int64 {
+	points := horizon.Milliseconds() / step
+	if points > constants.MaxAllowedPointsInTimeSeries {
+		step = step * int64(math.Ceil(float64(points)/constants.MaxAllowedPointsInTimeSeries))
+		points = horizon.Milliseconds() / step
+	}
+	timestamps := make([]int64, 0, points)
+	for idx := int64(1); idx <= points; idx++ {
+		timestamps = append(timestamps, last+idx*step)
+	}
+	return timestamps
+}
+
+// forecastLinear fits a line to the non-NaN points with least squares and returns the predicted
+// values at the timestamps with the half width of their prediction intervals for z
+func forecastLinear(points []v3.Point, timestamps []int64, z float64) ([]float64, []float64) {
+	xs := make([]float64, 0, len(points))
+	ys := make([]float64, 0, len(points))
+	for _, point := range points {
+		if !math.IsNaN(point.Value) {
+			// seconds relative to the first point to keep the values small
+			xs = append(xs, float64(point.Timestamp-points[0].Timestamp)/1000)
+			ys = append(ys, point.Value)
+		}
+	}
+	if len(xs) < 2 {
+		return nil, nil
+	}
+
+	meanX, meanY := avg(xs), avg(ys)
+	var sxx, sxy float64
+	for idx := range xs {
+		sxx += (xs[idx] - meanX) * (xs[idx] - meanX)
+		sxy += (xs[idx] - meanX) * (ys[idx] - meanY)
+	}
+	if sxx == 0 {
+		return nil, nil
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sse float64
+	for idx := range xs {
+		residual := ys[idx] - (intercept + slope*xs[idx])
+		sse += residual * residual
+	}
+	var stdErr float64
+	if len(xs) > 2 {
+		stdErr = math.Sqrt(sse / float64(len(xs)-2))
+	}
+
+	predicted := make([]float64, len(timestamps))
+	widths := make([]float64, len(timestamps))
+	n := float64(len(xs))
+	for idx, timestamp := range timestamps {
+		x := float64(timestamp-points[0].Timestamp) / 1000
+		predicted[idx] = intercept + slope*x
+		widths[idx] = z * stdErr * math.Sqrt(1+1/n+(x-meanX)*(x-meanX)/sxx)
+	}
+	return predicted, widths
+}
+
+// forecastHoltWinters fits the additive Holt-Winters model with the season of seasonLength points
+// and returns the predicted values for the number of points with the half width of their
+// prediction intervals for z. The NaN values are replaced with the previous value. It returns
+// nil if the series doesn't have two seasons of points to initialise the model
+func forecastHoltWinters(points []v3.Point, count, seasonLength int, params v3.ForecastParams, z float64) ([]float64, []float64) {
+	values := make([]float64, 0, len(points))
+	for _, point := range points {
+		switch {
+		case !math.IsNaN(point.Value):
+			values = append(values, point.Value)
+		case len(values) > 0:
+			values = append(values, values[len(values)-1])
+		}
+	}
+	if seasonLength < 2 || len(values) < 2*seasonLength {
+		return nil, nil
+	}
+
+	level := avg(values[:seasonLength])
+	trend := (avg(values[seasonLength:2*seasonLength]) - level) / float64(seasonLength)
+	season := make([]float64, seasonLength)
+	for idx := range season {
+		season[idx] = values[idx] - level
+	}
+
+	var sse float64
+	for idx := seasonLength; idx < len(values); idx++ {
+		value := values[idx]
+		seasonIdx := idx % seasonLength
+		residual := value - (level + trend + season[seasonIdx])
+		sse += residual * residual
+
+		prevLevel := level
+		level = params.Alpha*(value-season[seasonIdx]) + (1-params.Alpha)*(level+trend)
+		trend = params.Beta*(level-prevLevel) + (1-params.Beta)*trend
+		season[seasonIdx] = params.Gamma*(value-level) + (1-params.Gamma)*season[seasonIdx]
+	}
+	stdErr := math.Sqrt(sse / float64(len(values)-seasonLength))
+
+	predicted := make([]float64, count)
+	widths := make([]float64, count)
+	for idx := range predicted {
+		h := float64(idx + 1)
+		predicted[idx] = level + h*trend + season[(len(values)+idx)%seasonLength]
+		// the error grows with the number of steps ahead
+		widths[idx] = z * stdErr * math.Sqrt(h)
+	}
+	return predicted, widths
+}
+
+// funcForecast sets the predicted, upper bound and lower bound series of the result with the
+// points after the last point of each series up to the horizon. The Holt-Winters method falls
+// back to the linear method for the series without two seasons of points
+func funcForecast(result *v3.Result, params v3.ForecastParams) *v3.Result {
+	// the two sided z value of the confidence
+	z := math.Sqrt2 * math.Erfinv(params.Confidence)
+
+	result.PredictedSeries = make([]*v3.Series, 0, len(result.Series))
+	result.UpperBoundSeries = make([]*v3.Series, 0, len(result.Series))
+	result.LowerBoundSeries = make([]*v3.Series, 0, len(result.Series))
+	for _, series := range result.Series {
+		step := seriesStep(series.Points)
+		if step == 0 {
+			continue
+		}
+		last := series.Points[len(series.Points)-1].Timestamp
+		timestamps := forecastTimestamps(last, step, params.Horizon)
+		if len(timestamps) == 0 {
+			continue
+		}
+
+		var predicted, widths []float64
+		if params.Method == v3.ForecastMethodHoltWinters {
+			// the model predicts a value for every step of the series, only the values at the
+			// timestamps are kept if the step of the forecast is increased
+			seasonLength := int(params.Seasonality.Period().Milliseconds() / step)
+			stepPredicted, stepWidths := forecastHoltWinters(series.Points, int((timestamps[len(timestamps)-1]-last)/step), seasonLength, params, z)
+			if stepPredicted != nil {
+				predicted = make([]float64, len(timestamps))
+				widths = make([]float64, len(timestamps))
+				for idx, timestamp := range timestamps {
+					predicted[idx] = stepPredicted[(timestamp-last)/step-1]
+					widths[idx] = stepWidths[(timestamp-last)/step-1]
+				}
+			}
+		}
+		if predicted == nil {
+			predicted, widths = forecastLinear(series.Points, timestamps, z)
+		}
+		if predicted == nil {
+			continue
+		}
+
+		predictedSeries := &v3.Series{Labels: series.Labels, LabelsArray: series.LabelsArray, Points: make([]v3.Point, len(timestamps))}
+		upperSeries := &v3.Series{Labels: series.Labels, LabelsArray: series.LabelsArray, Points: make([]v3.Point, len(timestamps))}
+		lowerSeries := &v3.Series{Labels: series.Labels, LabelsArray: series.LabelsArray, Points: make([]v3.Point, len(timestamps))}
+		for idx, timestamp := range timestamps {
+			predictedSeries.Points[idx] = v3.Point{Timestamp: timestamp, Value: predicted[idx]}
+			upperSeries.Points[idx] = v3.Point{Timestamp: timestamp, Value: predicted[idx] + widths[idx]}
+			lowerSeries.Points[idx] = v3.Point{Timestamp: timestamp, Value: predicted[idx] - widths[idx]}
+		}
+		result.PredictedSeries = append(result.PredictedSeries, predictedSeries)
+		result.UpperBoundSeries = append(result.UpperBoundSeries, upperSeries)
+		result.LowerBoundSeries = append(result.LowerBoundSeries, lowerSeries)
+	}
+	return result
+}
+
+// outlierLabel is the label set by the outliers function, true for the outlier series
+const outlierLabel = "outlier"
+
+// madScale and meanADScale make the median and the mean absolute deviations an estimate of the
+// standard deviation of the normal distribution
+const (
+	madScale    = 1.4826
+	meanADScale = 1.2533
+)
+
+// funcOutliers flags the series that are far from the other series of the result, e.g the pod with
+// the latency far from its siblings. The series get the outlier label and the anomaly scores of the
+// result are the scores of the points, so the rules can alert on the scores above the tolerance.
+// At least three series are needed to tell the outliers apart
+func funcOutliers(result *v3.Result, params v3.OutlierParams) *v3.Result {
+	if len(result.Series) < 3 {
+		return result
+	}
+
+	// values[timestamp] are the values of the series at the timestamp
+	values := make(map[int64][]float64)
+	for _, series := range result.Series {
+		for _, point := range series.Points {
+			if !math.IsNaN(point.Value) {
+				values[point.Timestamp] = append(values[point.Timestamp], point.Value)
+			}
+		}
+	}
+	// the median of the series at the timestamps with at least three values
+	medians := make(map[int64]float64)
+	deviations := make(map[int64]float64)
+	for timestamp, points := range values {
+		if len(points) < 3 {
+			continue
+		}
+		m := median(append([]float64(nil), points...))
+		medians[timestamp] = m
+		absDeviations := make([]float64, 0, len(points))
+		sum := 0.0
+		for _, value := range points {
+			absDeviations = append(absDeviations, math.Abs(value-m))
+			sum += math.Abs(value - m)
+		}
+		deviations[timestamp] = madScale * median(absDeviations)
+		// the median absolute deviation is zero when most of the values are equal to the median
+		if deviations[timestamp] == 0 {
+			deviations[timestamp] = meanADScale * sum / float64(len(points))
+		}
+	}
+
+	var scores [][]v3.Point
+	var outliers []bool
+	switch params.Algorithm {
+	case v3.OutlierAlgorithmDBSCAN:
+		scores, outliers = dbscanOutliers(result.Series, medians, params)
+	default:
+		scores, outliers = madOutliers(result.Series, medians, deviations, params)
+	}
+
+	result.AnomalyScores = make([]*v3.Series, 0, len(result.Series))
+	for idx, series := range result.Series {
+		setLabel(series, outlierLabel, strconv.FormatBool(outliers[idx]))
+		labels := make(map[string]string, len(series.Labels))
+		for k, v := range series.Labels {
+			labels[k] = v
+		}
+		result.AnomalyScores = append(result.AnomalyScores, &v3.Series{
+			Labels:      labels,
+			LabelsArray: append([]map[string]string(nil), series.LabelsArray...),
+			Points:      scores[idx],
+		})
+	}
+	return result
+}
+
+// madOutliers scores the points by the distance from the median in median absolute deviations,
+// the series with the percentage of the points above the tolerance are the outliers
+func madOutliers(seriesList []*v3.Series, medians, deviations map[int64]float64, params v3.OutlierParams) ([][]v3.Point, []bool) {
+	scores := make([][]v3.Point, 0, len(seriesList))
+	outliers := make([]bool, 0, len(seriesList))
+	for _, series := range seriesList {
+		points := make([]v3.Point, 0, len(series.Points))
+		above := 0
+		for _, point := range series.Points {
+			m, ok := medians[point.Timestamp]
+			if !ok || math.IsNaN(point.Value) {
+				continue
+			}
+			// the deviation is zero only when all the values at the timestamp are equal
+			score := 0.0
+			if deviation := deviations[point.Timestamp]; deviation > 0 {
+				score = math.Abs(point.Value-m) / deviation
+			}
+			if score > params.Tolerance {
+				above++
+			}
+			points = append(points, v3.Point{Timestamp: point.Timestamp, Value: score})
+		}
+		scores = append(scores, points)
+		outliers = append(outliers, len(points) > 0 && float64(above)*100 >= params.Percentage*float64(len(points)))
+	}
+	return scores, outliers
+}
+
+// dbscanNoise is the cluster of the series that are in no cluster
+const dbscanNoise = -1
+
+// dbscanOutliers clusters the series by density with DBSCAN, the distance of two series is the mean
+// absolute difference of their values at the timestamps of both. The series with at least minPts series,
+// themselves included, within eps are the core series, the clusters are the core series within eps of
+// each other and the series within eps of them. The series in no cluster, the noise, are the outliers.
+// The score of a series is its distance from the nearest core series in eps, times the tolerance, so
+// the outliers are the series with the score above the tolerance. Without clusters there are no outliers
+func dbscanOutliers(seriesList []*v3.Series, medians map[int64]float64, params v3.OutlierParams) ([][]v3.Point, []bool) {
+	// values[idx][timestamp] are the values of the series at the timestamps of the median series
+	values := make([]map[int64]float64, len(seriesList))
+	for idx, series := range seriesList {
+		values[idx] = make(map[int64]float64)
+		for _, point := range series.Points {
+			if _, ok := medians[point.Timestamp]; ok && !math.IsNaN(point.Value) {
+				values[idx][point.Timestamp] = point.Value
+			}
+		}
+	}
+
+	eps := params.Eps
+	if eps == 0 {
+		eps = params.Tolerance * medianDistance(values, medians)
+	}
+
+	distances := make([][]float64, len(seriesList))
+	for i := range distances {
+		distances[i] = make([]float64, len(seriesList))
+	}
+	for i := range values {
+		for j := i + 1; j < len(values); j++ {
+			distance := seriesDistance(values[i], values[j])
+			distances[i][j], distances[j][i] = distance, distance
+		}
+	}
+
+	// neighbours[idx] are the series within eps of the series, the series included
+	neighbours := make([][]int, len(seriesList))
+	for i := range values {
+		if len(values[i]) == 0 {
+			continue
+		}
+		for j := range values {
+			if i == j || distances[i][j] <= eps {
+				neighbours[i] = append(neighbours[i], j)
+			}
+		}
+	}
+	isCore := func(idx int) bool {
+		return len(neighbours[idx]) >= params.MinPts
+	}
+
+	clusters := make([]int, len(seriesList))
+	for idx := range clusters {
+		clusters[idx] = dbscanNoise
+	}
+	cluster := 0
+	for idx := range seriesList {
+		if clusters[idx] != dbscanNoise || !isCore(idx) {
+			continue
+		}
+		// expand the cluster from the core series to the series reachable through the core series
+		clusters[idx] = cluster
+		queue := []int{idx}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, neighbour := range neighbours[current] {
+				if clusters[neighbour] != dbscanNoise {
+					continue
+				}
+				clusters[neighbour] = cluster
+				if isCore(neighbour) {
+					queue = append(queue, neighbour)
+				}
+			}
+		}
+		cluster++
+	}
+
+	scores := make([][]v3.Point, 0, len(seriesList))
+	outliers := make([]bool, 0, len(seriesList))
+	for idx, series := range seriesList {
+		points := make([]v3.Point, 0, len(series.Points))
+		nearestCore := math.Inf(1)
+		for other := range seriesList {
+			if isCore(other) {
+				nearestCore = math.Min(nearestCore, distances[idx][other])
+			}
+		}
+		// the series that can not be compared with the clusters are neither scored nor outliers
+		if len(values[idx]) == 0 || math.IsInf(nearestCore, 1) {
+			scores = append(scores, points)
+			outliers = append(outliers, false)
+			continue
+		}
+		// eps is zero only when all the series are equal to the median series
+		score := 0.0
+		if eps > 0 {
+			score = params.Tolerance * nearestCore / eps
+		}
+		for _, point := range series.Points {
+			if _, ok := values[idx][point.Timestamp]; ok {
+				points = append(points, v3.Point{Timestamp: point.Timestamp, Value: score})
+			}
+		}
+		scores = append(scores, points)
+		outliers = append(outliers, clusters[idx] == dbscanNoise)
+	}
+	return scores, outliers
+}
+
+// seriesDistance returns the mean absolute difference of the values of the series at the
+// timestamps of both, +Inf when they have no timestamps in common
+func seriesDistance(a, b map[int64]float64) float64 {
+	sum, count := 0.0, 0
+	for timestamp, value := range a {
+		if other, ok := b[timestamp]; ok {
+			sum += math.Abs(value - other)
+			count++
+		}
+	}
+	if count == 0 {
+		return math.Inf(1)
+	}
+	return sum / float64(count)
+}
+
+// medianDistance returns the median of the distances of the series from the median series,
+// the mean distance when most of the series are equal to the median series
+func medianDistance(values []map[int64]float64, medians map[int64]float64) float64 {
+	distances := make([]float64, 0, len(values))
+	sum := 0.0
+	for _, seriesValues := range values {
+		if len(seriesValues) == 0 {
+			continue
+		}
+		distance := seriesDistance(seriesValues, medians)
+		distances = append(distances, distance)
+		sum += distance
+	}
+	if len(distances) == 0 {
+		return 0
+	}
+	if m := median(distances); m > 0 {
+		return m
+	}
+	return sum / float64(len(distances))
+}
+
 func ApplyFunction(fn v3.Function, result *v3.Result) *v3.Result {
 
 	switch fn.Name {
@@ -305,6 +1041,66 @@ func ApplyFunction(fn v3.Function, result *v3.Result) *v3.Result {
 			return result
 		}
 		return funcTimeShift(result, shift)
+	case v3.FunctionNameMovingAvg:
+		return funcMovingWindow(result, fn.Args[0], avg)
+	case v3.FunctionNameMovingSum:
+		return funcMovingWindow(result, fn.Args[0], sum)
+	case v3.FunctionNameMovingMin:
+		return funcMovingWindow(result, fn.Args[0], minimum)
+	case v3.FunctionNameMovingMax:
+		return funcMovingWindow(result, fn.Args[0], maximum)
+	case v3.FunctionNameMovingStdDev:
+		return funcMovingWindow(result, fn.Args[0], stdDev)
+	case v3.FunctionNameRollingPercentile:
+		p, ok := fn.Args[1].(float64)
+		if !ok {
+			return result
+		}
+		return funcMovingWindow(result, fn.Args[0], func(values []float64) float64 {
+			return percentile(values, p)
+		})
+	case v3.FunctionNameZScore:
+		var window interface{}
+		if len(fn.Args) > 0 {
+			window = fn.Args[0]
+		}
+		return funcZScore(result, window)
+	case v3.FunctionNameRateOfChange:
+		return funcRateOfChange(result)
+	case v3.FunctionNameSumBy:
+		return funcAggregateBy(result, stringArgs(fn), sum)
+	case v3.FunctionNameAvgBy:
+		return funcAggregateBy(result, stringArgs(fn), avg)
+	case v3.FunctionNameMinBy:
+		return funcAggregateBy(result, stringArgs(fn), minimum)
+	case v3.FunctionNameMaxBy:
+		return funcAggregateBy(result, stringArgs(fn), maximum)
+	case v3.FunctionNameCountBy:
+		return funcAggregateBy(result, stringArgs(fn), count)
+	case v3.FunctionNameLabelReplace:
+		args := stringArgs(fn)
+		if len(args) != 4 {
+			return result
+		}
+		return funcLabelReplace(result, args[0], args[1], args[2], args[3])
+	case v3.FunctionNameLabelJoin:
+		args := stringArgs(fn)
+		if len(args) < 3 {
+			return result
+		}
+		return funcLabelJoin(result, args[0], args[1], args[2:])
+	case v3.FunctionNameForecast:
+		params, err := fn.ForecastParams()
+		if err != nil {
+			return result
+		}
+		return funcForecast(result, params)
+	case v3.FunctionNameOutliers:
+		params, err := fn.OutlierParams()
+		if err != nil {
+			return result
+		}
+		return funcOutliers(result, params)
 	}
 	return result
 }
@@ -2,6 +2,7 @@ package queryBuilder
 
 import (
 	"math"
+	"reflect"
 	"testing"
 
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
@@ -669,3 +670,447 @@ func TestFuncRunningDiff(t *testing.T) {
 		})
 	}
 }
+
+func TestMovingWindowFunctions(t *testing.T) {
+	points := func() []v3.Point {
+		return []v3.Point{
+			{Timestamp: 60000, Value: 1},
+			{Timestamp: 120000, Value: 3},
+			{Timestamp: 180000, Value: math.NaN()},
+			{Timestamp: 240000, Value: 8},
+			{Timestamp: 360000, Value: 4},
+		}
+	}
+
+	tests := []struct {
+		name string
+		fn   v3.Function
+		want []float64
+	}{
+		{
+			name: "moving avg over 2 points",
+			fn:   v3.Function{Name: v3.FunctionNameMovingAvg, Args: []interface{}{float64(2)}},
+			want: []float64{1, 2, 3, 8, 6},
+		},
+		{
+			name: "moving sum over 3 minutes",
+			fn:   v3.Function{Name: v3.FunctionNameMovingSum, Args: []interface{}{"3m"}},
+			want: []float64{1, 4, 4, 11, 12},
+		},
+		{
+			name: "moving min over 3 points",
+			fn:   v3.Function{Name: v3.FunctionNameMovingMin, Args: []interface{}{float64(3)}},
+			want: []float64{1, 1, 1, 3, 4},
+		},
+		{
+			name: "moving max over 3 points",
+			fn:   v3.Function{Name: v3.FunctionNameMovingMax, Args: []interface{}{float64(3)}},
+			want: []float64{1, 3, 3, 8, 8},
+		},
+		{
+			name: "moving std dev over 2 points",
+			fn:   v3.Function{Name: v3.FunctionNameMovingStdDev, Args: []interface{}{float64(2)}},
+			want: []float64{0, 1, 0, 0, 2},
+		},
+		{
+			name: "rolling p50 over 4 points",
+			fn:   v3.Function{Name: v3.FunctionNameRollingPercentile, Args: []interface{}{float64(4), float64(50)}},
+			want: []float64{1, 2, 2, 3, 4},
+		},
+		{
+			name: "z-score of the series",
+			fn:   v3.Function{Name: v3.FunctionNameZScore},
+			want: []float64{-3 / math.Sqrt(6.5), -1 / math.Sqrt(6.5), math.NaN(), 4 / math.Sqrt(6.5), 0},
+		},
+		{
+			name: "rate of change per second",
+			fn:   v3.Function{Name: v3.FunctionNameRateOfChange},
+			want: []float64{2.0 / 60, math.NaN(), math.NaN(), -4.0 / 120},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ApplyFunction(tt.fn, &v3.Result{Series: []*v3.Series{{Points: points()}}})
+			series := got.Series[0]
+			if len(series.Points) != len(tt.want) {
+				t.Fatalf("ApplyFunction() = len(series.Points) %v, want %v", len(series.Points), len(tt.want))
+			}
+			for k, point := range series.Points {
+				if math.IsNaN(tt.want[k]) {
+					if !math.IsNaN(point.Value) {
+						t.Errorf("ApplyFunction() point %d = %v, want NaN", k, point.Value)
+					}
+					continue
+				}
+				if math.Abs(point.Value-tt.want[k]) > 1e-9 {
+					t.Errorf("ApplyFunction() point %d = %v, want %v", k, point.Value, tt.want[k])
+				}
+			}
+		})
+	}
+}
+
+func TestSeriesFunctions(t *testing.T) {
+	series := func() []*v3.Series {
+		return []*v3.Series{
+			{
+				Labels:      map[string]string{"cluster": "prod", "k8s.pod.name": "api-7d9f8-x2k"},
+				LabelsArray: []map[string]string{{"cluster": "prod"}, {"k8s.pod.name": "api-7d9f8-x2k"}},
+				Points:      []v3.Point{{Timestamp: 1, Value: 1}, {Timestamp: 2, Value: 2}},
+			},
+			{
+				Labels:      map[string]string{"cluster": "prod", "k8s.pod.name": "api-7d9f8-p4m"},
+				LabelsArray: []map[string]string{{"cluster": "prod"}, {"k8s.pod.name": "api-7d9f8-p4m"}},
+				Points:      []v3.Point{{Timestamp: 1, Value: 3}, {Timestamp: 2, Value: math.NaN()}},
+			},
+			{
+				Labels:      map[string]string{"cluster": "staging", "k8s.pod.name": "worker-5c4b-abc"},
+				LabelsArray: []map[string]string{{"cluster": "staging"}, {"k8s.pod.name": "worker-5c4b-abc"}},
+				Points:      []v3.Point{{Timestamp: 2, Value: 10}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name string
+		fn   v3.Function
+		want []*v3.Series
+	}{
+		{
+			name: "sum by cluster",
+			fn:   v3.Function{Name: v3.FunctionNameSumBy, Args: []interface{}{"cluster"}},
+			want: []*v3.Series{
+				{
+					Labels:      map[string]string{"cluster": "prod"},
+					LabelsArray: []map[string]string{{"cluster": "prod"}},
+					Points:      []v3.Point{{Timestamp: 1, Value: 4}, {Timestamp: 2, Value: 2}},
+				},
+				{
+					Labels:      map[string]string{"cluster": "staging"},
+					LabelsArray: []map[string]string{{"cluster": "staging"}},
+					Points:      []v3.Point{{Timestamp: 2, Value: 10}},
+				},
+			},
+		},
+		{
+			name: "count of all the series",
+			fn:   v3.Function{Name: v3.FunctionNameCountBy},
+			want: []*v3.Series{
+				{
+					Labels:      map[string]string{},
+					LabelsArray: []map[string]string{},
+					Points:      []v3.Point{{Timestamp: 1, Value: 2}, {Timestamp: 2, Value: 2}},
+				},
+			},
+		},
+		{
+			name: "label replace pod name with the deployment",
+			fn:   v3.Function{Name: v3.FunctionNameLabelReplace, Args: []interface{}{"deployment", "$1", "k8s.pod.name", "(.*)-[^-]+-[^-]+"}},
+			want: func() []*v3.Series {
+				want := series()
+				for idx, deployment := range []string{"api", "api", "worker"} {
+					want[idx].Labels["deployment"] = deployment
+					want[idx].LabelsArray = append(want[idx].LabelsArray, map[string]string{"deployment": deployment})
+				}
+				return want
+			}(),
+		},
+		{
+			name: "label join",
+			fn:   v3.Function{Name: v3.FunctionNameLabelJoin, Args: []interface{}{"cluster", "/", "cluster", "k8s.pod.name"}},
+			want: func() []*v3.Series {
+				want := series()
+				for _, s := range want {
+					joined := s.Labels["cluster"] + "/" + s.Labels["k8s.pod.name"]
+					s.Labels["cluster"] = joined
+					s.LabelsArray = []map[string]string{{"k8s.pod.name": s.Labels["k8s.pod.name"]}, {"cluster": joined}}
+				}
+				return want
+			}(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ApplyFunction(tt.fn, &v3.Result{Series: series()})
+			if len(got.Series) != len(tt.want) {
+				t.Fatalf("ApplyFunction() = len(series) %v, want %v", len(got.Series), len(tt.want))
+			}
+			for j, s := range got.Series {
+				if !reflect.DeepEqual(s.Labels, tt.want[j].Labels) {
+					t.Errorf("ApplyFunction() labels = %v, want %v", s.Labels, tt.want[j].Labels)
+				}
+				if !reflect.DeepEqual(s.LabelsArray, tt.want[j].LabelsArray) {
+					t.Errorf("ApplyFunction() labels array = %v, want %v", s.LabelsArray, tt.want[j].LabelsArray)
+				}
+				if len(s.Points) != len(tt.want[j].Points) {
+					t.Fatalf("ApplyFunction() = len(points) %v, want %v", len(s.Points), len(tt.want[j].Points))
+				}
+				for k, point := range s.Points {
+					want := tt.want[j].Points[k]
+					if point.Timestamp != want.Timestamp || (point.Value != want.Value && !(math.IsNaN(point.Value) && math.IsNaN(want.Value))) {
+						t.Errorf("ApplyFunction() point = %v, want %v", point, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFuncForecast(t *testing.T) {
+	points := func(values ...float64) []v3.Point {
+		result := make([]v3.Point, 0, len(values))
+		for idx, value := range values {
+			result = append(result, v3.Point{Timestamp: int64(idx) * 15 * 60000, Value: value})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name      string
+		fn        v3.Function
+		points    []v3.Point
+		want      []v3.Point
+		wantBands bool
+	}{
+		{
+			name:   "linear",
+			fn:     v3.Function{Name: v3.FunctionNameForecast, NamedArgs: map[string]interface{}{"horizon": "45m"}},
+			points: points(10, 12, math.NaN(), 16, 18),
+			want:   []v3.Point{{Timestamp: 75 * 60000, Value: 20}, {Timestamp: 90 * 60000, Value: 22}, {Timestamp: 105 * 60000, Value: 24}},
+		},
+		{
+			name: "holt winters",
+			fn: v3.Function{Name: v3.FunctionNameForecast, NamedArgs: map[string]interface{}{
+				"horizon": "1h", "method": "holtWinters", "seasonality": "hourly",
+			}},
+			points: points(1, 3, 5, 3, 1, 3, 5, 3, 1, 3, 5, 3),
+			want:   []v3.Point{{Timestamp: 180 * 60000, Value: 1}, {Timestamp: 195 * 60000, Value: 3}, {Timestamp: 210 * 60000, Value: 5}, {Timestamp: 225 * 60000, Value: 3}},
+		},
+		{
+			name: "holt winters without two seasons falls back to linear",
+			fn: v3.Function{Name: v3.FunctionNameForecast, NamedArgs: map[string]interface{}{
+				"horizon": "15m", "method": "holtWinters", "seasonality": "daily",
+			}},
+			points: points(1, 2, 3),
+			want:   []v3.Point{{Timestamp: 45 * 60000, Value: 4}},
+		},
+		{
+			name:      "confidence bands",
+			fn:        v3.Function{Name: v3.FunctionNameForecast, NamedArgs: map[string]interface{}{"horizon": "15m", "confidence": 0.9}},
+			points:    points(10, 13, 12, 15, 14),
+			want:      []v3.Point{{Timestamp: 75 * 60000, Value: 15.8}},
+			wantBands: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			series := &v3.Series{Labels: map[string]string{"host.name": "db-1"}, Points: tt.points}
+			got := ApplyFunction(tt.fn, &v3.Result{Series: []*v3.Series{series}})
+			if len(got.Series) != 1 || len(got.Series[0].Points) != len(tt.points) {
+				t.Fatalf("ApplyFunction() changed the series = %v", got.Series)
+			}
+			if len(got.PredictedSeries) != 1 || len(got.UpperBoundSeries) != 1 || len(got.LowerBoundSeries) != 1 {
+				t.Fatalf("ApplyFunction() = %d predicted series, want 1", len(got.PredictedSeries))
+			}
+			if !reflect.DeepEqual(got.PredictedSeries[0].Labels, series.Labels) {
+				t.Errorf("ApplyFunction() predicted labels = %v, want %v", got.PredictedSeries[0].Labels, series.Labels)
+			}
+			predicted := got.PredictedSeries[0].Points
+			if len(predicted) != len(tt.want) {
+				t.Fatalf("ApplyFunction() = len(points) %v, want %v", len(predicted), len(tt.want))
+			}
+			for idx, point := range predicted {
+				if point.Timestamp != tt.want[idx].Timestamp || math.Abs(point.Value-tt.want[idx].Value) > 1e-9 {
+					t.Errorf("ApplyFunction() point = %v, want %v", point, tt.want[idx])
+				}
+				upper := got.UpperBoundSeries[0].Points[idx].Value - point.Value
+				lower := point.Value - got.LowerBoundSeries[0].Points[idx].Value
+				if math.Abs(upper-lower) > 1e-9 || (upper > 1e-9) != tt.wantBands {
+					t.Errorf("ApplyFunction() bounds = +%v -%v around %v", upper, lower, point.Value)
+				}
+			}
+		})
+	}
+
+	t.Run("long horizon", func(t *testing.T) {
+		fn := v3.Function{Name: v3.FunctionNameForecast, NamedArgs: map[string]interface{}{"horizon": "7d", "method": "holtWinters"}}
+		if err := fn.Validate(); err != nil {
+			t.Fatalf("Validate() error = %v", err)
+		}
+		got := ApplyFunction(fn, &v3.Result{Series: []*v3.Series{{Points: points(1, 2, 3, 4)}}})
+		predicted := got.PredictedSeries[0].Points
+		// 672 steps of 15m are forecast with a step of 45m to keep at most 300 points
+		if len(predicted) != 224 || predicted[0].Timestamp != 90*60000 || predicted[223].Timestamp != 45*60000+7*24*60*60000 {
+			t.Errorf("ApplyFunction() = %d points from %d to %d", len(predicted), predicted[0].Timestamp, predicted[len(predicted)-1].Timestamp)
+		}
+	})
+
+	t.Run("invalid params", func(t *testing.T) {
+		for _, namedArgs := range []map[string]interface{}{
+			{},
+			{"horizon": "soon"},
+			{"horizon": "1h", "method": "arima"},
+			{"horizon": "1h", "seasonality": "monthly"},
+			{"horizon": "1h", "confidence": 95.0},
+			{"horizon": "1h", "alpha": "1.5"},
+		} {
+			fn := v3.Function{Name: v3.FunctionNameForecast, NamedArgs: namedArgs}
+			if err := fn.Validate(); err == nil {
+				t.Errorf("Validate(%v) expected error", namedArgs)
+			}
+		}
+	})
+}
+
+func TestFuncOutliers(t *testing.T) {
+	newResult := func() *v3.Result {
+		values := map[string][]float64{
+			"pod-a": {100, 102, 98, 100},
+			"pod-b": {101, 99, 100, 102},
+			"pod-c": {99, 100, 103, 101},
+			"pod-d": {100, 101, 99, 100},
+			"pod-e": {100, 300, 310, 320},
+		}
+		result := &v3.Result{}
+		for _, pod := range []string{"pod-a", "pod-b", "pod-c", "pod-d", "pod-e"} {
+			points := make([]v3.Point, 0, len(values[pod]))
+			for idx, value := range values[pod] {
+				points = append(points, v3.Point{Timestamp: int64(idx) * 60000, Value: value})
+			}
+			result.Series = append(result.Series, &v3.Series{
+				Labels:      map[string]string{"pod": pod},
+				LabelsArray: []map[string]string{{"pod": pod}},
+				Points:      points,
+			})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		fn       v3.Function
+		outliers map[string]bool
+	}{
+		{
+			name:     "mad",
+			fn:       v3.Function{Name: v3.FunctionNameOutliers},
+			outliers: map[string]bool{"pod-a": false, "pod-b": false, "pod-c": false, "pod-d": false, "pod-e": true},
+		},
+		{
+			name: "mad with the percentage of the points",
+			fn: v3.Function{Name: v3.FunctionNameOutliers, NamedArgs: map[string]interface{}{
+				"percentage": "80",
+			}},
+			outliers: map[string]bool{"pod-a": false, "pod-b": false, "pod-c": false, "pod-d": false, "pod-e": false},
+		},
+		{
+			name: "dbscan",
+			fn: v3.Function{Name: v3.FunctionNameOutliers, NamedArgs: map[string]interface{}{
+				"algorithm": "dbscan", "tolerance": 5.0,
+			}},
+			outliers: map[string]bool{"pod-a": false, "pod-b": false, "pod-c": false, "pod-d": false, "pod-e": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			got := ApplyFunction(tt.fn, newResult())
+			if len(got.AnomalyScores) != len(got.Series) {
+				t.Fatalf("ApplyFunction() = %d scores, want %d", len(got.AnomalyScores), len(got.Series))
+			}
+			outliers := make(map[string]bool)
+			for idx, series := range got.Series {
+				outliers[series.Labels["pod"]] = series.Labels["outlier"] == "true"
+				if !reflect.DeepEqual(got.AnomalyScores[idx].Labels, series.Labels) {
+					t.Errorf("score labels = %v, want %v", got.AnomalyScores[idx].Labels, series.Labels)
+				}
+				if len(got.AnomalyScores[idx].Points) != len(series.Points) {
+					t.Errorf("%s has %d scores, want %d", series.Labels["pod"], len(got.AnomalyScores[idx].Points), len(series.Points))
+				}
+			}
+			if !reflect.DeepEqual(outliers, tt.outliers) {
+				t.Errorf("outliers = %v, want %v", outliers, tt.outliers)
+			}
+		})
+	}
+
+	for _, namedArgs := range []map[string]interface{}{
+		{"algorithm": "kmeans"},
+		{"algorithm": "dbscan", "eps": -1.0},
+		{"algorithm": "dbscan", "minPts": 0.0},
+		{"algorithm": "dbscan", "minPts": "2.5"},
+	} {
+		invalid := v3.Function{Name: v3.FunctionNameOutliers, NamedArgs: namedArgs}
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%v) expected error", namedArgs)
+		}
+	}
+}
+
+func TestFuncOutliersDBSCANClusters(t *testing.T) {
+	// two tight clusters of the pods of two zones and a pod between them in no cluster
+	values := map[string][]float64{
+		"zone-a-1": {10, 11, 10, 12},
+		"zone-a-2": {11, 10, 11, 11},
+		"zone-a-3": {10, 10, 12, 11},
+		"zone-b-1": {100, 101, 99, 100},
+		"zone-b-2": {101, 100, 100, 99},
+		"zone-b-3": {99, 100, 101, 101},
+		"isolated": {55, 54, 56, 55},
+	}
+	newResult := func() *v3.Result {
+		result := &v3.Result{}
+		for _, pod := range []string{"zone-a-1", "zone-a-2", "zone-a-3", "zone-b-1", "zone-b-2", "zone-b-3", "isolated"} {
+			points := make([]v3.Point, 0, len(values[pod]))
+			for idx, value := range values[pod] {
+				points = append(points, v3.Point{Timestamp: int64(idx) * 60000, Value: value})
+			}
+			result.Series = append(result.Series, &v3.Series{Labels: map[string]string{"pod": pod}, Points: points})
+		}
+		return result
+	}
+	want := map[string]bool{
+		"zone-a-1": false, "zone-a-2": false, "zone-a-3": false,
+		"zone-b-1": false, "zone-b-2": false, "zone-b-3": false,
+		"isolated": true,
+	}
+
+	fn := v3.Function{Name: v3.FunctionNameOutliers, NamedArgs: map[string]interface{}{
+		"algorithm": "dbscan", "eps": 5.0, "minPts": 3.0,
+	}}
+	if err := fn.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	got := ApplyFunction(fn, newResult())
+	outliers := make(map[string]bool)
+	for idx, series := range got.Series {
+		pod := series.Labels["pod"]
+		outliers[pod] = series.Labels["outlier"] == "true"
+		// the outliers score above the tolerance and the clustered series at most the tolerance
+		for _, point := range got.AnomalyScores[idx].Points {
+			if (point.Value > 3) != want[pod] {
+				t.Errorf("%s has the score %v", pod, point.Value)
+			}
+		}
+	}
+	if !reflect.DeepEqual(outliers, want) {
+		t.Errorf("outliers = %v, want %v", outliers, want)
+	}
+
+	// the pod between the zones is not far from the median of the pods, so mad doesn't flag it
+	got = ApplyFunction(v3.Function{Name: v3.FunctionNameOutliers}, newResult())
+	for _, series := range got.Series {
+		if series.Labels["pod"] == "isolated" && series.Labels["outlier"] == "true" {
+			t.Errorf("mad flagged the isolated pod")
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package queryBuilder
+
+import (
+	"context"
+	"fmt"
+
+	"go.signoz.io/signoz/pkg/query-service/interfaces"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.signoz.io/signoz/pkg/query-service/querycache"
+	"go.signoz.io/signoz/pkg/query-service/utils"
+	"go.uber.org/multierr"
+)
+
+// CachedListQuery is a list query whose rows are served from the query cache
+type CachedListQuery struct {
+	queryCache interfaces.QueryCache
+
+	name  string
+	query *v3.BuilderQuery
+	key   string
+	// cached is the cached list data of the key
+	cached []querycache.CachedListData
+	// end is the end of the rows in nanoseconds, the timestamp of the cursor for the next pages
+	end  int64
+	keep func(row *v3.Row) bool
+	// the rows in [offset, limit) of the latest rows are returned
+	offset uint64
+	limit  uint64
+}
+
+// RowID returns the id of the row
+func RowID(row *v3.Row) string {
+	switch id := row.Data["id"].(type) {
+	case string:
+		return id
+	case *string:
+		return *id
+	}
+	return ""
+}
+
+// NewCachedListQuery returns the list query if its rows can be served from the query cache. Only
+// the list queries ordered by the timestamp in descending order are cached, the logs list queries
+// paginated with the id of the last log are cached if the last log is in the cache
+func NewCachedListQuery(queryCache interfaces.QueryCache, params *v3.QueryRangeParamsV3, useLogsNewSchema bool) (*CachedListQuery, bool) {
+	if queryCache == nil || params.NoCache || params.CompositeQuery.PanelType != v3.PanelTypeList || len(params.CompositeQuery.BuilderQueries) != 1 {
+		return nil, false
+	}
+
+	var cq *CachedListQuery
+	for name, query := range params.CompositeQuery.BuilderQueries {
+		orderedByTs := len(query.OrderBy) == 1 && query.OrderBy[0].ColumnName == "timestamp" && query.OrderBy[0].Order == "desc"
+		switch query.DataSource {
+		case v3.DataSourceLogs:
+			// the offset is not used for the logs ordered by the timestamp
+			if !useLogsNewSchema || !orderedByTs || query.PageSize == 0 || (query.Limit > 0 && query.Offset >= query.Limit) {
+				return nil, false
+			}
+			limit := query.PageSize
+			if query.Limit > 0 && query.Offset+query.PageSize > query.Limit {
+				limit = query.Limit - query.Offset
+			}
+			cq = &CachedListQuery{queryCache: queryCache, name: name, query: query, limit: limit}
+		case v3.DataSourceTraces:
+			// the traces list queries are ordered by the timestamp by default
+			if (len(query.OrderBy) > 0 && !orderedByTs) || query.Limit == 0 {
+				return nil, false
+			}
+			cq = &CachedListQuery{queryCache: queryCache, name: name, query: query, offset: query.Offset, limit: query.Offset + query.Limit}
+		default:
+			return nil, false
+		}
+	}
+
+	cq.key = ListCacheKey(cq.query)
+	cq.cached = queryCache.GetCachedListData(cq.key)
+	cq.end = utils.GetEpochNanoSecs(params.End)
+
+	if cq.query.Filters == nil {
+		return cq, true
+	}
+	for _, item := range cq.query.Filters.Items {
+		if !IsListCursor(cq.query, item) {
+			continue
+		}
+		cursor, ok := item.Value.(string)
+		if !ok || cq.keep != nil {
+			return nil, false
+		}
+		found := false
+		for _, data := range cq.cached {
+			for _, row := range data.Rows {
+				if RowID(row) == cursor {
+					cq.end = min(cq.end, row.Timestamp.UnixNano())
+					found = true
+				}
+			}
+		}
+		if !found {
+			return nil, false
+		}
+		cursorTs := cq.end
+		cq.keep = func(row *v3.Row) bool {
+			return row.Timestamp.UnixNano() < cursorTs || RowID(row) < cursor
+		}
+	}
+	return cq, true
+}
+
+// Run returns the rows of the list query from the cache, the ranges missing in the
+// cache are queried with the builder and the reader and cached
+func (cq *CachedListQuery) Run(ctx context.Context, builder *QueryBuilder, reader interfaces.Reader, params *v3.QueryRangeParamsV3) ([]*v3.Result, map[string]error, error) {
+	fetch := func(start, end int64, limit uint64) ([]*v3.Row, error) {
+		fetchParams := params.Clone()
+		fetchParams.Start = start
+		fetchParams.End = end
+		query := fetchParams.CompositeQuery.BuilderQueries[cq.name]
+		query.Offset = 0
+		if query.DataSource == v3.DataSourceLogs {
+			query.Limit = 0
+			query.PageSize = limit
+		} else {
+			query.Limit = limit
+		}
+		// the cursor is applied on the rows
+		if query.Filters != nil {
+			items := make([]v3.FilterItem, 0, len(query.Filters.Items))
+			for _, item := range query.Filters.Items {
+				if !IsListCursor(query, item) {
+					items = append(items, item)
+				}
+			}
+			query.Filters.Items = items
+		}
+
+		queries, err := builder.PrepareQueries(fetchParams)
+		if err != nil {
+			return nil, err
+		}
+		return reader.GetListResultV3(ctx, queries[cq.name])
+	}
+
+	start := utils.GetEpochNanoSecs(params.Start)
+	ranges := []utils.LogsListTsRange{{Start: start, End: utils.GetEpochNanoSecs(params.End)}}
+	if cq.query.DataSource == v3.DataSourceLogs {
+		if logsRanges := utils.GetLogsListTsRanges(params.Start, params.End); len(logsRanges) > 0 {
+			ranges = logsRanges
+		}
+	}
+
+	rows := make([]*v3.Row, 0)
+	var fetched []querycache.CachedListData
+	end := cq.end
+	for _, tsRange := range ranges {
+		rangeEnd := min(tsRange.End, end)
+		if rangeEnd < tsRange.Start {
+			continue
+		}
+		rangeRows, rangeFetched, err := querycache.FillList(cq.cached, tsRange.Start, rangeEnd, cq.limit-uint64(len(rows)), cq.keep, fetch)
+		if err != nil {
+			return nil, map[string]error{cq.name: err}, fmt.Errorf("encountered multiple errors: %s", multierr.Combine(err))
+		}
+		rows = append(rows, rangeRows...)
+		fetched = append(fetched, rangeFetched...)
+		// the ranges share the boundaries
+		end = tsRange.Start - 1
+		if uint64(len(rows)) >= cq.limit {
+			break
+		}
+	}
+	cq.queryCache.MergeWithCachedListData(cq.key, fetched)
+
+	if cq.offset >= uint64(len(rows)) {
+		rows = make([]*v3.Row, 0)
+	} else {
+		rows = rows[cq.offset:]
+	}
+	return []*v3.Result{{QueryName: cq.name, List: rows}}, nil, nil
+}
@@ -38,6 +38,45 @@ var SupportedFunctions = []string{
 	"radians",
 	"now",
 	"toUnixTimestamp",
+	// the functions below are only evaluated by the query service, see formulaOnlyFunctions
+	"if",
+	"coalesce",
+	"default",
+	"min",
+	"max",
+	"timestamp",
+	"sum",
+	"avg",
+	"count",
+}
+
+// formulaOnlyFunctions are the functions of the formulas evaluated on the query results by the
+// query service, they can't be used in the formulas run in ClickHouse
+var formulaOnlyFunctions = map[string]struct{}{
+	"if":        {},
+	"coalesce":  {},
+	"default":   {},
+	"min":       {},
+	"max":       {},
+	"timestamp": {},
+	"sum":       {},
+	"avg":       {},
+	"count":     {},
+}
+
+// FormulaOnlyFunction returns the first function of the expression that can't be used in
+// the formulas run in ClickHouse
+func FormulaOnlyFunction(expression *govaluate.EvaluableExpression) (string, bool) {
+	for _, token := range expression.Tokens() {
+		if token.Kind != govaluate.FUNCTION {
+			continue
+		}
+		name, _ := token.Meta.(string)
+		if _, ok := formulaOnlyFunctions[name]; ok {
+			return name, true
+		}
+	}
+	return "", false
 }
 
 var EvalFuncs = map[string]govaluate.ExpressionFunction{}
@@ -246,14 +285,18 @@ func (qb *QueryBuilder) PrepareQueries(params *v3.QueryRangeParamsV3) (map[strin
 			}
 		}
 
-		// Build queries for each expression
+		// Build queries for each expression, the formulas with join or the functions
+		// evaluated by the query service are evaluated on the results of the queries
 		for _, query := range compositeQuery.BuilderQueries {
-			if query.Expression != query.QueryName {
+			if query.Expression != query.QueryName && query.Join == nil {
 				expression, err := govaluate.NewEvaluableExpressionWithFunctions(query.Expression, EvalFuncs)
 
 				if err != nil {
 					return nil, err
 				}
+				if _, ok := FormulaOnlyFunction(expression); ok {
+					continue
+				}
 
 				queryString, err := expressionToQuery(params, queries, expression, query.QueryName)
 				if err != nil {
@@ -317,6 +360,12 @@ func isLogExpression(expression *govaluate.EvaluableExpression, params *v3.Query
 func (c *cacheKeyGenerator) GenerateKeys(params *v3.QueryRangeParamsV3) map[string]string {
 	keys := make(map[string]string)
 
+	// heatmap queries group by the buckets which the graph queries with the
+	// same key don't, so they are not cached
+	if params.CompositeQuery.PanelType == v3.PanelTypeHeatmap {
+		return keys
+	}
+
 	// Use query as the cache key for PromQL queries
 	if params.CompositeQuery.QueryType == v3.QueryTypePromQL {
 		if params.CompositeQuery.PanelType != v3.PanelTypeGraph {
@@ -345,6 +394,10 @@ func (c *cacheKeyGenerator) GenerateKeys(params *v3.QueryRangeParamsV3) map[stri
 			parts = append(parts, fmt.Sprintf("aggregate=%s", query.AggregateOperator))
 			parts = append(parts, fmt.Sprintf("limit=%d", query.Limit))
 
+			if query.Quantile != 0 {
+				parts = append(parts, fmt.Sprintf("quantile=%v", query.Quantile))
+			}
+
 			if query.ShiftBy != 0 {
 				parts = append(parts, fmt.Sprintf("shiftBy=%d", query.ShiftBy))
 			}
@@ -359,6 +412,12 @@ func (c *cacheKeyGenerator) GenerateKeys(params *v3.QueryRangeParamsV3) map[stri
 				}
 			}
 
+			if query.Filters != nil && len(query.Filters.Groups) > 0 {
+				for idx, group := range query.Filters.Groups {
+					parts = append(parts, fmt.Sprintf("filterGroup-%d=%s", idx, group.CacheKey()))
+				}
+			}
+
 			if len(query.GroupBy) > 0 {
 				for idx, groupBy := range query.GroupBy {
 					parts = append(parts, fmt.Sprintf("groupBy-%d=%s", idx, groupBy.CacheKey()))
@@ -406,6 +465,10 @@ func (c *cacheKeyGenerator) GenerateKeys(params *v3.QueryRangeParamsV3) map[stri
 			parts = append(parts, fmt.Sprintf("timeAggregation=%s", query.TimeAggregation))
 			parts = append(parts, fmt.Sprintf("spaceAggregation=%s", query.SpaceAggregation))
 
+			if query.Quantile != 0 {
+				parts = append(parts, fmt.Sprintf("quantile=%v", query.Quantile))
+			}
+
 			if query.ShiftBy != 0 {
 				parts = append(parts, fmt.Sprintf("shiftBy=%d", query.ShiftBy))
 			}
@@ -420,6 +483,12 @@ func (c *cacheKeyGenerator) GenerateKeys(params *v3.QueryRangeParamsV3) map[stri
 				}
 			}
 
+			if query.Filters != nil && len(query.Filters.Groups) > 0 {
+				for idx, group := range query.Filters.Groups {
+					parts = append(parts, fmt.Sprintf("filterGroup-%d=%s", idx, group.CacheKey()))
+				}
+			}
+
 			if len(query.GroupBy) > 0 {
 				for idx, groupBy := range query.GroupBy {
 					parts = append(parts, fmt.Sprintf("groupBy-%d=%s", idx, groupBy.CacheKey()))
@@ -439,7 +508,7 @@ func (c *cacheKeyGenerator) GenerateKeys(params *v3.QueryRangeParamsV3) map[stri
 
 	// Build keys for each expression
 	for _, query := range params.CompositeQuery.BuilderQueries {
-		if query.Expression != query.QueryName {
+		if query.Expression != query.QueryName && query.Join == nil {
 			expression, _ := govaluate.NewEvaluableExpressionWithFunctions(query.Expression, EvalFuncs)
 
 			if !isMetricExpression(expression, params) && !isLogExpression(expression, params) {
@@ -454,6 +523,46 @@ func (c *cacheKeyGenerator) GenerateKeys(params *v3.QueryRangeParamsV3) map[stri
 	return keys
 }
 
+// IsListCursor returns true if the filter item is the cursor of the logs list pagination
+// i.e. the id of the last log of the previous page
+func IsListCursor(query *v3.BuilderQuery, item v3.FilterItem) bool {
+	return query.DataSource == v3.DataSourceLogs && item.Key.Key == "id" && item.Operator == v3.FilterOperatorLessThan
+}
+
+// ListCacheKey returns the cache key for the rows of the list query
+// The time range and the pagination are not part of the key so that the
+// pages of the query are served from the same cached rows
+func ListCacheKey(query *v3.BuilderQuery) string {
+	var parts []string
+
+	parts = append(parts, fmt.Sprintf("source=%s", query.DataSource))
+	parts = append(parts, fmt.Sprintf("panel=%s", v3.PanelTypeList))
+
+	for idx, selectColumn := range query.SelectColumns {
+		parts = append(parts, fmt.Sprintf("selectColumn-%d=%s", idx, selectColumn.CacheKey()))
+	}
+
+	if query.Filters != nil {
+		idx := 0
+		for _, filter := range query.Filters.Items {
+			if IsListCursor(query, filter) {
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("filter-%d=%s", idx, filter.CacheKey()))
+			idx++
+		}
+		for idx, group := range query.Filters.Groups {
+			parts = append(parts, fmt.Sprintf("filterGroup-%d=%s", idx, group.CacheKey()))
+		}
+	}
+
+	for idx, orderBy := range query.OrderBy {
+		parts = append(parts, fmt.Sprintf("orderBy-%d=%s", idx, orderBy.CacheKey()))
+	}
+
+	return strings.Join(parts, "&")
+}
+
 func NewKeyGenerator() cache.KeyGenerator {
 	return &cacheKeyGenerator{}
 }
@@ -1310,6 +1310,50 @@ func TestGenerateCacheKeysLogs(t *testing.T) {
 				"A": "source=logs&step=60&aggregate=count&limit=0&aggregateAttribute=log_level---false&filter-0=key:service_name---false,op:=,value:A&groupBy-0=service_name---false&groupBy-1=log_level---false&orderBy-0=#SIGNOZ_VALUE-desc&having-0=column:value,op:>,value:100",
 			},
 		},
+		{
+			name: "panelType=graph;dataSource=logs;queryType=builder with filter groups",
+			query: &v3.QueryRangeParamsV3{
+				CompositeQuery: &v3.CompositeQuery{
+					PanelType: v3.PanelTypeGraph,
+					QueryType: v3.QueryTypeBuilder,
+					BuilderQueries: map[string]*v3.BuilderQuery{
+						"A": {
+							QueryName:          "A",
+							StepInterval:       60,
+							DataSource:         v3.DataSourceLogs,
+							AggregateOperator:  v3.AggregateOperatorCount,
+							AggregateAttribute: v3.AttributeKey{Key: "log_level"},
+							Filters: &v3.FilterSet{
+								Operator: "AND",
+								Items: []v3.FilterItem{
+									{Key: v3.AttributeKey{Key: "service_name"}, Value: "A", Operator: v3.FilterOperatorEqual},
+								},
+								Groups: []*v3.FilterSet{
+									{
+										Operator: "OR",
+										Items: []v3.FilterItem{
+											{Key: v3.AttributeKey{Key: "status"}, Value: 500, Operator: v3.FilterOperatorGreaterThanOrEq},
+										},
+										Groups: []*v3.FilterSet{
+											{
+												Not: true,
+												Items: []v3.FilterItem{
+													{Key: v3.AttributeKey{Key: "env"}, Value: "dev", Operator: v3.FilterOperatorEqual},
+												},
+											},
+										},
+									},
+								},
+							},
+							Expression: "A",
+						},
+					},
+				},
+			},
+			expectedCacheKeys: map[string]string{
+				"A": "source=logs&step=60&aggregate=count&limit=0&aggregateAttribute=log_level---false&filter-0=key:service_name---false,op:=,value:A&filterGroup-0=op:OR,item:(key:status---false,op:>=,value:500),group:(op:AND,not:true,item:(key:env---false,op:=,value:dev))",
+			},
+		},
 		{
 			name: "panelType=table;dataSource=logs;queryType=builder",
 			query: &v3.QueryRangeParamsV3{
@@ -1455,3 +1499,30 @@ func TestGenerateCacheKeysMetricsPromQL(t *testing.T) {
 		})
 	}
 }
+
+func TestListCacheKey(t *testing.T) {
+	query := func(pageSize uint64, cursor string) *v3.BuilderQuery {
+		q := &v3.BuilderQuery{
+			QueryName:         "A",
+			DataSource:        v3.DataSourceLogs,
+			AggregateOperator: v3.AggregateOperatorNoOp,
+			Filters: &v3.FilterSet{
+				Operator: "AND",
+				Items: []v3.FilterItem{
+					{Key: v3.AttributeKey{Key: "service_name"}, Value: "A", Operator: v3.FilterOperatorEqual},
+				},
+			},
+			OrderBy:  []v3.OrderBy{{ColumnName: "timestamp", Order: "desc"}},
+			PageSize: pageSize,
+		}
+		if cursor != "" {
+			q.Filters.Items = append(q.Filters.Items, v3.FilterItem{Key: v3.AttributeKey{Key: "id", IsColumn: true}, Value: cursor, Operator: v3.FilterOperatorLessThan})
+		}
+		return q
+	}
+
+	expectedKey := "source=logs&panel=list&filter-0=key:service_name---false,op:=,value:A&orderBy-0=timestamp-desc"
+	require.Equal(t, expectedKey, ListCacheKey(query(10, "")))
+	// the pages share the key
+	require.Equal(t, expectedKey, ListCacheKey(query(50, "2O8zrJnQQvFpZxYEdY2sVpNqD3w")))
+}
@@ -0,0 +1,320 @@
+package querylang
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenDuration
+	tokenVariable
+	tokenPipe
+	tokenComma
+	tokenColon
+	tokenDoubleColon
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+	tokenOperator
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of query"
+	case tokenIdent:
+		return "identifier"
+	case tokenString:
+		return "string"
+	case tokenNumber:
+		return "number"
+	case tokenDuration:
+		return "duration"
+	case tokenVariable:
+		return "variable"
+	case tokenPipe:
+		return "'|'"
+	case tokenComma:
+		return "','"
+	case tokenColon:
+		return "':'"
+	case tokenDoubleColon:
+		return "'::'"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	case tokenLBracket:
+		return "'['"
+	case tokenRBracket:
+		return "']'"
+	case tokenOperator:
+		return "operator"
+	default:
+		return "unknown token"
+	}
+}
+
+// Position is the location of a token in the query text, line and column start at 1
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// Error is returned for the queries that can't be parsed, it points to the
+// location in the query text where the parsing failed
+type Error struct {
+	Pos Position
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+type token struct {
+	kind tokenKind
+	// text is the value of the token, the quotes of the strings and
+	// the backticks of the quoted identifiers are removed
+	text   string
+	quoted bool
+	pos    Position
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return t.kind.String()
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("'%s'", t.text)
+	}
+}
+
+// is reports whether the token is the given keyword, the keywords are case insensitive
+func (t token) is(keyword string) bool {
+	return t.kind == tokenIdent && !t.quoted && strings.EqualFold(t.text, keyword)
+}
+
+type lexer struct {
+	input  string
+	offset int
+	line   int
+	column int
+}
+
+func tokenize(input string) ([]token, error) {
+	l := &lexer{input: input, line: 1, column: 1}
+	var tokens []token
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, tok)
+		if tok.kind == tokenEOF {
+			return tokens, nil
+		}
+	}
+}
+
+func (l *lexer) pos() Position {
+	return Position{Offset: l.offset, Line: l.line, Column: l.column}
+}
+
+func (l *lexer) peek() rune {
+	if l.offset >= len(l.input) {
+		return 0
+	}
+	r, _ := utf8.DecodeRuneInString(l.input[l.offset:])
+	return r
+}
+
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.input[l.offset:])
+	l.offset += size
+	if r == '\n' {
+		l.line++
+		l.column = 1
+	} else {
+		l.column++
+	}
+	return r
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentPart(r rune) bool {
+	return r == '_' || r == '.' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (l *lexer) next() (token, error) {
+	for l.offset < len(l.input) && unicode.IsSpace(l.peek()) {
+		l.advance()
+	}
+
+	start := l.pos()
+	if l.offset >= len(l.input) {
+		return token{kind: tokenEOF, pos: start}, nil
+	}
+
+	r := l.peek()
+	switch {
+	case r == '|':
+		l.advance()
+		return token{kind: tokenPipe, text: "|", pos: start}, nil
+	case r == ',':
+		l.advance()
+		return token{kind: tokenComma, text: ",", pos: start}, nil
+	case r == '(':
+		l.advance()
+		return token{kind: tokenLParen, text: "(", pos: start}, nil
+	case r == ')':
+		l.advance()
+		return token{kind: tokenRParen, text: ")", pos: start}, nil
+	case r == '[':
+		l.advance()
+		return token{kind: tokenLBracket, text: "[", pos: start}, nil
+	case r == ']':
+		l.advance()
+		return token{kind: tokenRBracket, text: "]", pos: start}, nil
+	case r == ':':
+		l.advance()
+		if l.peek() == ':' {
+			l.advance()
+			return token{kind: tokenDoubleColon, text: "::", pos: start}, nil
+		}
+		return token{kind: tokenColon, text: ":", pos: start}, nil
+	case r == '=' || r == '!' || r == '<' || r == '>':
+		l.advance()
+		op := string(r)
+		if l.peek() == '=' {
+			l.advance()
+			op += "="
+		} else if r == '=' && l.peek() == '~' {
+			l.advance()
+			op += "~"
+		} else if r == '!' && l.peek() == '~' {
+			l.advance()
+			op += "~"
+		} else if r == '!' {
+			return token{}, &Error{Pos: start, Msg: "unexpected '!', did you mean '!='"}
+		}
+		return token{kind: tokenOperator, text: op, pos: start}, nil
+	case r == '"' || r == '\'':
+		return l.lexString(start, r)
+	case r == '`':
+		return l.lexQuotedIdent(start)
+	case r == '$':
+		l.advance()
+		if !isIdentStart(l.peek()) {
+			return token{}, &Error{Pos: start, Msg: "expected variable name after '$'"}
+		}
+		name := l.lexWhile(isIdentPart)
+		return token{kind: tokenVariable, text: "$" + name, pos: start}, nil
+	case r == '-' || unicode.IsDigit(r):
+		return l.lexNumber(start)
+	case isIdentStart(r):
+		return token{kind: tokenIdent, text: l.lexWhile(isIdentPart), pos: start}, nil
+	}
+
+	return token{}, &Error{Pos: start, Msg: fmt.Sprintf("unexpected character %q", r)}
+}
+
+func (l *lexer) lexWhile(fn func(rune) bool) string {
+	start := l.offset
+	for l.offset < len(l.input) && fn(l.peek()) {
+		l.advance()
+	}
+	return l.input[start:l.offset]
+}
+
+func (l *lexer) lexString(start Position, quote rune) (token, error) {
+	l.advance()
+	var sb strings.Builder
+	for {
+		if l.offset >= len(l.input) {
+			return token{}, &Error{Pos: start, Msg: "unterminated string"}
+		}
+		r := l.advance()
+		switch r {
+		case quote:
+			return token{kind: tokenString, text: sb.String(), pos: start}, nil
+		case '\\':
+			if l.offset >= len(l.input) {
+				return token{}, &Error{Pos: start, Msg: "unterminated string"}
+			}
+			escaped := l.advance()
+			switch escaped {
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			default:
+				// \", \', \\ and any other escaped character are kept as is
+				sb.WriteRune(escaped)
+			}
+		default:
+			sb.WriteRune(r)
+		}
+	}
+}
+
+func (l *lexer) lexQuotedIdent(start Position) (token, error) {
+	l.advance()
+	name := l.lexWhile(func(r rune) bool { return r != '`' })
+	if l.offset >= len(l.input) {
+		return token{}, &Error{Pos: start, Msg: "unterminated quoted identifier"}
+	}
+	l.advance()
+	if name == "" {
+		return token{}, &Error{Pos: start, Msg: "empty quoted identifier"}
+	}
+	return token{kind: tokenIdent, text: name, quoted: true, pos: start}, nil
+}
+
+// lexNumber reads a number, a number followed by a unit e.g 30s or 5m is a duration
+func (l *lexer) lexNumber(start Position) (token, error) {
+	begin := l.offset
+	if l.peek() == '-' {
+		l.advance()
+		if !unicode.IsDigit(l.peek()) {
+			return token{}, &Error{Pos: start, Msg: "expected number after '-'"}
+		}
+	}
+	l.lexWhile(unicode.IsDigit)
+	if l.peek() == '.' {
+		l.advance()
+		l.lexWhile(unicode.IsDigit)
+	}
+	if l.peek() == 'e' || l.peek() == 'E' {
+		// exponent, only when followed by digits so that it doesn't eat a unit
+		rest := l.input[l.offset+1:]
+		if len(rest) > 0 && (unicode.IsDigit(rune(rest[0])) || ((rest[0] == '-' || rest[0] == '+') && len(rest) > 1 && unicode.IsDigit(rune(rest[1])))) {
+			l.advance()
+			if l.peek() == '-' || l.peek() == '+' {
+				l.advance()
+			}
+			l.lexWhile(unicode.IsDigit)
+		}
+	}
+	number := l.input[begin:l.offset]
+	if unicode.IsLetter(l.peek()) {
+		unit := l.lexWhile(unicode.IsLetter)
+		return token{kind: tokenDuration, text: number + unit, pos: start}, nil
+	}
+	return token{kind: tokenNumber, text: number, pos: start}, nil
+}
@@ -0,0 +1,891 @@
+// Package querylang implements a text form of the builder queries e.g
+//
+//	logs | where service.name = "api" and severity_text = "ERROR" | count() by k8s.pod.name | every 1m
+//
+// A query starts with the data source followed by stages separated by '|'.
+//
+//	where <filter>                        filters joined with and, or, not and parentheses
+//	<op>(<key>[, <quantile>]) [by <keys>] aggregation e.g count(), avg(duration_nano), percentile(duration_nano, 0.95)
+//	<space>(<time>(<metric>)) [by <keys>] metrics aggregation in space and time e.g sum(rate(signoz_calls_total))
+//	every <duration>                      step interval e.g 30s, 1m, 1h
+//	having <column> <op> <value> [and ...]
+//	order by <key> [asc|desc][, ...]      value is the aggregated value of the series
+//	limit <n>, offset <n>
+//	select <keys>                         columns of the list queries
+//	reduce <last|sum|avg|min|max>         reduce to operator of the value panel
+//	legend "<format>"
+//	fill <none|zero|previous|linear|null> fill mode of the missing points of the series
+//	apply <function>(<args>)              function applied on the result e.g apply timeShift(3600)
+//	buckets <bound>[, ...]                upper bounds of the buckets of the heatmap panel
+//	top|bottom <k> by <reducer> [with other] series with the highest or lowest reduced value
+//
+// A key is written as [resource:|tag:]name[::data type], the names that are not plain
+// identifiers can be quoted with backticks. The text that doesn't start with a data source
+// is a formula e.g A / B * 100.
+package querylang
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.signoz.io/signoz/pkg/query-service/constants"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+// ParseCompositeQuery parses the text form of each of the named queries
+func ParseCompositeQuery(panelType v3.PanelType, queries map[string]string) (*v3.CompositeQuery, error) {
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("at least one query is required")
+	}
+	compositeQuery := &v3.CompositeQuery{
+		PanelType:      panelType,
+		QueryType:      v3.QueryTypeBuilder,
+		BuilderQueries: make(map[string]*v3.BuilderQuery, len(queries)),
+	}
+	for name, text := range queries {
+		query, err := Parse(name, text)
+		if err != nil {
+			return nil, fmt.Errorf("query %s: %w", name, err)
+		}
+		compositeQuery.BuilderQueries[name] = query
+	}
+	return compositeQuery, nil
+}
+
+// Parse parses the text form of a builder query, the errors are of type *Error
+// and point to the location in the text where the parsing failed
+func Parse(name, text string) (*v3.BuilderQuery, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, &Error{Pos: Position{Line: 1, Column: 1}, Msg: "empty query"}
+	}
+
+	if !startsWithDataSource(text) {
+		// formula, the expression is validated when the query range params are parsed
+		return &v3.BuilderQuery{
+			QueryName:  name,
+			Expression: strings.TrimSpace(text),
+		}, nil
+	}
+
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	return p.parseQuery(name)
+}
+
+var dataSources = []v3.DataSource{v3.DataSourceLogs, v3.DataSourceTraces, v3.DataSourceMetrics}
+
+var stages = map[string]struct{}{
+	"where":   {},
+	"every":   {},
+	"having":  {},
+	"order":   {},
+	"limit":   {},
+	"offset":  {},
+	"select":  {},
+	"reduce":  {},
+	"legend":  {},
+	"fill":    {},
+	"apply":   {},
+	"buckets": {},
+	"top":     {},
+	"bottom":  {},
+}
+
+// defaultOrder is the order of the order by keys without asc or desc, same as in SQL
+const defaultOrder = "asc"
+
+func startsWithDataSource(text string) bool {
+	fields := strings.FieldsFunc(strings.TrimSpace(text), func(r rune) bool {
+		return r == '|' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+	if len(fields) == 0 {
+		return false
+	}
+	for _, dataSource := range dataSources {
+		if strings.EqualFold(fields[0], string(dataSource)) {
+			return true
+		}
+	}
+	return false
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) peekAt(n int) token {
+	if p.pos+n >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.pos+n]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return &Error{Pos: tok.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) expect(kind tokenKind) (token, error) {
+	tok := p.next()
+	if tok.kind != kind {
+		return tok, p.errorf(tok, "expected %s, got %s", kind, tok)
+	}
+	return tok, nil
+}
+
+func (p *parser) expectKeyword(keyword string) error {
+	tok := p.next()
+	if !tok.is(keyword) {
+		return p.errorf(tok, "expected '%s', got %s", keyword, tok)
+	}
+	return nil
+}
+
+func (p *parser) parseQuery(name string) (*v3.BuilderQuery, error) {
+	sourceTok := p.next()
+	query := &v3.BuilderQuery{
+		QueryName:         name,
+		Expression:        name,
+		DataSource:        v3.DataSource(strings.ToLower(sourceTok.text)),
+		AggregateOperator: v3.AggregateOperatorNoOp,
+	}
+
+	// the stages that can only be used once
+	seen := make(map[string]token)
+	for p.peek().kind == tokenPipe {
+		p.next()
+		tok := p.peek()
+		if tok.kind != tokenIdent {
+			return nil, p.errorf(tok, "expected stage, got %s", tok)
+		}
+
+		stage := strings.ToLower(tok.text)
+		if _, ok := stages[stage]; !ok && p.peekAt(1).kind == tokenLParen {
+			stage = "aggregation"
+		}
+		if stage == "bottom" {
+			// top and bottom set the same option
+			stage = "top"
+		}
+		if first, ok := seen[stage]; ok && stage != "where" && stage != "apply" {
+			return nil, p.errorf(tok, "%s is already set at line %d, column %d", stage, first.pos.Line, first.pos.Column)
+		}
+		seen[stage] = tok
+
+		var err error
+		switch stage {
+		case "where":
+			p.next()
+			err = p.parseWhere(query)
+		case "aggregation":
+			err = p.parseAggregation(query)
+		case "every":
+			p.next()
+			err = p.parseEvery(query)
+		case "having":
+			p.next()
+			err = p.parseHaving(query)
+		case "order":
+			p.next()
+			err = p.parseOrderBy(query)
+		case "limit":
+			p.next()
+			query.Limit, err = p.parseUint()
+		case "offset":
+			p.next()
+			query.Offset, err = p.parseUint()
+		case "select":
+			p.next()
+			query.SelectColumns, err = p.parseKeyList()
+		case "reduce":
+			p.next()
+			err = p.parseReduce(query)
+		case "legend":
+			p.next()
+			var legendTok token
+			legendTok, err = p.expect(tokenString)
+			query.Legend = legendTok.text
+		case "fill":
+			p.next()
+			err = p.parseFill(query)
+		case "apply":
+			p.next()
+			err = p.parseFunction(query)
+		case "buckets":
+			p.next()
+			err = p.parseBuckets(query)
+		case "top":
+			err = p.parseTopK(query)
+		default:
+			err = p.errorf(tok, "unknown stage %s, expected one of where, <aggregation>(), every, having, order by, limit, offset, select, reduce, legend, fill, apply, buckets, top, bottom", tok)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok, "expected '|' or end of query, got %s", tok)
+	}
+	return query, nil
+}
+
+// parseKey parses [resource:|tag:]name[::data type]
+func (p *parser) parseKey() (v3.AttributeKey, error) {
+	tok := p.next()
+	if tok.kind != tokenIdent {
+		return v3.AttributeKey{}, p.errorf(tok, "expected key, got %s", tok)
+	}
+
+	key := v3.AttributeKey{Key: tok.text}
+	if !tok.quoted && p.peek().kind == tokenColon {
+		switch v3.AttributeKeyType(strings.ToLower(tok.text)) {
+		case v3.AttributeKeyTypeResource, v3.AttributeKeyTypeTag:
+			key.Type = v3.AttributeKeyType(strings.ToLower(tok.text))
+		default:
+			return v3.AttributeKey{}, p.errorf(tok, "unknown key type %s, expected resource or tag", tok)
+		}
+		p.next()
+		nameTok := p.next()
+		if nameTok.kind != tokenIdent {
+			return v3.AttributeKey{}, p.errorf(nameTok, "expected key, got %s", nameTok)
+		}
+		key.Key = nameTok.text
+	}
+
+	if p.peek().kind == tokenDoubleColon {
+		p.next()
+		typeTok := p.next()
+		if typeTok.kind != tokenIdent {
+			return v3.AttributeKey{}, p.errorf(typeTok, "expected data type, got %s", typeTok)
+		}
+		dataType := strings.ToLower(typeTok.text)
+		if dataType == "array" {
+			if _, err := p.expect(tokenLParen); err != nil {
+				return v3.AttributeKey{}, err
+			}
+			elemTok := p.next()
+			if _, err := p.expect(tokenRParen); err != nil {
+				return v3.AttributeKey{}, err
+			}
+			dataType = fmt.Sprintf("array(%s)", strings.ToLower(elemTok.text))
+		}
+		switch v3.AttributeKeyDataType(dataType) {
+		case v3.AttributeKeyDataTypeString, v3.AttributeKeyDataTypeInt64, v3.AttributeKeyDataTypeFloat64, v3.AttributeKeyDataTypeBool,
+			v3.AttributeKeyDataTypeArrayString, v3.AttributeKeyDataTypeArrayInt64, v3.AttributeKeyDataTypeArrayFloat64, v3.AttributeKeyDataTypeArrayBool:
+			key.DataType = v3.AttributeKeyDataType(dataType)
+		default:
+			return v3.AttributeKey{}, p.errorf(typeTok, "unknown data type %s", dataType)
+		}
+	}
+	return key, nil
+}
+
+func (p *parser) parseKeyList() ([]v3.AttributeKey, error) {
+	var keys []v3.AttributeKey
+	for {
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		if p.peek().kind != tokenComma {
+			return keys, nil
+		}
+		p.next()
+	}
+}
+
+// parseValue parses a string, number, bool or a variable
+func (p *parser) parseValue() (interface{}, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenString:
+		return tok.text, nil
+	case tokenVariable:
+		// the variables are replaced with their values when the query range params are parsed
+		return tok.text, nil
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "invalid number %s", tok)
+		}
+		return value, nil
+	case tokenIdent:
+		if tok.is("true") {
+			return true, nil
+		}
+		if tok.is("false") {
+			return false, nil
+		}
+	}
+	return nil, p.errorf(tok, "expected value, got %s", tok)
+}
+
+// parseList parses (v1, v2, ...) or [v1, v2, ...], a variable can be used in place of the list
+func (p *parser) parseList() (interface{}, error) {
+	open := p.peek()
+	if open.kind == tokenVariable {
+		p.next()
+		return open.text, nil
+	}
+	if open.kind != tokenLParen && open.kind != tokenLBracket {
+		return nil, p.errorf(open, "expected list of values, got %s", open)
+	}
+	p.next()
+	closing := tokenRParen
+	if open.kind == tokenLBracket {
+		closing = tokenRBracket
+	}
+
+	values := []interface{}{}
+	for p.peek().kind != closing {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if p.peek().kind != tokenComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(closing); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (p *parser) parseUint() (uint64, error) {
+	tok, err := p.expect(tokenNumber)
+	if err != nil {
+		return 0, err
+	}
+	value, err := strconv.ParseUint(tok.text, 10, 64)
+	if err != nil {
+		return 0, p.errorf(tok, "expected a positive integer, got %s", tok)
+	}
+	return value, nil
+}
+
+// parseDuration parses a duration e.g 30s, 5m, 1h, 1d, a number without a unit is in seconds
+func (p *parser) parseDuration() (int64, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		seconds, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil || seconds <= 0 {
+			return 0, p.errorf(tok, "expected a positive duration, got %s", tok)
+		}
+		return seconds, nil
+	case tokenDuration:
+		text := tok.text
+		multiplier := time.Duration(1)
+		if strings.HasSuffix(text, "d") {
+			text = strings.TrimSuffix(text, "d") + "h"
+			multiplier = 24
+		}
+		duration, err := time.ParseDuration(text)
+		if err != nil || duration <= 0 {
+			return 0, p.errorf(tok, "expected a positive duration e.g 30s, 5m, 1h, got %s", tok)
+		}
+		duration *= multiplier
+		if duration%time.Second != 0 {
+			return 0, p.errorf(tok, "duration %s should be a whole number of seconds", tok.text)
+		}
+		return int64(duration / time.Second), nil
+	}
+	return 0, p.errorf(tok, "expected duration, got %s", tok)
+}
+
+func (p *parser) parseEvery(query *v3.BuilderQuery) error {
+	step, err := p.parseDuration()
+	if err != nil {
+		return err
+	}
+	query.StepInterval = step
+	return nil
+}
+
+// parseBuckets parses the comma separated upper bounds of the heatmap buckets in increasing order
+func (p *parser) parseBuckets(query *v3.BuilderQuery) error {
+	for {
+		tok, err := p.expect(tokenNumber)
+		if err != nil {
+			return err
+		}
+		bound, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return p.errorf(tok, "invalid number %s", tok)
+		}
+		if len(query.Buckets) > 0 && bound <= query.Buckets[len(query.Buckets)-1] {
+			return p.errorf(tok, "buckets should be in increasing order, got %s after %v", tok.text, query.Buckets[len(query.Buckets)-1])
+		}
+		query.Buckets = append(query.Buckets, bound)
+		if p.peek().kind != tokenComma {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// parseTopK parses top|bottom <k> by <reducer> [with other]
+func (p *parser) parseTopK(query *v3.BuilderQuery) error {
+	topK := &v3.TopK{Direction: v3.TopKDirection(strings.ToLower(p.next().text))}
+	var err error
+	if topK.K, err = p.parseUint(); err != nil {
+		return err
+	}
+	if topK.K == 0 {
+		return p.errorf(p.tokens[p.pos-1], "k should be greater than 0")
+	}
+	if err := p.expectKeyword("by"); err != nil {
+		return err
+	}
+	tok := p.next()
+	topK.ReduceTo = v3.ReduceToOperator(strings.ToLower(tok.text))
+	if tok.kind != tokenIdent || topK.ReduceTo.Validate() != nil {
+		return p.errorf(tok, "expected one of last, sum, avg, min, max, got %s", tok)
+	}
+	if p.peek().is("with") {
+		p.next()
+		if err := p.expectKeyword("other"); err != nil {
+			return err
+		}
+		topK.Other = true
+	}
+	query.TopK = topK
+	return nil
+}
+
+func (p *parser) parseFill(query *v3.BuilderQuery) error {
+	tok := p.next()
+	mode := v3.FillMode(strings.ToLower(tok.text))
+	if tok.kind != tokenIdent || mode.Validate() != nil {
+		return p.errorf(tok, "expected one of none, zero, previous, linear, null, got %s", tok)
+	}
+	query.FillMode = mode
+	return nil
+}
+
+func (p *parser) parseReduce(query *v3.BuilderQuery) error {
+	tok := p.next()
+	reduceTo := v3.ReduceToOperator(strings.ToLower(tok.text))
+	if tok.kind != tokenIdent || reduceTo.Validate() != nil {
+		return p.errorf(tok, "expected one of last, sum, avg, min, max, got %s", tok)
+	}
+	query.ReduceTo = reduceTo
+	return nil
+}
+
+// parseAggregation parses op(key[, quantile]) and for metrics space(time(metric)[, quantile])
+// followed by an optional by key1, key2
+func (p *parser) parseAggregation(query *v3.BuilderQuery) error {
+	opTok := p.next()
+	p.next()
+	op := strings.ToLower(opTok.text)
+
+	if query.DataSource == v3.DataSourceMetrics && p.peek().kind == tokenIdent && p.peekAt(1).kind == tokenLParen {
+		timeTok := p.next()
+		p.next()
+		spaceAggregation := v3.SpaceAggregation(op)
+		if err := spaceAggregation.Validate(); err != nil {
+			return p.errorf(opTok, "unknown space aggregation %s", opTok)
+		}
+		timeAggregation := v3.TimeAggregation(strings.ToLower(timeTok.text))
+		if err := timeAggregation.Validate(); err != nil {
+			return p.errorf(timeTok, "unknown time aggregation %s", timeTok)
+		}
+		key, err := p.parseKey()
+		if err != nil {
+			return err
+		}
+		if _, err := p.expect(tokenRParen); err != nil {
+			return err
+		}
+		query.SpaceAggregation = spaceAggregation
+		query.TimeAggregation = timeAggregation
+		// same as the query builder, the operator follows the time aggregation
+		query.AggregateOperator = v3.AggregateOperator(timeAggregation)
+		query.AggregateAttribute = key
+	} else if query.DataSource == v3.DataSourceMetrics && v3.IsPercentileOperator(v3.SpaceAggregation(op)) {
+		// the percentiles of the metrics are only supported as space aggregation
+		key, err := p.parseKey()
+		if err != nil {
+			return err
+		}
+		query.SpaceAggregation = v3.SpaceAggregation(op)
+		query.AggregateOperator = v3.AggregateOperator(op)
+		query.AggregateAttribute = key
+	} else {
+		aggregateOperator := v3.AggregateOperator(op)
+		if err := aggregateOperator.Validate(); err != nil {
+			return p.errorf(opTok, "unknown aggregation %s", opTok)
+		}
+		query.AggregateOperator = aggregateOperator
+		if p.peek().kind != tokenRParen && p.peek().kind != tokenComma {
+			key, err := p.parseKey()
+			if err != nil {
+				return err
+			}
+			query.AggregateAttribute = key
+		}
+	}
+
+	if p.peek().kind == tokenComma {
+		p.next()
+		quantileTok, err := p.expect(tokenNumber)
+		if err != nil {
+			return err
+		}
+		if !query.IsParameterisedPercentile() {
+			return p.errorf(quantileTok, "quantile is only supported by percentile")
+		}
+		quantile, err := strconv.ParseFloat(quantileTok.text, 64)
+		if err != nil || quantile <= 0 || quantile >= 1 {
+			return p.errorf(quantileTok, "quantile should be between 0 and 1, got %s", quantileTok)
+		}
+		query.Quantile = quantile
+	} else if query.IsParameterisedPercentile() {
+		return p.errorf(p.peek(), "percentile requires a quantile e.g percentile(duration_nano, 0.95)")
+	}
+
+	if _, err := p.expect(tokenRParen); err != nil {
+		return err
+	}
+
+	if p.peek().is("by") {
+		p.next()
+		groupBy, err := p.parseKeyList()
+		if err != nil {
+			return err
+		}
+		query.GroupBy = groupBy
+	}
+	return nil
+}
+
+func (p *parser) parseOrderBy(query *v3.BuilderQuery) error {
+	if err := p.expectKeyword("by"); err != nil {
+		return err
+	}
+	for {
+		tok := p.peek()
+		key, err := p.parseKey()
+		if err != nil {
+			return err
+		}
+		orderBy := v3.OrderBy{
+			ColumnName: key.Key,
+			Order:      defaultOrder,
+			Key:        key.Key,
+			DataType:   key.DataType,
+			Type:       key.Type,
+		}
+		if tok.is("value") && key.Type == v3.AttributeKeyTypeUnspecified {
+			orderBy = v3.OrderBy{ColumnName: constants.SigNozOrderByValue, Order: defaultOrder}
+		}
+		if p.peek().is("asc") || p.peek().is("desc") {
+			orderBy.Order = strings.ToLower(p.next().text)
+		}
+		query.OrderBy = append(query.OrderBy, orderBy)
+		if p.peek().kind != tokenComma {
+			return nil
+		}
+		p.next()
+	}
+}
+
+var havingOperators = map[string]v3.HavingOperator{
+	"=":  v3.HavingOperatorEqual,
+	"==": v3.HavingOperatorEqual,
+	"!=": v3.HavingOperatorNotEqual,
+	">":  v3.HavingOperatorGreaterThan,
+	">=": v3.HavingOperatorGreaterThanOrEq,
+	"<":  v3.HavingOperatorLessThan,
+	"<=": v3.HavingOperatorLessThanOrEq,
+}
+
+func (p *parser) parseHaving(query *v3.BuilderQuery) error {
+	for {
+		columnTok := p.next()
+		if columnTok.kind != tokenIdent {
+			return p.errorf(columnTok, "expected column, got %s", columnTok)
+		}
+		having := v3.Having{ColumnName: columnTok.text}
+
+		opTok := p.next()
+		var err error
+		switch {
+		case opTok.kind == tokenOperator && havingOperators[opTok.text] != "":
+			having.Operator = havingOperators[opTok.text]
+			having.Value, err = p.parseValue()
+		case opTok.is("in"):
+			having.Operator = v3.HavingOperatorIn
+			having.Value, err = p.parseList()
+		case opTok.is("not") && p.peek().is("in"):
+			p.next()
+			having.Operator = v3.HavingOperatorNotIn
+			having.Value, err = p.parseList()
+		default:
+			err = p.errorf(opTok, "expected one of =, !=, >, >=, <, <=, in, not in, got %s", opTok)
+		}
+		if err != nil {
+			return err
+		}
+		query.Having = append(query.Having, having)
+
+		if !p.peek().is("and") {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// parseFunction parses name(arg1, arg2, name=value)
+func (p *parser) parseFunction(query *v3.BuilderQuery) error {
+	nameTok, err := p.expect(tokenIdent)
+	if err != nil {
+		return err
+	}
+	function := v3.Function{Name: v3.FunctionName(nameTok.text)}
+	if err := function.Name.Validate(); err != nil {
+		return p.errorf(nameTok, "unknown function %s", nameTok)
+	}
+	if _, err := p.expect(tokenLParen); err != nil {
+		return err
+	}
+	for p.peek().kind != tokenRParen {
+		if p.peek().kind == tokenIdent && p.peekAt(1).kind == tokenOperator && p.peekAt(1).text == "=" {
+			argName := p.next().text
+			p.next()
+			value, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+			if function.NamedArgs == nil {
+				function.NamedArgs = make(map[string]interface{})
+			}
+			function.NamedArgs[argName] = value
+		} else {
+			value, err := p.parseValue()
+			if err != nil {
+				return err
+			}
+			function.Args = append(function.Args, value)
+		}
+		if p.peek().kind != tokenComma {
+			break
+		}
+		p.next()
+	}
+	if _, err := p.expect(tokenRParen); err != nil {
+		return err
+	}
+	query.Functions = append(query.Functions, function)
+	return nil
+}
+
+// filterNode is the parsed where clause, a node is either a comparison or
+// one of and, or, not of its children
+type filterNode struct {
+	op       string
+	item     v3.FilterItem
+	children []*filterNode
+}
+
+// parseWhere parses the where clause, the clauses of the multiple where stages are joined with AND
+func (p *parser) parseWhere(query *v3.BuilderQuery) error {
+	node, err := p.parseOr()
+	if err != nil {
+		return err
+	}
+	filters := toFilterSet(node)
+	if query.Filters == nil {
+		query.Filters = filters
+		return nil
+	}
+	query.Filters.Items = append(query.Filters.Items, filters.Items...)
+	query.Filters.Groups = append(query.Filters.Groups, filters.Groups...)
+	return nil
+}
+
+func (p *parser) parseOr() (*filterNode, error) {
+	return p.parseBinary("or", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (*filterNode, error) {
+	return p.parseBinary("and", p.parseUnary)
+}
+
+func (p *parser) parseBinary(op string, operand func() (*filterNode, error)) (*filterNode, error) {
+	node, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	children := []*filterNode{node}
+	for p.peek().is(op) {
+		p.next()
+		node, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, node)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &filterNode{op: op, children: children}, nil
+}
+
+func (p *parser) parseUnary() (*filterNode, error) {
+	tok := p.peek()
+	if tok.is("not") {
+		p.next()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &filterNode{op: "not", children: []*filterNode{node}}, nil
+	}
+	if tok.kind == tokenLParen {
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRParen); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+var comparisonOperators = map[string]v3.FilterOperator{
+	"=":  v3.FilterOperatorEqual,
+	"==": v3.FilterOperatorEqual,
+	"!=": v3.FilterOperatorNotEqual,
+	">":  v3.FilterOperatorGreaterThan,
+	">=": v3.FilterOperatorGreaterThanOrEq,
+	"<":  v3.FilterOperatorLessThan,
+	"<=": v3.FilterOperatorLessThanOrEq,
+	"=~": v3.FilterOperatorRegex,
+	"!~": v3.FilterOperatorNotRegex,
+}
+
+// keywordOperators are the operators written as words, the value is the operator and its negation
+var keywordOperators = map[string][2]v3.FilterOperator{
+	"in":       {v3.FilterOperatorIn, v3.FilterOperatorNotIn},
+	"contains": {v3.FilterOperatorContains, v3.FilterOperatorNotContains},
+	"like":     {v3.FilterOperatorLike, v3.FilterOperatorNotLike},
+	"regex":    {v3.FilterOperatorRegex, v3.FilterOperatorNotRegex},
+	"exists":   {v3.FilterOperatorExists, v3.FilterOperatorNotExists},
+	"has":      {v3.FilterOperatorHas, v3.FilterOperatorNotHas},
+}
+
+func (p *parser) parseComparison() (*filterNode, error) {
+	key, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	item := v3.FilterItem{Key: key}
+
+	opTok := p.next()
+	if opTok.kind == tokenOperator {
+		operator, ok := comparisonOperators[opTok.text]
+		if !ok {
+			return nil, p.errorf(opTok, "unknown operator %s", opTok)
+		}
+		item.Operator = operator
+		if item.Value, err = p.parseValue(); err != nil {
+			return nil, err
+		}
+		return &filterNode{item: item}, nil
+	}
+
+	negate := 0
+	keywordTok := opTok
+	if opTok.is("not") {
+		negate = 1
+		keywordTok = p.next()
+	}
+	operators, ok := keywordOperators[strings.ToLower(keywordTok.text)]
+	if keywordTok.kind != tokenIdent || keywordTok.quoted || !ok {
+		return nil, p.errorf(keywordTok, "expected operator after key %s, got %s", key.Key, keywordTok)
+	}
+	item.Operator = operators[negate]
+
+	switch item.Operator {
+	case v3.FilterOperatorExists, v3.FilterOperatorNotExists:
+		// no value
+	case v3.FilterOperatorIn, v3.FilterOperatorNotIn:
+		item.Value, err = p.parseList()
+	default:
+		item.Value, err = p.parseValue()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &filterNode{item: item}, nil
+}
+
+// toFilterSet converts the where clause to a filter set, the top level and clauses
+// become the items of the filter set and the rest become its nested groups
+func toFilterSet(node *filterNode) *v3.FilterSet {
+	filters := &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{}}
+	conjuncts := []*filterNode{node}
+	if node.op == "and" {
+		conjuncts = node.children
+	}
+	for _, conjunct := range conjuncts {
+		if conjunct.op == "" {
+			filters.Items = append(filters.Items, conjunct.item)
+		} else {
+			filters.Groups = append(filters.Groups, toFilterGroup(conjunct))
+		}
+	}
+	return filters
+}
+
+func toFilterGroup(node *filterNode) *v3.FilterSet {
+	if node.op == "not" {
+		child := node.children[0]
+		if child.op == "" {
+			return &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{child.item}, Not: true}
+		}
+		group := toFilterGroup(child)
+		if group.Not {
+			return &v3.FilterSet{Operator: "AND", Groups: []*v3.FilterSet{group}, Not: true}
+		}
+		group.Not = true
+		return group
+	}
+
+	group := &v3.FilterSet{Operator: strings.ToUpper(node.op)}
+	for _, child := range node.children {
+		if child.op == "" {
+			group.Items = append(group.Items, child.item)
+		} else {
+			group.Groups = append(group.Groups, toFilterGroup(child))
+		}
+	}
+	return group
+}
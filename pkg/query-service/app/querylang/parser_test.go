@@ -0,0 +1,298 @@
+package querylang
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.signoz.io/signoz/pkg/query-service/constants"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected *v3.BuilderQuery
+	}{
+		{
+			name: "logs count by pod",
+			text: `logs | where service.name="api" and severity_text="ERROR" | count() by k8s.pod.name | every 1m`,
+			expected: &v3.BuilderQuery{
+				QueryName:         "A",
+				Expression:        "A",
+				DataSource:        v3.DataSourceLogs,
+				AggregateOperator: v3.AggregateOperatorCount,
+				StepInterval:      60,
+				Filters: &v3.FilterSet{
+					Operator: "AND",
+					Items: []v3.FilterItem{
+						{Key: v3.AttributeKey{Key: "service.name"}, Operator: v3.FilterOperatorEqual, Value: "api"},
+						{Key: v3.AttributeKey{Key: "severity_text"}, Operator: v3.FilterOperatorEqual, Value: "ERROR"},
+					},
+				},
+				GroupBy: []v3.AttributeKey{{Key: "k8s.pod.name"}},
+			},
+		},
+		{
+			name: "traces percentile with typed keys, having, order by and limit",
+			text: "traces | where resource:service.name in (\"a\", \"b\") and tag:http.status_code::int64 >= 500\n" +
+				"| percentile(duration_nano::float64, 0.95) by tag:http.route::string | every 5m\n" +
+				"| having value > 1000000 | order by value desc | limit 10 | legend \"{{http.route}}\"",
+			expected: &v3.BuilderQuery{
+				QueryName:          "A",
+				Expression:         "A",
+				DataSource:         v3.DataSourceTraces,
+				AggregateOperator:  v3.AggregateOperatorPercentile,
+				AggregateAttribute: v3.AttributeKey{Key: "duration_nano", DataType: v3.AttributeKeyDataTypeFloat64},
+				Quantile:           0.95,
+				StepInterval:       300,
+				Filters: &v3.FilterSet{
+					Operator: "AND",
+					Items: []v3.FilterItem{
+						{Key: v3.AttributeKey{Key: "service.name", Type: v3.AttributeKeyTypeResource}, Operator: v3.FilterOperatorIn, Value: []interface{}{"a", "b"}},
+						{Key: v3.AttributeKey{Key: "http.status_code", Type: v3.AttributeKeyTypeTag, DataType: v3.AttributeKeyDataTypeInt64}, Operator: v3.FilterOperatorGreaterThanOrEq, Value: float64(500)},
+					},
+				},
+				GroupBy: []v3.AttributeKey{{Key: "http.route", Type: v3.AttributeKeyTypeTag, DataType: v3.AttributeKeyDataTypeString}},
+				Having:  []v3.Having{{ColumnName: "value", Operator: v3.HavingOperatorGreaterThan, Value: float64(1000000)}},
+				OrderBy: []v3.OrderBy{{ColumnName: constants.SigNozOrderByValue, Order: "desc"}},
+				Limit:   10,
+				Legend:  "{{http.route}}",
+			},
+		},
+		{
+			name: "nested boolean filters",
+			text: `logs | where (service.name = "a" and status >= 500) or not (service.name = "b" and body contains "timeout") | where trace_id exists`,
+			expected: &v3.BuilderQuery{
+				QueryName:         "A",
+				Expression:        "A",
+				DataSource:        v3.DataSourceLogs,
+				AggregateOperator: v3.AggregateOperatorNoOp,
+				Filters: &v3.FilterSet{
+					Operator: "AND",
+					Items: []v3.FilterItem{
+						{Key: v3.AttributeKey{Key: "trace_id"}, Operator: v3.FilterOperatorExists},
+					},
+					Groups: []*v3.FilterSet{
+						{
+							Operator: "OR",
+							Groups: []*v3.FilterSet{
+								{
+									Operator: "AND",
+									Items: []v3.FilterItem{
+										{Key: v3.AttributeKey{Key: "service.name"}, Operator: v3.FilterOperatorEqual, Value: "a"},
+										{Key: v3.AttributeKey{Key: "status"}, Operator: v3.FilterOperatorGreaterThanOrEq, Value: float64(500)},
+									},
+								},
+								{
+									Operator: "AND",
+									Not:      true,
+									Items: []v3.FilterItem{
+										{Key: v3.AttributeKey{Key: "service.name"}, Operator: v3.FilterOperatorEqual, Value: "b"},
+										{Key: v3.AttributeKey{Key: "body"}, Operator: v3.FilterOperatorContains, Value: "timeout"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "metrics space and time aggregation",
+			text: `metrics | where service_name != $service | sum(rate(signoz_calls_total)) by service_name, operation | every 60`,
+			expected: &v3.BuilderQuery{
+				QueryName:          "A",
+				Expression:         "A",
+				DataSource:         v3.DataSourceMetrics,
+				AggregateOperator:  v3.AggregateOperatorRate,
+				AggregateAttribute: v3.AttributeKey{Key: "signoz_calls_total"},
+				TimeAggregation:    v3.TimeAggregationRate,
+				SpaceAggregation:   v3.SpaceAggregationSum,
+				StepInterval:       60,
+				Filters: &v3.FilterSet{
+					Operator: "AND",
+					Items: []v3.FilterItem{
+						{Key: v3.AttributeKey{Key: "service_name"}, Operator: v3.FilterOperatorNotEqual, Value: "$service"},
+					},
+				},
+				GroupBy: []v3.AttributeKey{{Key: "service_name"}, {Key: "operation"}},
+			},
+		},
+		{
+			name: "metrics percentile",
+			text: `metrics | p99(signoz_latency_bucket) by service_name | reduce avg`,
+			expected: &v3.BuilderQuery{
+				QueryName:          "A",
+				Expression:         "A",
+				DataSource:         v3.DataSourceMetrics,
+				AggregateOperator:  v3.AggregateOperatorP99,
+				AggregateAttribute: v3.AttributeKey{Key: "signoz_latency_bucket"},
+				SpaceAggregation:   v3.SpaceAggregationPercentile99,
+				GroupBy:            []v3.AttributeKey{{Key: "service_name"}},
+				ReduceTo:           v3.ReduceToOperatorAvg,
+			},
+		},
+		{
+			name: "list with select, order by and functions",
+			text: "logs | where `user-agent` like \"%curl%\" | select `user-agent`, host::string | order by timestamp desc, `value` | limit 100 | offset 200 | apply timeShift(3600)",
+			expected: &v3.BuilderQuery{
+				QueryName:         "A",
+				Expression:        "A",
+				DataSource:        v3.DataSourceLogs,
+				AggregateOperator: v3.AggregateOperatorNoOp,
+				Filters: &v3.FilterSet{
+					Operator: "AND",
+					Items: []v3.FilterItem{
+						{Key: v3.AttributeKey{Key: "user-agent"}, Operator: v3.FilterOperatorLike, Value: "%curl%"},
+					},
+				},
+				SelectColumns: []v3.AttributeKey{{Key: "user-agent"}, {Key: "host", DataType: v3.AttributeKeyDataTypeString}},
+				OrderBy: []v3.OrderBy{
+					{ColumnName: "timestamp", Order: "desc", Key: "timestamp"},
+					{ColumnName: "value", Order: "asc", Key: "value"},
+				},
+				Limit:     100,
+				Offset:    200,
+				Functions: []v3.Function{{Name: v3.FunctionNameTimeShift, Args: []interface{}{float64(3600)}}},
+			},
+		},
+		{
+			name: "formula",
+			text: " A / B * 100 ",
+			expected: &v3.BuilderQuery{
+				QueryName:  "A",
+				Expression: "A / B * 100",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := Parse("A", tt.text)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, query)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{
+			name:     "empty",
+			text:     "  ",
+			expected: "line 1, column 1: empty query",
+		},
+		{
+			name:     "unknown stage",
+			text:     `logs | filter a = "b"`,
+			expected: "line 1, column 8: unknown stage 'filter', expected one of where, <aggregation>(), every, having, order by, limit, offset, select, reduce, legend, fill, apply, buckets, top, bottom",
+		},
+		{
+			name:     "unknown fill mode",
+			text:     `logs | count() | fill sideways`,
+			expected: "line 1, column 23: expected one of none, zero, previous, linear, null, got 'sideways'",
+		},
+		{
+			name:     "missing value",
+			text:     `logs | where a = | count()`,
+			expected: "line 1, column 18: expected value, got '|'",
+		},
+		{
+			name:     "unterminated string",
+			text:     "logs\n| where a = \"b",
+			expected: "line 2, column 13: unterminated string",
+		},
+		{
+			name:     "unknown aggregation",
+			text:     "traces | where a = 1\n  | median(duration_nano)",
+			expected: "line 2, column 5: unknown aggregation 'median'",
+		},
+		{
+			name:     "percentile without quantile",
+			text:     `traces | percentile(duration_nano)`,
+			expected: "line 1, column 34: percentile requires a quantile e.g percentile(duration_nano, 0.95)",
+		},
+		{
+			name:     "quantile out of range",
+			text:     `traces | percentile(duration_nano, 1.5)`,
+			expected: "line 1, column 36: quantile should be between 0 and 1, got '1.5'",
+		},
+		{
+			name:     "invalid duration",
+			text:     `logs | count() | every 5x`,
+			expected: "line 1, column 24: expected a positive duration e.g 30s, 5m, 1h, got '5x'",
+		},
+		{
+			name:     "missing closing parenthesis",
+			text:     `logs | where (a = 1 or b = 2 | count()`,
+			expected: "line 1, column 30: expected ')', got '|'",
+		},
+		{
+			name:     "stage used twice",
+			text:     `logs | count() | every 1m | every 5m`,
+			expected: "line 1, column 29: every is already set at line 1, column 18",
+		},
+		{
+			name:     "missing operator",
+			text:     `logs | where service.name "api"`,
+			expected: "line 1, column 27: expected operator after key service.name, got string \"api\"",
+		},
+		{
+			name:     "unknown data type",
+			text:     `logs | where status::int = 1`,
+			expected: "line 1, column 22: unknown data type int",
+		},
+		{
+			name:     "buckets not in increasing order",
+			text:     `traces | buckets 10, 100, 50`,
+			expected: "line 1, column 27: buckets should be in increasing order, got 50 after 100",
+		},
+		{
+			name:     "top k without reducer",
+			text:     `metrics | sum(rate(signoz_calls_total)) by service_name | top 5`,
+			expected: "line 1, column 64: expected 'by', got end of query",
+		},
+		{
+			name:     "top and bottom",
+			text:     `logs | count() by host | top 5 by max | bottom 2 by avg`,
+			expected: "line 1, column 41: top is already set at line 1, column 26",
+		},
+		{
+			name:     "trailing tokens",
+			text:     `logs | count() by a b`,
+			expected: "line 1, column 21: expected '|' or end of query, got 'b'",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse("A", tt.text)
+			require.Error(t, err)
+			assert.Equal(t, tt.expected, err.Error())
+			var parseErr *Error
+			assert.ErrorAs(t, err, &parseErr)
+		})
+	}
+}
+
+func TestParseCompositeQuery(t *testing.T) {
+	compositeQuery, err := ParseCompositeQuery(v3.PanelTypeGraph, map[string]string{
+		"A":  `logs | count() | every 1m`,
+		"B":  `logs | where severity_text = "ERROR" | count() | every 1m`,
+		"F1": `B / A`,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, v3.QueryTypeBuilder, compositeQuery.QueryType)
+	assert.Equal(t, v3.PanelTypeGraph, compositeQuery.PanelType)
+	assert.Len(t, compositeQuery.BuilderQueries, 3)
+	assert.Equal(t, "B / A", compositeQuery.BuilderQueries["F1"].Expression)
+
+	_, err = ParseCompositeQuery(v3.PanelTypeGraph, map[string]string{"A": `logs | count(`})
+	assert.EqualError(t, err, "query A: line 1, column 14: expected key, got end of query")
+}
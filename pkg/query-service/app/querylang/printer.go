@@ -0,0 +1,377 @@
+package querylang
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.signoz.io/signoz/pkg/query-service/constants"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+// Format returns the text form of the builder query, Parse of the text returns the same query
+// except for the fields that are filled by the query service e.g temporality and isColumn
+func Format(query *v3.BuilderQuery) (string, error) {
+	if query.QueryName != query.Expression {
+		if query.Join != nil {
+			return "", fmt.Errorf("formulas with join don't have a text form")
+		}
+		return query.Expression, nil
+	}
+
+	stages := []string{string(query.DataSource)}
+
+	if query.Filters != nil && (len(query.Filters.Items) > 0 || len(query.Filters.Groups) > 0) {
+		where, err := formatFilters(query.Filters)
+		if err != nil {
+			return "", err
+		}
+		stages = append(stages, "where "+where)
+	}
+
+	if len(query.SelectColumns) > 0 {
+		stages = append(stages, "select "+formatKeys(query.SelectColumns))
+	}
+
+	if query.AggregateOperator != v3.AggregateOperatorNoOp && query.AggregateOperator != "" ||
+		query.SpaceAggregation != v3.SpaceAggregationUnspecified {
+		stages = append(stages, formatAggregation(query))
+	}
+
+	if query.StepInterval > 0 {
+		stages = append(stages, "every "+formatDuration(query.StepInterval))
+	}
+
+	if len(query.Having) > 0 {
+		var conditions []string
+		for _, having := range query.Having {
+			condition, err := formatHaving(having)
+			if err != nil {
+				return "", err
+			}
+			conditions = append(conditions, condition)
+		}
+		stages = append(stages, "having "+strings.Join(conditions, " and "))
+	}
+
+	if len(query.OrderBy) > 0 {
+		var keys []string
+		for _, orderBy := range query.OrderBy {
+			keys = append(keys, formatOrderBy(orderBy))
+		}
+		stages = append(stages, "order by "+strings.Join(keys, ", "))
+	}
+
+	if query.Limit > 0 {
+		stages = append(stages, fmt.Sprintf("limit %d", query.Limit))
+	}
+	if query.Offset > 0 {
+		stages = append(stages, fmt.Sprintf("offset %d", query.Offset))
+	}
+	if query.TopK != nil {
+		text := fmt.Sprintf("%s %d by %s", query.TopK.Direction, query.TopK.K, query.TopK.ReduceTo)
+		if query.TopK.Other {
+			text += " with other"
+		}
+		stages = append(stages, text)
+	}
+	if query.ReduceTo != "" {
+		stages = append(stages, "reduce "+string(query.ReduceTo))
+	}
+	if query.Legend != "" {
+		stages = append(stages, "legend "+quote(query.Legend))
+	}
+	if query.FillMode != "" {
+		stages = append(stages, "fill "+string(query.FillMode))
+	}
+
+	for _, function := range query.Functions {
+		text, err := formatFunction(function)
+		if err != nil {
+			return "", err
+		}
+		stages = append(stages, "apply "+text)
+	}
+
+	if len(query.Buckets) > 0 {
+		bounds := make([]string, 0, len(query.Buckets))
+		for _, bound := range query.Buckets {
+			bounds = append(bounds, strconv.FormatFloat(bound, 'f', -1, 64))
+		}
+		stages = append(stages, "buckets "+strings.Join(bounds, ", "))
+	}
+
+	return strings.Join(stages, " | "), nil
+}
+
+// reserved are the words that have to be quoted when used as a key
+var reserved = map[string]struct{}{
+	"and": {}, "or": {}, "not": {}, "by": {}, "in": {}, "asc": {}, "desc": {},
+	"true": {}, "false": {}, "contains": {}, "like": {}, "regex": {}, "exists": {}, "has": {},
+	"resource": {}, "tag": {}, "value": {},
+}
+
+func isIdent(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if i == 0 && !isIdentStart(r) || i > 0 && !isIdentPart(r) {
+			return false
+		}
+	}
+	_, ok := reserved[strings.ToLower(name)]
+	return !ok
+}
+
+func formatName(name string) string {
+	if isIdent(name) {
+		return name
+	}
+	return "`" + name + "`"
+}
+
+func formatKey(key v3.AttributeKey) string {
+	text := formatName(key.Key)
+	if key.Type != v3.AttributeKeyTypeUnspecified {
+		text = string(key.Type) + ":" + text
+	}
+	if key.DataType != v3.AttributeKeyDataTypeUnspecified {
+		text += "::" + string(key.DataType)
+	}
+	return text
+}
+
+func formatKeys(keys []v3.AttributeKey) string {
+	var texts []string
+	for _, key := range keys {
+		texts = append(texts, formatKey(key))
+	}
+	return strings.Join(texts, ", ")
+}
+
+// isVariable reports whether the value is a dashboard variable e.g $service
+func isVariable(s string) bool {
+	return strings.HasPrefix(s, "$") && len(s) > 1 && isIdentStart(rune(s[1])) && strings.IndexFunc(s[1:], func(r rune) bool { return !isIdentPart(r) }) == -1
+}
+
+func quote(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`)
+	return `"` + replacer.Replace(s) + `"`
+}
+
+func formatValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		if isVariable(v) {
+			return v, nil
+		}
+		return quote(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v), nil
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			text, err := formatValue(item)
+			if err != nil {
+				return "", err
+			}
+			values = append(values, text)
+		}
+		return "(" + strings.Join(values, ", ") + ")", nil
+	case []string:
+		var values []string
+		for _, item := range v {
+			values = append(values, quote(item))
+		}
+		return "(" + strings.Join(values, ", ") + ")", nil
+	}
+	return "", fmt.Errorf("unsupported value %v of type %T", value, value)
+}
+
+// formatDuration returns the step interval in the largest unit it is a multiple of
+func formatDuration(seconds int64) string {
+	switch {
+	case seconds%86400 == 0:
+		return fmt.Sprintf("%dd", seconds/86400)
+	case seconds%3600 == 0:
+		return fmt.Sprintf("%dh", seconds/3600)
+	case seconds%60 == 0:
+		return fmt.Sprintf("%dm", seconds/60)
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+func formatQuantile(quantile float64) string {
+	return strconv.FormatFloat(quantile, 'f', -1, 64)
+}
+
+func formatAggregation(query *v3.BuilderQuery) string {
+	var text string
+	switch {
+	case query.DataSource == v3.DataSourceMetrics && query.TimeAggregation != v3.TimeAggregationUnspecified &&
+		query.SpaceAggregation != v3.SpaceAggregationUnspecified:
+		text = fmt.Sprintf("%s(%s(%s)", query.SpaceAggregation, query.TimeAggregation, formatKey(query.AggregateAttribute))
+	case query.DataSource == v3.DataSourceMetrics && query.SpaceAggregation != v3.SpaceAggregationUnspecified:
+		text = fmt.Sprintf("%s(%s", query.SpaceAggregation, formatKey(query.AggregateAttribute))
+	case query.AggregateAttribute.Key == "":
+		text = fmt.Sprintf("%s(", query.AggregateOperator)
+	default:
+		text = fmt.Sprintf("%s(%s", query.AggregateOperator, formatKey(query.AggregateAttribute))
+	}
+	if query.IsParameterisedPercentile() {
+		text += ", " + formatQuantile(query.Quantile)
+	}
+	text += ")"
+	if len(query.GroupBy) > 0 {
+		text += " by " + formatKeys(query.GroupBy)
+	}
+	return text
+}
+
+func formatHaving(having v3.Having) (string, error) {
+	column := having.ColumnName
+	if column == "" {
+		column = "value"
+	}
+	if !isIdent(column) && column != "value" {
+		column = "`" + column + "`"
+	}
+	value, err := formatValue(having.Value)
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToUpper(string(having.Operator)) {
+	case string(v3.HavingOperatorIn):
+		return fmt.Sprintf("%s in %s", column, value), nil
+	case string(v3.HavingOperatorNotIn):
+		return fmt.Sprintf("%s not in %s", column, value), nil
+	}
+	return fmt.Sprintf("%s %s %s", column, having.Operator, value), nil
+}
+
+func formatOrderBy(orderBy v3.OrderBy) string {
+	order := strings.ToLower(orderBy.Order)
+	if order == "" {
+		order = defaultOrder
+	}
+	if orderBy.ColumnName == constants.SigNozOrderByValue {
+		return "value " + order
+	}
+	key := v3.AttributeKey{Key: orderBy.ColumnName, DataType: orderBy.DataType, Type: orderBy.Type}
+	return formatKey(key) + " " + order
+}
+
+func formatFunction(function v3.Function) (string, error) {
+	var args []string
+	for _, arg := range function.Args {
+		text, err := formatValue(arg)
+		if err != nil {
+			return "", err
+		}
+		args = append(args, text)
+	}
+	// sorted so that the text is stable
+	names := make([]string, 0, len(function.NamedArgs))
+	for name := range function.NamedArgs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		text, err := formatValue(function.NamedArgs[name])
+		if err != nil {
+			return "", err
+		}
+		args = append(args, fmt.Sprintf("%s=%s", name, text))
+	}
+	return fmt.Sprintf("%s(%s)", function.Name, strings.Join(args, ", ")), nil
+}
+
+var filterOperators = map[v3.FilterOperator]string{
+	v3.FilterOperatorEqual:           "=",
+	v3.FilterOperatorNotEqual:        "!=",
+	v3.FilterOperatorGreaterThan:     ">",
+	v3.FilterOperatorGreaterThanOrEq: ">=",
+	v3.FilterOperatorLessThan:        "<",
+	v3.FilterOperatorLessThanOrEq:    "<=",
+	v3.FilterOperatorIn:              "in",
+	v3.FilterOperatorNotIn:           "not in",
+	v3.FilterOperatorContains:        "contains",
+	v3.FilterOperatorNotContains:     "not contains",
+	v3.FilterOperatorRegex:           "=~",
+	v3.FilterOperatorNotRegex:        "!~",
+	v3.FilterOperatorLike:            "like",
+	v3.FilterOperatorNotLike:         "not like",
+	v3.FilterOperatorExists:          "exists",
+	v3.FilterOperatorNotExists:       "not exists",
+	v3.FilterOperatorHas:             "has",
+	v3.FilterOperatorNotHas:          "not has",
+}
+
+func formatFilterItem(item v3.FilterItem) (string, error) {
+	operator, ok := filterOperators[v3.FilterOperator(strings.ToLower(string(item.Operator)))]
+	if !ok {
+		return "", fmt.Errorf("unsupported operator %s", item.Operator)
+	}
+	if operator == "exists" || operator == "not exists" {
+		return fmt.Sprintf("%s %s", formatKey(item.Key), operator), nil
+	}
+
+	value := item.Value
+	if operator == "in" || operator == "not in" {
+		// a single value or a variable is written as a list of one value
+		if s, ok := value.(string); !ok || !isVariable(s) {
+			if _, ok := value.([]interface{}); !ok {
+				if _, ok := value.([]string); !ok {
+					value = []interface{}{value}
+				}
+			}
+		}
+	}
+	text, err := formatValue(value)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s %s", formatKey(item.Key), operator, text), nil
+}
+
+// formatFilters returns the where clause, the items and groups of the top level filter set are
+// joined with and, the ones of the nested groups are enclosed in parentheses
+func formatFilters(filters *v3.FilterSet) (string, error) {
+	conditions, err := formatFilterConditions(filters)
+	if err != nil {
+		return "", err
+	}
+	// the flat filter sets with OR are supported at the top level
+	return strings.Join(conditions, " "+strings.ToLower(filters.LogicalOperator())+" "), nil
+}
+
+func formatFilterConditions(filters *v3.FilterSet) ([]string, error) {
+	var conditions []string
+	for _, item := range filters.Items {
+		condition, err := formatFilterItem(item)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	for _, group := range filters.Groups {
+		groupConditions, err := formatFilterConditions(group)
+		if err != nil {
+			return nil, err
+		}
+		condition := "(" + strings.Join(groupConditions, " "+strings.ToLower(group.LogicalOperator())+" ") + ")"
+		if group.Not {
+			condition = "not " + condition
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
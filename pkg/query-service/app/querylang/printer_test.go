@@ -0,0 +1,128 @@
+package querylang
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.signoz.io/signoz/pkg/query-service/constants"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    *v3.BuilderQuery
+		expected string
+	}{
+		{
+			name: "logs count by pod",
+			query: &v3.BuilderQuery{
+				QueryName:         "A",
+				Expression:        "A",
+				DataSource:        v3.DataSourceLogs,
+				AggregateOperator: v3.AggregateOperatorCount,
+				StepInterval:      60,
+				Filters: &v3.FilterSet{
+					Operator: "AND",
+					Items: []v3.FilterItem{
+						{Key: v3.AttributeKey{Key: "service.name", Type: v3.AttributeKeyTypeResource, DataType: v3.AttributeKeyDataTypeString}, Operator: v3.FilterOperatorEqual, Value: "api"},
+						{Key: v3.AttributeKey{Key: "severity_text", IsColumn: true}, Operator: v3.FilterOperatorIn, Value: []interface{}{"ERROR", "FATAL"}},
+					},
+				},
+				GroupBy: []v3.AttributeKey{{Key: "k8s.pod.name"}},
+			},
+			expected: `logs | where resource:service.name::string = "api" and severity_text in ("ERROR", "FATAL") | count() by k8s.pod.name | every 1m`,
+		},
+		{
+			name: "traces with nested groups",
+			query: &v3.BuilderQuery{
+				QueryName:          "A",
+				Expression:         "A",
+				DataSource:         v3.DataSourceTraces,
+				AggregateOperator:  v3.AggregateOperatorP99,
+				AggregateAttribute: v3.AttributeKey{Key: "durationNano", IsColumn: true},
+				StepInterval:       3600,
+				Filters: &v3.FilterSet{
+					Operator: "AND",
+					Groups: []*v3.FilterSet{
+						{
+							Operator: "OR",
+							Items: []v3.FilterItem{
+								{Key: v3.AttributeKey{Key: "user-agent"}, Operator: v3.FilterOperatorRegex, Value: `^curl/\d+`},
+							},
+							Groups: []*v3.FilterSet{
+								{Not: true, Items: []v3.FilterItem{{Key: v3.AttributeKey{Key: "name"}, Operator: v3.FilterOperatorNotExists}}},
+							},
+						},
+					},
+				},
+				Having:  []v3.Having{{ColumnName: "value", Operator: v3.HavingOperatorNotIn, Value: []interface{}{1, 2}}},
+				OrderBy: []v3.OrderBy{{ColumnName: constants.SigNozOrderByValue, Order: "asc"}, {ColumnName: "value", Order: "desc"}},
+			},
+			expected: "traces | where (user-agent =~ \"^curl/\\\\d+\" or not (name not exists)) | p99(durationNano) | every 1h | having value not in (1, 2) | order by value asc, `value` desc",
+		},
+		{
+			name: "metrics space and time aggregation",
+			query: &v3.BuilderQuery{
+				QueryName:          "B",
+				Expression:         "B",
+				DataSource:         v3.DataSourceMetrics,
+				AggregateOperator:  v3.AggregateOperatorRate,
+				AggregateAttribute: v3.AttributeKey{Key: "signoz_latency_bucket"},
+				TimeAggregation:    v3.TimeAggregationRate,
+				SpaceAggregation:   v3.SpaceAggregationPercentile,
+				Quantile:           0.999,
+				StepInterval:       90,
+				GroupBy:            []v3.AttributeKey{{Key: "service_name", Type: v3.AttributeKeyTypeTag}},
+				Functions:          []v3.Function{{Name: v3.FunctionNameEWMA3, Args: []interface{}{0.2}, NamedArgs: map[string]interface{}{"b": "x", "a": true}}},
+			},
+			expected: `metrics | percentile(rate(signoz_latency_bucket), 0.999) by tag:service_name | every 90s | apply ewma3(0.2, a=true, b="x")`,
+		},
+		{
+			name: "formula",
+			query: &v3.BuilderQuery{
+				QueryName:  "F1",
+				Expression: "A / B",
+			},
+			expected: "A / B",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := Format(tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, text)
+		})
+	}
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	texts := []string{
+		`logs | where service.name = "api" and severity_text = "ERROR" | count() by k8s.pod.name | every 1m`,
+		`logs | where (a = "x" or (b != 1 and c contains "it's \"quoted\"")) and not (d in ("1", "2")) | count_distinct(trace_id) | every 5m | reduce last`,
+		`traces | where resource:service.name::string not like "%test%" and has_error = true | percentile(durationNano, 0.95) by tag:http.route | every 1d | order by value desc | limit 5`,
+		`metrics | where env = $env | sum(increase(signoz_calls_total)) by service_name | every 2m | having value >= 10 and value < 100 | legend "{{service_name}}"`,
+		`metrics | sum_rate(signoz_calls_total) by service_name | every 1m | apply timeShift(86400)`,
+		`metrics | avg(avg(k8s_pod_memory_usage)) by k8s_pod_name | every 1m | fill previous`,
+		`metrics | sum(rate(signoz_calls_total)) by service_name | every 1m | top 5 by max with other`,
+		`logs | count() by k8s.pod.name | every 5m | bottom 3 by last`,
+		"logs | where `log level` = \"info\" | select `log level`, host | order by timestamp desc | limit 100 | offset 100",
+		`traces | where name = "GET /api" | count() by serviceName | every 1m | buckets 1000000, 5000000, 25000000, 100000000`,
+	}
+
+	for _, text := range texts {
+		t.Run(text, func(t *testing.T) {
+			query, err := Parse("A", text)
+			require.NoError(t, err)
+			formatted, err := Format(query)
+			require.NoError(t, err)
+			assert.Equal(t, text, formatted)
+
+			reparsed, err := Parse("A", formatted)
+			require.NoError(t, err)
+			assert.Equal(t, query, reparsed)
+		})
+	}
+}
@@ -22,8 +22,10 @@ import (
 
 	"github.com/rs/cors"
 	"github.com/soheilhy/cmux"
+	"go.opentelemetry.io/otel/metric"
 	"go.signoz.io/signoz/pkg/query-service/agentConf"
 	"go.signoz.io/signoz/pkg/query-service/app/clickhouseReader"
+	queryprogress "go.signoz.io/signoz/pkg/query-service/app/clickhouseReader/query_progress"
 	"go.signoz.io/signoz/pkg/query-service/app/dashboards"
 	"go.signoz.io/signoz/pkg/query-service/app/integrations"
 	"go.signoz.io/signoz/pkg/query-service/app/logparsingpipeline"
@@ -45,6 +47,7 @@ import (
 	"go.signoz.io/signoz/pkg/query-service/interfaces"
 	"go.signoz.io/signoz/pkg/query-service/model"
 	pqle "go.signoz.io/signoz/pkg/query-service/pqlEngine"
+	"go.signoz.io/signoz/pkg/query-service/querylimits"
 	"go.signoz.io/signoz/pkg/query-service/rules"
 	"go.signoz.io/signoz/pkg/query-service/telemetry"
 	"go.signoz.io/signoz/pkg/query-service/utils"
@@ -67,6 +70,11 @@ type ServerOptions struct {
 	FluxInterval      string
 	Cluster           string
 	UseLogsNewSchema  bool
+	QueryLimitsPath   string
+	// tracks the progress of the queries in the redis of the cache so that it can be followed from any replica
+	ProgressInRedis bool
+	// MeterProvider provides the meters of the query service metrics
+	MeterProvider metric.MeterProvider
 }
 
 // Server runs HTTP, Mux and a grpc server
@@ -115,6 +123,22 @@ func NewServer(serverOptions *ServerOptions) (*Server, error) {
 	// initiate feature manager
 	fm := featureManager.StartManager()
 
+	var c cache.Cache
+	var cacheOpts *cache.Options
+	if serverOptions.CacheConfigPath != "" {
+		cacheOpts, err = cache.LoadFromYAMLCacheConfigFile(serverOptions.CacheConfigPath)
+		if err != nil {
+			return nil, err
+		}
+		cacheOpts.MeterProvider = serverOptions.MeterProvider
+		c = cache.NewCache(cacheOpts)
+		if c != nil {
+			if err := c.Connect(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	readerReady := make(chan bool)
 
 	var reader interfaces.Reader
@@ -131,6 +155,14 @@ func NewServer(serverOptions *ServerOptions) (*Server, error) {
 			serverOptions.Cluster,
 			serverOptions.UseLogsNewSchema,
 		)
+		// the progress of the queries is tracked in redis when enabled, so that it can be
+		// followed from any replica
+		if serverOptions.ProgressInRedis {
+			if !cacheOpts.UsesRedis() {
+				return nil, fmt.Errorf("tracking the query progress in redis needs a redis or tiered cache")
+			}
+			clickhouseReader.SetQueryProgressTracker(queryprogress.NewRedisQueryProgressTracker(cacheOpts.Redis))
+		}
 		go clickhouseReader.Start(readerReady)
 		reader = clickhouseReader
 	} else {
@@ -144,13 +176,12 @@ func NewServer(serverOptions *ServerOptions) (*Server, error) {
 			return nil, err
 		}
 	}
-	var c cache.Cache
-	if serverOptions.CacheConfigPath != "" {
-		cacheOpts, err := cache.LoadFromYAMLCacheConfigFile(serverOptions.CacheConfigPath)
+	var queryLimits *querylimits.Config
+	if serverOptions.QueryLimitsPath != "" {
+		queryLimits, err = querylimits.LoadFromYAMLConfigFile(serverOptions.QueryLimitsPath)
 		if err != nil {
 			return nil, err
 		}
-		c = cache.NewCache(cacheOpts)
 	}
 
 	<-readerReady
@@ -202,6 +233,7 @@ func NewServer(serverOptions *ServerOptions) (*Server, error) {
 		Cache:                         c,
 		FluxInterval:                  fluxInterval,
 		UseLogsNewSchema:              serverOptions.UseLogsNewSchema,
+		QueryLimits:                   queryLimits,
 	})
 	if err != nil {
 		return nil, err
@@ -3,6 +3,7 @@ package v3
 import (
 	"fmt"
 	"math"
+	"sort"
 	"strings"
 
 	"go.signoz.io/signoz/pkg/query-service/constants"
@@ -149,51 +150,81 @@ func getZerosForEpochNano(epoch int64) int64 {
 	return int64(math.Pow(10, float64(19-count)))
 }
 
+// buildTracesFilterItem builds the condition for a single filter item
+func buildTracesFilterItem(item v3.FilterItem) (string, error) {
+	val := item.Value
+	// generate the key
+	columnName := getColumnName(item.Key)
+	var fmtVal string
+	item.Operator = v3.FilterOperator(strings.ToLower(strings.TrimSpace(string(item.Operator))))
+	if item.Operator != v3.FilterOperatorExists && item.Operator != v3.FilterOperatorNotExists {
+		var err error
+		val, err = utils.ValidateAndCastValue(val, item.Key.DataType)
+		if err != nil {
+			return "", fmt.Errorf("invalid value for key %s: %v", item.Key.Key, err)
+		}
+	}
+	if val != nil {
+		fmtVal = utils.ClickHouseFormattedValue(val)
+	}
+	operator, ok := tracesOperatorMappingV3[item.Operator]
+	if !ok {
+		return "", fmt.Errorf("unsupported operator %s", item.Operator)
+	}
+	switch item.Operator {
+	case v3.FilterOperatorContains, v3.FilterOperatorNotContains:
+		val = utils.QuoteEscapedString(fmt.Sprintf("%v", item.Value))
+		return fmt.Sprintf("%s %s '%%%s%%'", columnName, operator, val), nil
+	case v3.FilterOperatorRegex, v3.FilterOperatorNotRegex:
+		return fmt.Sprintf(operator, columnName, fmtVal), nil
+	case v3.FilterOperatorExists, v3.FilterOperatorNotExists:
+		if item.Key.IsColumn {
+			return existsSubQueryForFixedColumn(item.Key, item.Operator)
+		}
+		columnType, columnDataType := getClickhouseTracesColumnDataTypeAndType(item.Key)
+		return fmt.Sprintf(operator, columnDataType, columnType, item.Key.Key), nil
+	default:
+		return fmt.Sprintf("%s %s %s", columnName, operator, fmtVal), nil
+	}
+}
+
+// buildTracesFilterGroup builds the condition for a nested filter group
+func buildTracesFilterGroup(fs *v3.FilterSet) (string, error) {
+	var conditions []string
+	for _, item := range fs.Items {
+		condition, err := buildTracesFilterItem(item)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, condition)
+	}
+	for _, group := range fs.Groups {
+		condition, err := buildTracesFilterGroup(group)
+		if err != nil {
+			return "", err
+		}
+		conditions = append(conditions, condition)
+	}
+	return fs.JoinConditions(conditions), nil
+}
+
 func buildTracesFilterQuery(fs *v3.FilterSet) (string, error) {
 	var conditions []string
 
-	if fs != nil && len(fs.Items) != 0 {
+	if fs != nil {
 		for _, item := range fs.Items {
-			val := item.Value
-			// generate the key
-			columnName := getColumnName(item.Key)
-			var fmtVal string
-			item.Operator = v3.FilterOperator(strings.ToLower(strings.TrimSpace(string(item.Operator))))
-			if item.Operator != v3.FilterOperatorExists && item.Operator != v3.FilterOperatorNotExists {
-				var err error
-				val, err = utils.ValidateAndCastValue(val, item.Key.DataType)
-				if err != nil {
-					return "", fmt.Errorf("invalid value for key %s: %v", item.Key.Key, err)
-				}
-			}
-			if val != nil {
-				fmtVal = utils.ClickHouseFormattedValue(val)
+			condition, err := buildTracesFilterItem(item)
+			if err != nil {
+				return "", err
 			}
-			if operator, ok := tracesOperatorMappingV3[item.Operator]; ok {
-				switch item.Operator {
-				case v3.FilterOperatorContains, v3.FilterOperatorNotContains:
-					val = utils.QuoteEscapedString(fmt.Sprintf("%v", item.Value))
-					conditions = append(conditions, fmt.Sprintf("%s %s '%%%s%%'", columnName, operator, val))
-				case v3.FilterOperatorRegex, v3.FilterOperatorNotRegex:
-					conditions = append(conditions, fmt.Sprintf(operator, columnName, fmtVal))
-				case v3.FilterOperatorExists, v3.FilterOperatorNotExists:
-					if item.Key.IsColumn {
-						subQuery, err := existsSubQueryForFixedColumn(item.Key, item.Operator)
-						if err != nil {
-							return "", err
-						}
-						conditions = append(conditions, subQuery)
-					} else {
-						columnType, columnDataType := getClickhouseTracesColumnDataTypeAndType(item.Key)
-						conditions = append(conditions, fmt.Sprintf(operator, columnDataType, columnType, item.Key.Key))
-					}
-
-				default:
-					conditions = append(conditions, fmt.Sprintf("%s %s %s", columnName, operator, fmtVal))
-				}
-			} else {
-				return "", fmt.Errorf("unsupported operator %s", item.Operator)
+			conditions = append(conditions, condition)
+		}
+		for _, group := range fs.Groups {
+			condition, err := buildTracesFilterGroup(group)
+			if err != nil {
+				return "", err
 			}
+			conditions = append(conditions, condition)
 		}
 	}
 	queryString := strings.Join(conditions, " AND ")
@@ -332,6 +363,10 @@ func buildTracesQuery(start, end, step int64, mq *v3.BuilderQuery, _ string, pan
 		op := fmt.Sprintf("quantile(%v)(%s)", aggregateOperatorToPercentile[mq.AggregateOperator], aggregationKey)
 		query := fmt.Sprintf(queryTmpl, op, filterSubQuery, groupBy, having, orderBy)
 		return query, nil
+	case v3.AggregateOperatorPercentile:
+		op := fmt.Sprintf("quantile(%v)(%s)", mq.Quantile, aggregationKey)
+		query := fmt.Sprintf(queryTmpl, op, filterSubQuery, groupBy, having, orderBy)
+		return query, nil
 	case v3.AggregateOperatorAvg, v3.AggregateOperatorSum, v3.AggregateOperatorMin, v3.AggregateOperatorMax:
 		op := fmt.Sprintf("%s(%s)", aggregateOperatorToSQLFunc[mq.AggregateOperator], aggregationKey)
 		query := fmt.Sprintf(queryTmpl, op, filterSubQuery, groupBy, having, orderBy)
@@ -355,6 +390,10 @@ func buildTracesQuery(start, end, step int64, mq *v3.BuilderQuery, _ string, pan
 		op := fmt.Sprintf("toFloat64(count(distinct(%s)))", aggregationKey)
 		query := fmt.Sprintf(queryTmpl, op, filterSubQuery, groupBy, having, orderBy)
 		return query, nil
+	case v3.AggregateOperatorApdex:
+		op := apdexAggregation(mq.Apdex)
+		query := fmt.Sprintf(queryTmpl, op, filterSubQuery, groupBy, having, orderBy)
+		return query, nil
 	case v3.AggregateOperatorNoOp:
 		var query string
 		if panelType == v3.PanelTypeTrace {
@@ -380,6 +419,109 @@ func buildTracesQuery(start, end, step int64, mq *v3.BuilderQuery, _ string, pan
 	}
 }
 
+// apdexThreshold returns the expression of the apdex threshold of the span in nanoseconds,
+// the threshold of the options or the stored threshold of the service of the span
+func apdexThreshold(options *v3.ApdexOptions) string {
+	threshold := v3.DefaultApdexThreshold
+	if options != nil && options.Threshold > 0 {
+		threshold = options.Threshold
+	} else if options != nil && len(options.ServiceThresholds) > 0 {
+		services := make([]string, 0, len(options.ServiceThresholds))
+		for service := range options.ServiceThresholds {
+			services = append(services, service)
+		}
+		sort.Strings(services)
+		thresholds := make([]string, 0, len(services))
+		for _, service := range services {
+			thresholds = append(thresholds, fmt.Sprintf("%f", options.ServiceThresholds[service]))
+		}
+		return fmt.Sprintf("transform(serviceName, %s, [%s], %f) * 1000000000", utils.ClickHouseFormattedValue(services), strings.Join(thresholds, ","), threshold)
+	}
+	return fmt.Sprintf("%f * 1000000000", threshold)
+}
+
+// apdexAggregation returns the aggregation of the ratio of the apdex options. The spans with errors
+// are frustrated, the others are satisfied up to the threshold and tolerating up to four times the threshold
+func apdexAggregation(options *v3.ApdexOptions) string {
+	threshold := apdexThreshold(options)
+	satisfied := fmt.Sprintf("countIf(hasError = false AND durationNano <= %s)", threshold)
+	tolerating := fmt.Sprintf("countIf(hasError = false AND durationNano > %s AND durationNano <= 4 * %s)", threshold, threshold)
+
+	switch options.GetRatio() {
+	case v3.ApdexRatioSatisfied:
+		return fmt.Sprintf("%s / count()", satisfied)
+	case v3.ApdexRatioTolerating:
+		return fmt.Sprintf("%s / count()", tolerating)
+	case v3.ApdexRatioFrustrated:
+		return fmt.Sprintf("countIf(hasError = true OR durationNano > 4 * %s) / count()", threshold)
+	default:
+		return fmt.Sprintf("(%s + %s / 2) / count()", satisfied, tolerating)
+	}
+}
+
+// ApdexRequired returns true if any traces query uses the apdex aggregate operator without a threshold
+func ApdexRequired(params *v3.QueryRangeParamsV3) bool {
+	if params.CompositeQuery == nil || params.CompositeQuery.QueryType != v3.QueryTypeBuilder {
+		return false
+	}
+	for _, query := range params.CompositeQuery.BuilderQueries {
+		if query.DataSource == v3.DataSourceTraces && query.AggregateOperator == v3.AggregateOperatorApdex {
+			if query.Apdex == nil || query.Apdex.Threshold <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EnrichApdex sets the stored thresholds of the services on the apdex queries
+func EnrichApdex(params *v3.QueryRangeParamsV3, thresholds map[string]float64) {
+	for _, query := range params.CompositeQuery.BuilderQueries {
+		if query.DataSource == v3.DataSourceTraces && query.AggregateOperator == v3.AggregateOperatorApdex {
+			if query.Apdex == nil {
+				query.Apdex = &v3.ApdexOptions{}
+			}
+			query.Apdex.ServiceThresholds = thresholds
+		}
+	}
+}
+
+// buildTracesHeatmapQuery returns the number of spans in each bucket of the aggregate attribute
+// for every step, the duration of the span is bucketed when the aggregate attribute is not set
+func buildTracesHeatmapQuery(start, end, step int64, mq *v3.BuilderQuery) (string, error) {
+
+	filterSubQuery, err := buildTracesFilterQuery(mq.Filters)
+	if err != nil {
+		return "", err
+	}
+	spanIndexTableTimeFilter := fmt.Sprintf("(timestamp >= '%d' AND timestamp <= '%d')", start*getZerosForEpochNano(start), end*getZerosForEpochNano(end))
+
+	emptyValuesInGroupByFilter, err := handleEmptyValuesInGroupBy(mq.GroupBy)
+	if err != nil {
+		return "", err
+	}
+	filterSubQuery += emptyValuesInGroupByFilter
+
+	valueKey := "durationNano"
+	if mq.AggregateAttribute.Key != "" {
+		valueKey = getColumnName(mq.AggregateAttribute)
+		if !mq.AggregateAttribute.IsColumn {
+			columnType, columnDataType := getClickhouseTracesColumnDataTypeAndType(mq.AggregateAttribute)
+			filterSubQuery = fmt.Sprintf("%s AND has(%s%s, '%s')", filterSubQuery, columnDataType, columnType, mq.AggregateAttribute.Key)
+		}
+	}
+
+	// the labels are selected irrespective of the aggregate operator, the spans are always counted
+	selectLabels := getSelectLabels(v3.AggregateOperatorCount, mq.GroupBy)
+	groupBy := groupByAttributeKeyTags(v3.PanelTypeHeatmap, "", mq.GroupBy...)
+
+	query := fmt.Sprintf("SELECT toStartOfInterval(timestamp, INTERVAL %d SECOND) AS ts,%s %s as le, toFloat64(count()) as value "+
+		"from "+constants.SIGNOZ_TRACE_DBNAME+"."+constants.SIGNOZ_SPAN_INDEX_TABLENAME+
+		" where "+spanIndexTableTimeFilter+"%s group by %s",
+		step, selectLabels, utils.HeatmapBucket(valueKey, mq.Buckets), filterSubQuery, groupBy)
+	return query, nil
+}
+
 func enrichOrderBy(items []v3.OrderBy, keys map[string]v3.AttributeKey) []v3.OrderBy {
 	enrichedItems := []v3.OrderBy{}
 	for i := 0; i < len(items); i++ {
@@ -404,6 +546,9 @@ func groupBy(panelType v3.PanelType, graphLimitQtype string, tags ...string) str
 	if (graphLimitQtype != constants.FirstQueryGraphLimit) && (panelType == v3.PanelTypeGraph || panelType == v3.PanelTypeValue) {
 		tags = append(tags, "ts")
 	}
+	if panelType == v3.PanelTypeHeatmap {
+		tags = append(tags, "le", "ts")
+	}
 	return strings.Join(tags, ",")
 }
 
@@ -507,6 +652,9 @@ func PrepareTracesQuery(start, end int64, panelType v3.PanelType, mq *v3.Builder
 	// adjust the start and end time to the step interval
 	start = start - (start % (mq.StepInterval * 1000))
 	end = end - (end % (mq.StepInterval * 1000))
+	if panelType == v3.PanelTypeHeatmap {
+		return buildTracesHeatmapQuery(start, end, mq.StepInterval, mq)
+	}
 	if options.GraphLimitQtype == constants.FirstQueryGraphLimit {
 		// give me just the group by names
 		query, err := buildTracesQuery(start, end, mq.StepInterval, mq, constants.SIGNOZ_SPAN_INDEX_TABLENAME, panelType, options)
@@ -554,12 +702,11 @@ func Enrich(params *v3.QueryRangeParamsV3, keys map[string]v3.AttributeKey) {
 func EnrichTracesQuery(query *v3.BuilderQuery, keys map[string]v3.AttributeKey) {
 	// enrich aggregate attribute
 	query.AggregateAttribute = enrichKeyWithMetadata(query.AggregateAttribute, keys)
-	// enrich filter items
-	if query.Filters != nil && len(query.Filters.Items) > 0 {
-		for idx, filter := range query.Filters.Items {
-			query.Filters.Items[idx].Key = enrichKeyWithMetadata(filter.Key, keys)
-		}
-	}
+	// enrich filter items, including the ones in nested groups
+	_ = query.Filters.ForEachItem(func(item *v3.FilterItem) error {
+		item.Key = enrichKeyWithMetadata(item.Key, keys)
+		return nil
+	})
 	// enrich group by
 	for idx, groupBy := range query.GroupBy {
 		query.GroupBy[idx] = enrichKeyWithMetadata(groupBy, keys)
@@ -128,6 +128,22 @@ var buildFilterQueryData = []struct {
 		}},
 		ExpectedFilter: " AND NOT match(stringTagMap['name'], '102.')",
 	},
+	{
+		Name: "Test nested groups",
+		FilterSet: &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{
+			{Key: v3.AttributeKey{Key: "user.name", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}, Value: "john", Operator: "="},
+		}, Groups: []*v3.FilterSet{
+			{Operator: "OR", Items: []v3.FilterItem{
+				{Key: v3.AttributeKey{Key: "k8s_namespace", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeResource}, Value: "my_service", Operator: "="},
+				{Key: v3.AttributeKey{Key: "bytes", DataType: v3.AttributeKeyDataTypeInt64, Type: v3.AttributeKeyTypeTag}, Value: 10, Operator: ">"},
+			}, Groups: []*v3.FilterSet{
+				{Not: true, Items: []v3.FilterItem{
+					{Key: v3.AttributeKey{Key: "host", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}, Value: "102.", Operator: "contains"},
+				}},
+			}},
+		}},
+		ExpectedFilter: " AND stringTagMap['user.name'] = 'john' AND (resourceTagsMap['k8s_namespace'] = 'my_service' OR numberTagMap['bytes'] > 10 OR NOT (stringTagMap['host'] ILIKE '%102.%'))",
+	},
 }
 
 func TestBuildTracesFilterQuery(t *testing.T) {
@@ -1069,6 +1085,71 @@ var testBuildTracesQueryData = []struct {
 			"where (timestamp >= '1680066360726210000' AND timestamp <= '1680066458000000000')",
 		PanelType: v3.PanelTypeTable,
 	},
+	{
+		Name:  "Test aggregate parameterised percentile",
+		Start: 1680066360726210000,
+		End:   1680066458000000000,
+		BuilderQuery: &v3.BuilderQuery{
+			QueryName:          "A",
+			StepInterval:       60,
+			AggregateAttribute: v3.AttributeKey{Key: "durationNano", IsColumn: true, DataType: v3.AttributeKeyDataTypeFloat64, Type: v3.AttributeKeyTypeTag},
+			AggregateOperator:  v3.AggregateOperatorPercentile,
+			Quantile:           0.9999,
+			Expression:         "A",
+			Filters:            &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{}},
+			GroupBy:            []v3.AttributeKey{},
+			OrderBy:            []v3.OrderBy{},
+		},
+		TableName: "signoz_traces.distributed_signoz_index_v2",
+		ExpectedQuery: "SELECT now() as ts, quantile(0.9999)(durationNano) as value " +
+			"from signoz_traces.distributed_signoz_index_v2 " +
+			"where (timestamp >= '1680066360726210000' AND timestamp <= '1680066458000000000')",
+		PanelType: v3.PanelTypeTable,
+	},
+	{
+		Name:  "Test aggregate apdex with the thresholds of the services",
+		Start: 1680066360726210000,
+		End:   1680066458000000000,
+		BuilderQuery: &v3.BuilderQuery{
+			QueryName:         "A",
+			StepInterval:      60,
+			AggregateOperator: v3.AggregateOperatorApdex,
+			Apdex:             &v3.ApdexOptions{ServiceThresholds: map[string]float64{"route": 0.2, "frontend": 1}},
+			Expression:        "A",
+			Filters:           &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{}},
+			GroupBy:           []v3.AttributeKey{{Key: "serviceName", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag, IsColumn: true}},
+			OrderBy:           []v3.OrderBy{},
+		},
+		TableName: "signoz_traces.distributed_signoz_index_v2",
+		ExpectedQuery: "SELECT toStartOfInterval(timestamp, INTERVAL 60 SECOND) AS ts, serviceName as `serviceName`, " +
+			"(countIf(hasError = false AND durationNano <= transform(serviceName, ['frontend','route'], [1.000000,0.200000], 0.500000) * 1000000000) + " +
+			"countIf(hasError = false AND durationNano > transform(serviceName, ['frontend','route'], [1.000000,0.200000], 0.500000) * 1000000000 AND " +
+			"durationNano <= 4 * transform(serviceName, ['frontend','route'], [1.000000,0.200000], 0.500000) * 1000000000) / 2) / count() as value " +
+			"from signoz_traces.distributed_signoz_index_v2 " +
+			"where (timestamp >= '1680066360726210000' AND timestamp <= '1680066458000000000') " +
+			"group by `serviceName`,ts order by value DESC",
+		PanelType: v3.PanelTypeGraph,
+	},
+	{
+		Name:  "Test aggregate apdex frustrated ratio with threshold",
+		Start: 1680066360726210000,
+		End:   1680066458000000000,
+		BuilderQuery: &v3.BuilderQuery{
+			QueryName:         "A",
+			StepInterval:      60,
+			AggregateOperator: v3.AggregateOperatorApdex,
+			Apdex:             &v3.ApdexOptions{Threshold: 0.3, Ratio: v3.ApdexRatioFrustrated, ServiceThresholds: map[string]float64{"route": 0.2}},
+			Expression:        "A",
+			Filters:           &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{}},
+			GroupBy:           []v3.AttributeKey{},
+			OrderBy:           []v3.OrderBy{},
+		},
+		TableName: "signoz_traces.distributed_signoz_index_v2",
+		ExpectedQuery: "SELECT now() as ts, countIf(hasError = true OR durationNano > 4 * 0.300000 * 1000000000) / count() as value " +
+			"from signoz_traces.distributed_signoz_index_v2 " +
+			"where (timestamp >= '1680066360726210000' AND timestamp <= '1680066458000000000')",
+		PanelType: v3.PanelTypeTable,
+	},
 	{
 		Name:  "Test aggregate rate table panel",
 		Start: 1680066360726210000,
@@ -1411,6 +1492,45 @@ var testPrepTracesQueryData = []struct {
 			GraphLimitQtype: constants.SecondQueryGraphLimit,
 		},
 	},
+	{
+		Name:      "Test heatmap of duration",
+		PanelType: v3.PanelTypeHeatmap,
+		Start:     1680066360726,
+		End:       1680066458000,
+		BuilderQuery: &v3.BuilderQuery{
+			QueryName:         "A",
+			AggregateOperator: v3.AggregateOperatorP99,
+			Expression:        "A",
+			Filters: &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{
+				{Key: v3.AttributeKey{Key: "method", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}, Value: "GET", Operator: "="},
+			},
+			},
+			GroupBy:      []v3.AttributeKey{{Key: "serviceName", DataType: v3.AttributeKeyDataTypeString, IsColumn: true}},
+			StepInterval: 60,
+		},
+		ExpectedQuery: "SELECT toStartOfInterval(timestamp, INTERVAL 60 SECOND) AS ts, serviceName as `serviceName`," +
+			" if(durationNano <= 0, 0, exp2(ceil(log2(durationNano)))) as le, toFloat64(count()) as value" +
+			" from signoz_traces.distributed_signoz_index_v2 where (timestamp >= '1680066360000000000' AND" +
+			" timestamp <= '1680066420000000000') AND stringTagMap['method'] = 'GET' group by `serviceName`,le,ts",
+	},
+	{
+		Name:      "Test heatmap of attribute with buckets",
+		PanelType: v3.PanelTypeHeatmap,
+		Start:     1680066360726,
+		End:       1680066458000,
+		BuilderQuery: &v3.BuilderQuery{
+			QueryName:          "A",
+			AggregateAttribute: v3.AttributeKey{Key: "http.response_content_length", DataType: v3.AttributeKeyDataTypeFloat64, Type: v3.AttributeKeyTypeTag},
+			AggregateOperator:  v3.AggregateOperatorCount,
+			Expression:         "A",
+			Buckets:            []float64{100, 1000, 10000.5},
+			StepInterval:       60,
+		},
+		ExpectedQuery: "SELECT toStartOfInterval(timestamp, INTERVAL 60 SECOND) AS ts," +
+			" arrayFirst(b -> numberTagMap['http.response_content_length'] <= b, [100,1000,10000.5,inf]) as le," +
+			" toFloat64(count()) as value from signoz_traces.distributed_signoz_index_v2 where (timestamp >= '1680066360000000000' AND" +
+			" timestamp <= '1680066420000000000') AND has(numberTagMap, 'http.response_content_length') group by le,ts",
+	},
 }
 
 func TestPrepareTracesQuery(t *testing.T) {
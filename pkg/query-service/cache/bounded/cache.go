@@ -0,0 +1,297 @@
+package bounded
+
+import (
+	"bytes"
+	"compress/flate"
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.signoz.io/signoz/pkg/query-service/cache/status"
+	"go.uber.org/zap"
+)
+
+const (
+	meterName = "go.signoz.io/signoz/pkg/query-service/cache/bounded"
+
+	// entryOverhead is the approximate memory used by an entry besides the key and the data
+	entryOverhead = 128
+
+	evictionReasonCapacity = "capacity"
+	evictionReasonExpired  = "expired"
+)
+
+// entry is a cache entry, the fields other than the data are maintained by the policy
+type entry struct {
+	key        string
+	data       []byte
+	compressed bool
+	expiresAt  time.Time
+
+	// lru
+	element *list.Element
+	// lfu
+	frequency uint64
+	tick      uint64
+	index     int
+}
+
+func (e *entry) size() int64 {
+	return int64(len(e.key) + len(e.data) + entryOverhead)
+}
+
+func (e *entry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// cache implements the Cache interface with a memory budget, the entries are
+// evicted using the eviction policy once the budget is exceeded
+type cache struct {
+	opts *Options
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	policy  policy
+	bytes   int64
+
+	hits      metric.Int64Counter
+	misses    metric.Int64Counter
+	evictions metric.Int64Counter
+}
+
+// New creates a new bounded in-memory cache
+func New(opts *Options) *cache {
+	opts = opts.withDefaults()
+	c := &cache{
+		opts:    opts,
+		entries: make(map[string]*entry),
+		policy:  newPolicy(opts.Policy),
+	}
+	c.registerMetrics(opts.MeterProvider.Meter(meterName))
+	return c
+}
+
+func (c *cache) registerMetrics(meter metric.Meter) {
+	var err error
+	if c.hits, err = meter.Int64Counter("signoz_query_cache_hits", metric.WithDescription("Number of the cache hits")); err != nil {
+		zap.L().Error("error creating the cache hits counter", zap.Error(err))
+	}
+	if c.misses, err = meter.Int64Counter("signoz_query_cache_misses", metric.WithDescription("Number of the cache misses")); err != nil {
+		zap.L().Error("error creating the cache misses counter", zap.Error(err))
+	}
+	if c.evictions, err = meter.Int64Counter("signoz_query_cache_evictions", metric.WithDescription("Number of the evicted cache entries")); err != nil {
+		zap.L().Error("error creating the cache evictions counter", zap.Error(err))
+	}
+
+	size, err := meter.Int64ObservableGauge("signoz_query_cache_size", metric.WithDescription("Memory used by the cache entries"), metric.WithUnit("By"))
+	if err != nil {
+		zap.L().Error("error creating the cache size gauge", zap.Error(err))
+	}
+	entries, err := meter.Int64ObservableGauge("signoz_query_cache_entries", metric.WithDescription("Number of the cache entries"))
+	if err != nil {
+		zap.L().Error("error creating the cache entries gauge", zap.Error(err))
+	}
+	if size == nil || entries == nil {
+		return
+	}
+	_, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		o.ObserveInt64(size, c.bytes)
+		o.ObserveInt64(entries, int64(len(c.entries)))
+		return nil
+	}, size, entries)
+	if err != nil {
+		zap.L().Error("error registering the cache gauges", zap.Error(err))
+	}
+}
+
+func (c *cache) addHit() {
+	if c.hits != nil {
+		c.hits.Add(context.Background(), 1)
+	}
+}
+
+func (c *cache) addMiss() {
+	if c.misses != nil {
+		c.misses.Add(context.Background(), 1)
+	}
+}
+
+func (c *cache) addEviction(reason string) {
+	if c.evictions != nil {
+		c.evictions.Add(context.Background(), 1, metric.WithAttributes(attribute.String("reason", reason)))
+	}
+}
+
+// Connect does nothing
+func (c *cache) Connect() error {
+	return nil
+}
+
+// Store stores the data in the cache, a ttl of 0 uses the default TTL
+// and a negative ttl stores the data without expiration
+func (c *cache) Store(cacheKey string, data []byte, ttl time.Duration) error {
+	e := &entry{key: cacheKey, data: data}
+	if c.opts.Compression {
+		if compressed, err := compress(data); err == nil && len(compressed) < len(data) {
+			e.data = compressed
+			e.compressed = true
+		}
+	}
+	if e.size() > c.opts.MaxBytes {
+		return fmt.Errorf("cache entry of %d bytes exceeds the cache size of %d bytes", e.size(), c.opts.MaxBytes)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	e.expiresAt = c.expiresAt(now, ttl)
+	if existing, ok := c.entries[cacheKey]; ok {
+		c.remove(existing)
+	}
+	for c.bytes+e.size() > c.opts.MaxBytes {
+		victim := c.policy.victim()
+		if victim == nil {
+			break
+		}
+		c.remove(victim)
+		if victim.expired(now) {
+			c.addEviction(evictionReasonExpired)
+		} else {
+			c.addEviction(evictionReasonCapacity)
+		}
+	}
+
+	c.entries[cacheKey] = e
+	c.policy.add(e)
+	c.bytes += e.size()
+	return nil
+}
+
+// Retrieve retrieves the data from the cache
+func (c *cache) Retrieve(cacheKey string, allowExpired bool) ([]byte, status.RetrieveStatus, error) {
+	c.mu.Lock()
+	e, ok := c.entries[cacheKey]
+	if ok && e.expired(time.Now()) {
+		c.remove(e)
+		c.addEviction(evictionReasonExpired)
+		ok = false
+	}
+	if !ok {
+		c.mu.Unlock()
+		c.addMiss()
+		return nil, status.RetrieveStatusKeyMiss, nil
+	}
+	c.policy.touch(e)
+	data, compressed := e.data, e.compressed
+	c.mu.Unlock()
+
+	c.addHit()
+	if !compressed {
+		return data, status.RetrieveStatusHit, nil
+	}
+	data, err := decompress(data)
+	if err != nil {
+		return nil, status.RetrieveStatusError, err
+	}
+	return data, status.RetrieveStatusHit, nil
+}
+
+// SetTTL sets the TTL for the cache entry
+func (c *cache) SetTTL(cacheKey string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[cacheKey]; ok {
+		e.expiresAt = c.expiresAt(time.Now(), ttl)
+	}
+}
+
+// Remove removes the cache entry
+func (c *cache) Remove(cacheKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[cacheKey]; ok {
+		c.remove(e)
+	}
+}
+
+// BulkRemove removes the cache entries
+func (c *cache) BulkRemove(cacheKeys []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, cacheKey := range cacheKeys {
+		if e, ok := c.entries[cacheKey]; ok {
+			c.remove(e)
+		}
+	}
+}
+
+// Keys returns the keys of the unexpired cache entries starting with the prefix
+func (c *cache) Keys(prefix string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	keys := make([]string, 0)
+	for cacheKey, e := range c.entries {
+		if strings.HasPrefix(cacheKey, prefix) && !e.expired(now) {
+			keys = append(keys, cacheKey)
+		}
+	}
+	return keys, nil
+}
+
+// Close does nothing
+func (c *cache) Close() error {
+	return nil
+}
+
+// Configuration returns the cache configuration
+func (c *cache) Configuration() *Options {
+	return c.opts
+}
+
+// remove removes the entry, the lock must be held
+func (c *cache) remove(e *entry) {
+	delete(c.entries, e.key)
+	c.policy.remove(e)
+	c.bytes -= e.size()
+}
+
+func (c *cache) expiresAt(now time.Time, ttl time.Duration) time.Time {
+	if ttl == 0 {
+		ttl = c.opts.TTL
+	}
+	if ttl < 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	return io.ReadAll(r)
+}
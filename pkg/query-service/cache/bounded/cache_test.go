@@ -0,0 +1,207 @@
+package bounded
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.signoz.io/signoz/pkg/query-service/cache/status"
+)
+
+// entryBytes returns the size of an uncompressed entry
+func entryBytes(key string, data []byte) int64 {
+	return int64(len(key) + len(data) + entryOverhead)
+}
+
+func retrieved(t *testing.T, c *cache, key string) bool {
+	_, retrieveStatus, err := c.Retrieve(key, false)
+	require.NoError(t, err)
+	return retrieveStatus == status.RetrieveStatusHit
+}
+
+// TestNew tests the defaults of the New function
+func TestNew(t *testing.T) {
+	c := New(nil)
+	assert.Equal(t, int64(defaultMaxBytes), c.Configuration().MaxBytes)
+	assert.Equal(t, PolicyLRU, c.Configuration().Policy)
+	assert.Equal(t, defaultTTL, c.Configuration().TTL)
+	assert.NoError(t, c.Connect())
+	assert.NoError(t, c.Close())
+}
+
+// TestStoreRetrieve tests the Store and Retrieve functions
+func TestStoreRetrieve(t *testing.T) {
+	c := New(nil)
+	assert.NoError(t, c.Store("key", []byte("value"), 10*time.Second))
+	data, retrieveStatus, err := c.Retrieve("key", false)
+	assert.NoError(t, err)
+	assert.Equal(t, status.RetrieveStatusHit, retrieveStatus)
+	assert.Equal(t, []byte("value"), data)
+
+	_, retrieveStatus, err = c.Retrieve("missing", false)
+	assert.NoError(t, err)
+	assert.Equal(t, status.RetrieveStatusKeyMiss, retrieveStatus)
+
+	// replacing the entry does not count it twice
+	assert.NoError(t, c.Store("key", []byte("other value"), 10*time.Second))
+	assert.Equal(t, entryBytes("key", []byte("other value")), c.bytes)
+}
+
+// TestLRUEviction tests that the least recently used entry is evicted
+func TestLRUEviction(t *testing.T) {
+	data := []byte("value")
+	c := New(&Options{MaxBytes: 3 * entryBytes("a", data), Policy: PolicyLRU})
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, c.Store(key, data, 0))
+	}
+	// a is now more recent than b
+	assert.True(t, retrieved(t, c, "a"))
+
+	require.NoError(t, c.Store("d", data, 0))
+	assert.False(t, retrieved(t, c, "b"))
+	assert.True(t, retrieved(t, c, "a"))
+	assert.True(t, retrieved(t, c, "c"))
+	assert.True(t, retrieved(t, c, "d"))
+	assert.LessOrEqual(t, c.bytes, c.opts.MaxBytes)
+}
+
+// TestLFUEviction tests that the least frequently used entry is evicted
+func TestLFUEviction(t *testing.T) {
+	data := []byte("value")
+	c := New(&Options{MaxBytes: 3 * entryBytes("a", data), Policy: PolicyLFU})
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, c.Store(key, data, 0))
+	}
+	assert.True(t, retrieved(t, c, "a"))
+	assert.True(t, retrieved(t, c, "a"))
+	assert.True(t, retrieved(t, c, "c"))
+	assert.True(t, retrieved(t, c, "b"))
+	assert.True(t, retrieved(t, c, "b"))
+
+	// c is the least frequently used even though it is not the least recently used
+	require.NoError(t, c.Store("d", data, 0))
+	assert.False(t, retrieved(t, c, "c"))
+
+	// d and c were used once, d is evicted being the least recently used of the two
+	require.NoError(t, c.Store("e", data, 0))
+	assert.False(t, retrieved(t, c, "d"))
+	assert.True(t, retrieved(t, c, "a"))
+	assert.True(t, retrieved(t, c, "b"))
+	assert.True(t, retrieved(t, c, "e"))
+}
+
+// TestStoreTooLarge tests that the entries larger than the cache are rejected
+func TestStoreTooLarge(t *testing.T) {
+	c := New(&Options{MaxBytes: 256})
+	require.NoError(t, c.Store("small", []byte("value"), 0))
+	assert.Error(t, c.Store("large", make([]byte, 256), 0))
+	assert.True(t, retrieved(t, c, "small"))
+}
+
+// TestCompression tests that the compressed entries are smaller and retrieved as stored
+func TestCompression(t *testing.T) {
+	data := bytes.Repeat([]byte(`{"timestamp":1700000000000,"value":"1"}`), 100)
+	c := New(&Options{Compression: true})
+	require.NoError(t, c.Store("key", data, 0))
+	assert.Less(t, c.bytes, entryBytes("key", data))
+
+	retrievedData, retrieveStatus, err := c.Retrieve("key", false)
+	assert.NoError(t, err)
+	assert.Equal(t, status.RetrieveStatusHit, retrieveStatus)
+	assert.Equal(t, data, retrievedData)
+
+	// incompressible data is stored as is
+	require.NoError(t, c.Store("short", []byte("a"), 0))
+	assert.False(t, c.entries["short"].compressed)
+}
+
+// TestTTL tests the expiration of the entries
+func TestTTL(t *testing.T) {
+	c := New(&Options{TTL: 10 * time.Millisecond})
+	require.NoError(t, c.Store("default", []byte("value"), 0))
+	require.NoError(t, c.Store("forever", []byte("value"), -1))
+	require.NoError(t, c.Store("extended", []byte("value"), 0))
+	c.SetTTL("extended", time.Hour)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.False(t, retrieved(t, c, "default"))
+	assert.True(t, retrieved(t, c, "forever"))
+	assert.True(t, retrieved(t, c, "extended"))
+	assert.Len(t, c.entries, 2)
+}
+
+// TestRemove tests the Remove and BulkRemove functions
+func TestRemove(t *testing.T) {
+	c := New(nil)
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, c.Store(key, []byte("value"), 0))
+	}
+	c.Remove("a")
+	c.BulkRemove([]string{"b", "missing"})
+	assert.False(t, retrieved(t, c, "a"))
+	assert.False(t, retrieved(t, c, "b"))
+	assert.True(t, retrieved(t, c, "c"))
+	assert.Equal(t, entryBytes("c", []byte("value")), c.bytes)
+}
+
+func TestKeys(t *testing.T) {
+	c := New(nil)
+	require.NoError(t, c.Store("source=logs", []byte("value"), 0))
+	require.NoError(t, c.Store("source=traces", []byte("value"), 0))
+	require.NoError(t, c.Store("source=metrics", []byte("value"), time.Millisecond))
+	require.NoError(t, c.Store("async_query:1", []byte("value"), 0))
+	time.Sleep(5 * time.Millisecond)
+
+	keys, err := c.Keys("source=")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"source=logs", "source=traces"}, keys)
+}
+
+// TestMetrics tests the hit, miss and eviction counters and the size gauges
+func TestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	data := []byte("value")
+	c := New(&Options{MaxBytes: 2 * entryBytes("a", data), MeterProvider: provider})
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, c.Store(key, data, 0))
+	}
+	retrieved(t, c, "a")
+	retrieved(t, c, "b")
+	retrieved(t, c, "c")
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+
+	values := map[string]int64{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		switch d := m.Data.(type) {
+		case metricdata.Sum[int64]:
+			for _, dp := range d.DataPoints {
+				if reason, ok := dp.Attributes.Value(attribute.Key("reason")); ok {
+					values[m.Name+"_"+reason.AsString()] += dp.Value
+				} else {
+					values[m.Name] += dp.Value
+				}
+			}
+		case metricdata.Gauge[int64]:
+			for _, dp := range d.DataPoints {
+				values[m.Name] += dp.Value
+			}
+		}
+	}
+
+	assert.Equal(t, int64(2), values["signoz_query_cache_hits"])
+	assert.Equal(t, int64(1), values["signoz_query_cache_misses"])
+	assert.Equal(t, int64(1), values["signoz_query_cache_evictions_capacity"])
+	assert.Equal(t, int64(2), values["signoz_query_cache_entries"])
+	assert.Equal(t, 2*entryBytes("a", data), values["signoz_query_cache_size"])
+}
@@ -0,0 +1,65 @@
+package bounded
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	defaultMaxBytes = 256 * 1024 * 1024
+	defaultTTL      = time.Duration(-1)
+)
+
+// Policy is the policy used to pick the entry to evict when the cache is full
+type Policy string
+
+const (
+	// PolicyLRU evicts the least recently used entry
+	PolicyLRU Policy = "lru"
+	// PolicyLFU evicts the least frequently used entry, the least recently used one on ties
+	PolicyLFU Policy = "lfu"
+)
+
+// Options holds the options for the bounded in-memory cache
+type Options struct {
+	// MaxBytes is the budget of the keys and the stored data of the entries
+	MaxBytes int64 `yaml:"maxBytes,omitempty"`
+	// Policy is the eviction policy, lru or lfu, lru is used for any other value
+	Policy Policy `yaml:"policy,omitempty"`
+	// Compression compresses the stored data
+	Compression bool `yaml:"compression,omitempty"`
+	// TTL is the time to live for the entries stored without one, negative for no expiration
+	TTL time.Duration `yaml:"ttl,omitempty"`
+	// MeterProvider provides the meters of the cache, the global meter provider by default
+	MeterProvider metric.MeterProvider `yaml:"-"`
+}
+
+func defaultOptions() *Options {
+	return &Options{MaxBytes: defaultMaxBytes, Policy: PolicyLRU, TTL: defaultTTL}
+}
+
+// withDefaults returns a copy of the options with the defaults of the unset options
+func (o *Options) withDefaults() *Options {
+	opts := defaultOptions()
+	if o == nil {
+		opts.MeterProvider = otel.GetMeterProvider()
+		return opts
+	}
+	opts.Compression = o.Compression
+	if o.MaxBytes > 0 {
+		opts.MaxBytes = o.MaxBytes
+	}
+	if o.Policy != "" {
+		opts.Policy = o.Policy
+	}
+	if o.TTL != 0 {
+		opts.TTL = o.TTL
+	}
+	opts.MeterProvider = o.MeterProvider
+	if opts.MeterProvider == nil {
+		opts.MeterProvider = otel.GetMeterProvider()
+	}
+	return opts
+}
@@ -0,0 +1,114 @@
+package bounded
+
+import (
+	"container/heap"
+	"container/list"
+)
+
+// policy orders the entries of the cache for the eviction
+type policy interface {
+	// add adds the new entry
+	add(e *entry)
+	// touch records an access to the entry
+	touch(e *entry)
+	// remove removes the entry
+	remove(e *entry)
+	// victim returns the entry to evict, nil if there are no entries
+	victim() *entry
+}
+
+func newPolicy(p Policy) policy {
+	if p == PolicyLFU {
+		return &lfuPolicy{}
+	}
+	return &lruPolicy{entries: list.New()}
+}
+
+// lruPolicy keeps the entries in the order of the last access, the most recent first
+type lruPolicy struct {
+	entries *list.List
+}
+
+func (p *lruPolicy) add(e *entry) {
+	e.element = p.entries.PushFront(e)
+}
+
+func (p *lruPolicy) touch(e *entry) {
+	p.entries.MoveToFront(e.element)
+}
+
+func (p *lruPolicy) remove(e *entry) {
+	p.entries.Remove(e.element)
+	e.element = nil
+}
+
+func (p *lruPolicy) victim() *entry {
+	back := p.entries.Back()
+	if back == nil {
+		return nil
+	}
+	return back.Value.(*entry)
+}
+
+// lfuPolicy keeps the entries in a min heap of the number of accesses,
+// the entries with the same number of accesses are ordered by the last access
+type lfuPolicy struct {
+	entries []*entry
+	tick    uint64
+}
+
+func (p *lfuPolicy) Len() int {
+	return len(p.entries)
+}
+
+func (p *lfuPolicy) Less(i, j int) bool {
+	if p.entries[i].frequency != p.entries[j].frequency {
+		return p.entries[i].frequency < p.entries[j].frequency
+	}
+	return p.entries[i].tick < p.entries[j].tick
+}
+
+func (p *lfuPolicy) Swap(i, j int) {
+	p.entries[i], p.entries[j] = p.entries[j], p.entries[i]
+	p.entries[i].index = i
+	p.entries[j].index = j
+}
+
+func (p *lfuPolicy) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(p.entries)
+	p.entries = append(p.entries, e)
+}
+
+func (p *lfuPolicy) Pop() interface{} {
+	last := p.entries[len(p.entries)-1]
+	p.entries[len(p.entries)-1] = nil
+	p.entries = p.entries[:len(p.entries)-1]
+	last.index = -1
+	return last
+}
+
+func (p *lfuPolicy) add(e *entry) {
+	p.tick++
+	e.frequency = 1
+	e.tick = p.tick
+	heap.Push(p, e)
+}
+
+func (p *lfuPolicy) touch(e *entry) {
+	p.tick++
+	e.frequency++
+	e.tick = p.tick
+	heap.Fix(p, e.index)
+}
+
+func (p *lfuPolicy) remove(e *entry) {
+	heap.Remove(p, e.index)
+}
+
+func (p *lfuPolicy) victim() *entry {
+	if len(p.entries) == 0 {
+		return nil
+	}
+	return p.entries[0]
+}
@@ -4,9 +4,12 @@ import (
 	"os"
 	"time"
 
+	"go.opentelemetry.io/otel/metric"
+	bounded "go.signoz.io/signoz/pkg/query-service/cache/bounded"
 	inmemory "go.signoz.io/signoz/pkg/query-service/cache/inmemory"
 	redis "go.signoz.io/signoz/pkg/query-service/cache/redis"
 	"go.signoz.io/signoz/pkg/query-service/cache/status"
+	tiered "go.signoz.io/signoz/pkg/query-service/cache/tiered"
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
 	"gopkg.in/yaml.v2"
 )
@@ -16,6 +19,15 @@ type Options struct {
 	Provider string            `yaml:"provider"`
 	Redis    *redis.Options    `yaml:"redis,omitempty"`
 	InMemory *inmemory.Options `yaml:"inmemory,omitempty"`
+	Bounded  *bounded.Options  `yaml:"bounded,omitempty"`
+	Tiered   *tiered.Options   `yaml:"tiered,omitempty"`
+	// MeterProvider provides the meters of the in-memory caches that report metrics
+	MeterProvider metric.MeterProvider `yaml:"-"`
+}
+
+// UsesRedis returns whether the cache is backed by the redis server of the Redis options
+func (o *Options) UsesRedis() bool {
+	return o != nil && (o.Provider == "redis" || o.Provider == "tiered")
 }
 
 // Cache is the interface for the storage backend
@@ -29,6 +41,19 @@ type Cache interface {
 	Close() error
 }
 
+// KeyLister is implemented by the caches whose keys can be listed
+type KeyLister interface {
+	// Keys returns the keys of the cache entries starting with the prefix
+	Keys(prefix string) ([]string, error)
+}
+
+// Replacer is implemented by the caches that keep copies of the entries, such as the
+// local caches of the replicas, that have to be dropped when an entry is replaced
+type Replacer interface {
+	// Replace stores the data in place of the cache entry and drops the copies of the entry
+	Replace(cacheKey string, data []byte, ttl time.Duration) error
+}
+
 // KeyGenerator is the interface for the key generator
 // The key generator is used to generate the cache keys for the cache entries
 type KeyGenerator interface {
@@ -63,7 +88,28 @@ func NewCache(options *Options) Cache {
 		return redis.New(options.Redis)
 	case "inmemory":
 		return inmemory.New(options.InMemory)
+	case "bounded":
+		return bounded.New(options.boundedOptions(options.Bounded))
+	case "tiered":
+		var tieredOpts tiered.Options
+		if options.Tiered != nil {
+			tieredOpts = *options.Tiered
+		}
+		tieredOpts.L1 = options.boundedOptions(tieredOpts.L1)
+		return tiered.New(&tieredOpts, redis.New(options.Redis))
 	default:
 		return nil
 	}
 }
+
+// boundedOptions returns a copy of the bounded cache options with the meter provider of the options
+func (o *Options) boundedOptions(boundedOpts *bounded.Options) *bounded.Options {
+	var opts bounded.Options
+	if boundedOpts != nil {
+		opts = *boundedOpts
+	}
+	if opts.MeterProvider == nil {
+		opts.MeterProvider = o.MeterProvider
+	}
+	return &opts
+}
@@ -1,6 +1,12 @@
 package cache
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
 
 func TestNewCacheUnKnownProvider(t *testing.T) {
 	c := NewCache(&Options{
@@ -24,6 +30,28 @@ func TestNewCacheInMemory(t *testing.T) {
 	}
 }
 
+func TestNewCacheBounded(t *testing.T) {
+	c := NewCache(&Options{
+		Name:     "test",
+		Provider: "bounded",
+	})
+
+	if c == nil {
+		t.Fatalf("expected non-nil, got nil")
+	}
+}
+
+func TestNewCacheTiered(t *testing.T) {
+	c := NewCache(&Options{
+		Name:     "test",
+		Provider: "tiered",
+	})
+
+	if c == nil {
+		t.Fatalf("expected non-nil, got nil")
+	}
+}
+
 func TestNewCacheRedis(t *testing.T) {
 	c := NewCache(&Options{
 		Name:     "test",
@@ -50,3 +78,46 @@ func TestLoadFromYAMLCacheConfigFile(t *testing.T) {
 		t.Fatalf("unexpected error: %s", err)
 	}
 }
+
+func TestNewCacheMeterProvider(t *testing.T) {
+	for _, provider := range []string{"bounded", "tiered"} {
+		t.Run(provider, func(t *testing.T) {
+			reader := sdkmetric.NewManualReader()
+			c := NewCache(&Options{
+				Name:          "test",
+				Provider:      provider,
+				MeterProvider: sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)),
+			})
+			if c == nil {
+				t.Fatalf("expected non-nil, got nil")
+			}
+
+			var rm metricdata.ResourceMetrics
+			if err := reader.Collect(context.Background(), &rm); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			found := false
+			for _, sm := range rm.ScopeMetrics {
+				for _, m := range sm.Metrics {
+					found = found || m.Name == "signoz_query_cache_entries"
+				}
+			}
+			if !found {
+				t.Errorf("expected the cache metrics to be reported to the meter provider of the options")
+			}
+		})
+	}
+}
+
+func TestUsesRedis(t *testing.T) {
+	for provider, expected := range map[string]bool{"redis": true, "tiered": true, "inmemory": false, "bounded": false} {
+		if usesRedis := (&Options{Provider: provider}).UsesRedis(); usesRedis != expected {
+			t.Errorf("expected %t for provider %s, got %t", expected, provider, usesRedis)
+		}
+	}
+
+	var opts *Options
+	if opts.UsesRedis() {
+		t.Errorf("expected no redis without cache options")
+	}
+}
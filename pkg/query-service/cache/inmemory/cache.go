@@ -1,6 +1,7 @@
 package inmemory
 
 import (
+	"strings"
 	"time"
 
 	go_cache "github.com/patrickmn/go-cache"
@@ -62,6 +63,17 @@ func (c *cache) BulkRemove(cacheKeys []string) {
 	}
 }
 
+// Keys returns the keys of the unexpired cache entries starting with the prefix
+func (c *cache) Keys(prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	for cacheKey := range c.cc.Items() {
+		if strings.HasPrefix(cacheKey, prefix) {
+			keys = append(keys, cacheKey)
+		}
+	}
+	return keys, nil
+}
+
 // Close does nothing
 func (c *cache) Close() error {
 	return nil
@@ -90,6 +90,22 @@ func TestBulkRemove(t *testing.T) {
 	assert.Nil(t, data)
 }
 
+// TestKeys tests the Keys function
+func TestKeys(t *testing.T) {
+	c := New(nil)
+	assert.NoError(t, c.Store("source=logs&step=60", []byte("value"), 10*time.Second))
+	assert.NoError(t, c.Store("source=traces&step=60", []byte("value"), 10*time.Second))
+	assert.NoError(t, c.Store("async_query:1", []byte("value"), 10*time.Second))
+
+	keys, err := c.Keys("source=")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"source=logs&step=60", "source=traces&step=60"}, keys)
+
+	keys, err = c.Keys("")
+	assert.NoError(t, err)
+	assert.Len(t, keys, 3)
+}
+
 // TestCache tests the cache
 func TestCache(t *testing.T) {
 	c := New(nil)
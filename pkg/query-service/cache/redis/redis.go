@@ -3,6 +3,7 @@ package redis
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
@@ -28,13 +29,21 @@ func WithClient(client *redis.Client) *cache {
 	return &cache{client: client}
 }
 
+// NewClient creates a new client of the redis server
+func NewClient(opts *Options) *redis.Client {
+	if opts == nil {
+		opts = defaultOptions()
+	}
+	return redis.NewClient(&redis.Options{
+		Addr:     fmt.Sprintf("%s:%d", opts.Host, opts.Port),
+		Password: opts.Password,
+		DB:       opts.DB,
+	})
+}
+
 // Connect connects to the redis server
 func (c *cache) Connect() error {
-	c.client = redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", c.opts.Host, c.opts.Port),
-		Password: c.opts.Password,
-		DB:       c.opts.DB,
-	})
+	c.client = NewClient(c.opts)
 	return nil
 }
 
@@ -112,6 +121,21 @@ func (c *cache) GetKeys(pattern string) ([]string, error) {
 	return c.client.Keys(context.Background(), pattern).Result()
 }
 
+// keyPatternEscaper escapes the special characters of the key patterns
+var keyPatternEscaper = strings.NewReplacer(`\`, `\\`, `*`, `\*`, `?`, `\?`, `[`, `\[`, `]`, `\]`)
+
+// Keys returns the keys starting with the prefix, the keys are scanned so that
+// the redis server is not blocked
+func (c *cache) Keys(prefix string) ([]string, error) {
+	pattern := keyPatternEscaper.Replace(prefix) + "*"
+	keys := make([]string, 0)
+	iter := c.client.Scan(context.Background(), 0, pattern, 0).Iterator()
+	for iter.Next(context.Background()) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
 // GetKeysWithTTL returns the keys matching the pattern with their TTL
 func (c *cache) GetKeysWithTTL(pattern string) (map[string]time.Duration, error) {
 	keys, err := c.GetKeys(pattern)
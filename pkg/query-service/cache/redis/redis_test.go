@@ -90,3 +90,21 @@ func TestBulkRemove(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestKeys(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	c := WithClient(db)
+
+	mock.ExpectScan(0, `source=logs\*\[1\]*`, 0).SetVal([]string{"source=logs*[1]&step=60"}, 0)
+	keys, err := c.Keys("source=logs*[1]")
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if len(keys) != 1 || keys[0] != "source=logs*[1]&step=60" {
+		t.Errorf("unexpected keys: %v", keys)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,190 @@
+package tiered
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	bounded "go.signoz.io/signoz/pkg/query-service/cache/bounded"
+	"go.signoz.io/signoz/pkg/query-service/cache/status"
+	"go.uber.org/zap"
+)
+
+// Cache is the interface of the cache tiers, same as the cache.Cache interface
+type Cache interface {
+	Connect() error
+	Store(cacheKey string, data []byte, ttl time.Duration) error
+	Retrieve(cacheKey string, allowExpired bool) ([]byte, status.RetrieveStatus, error)
+	SetTTL(cacheKey string, ttl time.Duration)
+	Remove(cacheKey string)
+	BulkRemove(cacheKeys []string)
+	Close() error
+}
+
+// redisClient is implemented by the L2 caches backed by redis
+type redisClient interface {
+	GetClient() *goredis.Client
+}
+
+// cache implements the Cache interface with a local L1 cache in front of a shared L2 cache.
+// Reads go through the L1 to the L2 and the L2 hits are kept in the L1, writes go to
+// both. The L1 entries live at most for the L1 TTL and the remaining TTL of the redis L2
+// entries, the removals are also published to the other replicas when the invalidation
+// is enabled. The L1 entries of the other L2 caches can outlive the L2 entries by up to
+// the L1 TTL.
+type cache struct {
+	opts        *Options
+	l1          Cache
+	l2          Cache
+	invalidator invalidator
+}
+
+// New creates a new two-tier cache over the given L2 cache
+func New(opts *Options, l2 Cache) *cache {
+	opts = opts.withDefaults()
+	return &cache{
+		opts: opts,
+		l1:   bounded.New(opts.L1),
+		l2:   l2,
+	}
+}
+
+// Connect connects the tiers and subscribes to the invalidations
+func (c *cache) Connect() error {
+	if err := c.l1.Connect(); err != nil {
+		return err
+	}
+	if err := c.l2.Connect(); err != nil {
+		return err
+	}
+	if !c.opts.Invalidation {
+		return nil
+	}
+	if c.invalidator == nil {
+		client, ok := c.l2.(redisClient)
+		if !ok {
+			zap.L().Warn("cache invalidation needs a redis L2 cache, the invalidation is disabled")
+			return nil
+		}
+		c.invalidator = newRedisInvalidator(client.GetClient(), c.opts.Channel)
+	}
+	return c.invalidator.subscribe(c.l1.BulkRemove)
+}
+
+// l1TTL returns the TTL of the entry in the L1 cache for the ttl of the entry
+func (c *cache) l1TTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > c.opts.L1TTL {
+		return c.opts.L1TTL
+	}
+	return ttl
+}
+
+// l2TTL returns the remaining TTL of the entry in the redis L2 cache, zero when it's unknown
+// or the entry doesn't expire
+func (c *cache) l2TTL(cacheKey string) time.Duration {
+	client, ok := c.l2.(redisClient)
+	if !ok {
+		return 0
+	}
+	ttl, err := client.GetClient().PTTL(context.Background(), cacheKey).Result()
+	if err != nil || ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// Store stores the data in the L2 and the L1 caches
+func (c *cache) Store(cacheKey string, data []byte, ttl time.Duration) error {
+	if err := c.l2.Store(cacheKey, data, ttl); err != nil {
+		return err
+	}
+	if err := c.l1.Store(cacheKey, data, c.l1TTL(ttl)); err != nil {
+		zap.L().Debug("error storing the cache entry in L1", zap.String("cacheKey", cacheKey), zap.Error(err))
+	}
+	return nil
+}
+
+// Replace stores the data in the L2 cache in place of the cache entry and publishes
+// the removal of the entry, so the replicas drop the replaced entry from their L1
+func (c *cache) Replace(cacheKey string, data []byte, ttl time.Duration) error {
+	if err := c.l2.Store(cacheKey, data, ttl); err != nil {
+		return err
+	}
+	c.l1.Remove(cacheKey)
+	if c.invalidator != nil {
+		if err := c.invalidator.publish([]string{cacheKey}); err != nil {
+			zap.L().Error("error publishing the cache invalidation", zap.String("cacheKey", cacheKey), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Retrieve retrieves the data from the L1 cache, from the L2 cache on a miss
+func (c *cache) Retrieve(cacheKey string, allowExpired bool) ([]byte, status.RetrieveStatus, error) {
+	data, retrieveStatus, err := c.l1.Retrieve(cacheKey, allowExpired)
+	if err == nil && retrieveStatus == status.RetrieveStatusHit {
+		return data, retrieveStatus, nil
+	}
+
+	data, retrieveStatus, err = c.l2.Retrieve(cacheKey, allowExpired)
+	if err != nil || retrieveStatus != status.RetrieveStatusHit {
+		return data, retrieveStatus, err
+	}
+	if err := c.l1.Store(cacheKey, data, c.l1TTL(c.l2TTL(cacheKey))); err != nil {
+		zap.L().Debug("error storing the cache entry in L1", zap.String("cacheKey", cacheKey), zap.Error(err))
+	}
+	return data, retrieveStatus, nil
+}
+
+// SetTTL sets the TTL for the cache entry
+func (c *cache) SetTTL(cacheKey string, ttl time.Duration) {
+	c.l2.SetTTL(cacheKey, ttl)
+	c.l1.SetTTL(cacheKey, c.l1TTL(ttl))
+}
+
+// Remove removes the cache entry
+func (c *cache) Remove(cacheKey string) {
+	c.BulkRemove([]string{cacheKey})
+}
+
+// BulkRemove removes the cache entries and publishes their removal
+func (c *cache) BulkRemove(cacheKeys []string) {
+	c.l1.BulkRemove(cacheKeys)
+	c.l2.BulkRemove(cacheKeys)
+	if c.invalidator != nil {
+		if err := c.invalidator.publish(cacheKeys); err != nil {
+			zap.L().Error("error publishing the cache invalidation", zap.Strings("cacheKeys", cacheKeys), zap.Error(err))
+		}
+	}
+}
+
+// Keys returns the keys of the L2 cache entries starting with the prefix, the L1
+// cache entries are a subset of them
+func (c *cache) Keys(prefix string) ([]string, error) {
+	lister, ok := c.l2.(interface {
+		Keys(prefix string) ([]string, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("the keys of the L2 cache can not be listed")
+	}
+	return lister.Keys(prefix)
+}
+
+// Close closes the invalidation subscription and the tiers
+func (c *cache) Close() error {
+	if c.invalidator != nil {
+		if err := c.invalidator.close(); err != nil {
+			zap.L().Error("error closing the cache invalidation", zap.Error(err))
+		}
+	}
+	if err := c.l1.Close(); err != nil {
+		return err
+	}
+	return c.l2.Close()
+}
+
+// Configuration returns the cache configuration
+func (c *cache) Configuration() *Options {
+	return c.opts
+}
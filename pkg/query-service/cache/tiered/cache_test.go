@@ -0,0 +1,200 @@
+package tiered
+
+import (
+	"testing"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	inmemory "go.signoz.io/signoz/pkg/query-service/cache/inmemory"
+	"go.signoz.io/signoz/pkg/query-service/cache/redis"
+	"go.signoz.io/signoz/pkg/query-service/cache/status"
+)
+
+// bus delivers the published keys to the subscribers synchronously
+type bus struct {
+	subscribers []func(keys []string)
+}
+
+type busInvalidator struct {
+	bus *bus
+}
+
+func (i *busInvalidator) publish(keys []string) error {
+	for _, onInvalidate := range i.bus.subscribers {
+		onInvalidate(keys)
+	}
+	return nil
+}
+
+func (i *busInvalidator) subscribe(onInvalidate func(keys []string)) error {
+	i.bus.subscribers = append(i.bus.subscribers, onInvalidate)
+	return nil
+}
+
+func (i *busInvalidator) close() error {
+	return nil
+}
+
+// newReplica creates a tiered cache over the shared L2 with invalidation over the bus
+func newReplica(t *testing.T, l2 Cache, b *bus) *cache {
+	c := New(&Options{Invalidation: true}, l2)
+	c.invalidator = &busInvalidator{bus: b}
+	require.NoError(t, c.Connect())
+	return c
+}
+
+func retrieve(t *testing.T, c Cache, key string) ([]byte, status.RetrieveStatus) {
+	data, retrieveStatus, err := c.Retrieve(key, false)
+	require.NoError(t, err)
+	return data, retrieveStatus
+}
+
+// TestNew tests the defaults of the New function
+func TestNew(t *testing.T) {
+	c := New(nil, inmemory.New(nil))
+	assert.Equal(t, int64(defaultL1MaxBytes), c.Configuration().L1.MaxBytes)
+	assert.Equal(t, defaultL1TTL, c.Configuration().L1TTL)
+	assert.Equal(t, defaultChannel, c.Configuration().Channel)
+	assert.False(t, c.Configuration().Invalidation)
+	assert.NoError(t, c.Connect())
+	assert.Nil(t, c.invalidator)
+}
+
+// TestWriteThrough tests that the stored data is written to the L2
+func TestWriteThrough(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	c := New(nil, redis.WithClient(db))
+
+	mock.ExpectSet("key", []byte("value"), time.Hour).SetVal("OK")
+	require.NoError(t, c.Store("key", []byte("value"), time.Hour))
+
+	// served by the L1 without reaching redis
+	data, retrieveStatus := retrieve(t, c, "key")
+	assert.Equal(t, status.RetrieveStatusHit, retrieveStatus)
+	assert.Equal(t, []byte("value"), data)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestReadThrough tests that the L2 hits are kept in the L1
+func TestReadThrough(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	c := New(nil, redis.WithClient(db))
+
+	mock.ExpectGet("key").SetVal("value")
+	mock.ExpectPTTL("key").SetVal(time.Hour)
+	data, retrieveStatus := retrieve(t, c, "key")
+	assert.Equal(t, status.RetrieveStatusHit, retrieveStatus)
+	assert.Equal(t, []byte("value"), data)
+
+	data, retrieveStatus = retrieve(t, c, "key")
+	assert.Equal(t, status.RetrieveStatusHit, retrieveStatus)
+	assert.Equal(t, []byte("value"), data)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectGet("missing").RedisNil()
+	_, retrieveStatus = retrieve(t, c, "missing")
+	assert.Equal(t, status.RetrieveStatusKeyMiss, retrieveStatus)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestStoreL2Error tests that the data is not kept in the L1 when the L2 store fails
+func TestStoreL2Error(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	c := New(nil, redis.WithClient(db))
+
+	mock.ExpectSet("key", []byte("value"), time.Hour).SetErr(assert.AnError)
+	assert.Error(t, c.Store("key", []byte("value"), time.Hour))
+
+	mock.ExpectGet("key").RedisNil()
+	_, retrieveStatus := retrieve(t, c, "key")
+	assert.Equal(t, status.RetrieveStatusKeyMiss, retrieveStatus)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestL1TTL tests that the L1 entries expire after the L1 TTL
+func TestL1TTL(t *testing.T) {
+	l2 := inmemory.New(nil)
+	c := New(&Options{L1TTL: 10 * time.Millisecond}, l2)
+	require.NoError(t, c.Store("key", []byte("value"), 0))
+
+	// changed by another replica
+	require.NoError(t, l2.Store("key", []byte("other value"), 0))
+	data, _ := retrieve(t, c, "key")
+	assert.Equal(t, []byte("value"), data)
+
+	time.Sleep(20 * time.Millisecond)
+	data, _ = retrieve(t, c, "key")
+	assert.Equal(t, []byte("other value"), data)
+}
+
+// TestL1TTLRemainingL2TTL tests that the L2 hits don't outlive the redis L2 entries in the L1
+func TestL1TTLRemainingL2TTL(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	c := New(nil, redis.WithClient(db))
+
+	mock.ExpectGet("key").SetVal("value")
+	mock.ExpectPTTL("key").SetVal(10 * time.Millisecond)
+	data, _ := retrieve(t, c, "key")
+	assert.Equal(t, []byte("value"), data)
+
+	time.Sleep(20 * time.Millisecond)
+	mock.ExpectGet("key").RedisNil()
+	_, retrieveStatus := retrieve(t, c, "key")
+	assert.Equal(t, status.RetrieveStatusKeyMiss, retrieveStatus)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestSubscribeUnreachable tests that the subscription fails when redis is unreachable
+func TestSubscribeUnreachable(t *testing.T) {
+	client := goredis.NewClient(&goredis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	i := newRedisInvalidator(client, "signoz_cache_invalidation")
+	assert.Error(t, i.subscribe(func(keys []string) {}))
+}
+
+// TestInvalidation tests that the removals drop the entries from the L1 of the other replicas
+func TestInvalidation(t *testing.T) {
+	l2 := inmemory.New(nil)
+	b := &bus{}
+	first := newReplica(t, l2, b)
+	second := newReplica(t, l2, b)
+
+	require.NoError(t, first.Store("a", []byte("value"), 0))
+	require.NoError(t, first.Store("b", []byte("value"), 0))
+	// cached in the L1 of the second replica
+	retrieve(t, second, "a")
+	retrieve(t, second, "b")
+
+	second.Remove("a")
+	_, retrieveStatus := retrieve(t, first, "a")
+	assert.Equal(t, status.RetrieveStatusKeyMiss, retrieveStatus)
+
+	// without the invalidation the L1 of the first replica still has b
+	l2.Remove("b")
+	_, retrieveStatus = retrieve(t, first, "b")
+	assert.Equal(t, status.RetrieveStatusHit, retrieveStatus)
+
+	second.BulkRemove([]string{"b"})
+	_, retrieveStatus = retrieve(t, first, "b")
+	assert.Equal(t, status.RetrieveStatusKeyMiss, retrieveStatus)
+}
+
+func TestReplace(t *testing.T) {
+	l2 := inmemory.New(nil)
+	b := &bus{}
+	first := newReplica(t, l2, b)
+	second := newReplica(t, l2, b)
+
+	require.NoError(t, first.Store("a", []byte("value"), 0))
+	// cached in the L1 of the second replica
+	retrieve(t, second, "a")
+
+	require.NoError(t, first.Replace("a", []byte("replaced"), 0))
+	data, retrieveStatus := retrieve(t, second, "a")
+	assert.Equal(t, status.RetrieveStatusHit, retrieveStatus)
+	assert.Equal(t, []byte("replaced"), data)
+	data, _ = retrieve(t, first, "a")
+	assert.Equal(t, []byte("replaced"), data)
+}
@@ -0,0 +1,68 @@
+package tiered
+
+import (
+	"context"
+	"encoding/json"
+
+	goredis "github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// invalidator broadcasts the removed keys to the replicas
+type invalidator interface {
+	// publish publishes the removal of the keys
+	publish(keys []string) error
+	// subscribe calls onInvalidate with the keys removed by the replicas until closed, it returns
+	// once the subscription is confirmed
+	subscribe(onInvalidate func(keys []string)) error
+	close() error
+}
+
+// redisInvalidator broadcasts the removed keys over a redis channel
+type redisInvalidator struct {
+	client  *goredis.Client
+	channel string
+	pubsub  *goredis.PubSub
+}
+
+func newRedisInvalidator(client *goredis.Client, channel string) *redisInvalidator {
+	return &redisInvalidator{client: client, channel: channel}
+}
+
+func (i *redisInvalidator) publish(keys []string) error {
+	message, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	return i.client.Publish(context.Background(), i.channel, message).Err()
+}
+
+func (i *redisInvalidator) subscribe(onInvalidate func(keys []string)) error {
+	ctx := context.Background()
+	pubsub := i.client.Subscribe(ctx, i.channel)
+	// wait for the subscription to be confirmed so that an unreachable redis fails the connect
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return err
+	}
+	i.pubsub = pubsub
+	messages := i.pubsub.Channel()
+	go func() {
+		for message := range messages {
+			var keys []string
+			if err := json.Unmarshal([]byte(message.Payload), &keys); err != nil {
+				zap.L().Error("error decoding the cache invalidation", zap.String("payload", message.Payload), zap.Error(err))
+				continue
+			}
+			onInvalidate(keys)
+		}
+	}()
+	return nil
+}
+
+func (i *redisInvalidator) close() error {
+	if i.pubsub == nil {
+		return nil
+	}
+	return i.pubsub.Close()
+}
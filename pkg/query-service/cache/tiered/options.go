@@ -0,0 +1,57 @@
+package tiered
+
+import (
+	"time"
+
+	bounded "go.signoz.io/signoz/pkg/query-service/cache/bounded"
+)
+
+const (
+	defaultL1MaxBytes = 64 * 1024 * 1024
+	defaultL1TTL      = 1 * time.Minute
+	defaultChannel    = "signoz:cache:invalidate"
+)
+
+// Options holds the options for the two-tier cache, the L2 is the redis cache
+type Options struct {
+	// L1 holds the options of the local cache
+	L1 *bounded.Options `yaml:"l1,omitempty"`
+	// L1TTL is the max time to live of the entries in the local cache, it bounds
+	// how long a replica can serve an entry that was changed by another replica
+	L1TTL time.Duration `yaml:"l1TTL,omitempty"`
+	// Invalidation publishes the removed keys so the other replicas drop them from their local cache
+	Invalidation bool `yaml:"invalidation,omitempty"`
+	// Channel is the redis channel of the invalidations
+	Channel string `yaml:"channel,omitempty"`
+}
+
+func defaultOptions() *Options {
+	return &Options{
+		L1:      &bounded.Options{MaxBytes: defaultL1MaxBytes},
+		L1TTL:   defaultL1TTL,
+		Channel: defaultChannel,
+	}
+}
+
+// withDefaults returns a copy of the options with the defaults of the unset options
+func (o *Options) withDefaults() *Options {
+	opts := defaultOptions()
+	if o == nil {
+		return opts
+	}
+	opts.Invalidation = o.Invalidation
+	if o.L1 != nil {
+		l1 := *o.L1
+		if l1.MaxBytes <= 0 {
+			l1.MaxBytes = defaultL1MaxBytes
+		}
+		opts.L1 = &l1
+	}
+	if o.L1TTL > 0 {
+		opts.L1TTL = o.L1TTL
+	}
+	if o.Channel != "" {
+		opts.Channel = o.Channel
+	}
+	return opts
+}
@@ -396,6 +396,10 @@ var StaticFieldsLogsV3 = map[string]v3.AttributeKey{
 
 const SigNozOrderByValue = "#SIGNOZ_VALUE"
 
+// SigNozOtherSeriesValue is the value of the labels of the series which is the sum
+// of the series left out by the top k selection
+const SigNozOtherSeriesValue = "__other__"
+
 const TIMESTAMP = "timestamp"
 
 const FirstQueryGraphLimit = "first_query_graph_limit"
@@ -11,7 +11,7 @@ import (
 	"go.signoz.io/signoz/pkg/query-service/utils"
 )
 
-func PrepareLinksToTraces(start, end time.Time, filterItems []v3.FilterItem) string {
+func PrepareLinksToTraces(start, end time.Time, filters *v3.FilterSet) string {
 
 	// Traces list view expects time in nanoseconds
 	tr := v3.URLShareableTimeRange{
@@ -34,14 +34,11 @@ func PrepareLinksToTraces(start, end time.Time, filterItems []v3.FilterItem) str
 		QueryName:          "A",
 		AggregateOperator:  v3.AggregateOperatorNoOp,
 		AggregateAttribute: v3.AttributeKey{},
-		Filters: &v3.FilterSet{
-			Items:    filterItems,
-			Operator: "AND",
-		},
-		Expression:   "A",
-		Disabled:     false,
-		Having:       []v3.Having{},
-		StepInterval: 60,
+		Filters:            filters,
+		Expression:         "A",
+		Disabled:           false,
+		Having:             []v3.Having{},
+		StepInterval:       60,
 		OrderBy: []v3.OrderBy{
 			{
 				ColumnName: "timestamp",
@@ -69,7 +66,7 @@ func PrepareLinksToTraces(start, end time.Time, filterItems []v3.FilterItem) str
 	return fmt.Sprintf("compositeQuery=%s&timeRange=%s&startTime=%d&endTime=%d&options=%s", compositeQuery, urlEncodedTimeRange, tr.Start, tr.End, urlEncodedOptions)
 }
 
-func PrepareLinksToLogs(start, end time.Time, filterItems []v3.FilterItem) string {
+func PrepareLinksToLogs(start, end time.Time, filters *v3.FilterSet) string {
 
 	// Logs list view expects time in milliseconds
 	tr := v3.URLShareableTimeRange{
@@ -92,14 +89,11 @@ func PrepareLinksToLogs(start, end time.Time, filterItems []v3.FilterItem) strin
 		QueryName:          "A",
 		AggregateOperator:  v3.AggregateOperatorNoOp,
 		AggregateAttribute: v3.AttributeKey{},
-		Filters: &v3.FilterSet{
-			Items:    filterItems,
-			Operator: "AND",
-		},
-		Expression:   "A",
-		Disabled:     false,
-		Having:       []v3.Having{},
-		StepInterval: 60,
+		Filters:            filters,
+		Expression:         "A",
+		Disabled:           false,
+		Having:             []v3.Having{},
+		StepInterval:       60,
 		OrderBy: []v3.OrderBy{
 			{
 				ColumnName: "timestamp",
@@ -147,11 +141,21 @@ func PrepareLinksToLogs(start, end time.Time, filterItems []v3.FilterItem) strin
 // by clause, in which case we replace it with the actual value for the notification
 // i.e Severity text = WARN
 // If the Severity text is not part of the group by clause, then we add it as it is
-func PrepareFilters(labels map[string]string, whereClauseItems []v3.FilterItem, groupByItems []v3.AttributeKey, keys map[string]v3.AttributeKey) []v3.FilterItem {
+//
+// The nested groups of the where clause are carried over unchanged. A label that
+// is only referenced inside a group is added as key = value to the top level items.
+func PrepareFilters(labels map[string]string, whereClause *v3.FilterSet, groupByItems []v3.AttributeKey, keys map[string]v3.AttributeKey) *v3.FilterSet {
 	var filterItems []v3.FilterItem
 
 	added := make(map[string]struct{})
 
+	var whereClauseItems []v3.FilterItem
+	var whereClauseGroups []*v3.FilterSet
+	if whereClause != nil {
+		whereClauseItems = whereClause.Items
+		whereClauseGroups = whereClause.Groups
+	}
+
 	for _, item := range whereClauseItems {
 		exists := false
 		for key, value := range labels {
@@ -218,5 +222,11 @@ func PrepareFilters(labels map[string]string, whereClauseItems []v3.FilterItem,
 		}
 	}
 
-	return filterItems
+	// the nested groups are kept as they are, the labels they refer to are
+	// already added as key = value to the top level items above
+	return &v3.FilterSet{
+		Operator: "AND",
+		Items:    filterItems,
+		Groups:   whereClauseGroups,
+	}
 }
@@ -34,6 +34,7 @@ type Queries interface {
 	GetUsersByGroup(ctx context.Context, groupId string) ([]model.UserPayload, *model.ApiError)
 
 	GetApdexSettings(ctx context.Context, services []string) ([]model.ApdexSettings, *model.ApiError)
+	GetAllApdexSettings(ctx context.Context) ([]model.ApdexSettings, *model.ApiError)
 
 	GetIngestionKeys(ctx context.Context) ([]model.IngestionKey, *model.ApiError)
 
@@ -48,6 +48,19 @@ func (mds *ModelDaoSqlite) GetApdexSettings(ctx context.Context, services []stri
 	return apdexSettings, nil
 }
 
+func (mds *ModelDaoSqlite) GetAllApdexSettings(ctx context.Context) ([]model.ApdexSettings, *model.ApiError) {
+	apdexSettings := []model.ApdexSettings{}
+
+	err := mds.db.Select(&apdexSettings, "SELECT * FROM apdex_settings")
+	if err != nil {
+		return nil, &model.ApiError{
+			Err: err,
+		}
+	}
+
+	return apdexSettings, nil
+}
+
 func (mds *ModelDaoSqlite) SetApdexSettings(ctx context.Context, apdexSettings *model.ApdexSettings) *model.ApiError {
 
 	_, err := mds.db.NamedExec(`
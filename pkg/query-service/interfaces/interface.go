@@ -62,6 +62,7 @@ type Reader interface {
 	// QB V3 metrics/traces/logs
 	GetTimeSeriesResultV3(ctx context.Context, query string) ([]*v3.Series, error)
 	GetListResultV3(ctx context.Context, query string) ([]*v3.Row, error)
+	ExplainQueryV3(ctx context.Context, query string) ([]string, error)
 	LiveTailLogsV3(ctx context.Context, query string, timestampStart uint64, idStart string, client *model.LogsLiveTailClient)
 	LiveTailLogsV4(ctx context.Context, query string, timestampStart uint64, idStart string, client *model.LogsLiveTailClientV2)
 
@@ -113,10 +114,14 @@ type Reader interface {
 	// Query Progress tracking helpers.
 	ReportQueryStartForProgressTracking(queryId string) (reportQueryFinished func(), err *model.ApiError)
 	SubscribeToQueryProgress(queryId string) (<-chan model.QueryProgress, func(), *model.ApiError)
+	GetQueryProgress(queryId string) (*model.QueryProgress, *model.ApiError)
 }
 
 type Querier interface {
 	QueryRange(context.Context, *v3.QueryRangeParamsV3) ([]*v3.Result, map[string]error, error)
+	// Explain returns the queries QueryRange would run without running them, with the
+	// EXPLAIN output of clickhouse if withExplain is set
+	Explain(ctx context.Context, params *v3.QueryRangeParamsV3, withExplain bool) ([]*v3.QueryExplanation, error)
 
 	// test helpers
 	QueriesExecuted() []string
@@ -126,4 +131,6 @@ type Querier interface {
 type QueryCache interface {
 	FindMissingTimeRanges(start, end int64, step int64, cacheKey string) []querycache.MissInterval
 	MergeWithCachedSeriesData(cacheKey string, newData []querycache.CachedSeriesData) []querycache.CachedSeriesData
+	GetCachedListData(cacheKey string) []querycache.CachedListData
+	MergeWithCachedListData(cacheKey string, newData []querycache.CachedListData) []querycache.CachedListData
 }
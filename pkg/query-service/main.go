@@ -8,11 +8,16 @@ import (
 	"syscall"
 	"time"
 
+	"go.opentelemetry.io/collector/confmap"
+	"go.signoz.io/signoz/pkg/config"
+	"go.signoz.io/signoz/pkg/confmap/provider/signozenvprovider"
+	"go.signoz.io/signoz/pkg/instrumentation"
 	"go.signoz.io/signoz/pkg/query-service/app"
 	"go.signoz.io/signoz/pkg/query-service/auth"
 	"go.signoz.io/signoz/pkg/query-service/constants"
 	"go.signoz.io/signoz/pkg/query-service/migrate"
 	"go.signoz.io/signoz/pkg/query-service/version"
+	signozversion "go.signoz.io/signoz/pkg/version"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -34,8 +39,9 @@ func main() {
 	var disableRules bool
 
 	var useLogsNewSchema bool
+	var progressInRedis bool
 	// the url used to build link in the alert messages in slack and other systems
-	var ruleRepoURL, cacheConfigPath, fluxInterval string
+	var ruleRepoURL, cacheConfigPath, fluxInterval, queryLimitsPath string
 	var cluster string
 
 	var preferSpanMetrics bool
@@ -45,12 +51,14 @@ func main() {
 	var dialTimeout time.Duration
 
 	flag.BoolVar(&useLogsNewSchema, "use-logs-new-schema", false, "use logs_v2 schema for logs")
+	flag.BoolVar(&progressInRedis, "experimental.query-progress-redis", false, "(track the query progress in the redis of the cache config so it can be followed from any replica)")
 	flag.StringVar(&promConfigPath, "config", "./config/prometheus.yml", "(prometheus config to read metrics)")
 	flag.StringVar(&skipTopLvlOpsPath, "skip-top-level-ops", "", "(config file to skip top level operations)")
 	flag.BoolVar(&disableRules, "rules.disable", false, "(disable rule evaluation)")
 	flag.BoolVar(&preferSpanMetrics, "prefer-span-metrics", false, "(prefer span metrics for service level metrics)")
 	flag.StringVar(&ruleRepoURL, "rules.repo-url", constants.AlertHelpPage, "(host address used to build rule link in alert messages)")
 	flag.StringVar(&cacheConfigPath, "experimental.cache-config", "", "(cache config to use)")
+	flag.StringVar(&queryLimitsPath, "query-limits", "", "(query limits config of each role)")
 	flag.StringVar(&fluxInterval, "flux-interval", "5m", "(the interval to exclude data from being cached to avoid incorrect cache for data in motion)")
 	flag.StringVar(&cluster, "cluster", "cluster", "(cluster name - defaults to 'cluster')")
 	// Allow using the consistent naming with the signoz collector
@@ -67,6 +75,21 @@ func main() {
 	logger := loggerMgr.Sugar()
 	version.PrintVersion()
 
+	// the instrumentation config is read from the SIGNOZ__INSTRUMENTATION__* environment variables
+	signozConfig, err := config.New(context.Background(), config.ProviderSettings{
+		ResolverSettings: confmap.ResolverSettings{
+			URIs:              []string{"signozenv:"},
+			ProviderFactories: []confmap.ProviderFactory{signozenvprovider.NewFactory()},
+		},
+	})
+	if err != nil {
+		logger.Fatal("Failed to load the config", zap.Error(err))
+	}
+	instr, err := instrumentation.New(context.Background(), signozversion.Build{Name: "query-service", Version: version.GetVersion()}, signozConfig.Instrumentation)
+	if err != nil {
+		logger.Fatal("Failed to create the instrumentation", zap.Error(err))
+	}
+
 	serverOptions := &app.ServerOptions{
 		HTTPHostPort:      constants.HTTPHostPort,
 		PromConfigPath:    promConfigPath,
@@ -82,6 +105,9 @@ func main() {
 		FluxInterval:      fluxInterval,
 		Cluster:           cluster,
 		UseLogsNewSchema:  useLogsNewSchema,
+		QueryLimitsPath:   queryLimitsPath,
+		ProgressInRedis:   progressInRedis,
+		MeterProvider:     instr.MeterProvider,
 	}
 
 	// Read the jwt secret key
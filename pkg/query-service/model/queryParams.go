@@ -453,3 +453,13 @@ type LogsAggregateParams struct {
 	Function       string `json:"function"`
 	StepSeconds    int    `json:"step"`
 }
+
+// InvalidateCacheRequest selects the cache entries to invalidate, the selectors are combined
+// and only the data within [Start, End] milliseconds is invalidated when the time range is set
+type InvalidateCacheRequest struct {
+	Hashes      []string `json:"hashes"`
+	Prefix      string   `json:"prefix"`
+	DashboardID string   `json:"dashboardId"`
+	Start       int64    `json:"start"`
+	End         int64    `json:"end"`
+}
@@ -64,6 +64,7 @@ const (
 	ErrorConflict                 ErrorType = "conflict"
 	ErrorStreamingNotSupported    ErrorType = "streaming is not supported"
 	ErrorStatusServiceUnavailable ErrorType = "service unavailable"
+	ErrorQueryLimitExceeded       ErrorType = "query_limit_exceeded"
 )
 
 // BadRequest returns a ApiError object of bad request
@@ -118,6 +119,14 @@ func ForbiddenError(err error) *ApiError {
 	}
 }
 
+// QueryLimitError returns a ApiError object of a query that exceeds the query limits of the user
+func QueryLimitError(err error) *ApiError {
+	return &ApiError{
+		Typ: ErrorQueryLimitExceeded,
+		Err: err,
+	}
+}
+
 func WrapApiError(err *ApiError, msg string) *ApiError {
 	return &ApiError{
 		Typ: err.Type(),
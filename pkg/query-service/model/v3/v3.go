@@ -2,8 +2,11 @@ package v3
 
 import (
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"math"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
@@ -11,6 +14,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
+	promModel "github.com/prometheus/common/model"
 )
 
 type DataSource string
@@ -49,6 +53,7 @@ const (
 	AggregateOperatorP90           AggregateOperator = "p90"
 	AggregateOperatorP95           AggregateOperator = "p95"
 	AggregateOperatorP99           AggregateOperator = "p99"
+	AggregateOperatorPercentile    AggregateOperator = "percentile"
 	AggregateOperatorRate          AggregateOperator = "rate"
 	AggregateOperatorSumRate       AggregateOperator = "sum_rate"
 	AggregateOperatorAvgRate       AggregateOperator = "avg_rate"
@@ -63,6 +68,7 @@ const (
 	AggregateOperatorHistQuant90   AggregateOperator = "hist_quantile_90"
 	AggregateOperatorHistQuant95   AggregateOperator = "hist_quantile_95"
 	AggregateOperatorHistQuant99   AggregateOperator = "hist_quantile_99"
+	AggregateOperatorApdex         AggregateOperator = "apdex"
 )
 
 func (a AggregateOperator) Validate() error {
@@ -83,6 +89,7 @@ func (a AggregateOperator) Validate() error {
 		AggregateOperatorP90,
 		AggregateOperatorP95,
 		AggregateOperatorP99,
+		AggregateOperatorPercentile,
 		AggregateOperatorRate,
 		AggregateOperatorSumRate,
 		AggregateOperatorAvgRate,
@@ -96,7 +103,8 @@ func (a AggregateOperator) Validate() error {
 		AggregateOperatorHistQuant75,
 		AggregateOperatorHistQuant90,
 		AggregateOperatorHistQuant95,
-		AggregateOperatorHistQuant99:
+		AggregateOperatorHistQuant99,
+		AggregateOperatorApdex:
 		return nil
 	default:
 		return fmt.Errorf("invalid operator: %s", a)
@@ -128,7 +136,8 @@ func (a AggregateOperator) RequireAttribute(dataSource DataSource) bool {
 		switch a {
 		case AggregateOperatorNoOp,
 			AggregateOperatorCount,
-			AggregateOperatorRate:
+			AggregateOperatorRate,
+			AggregateOperatorApdex:
 			return false
 		default:
 			return true
@@ -196,16 +205,17 @@ func (q QueryType) Validate() error {
 type PanelType string
 
 const (
-	PanelTypeValue PanelType = "value"
-	PanelTypeGraph PanelType = "graph"
-	PanelTypeTable PanelType = "table"
-	PanelTypeList  PanelType = "list"
-	PanelTypeTrace PanelType = "trace"
+	PanelTypeValue   PanelType = "value"
+	PanelTypeGraph   PanelType = "graph"
+	PanelTypeTable   PanelType = "table"
+	PanelTypeList    PanelType = "list"
+	PanelTypeTrace   PanelType = "trace"
+	PanelTypeHeatmap PanelType = "heatmap"
 )
 
 func (p PanelType) Validate() error {
 	switch p {
-	case PanelTypeValue, PanelTypeGraph, PanelTypeTable, PanelTypeList, PanelTypeTrace:
+	case PanelTypeValue, PanelTypeGraph, PanelTypeTable, PanelTypeList, PanelTypeTrace, PanelTypeHeatmap:
 		return nil
 	default:
 		return fmt.Errorf("invalid panel type: %s", p)
@@ -376,8 +386,82 @@ type QueryRangeParamsV3 struct {
 	NoCache        bool                   `json:"noCache"`
 	Version        string                 `json:"-"`
 	FormatForWeb   bool                   `json:"formatForWeb,omitempty"`
+	// Compare runs the builder queries for the offsets, see CompareOptions
+	Compare *CompareOptions `json:"compare,omitempty"`
+}
+
+// MaxCompareOffsets is the maximum number of offsets of the compare option
+const MaxCompareOffsets = 8
+
+// CompareOptions runs each builder query again for the offsets and adds the
+// change from the shifted query e.g this week vs last week with the offset 1w
+type CompareOptions struct {
+	// Offsets are the durations the queries are shifted by e.g 1d, 1w
+	Offsets []string `json:"offsets"`
+	// Average adds the average of the shifted queries e.g the 4 week average
+	// with the offsets 1w, 2w, 3w, 4w
+	Average bool `json:"average,omitempty"`
+}
+
+func (c *CompareOptions) Clone() *CompareOptions {
+	if c == nil {
+		return nil
+	}
+	return &CompareOptions{
+		Offsets: append([]string(nil), c.Offsets...),
+		Average: c.Average,
+	}
+}
+
+// Durations returns the offsets as durations
+func (c *CompareOptions) Durations() ([]time.Duration, error) {
+	durations := make([]time.Duration, 0, len(c.Offsets))
+	for _, offset := range c.Offsets {
+		duration, err := promModel.ParseDuration(offset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compare offset %s: %w", offset, err)
+		}
+		if duration <= 0 {
+			return nil, fmt.Errorf("compare offset %s should be greater than 0", offset)
+		}
+		durations = append(durations, time.Duration(duration))
+	}
+	return durations, nil
+}
+
+func (c *CompareOptions) Validate() error {
+	if len(c.Offsets) == 0 {
+		return fmt.Errorf("compare offsets are required")
+	}
+	if len(c.Offsets) > MaxCompareOffsets {
+		return fmt.Errorf("compare supports at most %d offsets, got %d", MaxCompareOffsets, len(c.Offsets))
+	}
+	durations, err := c.Durations()
+	if err != nil {
+		return err
+	}
+	seen := make(map[time.Duration]struct{}, len(durations))
+	for idx, duration := range durations {
+		if _, ok := seen[duration]; ok {
+			return fmt.Errorf("duplicate compare offset %s", c.Offsets[idx])
+		}
+		seen[duration] = struct{}{}
+	}
+	return nil
 }
 
+// CompareKind is the kind of the series of a query added for the compare option
+type CompareKind string
+
+const (
+	// CompareKindShifted is the query shifted by the offset, or the average of the shifted queries
+	CompareKindShifted CompareKind = "shifted"
+	// CompareKindDelta is the difference of the query and the shifted query
+	CompareKindDelta CompareKind = "delta"
+	// CompareKindPercent is the change of the query from the shifted query in percent
+	CompareKindPercent CompareKind = "percent"
+)
+
 func (q *QueryRangeParamsV3) Clone() *QueryRangeParamsV3 {
 	if q == nil {
 		return nil
@@ -391,14 +475,30 @@ func (q *QueryRangeParamsV3) Clone() *QueryRangeParamsV3 {
 		NoCache:        q.NoCache,
 		Version:        q.Version,
 		FormatForWeb:   q.FormatForWeb,
+		Compare:        q.Compare.Clone(),
 	}
 }
 
+// QueryRangeTextParams is the query range request with the builder queries in the text form
+// e.g {"A": "logs | where severity_text = \"ERROR\" | count() | every 1m"}
+type QueryRangeTextParams struct {
+	Start        int64                  `json:"start"`
+	End          int64                  `json:"end"`
+	Step         int64                  `json:"step"`
+	PanelType    PanelType              `json:"panelType"`
+	Queries      map[string]string      `json:"queries"`
+	Variables    map[string]interface{} `json:"variables,omitempty"`
+	NoCache      bool                   `json:"noCache"`
+	FormatForWeb bool                   `json:"formatForWeb,omitempty"`
+}
+
 type PromQuery struct {
 	Query    string `json:"query"`
 	Stats    string `json:"stats,omitempty"`
 	Disabled bool   `json:"disabled"`
 	Legend   string `json:"legend,omitempty"`
+	// Functions are applied to the series of the result, in order
+	Functions []Function `json:"functions,omitempty"`
 }
 
 func (p *PromQuery) Clone() *PromQuery {
@@ -406,10 +506,11 @@ func (p *PromQuery) Clone() *PromQuery {
 		return nil
 	}
 	return &PromQuery{
-		Query:    p.Query,
-		Stats:    p.Stats,
-		Disabled: p.Disabled,
-		Legend:   p.Legend,
+		Query:     p.Query,
+		Stats:     p.Stats,
+		Disabled:  p.Disabled,
+		Legend:    p.Legend,
+		Functions: p.Functions,
 	}
 }
 
@@ -422,13 +523,15 @@ func (p *PromQuery) Validate() error {
 		return fmt.Errorf("query is empty")
 	}
 
-	return nil
+	return validateResultFunctions(p.Functions)
 }
 
 type ClickHouseQuery struct {
 	Query    string `json:"query"`
 	Disabled bool   `json:"disabled"`
 	Legend   string `json:"legend,omitempty"`
+	// Functions are applied to the series of the result, in order
+	Functions []Function `json:"functions,omitempty"`
 }
 
 func (c *ClickHouseQuery) Clone() *ClickHouseQuery {
@@ -436,9 +539,10 @@ func (c *ClickHouseQuery) Clone() *ClickHouseQuery {
 		return nil
 	}
 	return &ClickHouseQuery{
-		Query:    c.Query,
-		Disabled: c.Disabled,
-		Legend:   c.Legend,
+		Query:     c.Query,
+		Disabled:  c.Disabled,
+		Legend:    c.Legend,
+		Functions: c.Functions,
 	}
 }
 func (c *ClickHouseQuery) Validate() error {
@@ -450,6 +554,20 @@ func (c *ClickHouseQuery) Validate() error {
 		return fmt.Errorf("query is empty")
 	}
 
+	return validateResultFunctions(c.Functions)
+}
+
+// validateResultFunctions validates the functions of the promql and clickhouse queries, the
+// functions that change the query e.g timeShift are only supported for the builder queries
+func validateResultFunctions(functions []Function) error {
+	for idx := range functions {
+		if err := functions[idx].Validate(); err != nil {
+			return err
+		}
+		if functions[idx].Name == FunctionNameTimeShift || functions[idx].Name == FunctionNameAnomaly {
+			return fmt.Errorf("function %s is only supported for builder queries", functions[idx].Name)
+		}
+	}
 	return nil
 }
 
@@ -464,6 +582,8 @@ type CompositeQuery struct {
 	Unit string `json:"unit,omitempty"`
 	// FillGaps is used to fill the gaps in the time series data
 	FillGaps bool `json:"fillGaps,omitempty"`
+	// Table is the sorting, pagination and formatting of the rows of the table panel
+	Table *TableOptions `json:"table,omitempty"`
 }
 
 func (c *CompositeQuery) Clone() *CompositeQuery {
@@ -499,6 +619,7 @@ func (c *CompositeQuery) Clone() *CompositeQuery {
 		QueryType:         c.QueryType,
 		Unit:              c.Unit,
 		FillGaps:          c.FillGaps,
+		Table:             c.Table.Clone(),
 	}
 
 }
@@ -549,6 +670,19 @@ func (c *CompositeQuery) Validate() error {
 		return fmt.Errorf("composite query must contain at least one query type")
 	}
 
+	if c.PanelType == PanelTypeHeatmap && c.QueryType != QueryTypeBuilder {
+		return fmt.Errorf("heatmap panel type is only supported for builder queries")
+	}
+
+	if c.Table != nil {
+		if c.PanelType != PanelTypeTable || c.QueryType != QueryTypeBuilder {
+			return fmt.Errorf("table options are only supported for the table panel of builder queries")
+		}
+		if err := c.Table.Validate(); err != nil {
+			return fmt.Errorf("table options are invalid: %w", err)
+		}
+	}
+
 	if c.QueryType == QueryTypeBuilder {
 		for name, query := range c.BuilderQueries {
 			if err := query.Validate(c.PanelType); err != nil {
@@ -658,6 +792,7 @@ const (
 	SpaceAggregationPercentile90 SpaceAggregation = "p90"
 	SpaceAggregationPercentile95 SpaceAggregation = "p95"
 	SpaceAggregationPercentile99 SpaceAggregation = "p99"
+	SpaceAggregationPercentile   SpaceAggregation = "percentile"
 )
 
 func (s SpaceAggregation) Validate() error {
@@ -671,7 +806,8 @@ func (s SpaceAggregation) Validate() error {
 		SpaceAggregationPercentile75,
 		SpaceAggregationPercentile90,
 		SpaceAggregationPercentile95,
-		SpaceAggregationPercentile99:
+		SpaceAggregationPercentile99,
+		SpaceAggregationPercentile:
 		return nil
 	default:
 		return fmt.Errorf("invalid space aggregation: %s", s)
@@ -684,7 +820,8 @@ func IsPercentileOperator(operator SpaceAggregation) bool {
 		SpaceAggregationPercentile75,
 		SpaceAggregationPercentile90,
 		SpaceAggregationPercentile95,
-		SpaceAggregationPercentile99:
+		SpaceAggregationPercentile99,
+		SpaceAggregationPercentile:
 		return true
 	default:
 		return false
@@ -729,6 +866,37 @@ const (
 	FunctionNameMedian7     FunctionName = "median7"
 	FunctionNameTimeShift   FunctionName = "timeShift"
 	FunctionNameAnomaly     FunctionName = "anomaly"
+
+	// the moving functions take the window as the first argument, the window is
+	// a number of points or a duration e.g 5m
+	FunctionNameMovingAvg         FunctionName = "movingAvg"
+	FunctionNameMovingSum         FunctionName = "movingSum"
+	FunctionNameMovingMin         FunctionName = "movingMin"
+	FunctionNameMovingMax         FunctionName = "movingMax"
+	FunctionNameMovingStdDev      FunctionName = "movingStdDev"
+	FunctionNameRollingPercentile FunctionName = "rollingPercentile"
+	FunctionNameZScore            FunctionName = "zScore"
+	FunctionNameRateOfChange      FunctionName = "rateOfChange"
+
+	// the aggregate by functions take the labels to group the series by as the arguments,
+	// the series are aggregated into one series if there are no labels
+	FunctionNameSumBy   FunctionName = "sumBy"
+	FunctionNameAvgBy   FunctionName = "avgBy"
+	FunctionNameMinBy   FunctionName = "minBy"
+	FunctionNameMaxBy   FunctionName = "maxBy"
+	FunctionNameCountBy FunctionName = "countBy"
+	// labelReplace takes the destination label, the replacement, the source label and the regex
+	// like the label_replace of PromQL
+	FunctionNameLabelReplace FunctionName = "labelReplace"
+	// labelJoin takes the destination label, the separator and the source labels like the
+	// label_join of PromQL
+	FunctionNameLabelJoin FunctionName = "labelJoin"
+	// forecast extends the series into the future, the predicted points and the confidence
+	// bands are set in the predicted, upper bound and lower bound series of the result
+	FunctionNameForecast FunctionName = "forecast"
+	// outliers flags the series far from the other series of the result with the outlier
+	// label, the scores are set in the anomaly scores of the result
+	FunctionNameOutliers FunctionName = "outliers"
 )
 
 func (f FunctionName) Validate() error {
@@ -749,7 +917,24 @@ func (f FunctionName) Validate() error {
 		FunctionNameMedian5,
 		FunctionNameMedian7,
 		FunctionNameTimeShift,
-		FunctionNameAnomaly:
+		FunctionNameAnomaly,
+		FunctionNameMovingAvg,
+		FunctionNameMovingSum,
+		FunctionNameMovingMin,
+		FunctionNameMovingMax,
+		FunctionNameMovingStdDev,
+		FunctionNameRollingPercentile,
+		FunctionNameZScore,
+		FunctionNameRateOfChange,
+		FunctionNameSumBy,
+		FunctionNameAvgBy,
+		FunctionNameMinBy,
+		FunctionNameMaxBy,
+		FunctionNameCountBy,
+		FunctionNameLabelReplace,
+		FunctionNameLabelJoin,
+		FunctionNameForecast,
+		FunctionNameOutliers:
 		return nil
 	default:
 		return fmt.Errorf("invalid function name: %s", f)
@@ -762,6 +947,557 @@ type Function struct {
 	NamedArgs map[string]interface{} `json:"namedArgs,omitempty"`
 }
 
+// IsMovingWindow returns true if the function is computed over a moving window of points
+func (f FunctionName) IsMovingWindow() bool {
+	switch f {
+	case FunctionNameMovingAvg,
+		FunctionNameMovingSum,
+		FunctionNameMovingMin,
+		FunctionNameMovingMax,
+		FunctionNameMovingStdDev,
+		FunctionNameRollingPercentile:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsAggregateBy returns true if the function aggregates the series by labels
+func (f FunctionName) IsAggregateBy() bool {
+	switch f {
+	case FunctionNameSumBy,
+		FunctionNameAvgBy,
+		FunctionNameMinBy,
+		FunctionNameMaxBy,
+		FunctionNameCountBy:
+		return true
+	default:
+		return false
+	}
+}
+
+// Seasonality is the period after which the pattern of a series repeats
+type Seasonality string
+
+const (
+	SeasonalityHourly Seasonality = "hourly"
+	SeasonalityDaily  Seasonality = "daily"
+	SeasonalityWeekly Seasonality = "weekly"
+)
+
+func (s Seasonality) String() string {
+	return string(s)
+}
+
+func (s Seasonality) IsValid() bool {
+	switch s {
+	case SeasonalityHourly, SeasonalityDaily, SeasonalityWeekly:
+		return true
+	default:
+		return false
+	}
+}
+
+// Period returns the duration of a season
+func (s Seasonality) Period() time.Duration {
+	switch s {
+	case SeasonalityHourly:
+		return time.Hour
+	case SeasonalityWeekly:
+		return 7 * 24 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+type ForecastMethod string
+
+const (
+	ForecastMethodLinear      ForecastMethod = "linear"
+	ForecastMethodHoltWinters ForecastMethod = "holtWinters"
+)
+
+// ForecastParams are the named arguments of the forecast function
+type ForecastParams struct {
+	Method ForecastMethod
+	// Horizon is how far the series is extended after its last point
+	Horizon time.Duration
+	// Seasonality is the season of the Holt-Winters method
+	Seasonality Seasonality
+	// Confidence is the probability (0-1) of a value being within the bounds
+	Confidence float64
+	// Alpha, Beta and Gamma are the smoothing factors of the level, trend and season
+	// of the Holt-Winters method
+	Alpha float64
+	Beta  float64
+	Gamma float64
+}
+
+// floatNamedArg returns the named argument as float64, the numbers sent as strings are converted
+func (f *Function) floatNamedArg(name string, defaultValue float64) (float64, error) {
+	arg, ok := f.NamedArgs[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	switch value := arg.(type) {
+	case float64:
+		return value, nil
+	case string:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s param of %s should be a number", name, f.Name)
+		}
+		return number, nil
+	default:
+		return 0, fmt.Errorf("%s param of %s should be a number", name, f.Name)
+	}
+}
+
+// ForecastParams returns the named arguments of the forecast function with the defaults
+// for the missing ones. The horizon is a duration e.g 12h, 7d
+func (f *Function) ForecastParams() (ForecastParams, error) {
+	params := ForecastParams{
+		Method:      ForecastMethodLinear,
+		Seasonality: SeasonalityDaily,
+	}
+
+	if method, ok := f.NamedArgs["method"]; ok {
+		str, _ := method.(string)
+		params.Method = ForecastMethod(str)
+		if params.Method != ForecastMethodLinear && params.Method != ForecastMethodHoltWinters {
+			return params, fmt.Errorf("method param of forecast should be linear or holtWinters")
+		}
+	}
+
+	horizon, _ := f.NamedArgs["horizon"].(string)
+	if horizon == "" {
+		return params, fmt.Errorf("horizon param missing in forecast")
+	}
+	duration, err := promModel.ParseDuration(horizon)
+	if err != nil || duration <= 0 {
+		return params, fmt.Errorf("horizon param of forecast should be a duration e.g 12h, 7d")
+	}
+	params.Horizon = time.Duration(duration)
+
+	if seasonality, ok := f.NamedArgs["seasonality"]; ok {
+		str, _ := seasonality.(string)
+		params.Seasonality = Seasonality(str)
+		if !params.Seasonality.IsValid() {
+			return params, fmt.Errorf("seasonality param of forecast should be hourly, daily or weekly")
+		}
+	}
+
+	if params.Confidence, err = f.floatNamedArg("confidence", 0.95); err != nil {
+		return params, err
+	}
+	if params.Confidence <= 0 || params.Confidence >= 1 {
+		return params, fmt.Errorf("confidence param of forecast should be between 0 and 1")
+	}
+
+	for _, factor := range []struct {
+		name         string
+		value        *float64
+		defaultValue float64
+	}{
+		{"alpha", &params.Alpha, 0.5},
+		{"beta", &params.Beta, 0.1},
+		{"gamma", &params.Gamma, 0.3},
+	} {
+		if *factor.value, err = f.floatNamedArg(factor.name, factor.defaultValue); err != nil {
+			return params, err
+		}
+		if *factor.value < 0 || *factor.value > 1 {
+			return params, fmt.Errorf("%s param of forecast should be between 0 and 1", factor.name)
+		}
+	}
+	return params, nil
+}
+
+type OutlierAlgorithm string
+
+const (
+	// OutlierAlgorithmMAD scores each point by its distance from the median of the points of
+	// the series at the timestamp in median absolute deviations
+	OutlierAlgorithmMAD OutlierAlgorithm = "mad"
+	// OutlierAlgorithmDBSCAN clusters the series by density with DBSCAN over the distances
+	// between the series, the series that are in no cluster are the outliers
+	OutlierAlgorithmDBSCAN OutlierAlgorithm = "dbscan"
+)
+
+// OutlierParams are the named arguments of the outliers function
+type OutlierParams struct {
+	Algorithm OutlierAlgorithm
+	// Tolerance is the score above which a point or a series is far from the other series
+	Tolerance float64
+	// Percentage is the percentage (0-100) of the points of a series that should be above
+	// the tolerance for the series to be an outlier with the MAD algorithm
+	Percentage float64
+	// Eps is the max distance between the series of a neighbourhood with the DBSCAN algorithm,
+	// zero for the tolerance times the median distance of the series from the median series
+	Eps float64
+	// MinPts is the min number of the series, the series included, in the neighbourhood of
+	// a core series of a cluster with the DBSCAN algorithm
+	MinPts int
+}
+
+// OutlierParams returns the named arguments of the outliers function with the defaults
+// for the missing ones
+func (f *Function) OutlierParams() (OutlierParams, error) {
+	params := OutlierParams{Algorithm: OutlierAlgorithmMAD}
+
+	if algorithm, ok := f.NamedArgs["algorithm"]; ok {
+		str, _ := algorithm.(string)
+		params.Algorithm = OutlierAlgorithm(str)
+		if params.Algorithm != OutlierAlgorithmMAD && params.Algorithm != OutlierAlgorithmDBSCAN {
+			return params, fmt.Errorf("algorithm param of outliers should be mad or dbscan")
+		}
+	}
+
+	var err error
+	if params.Tolerance, err = f.floatNamedArg("tolerance", 3); err != nil {
+		return params, err
+	}
+	if params.Tolerance <= 0 {
+		return params, fmt.Errorf("tolerance param of outliers should be greater than 0")
+	}
+	if params.Percentage, err = f.floatNamedArg("percentage", 20); err != nil {
+		return params, err
+	}
+	if params.Percentage <= 0 || params.Percentage > 100 {
+		return params, fmt.Errorf("percentage param of outliers should be between 0 and 100")
+	}
+	if params.Eps, err = f.floatNamedArg("eps", 0); err != nil {
+		return params, err
+	}
+	if params.Eps < 0 {
+		return params, fmt.Errorf("eps param of outliers should not be negative")
+	}
+	minPts, err := f.floatNamedArg("minPts", 2)
+	if err != nil {
+		return params, err
+	}
+	if minPts < 1 || minPts != math.Trunc(minPts) {
+		return params, fmt.Errorf("minPts param of outliers should be a positive integer")
+	}
+	params.MinPts = int(minPts)
+	return params, nil
+}
+
+// LabelReplaceRegex returns the regex of the labelReplace function, the regex is anchored
+// to match the whole label value like the label_replace of PromQL
+func LabelReplaceRegex(regex string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + regex + ")$")
+}
+
+// stringArgs returns the arguments of the function, all the arguments must be strings
+func (f *Function) stringArgs() ([]string, error) {
+	args := make([]string, 0, len(f.Args))
+	for _, arg := range f.Args {
+		str, ok := arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("params of %s should be strings", f.Name)
+		}
+		args = append(args, str)
+	}
+	return args, nil
+}
+
+// validateWindowArg validates the window argument at idx, the window is a number of points
+// or a duration e.g 5m. The numbers sent as strings are converted to float64
+func (f *Function) validateWindowArg(idx int) error {
+	if len(f.Args) <= idx {
+		return fmt.Errorf("window param missing in %s", f.Name)
+	}
+	switch window := f.Args[idx].(type) {
+	case float64:
+		if window < 1 || window != math.Trunc(window) {
+			return fmt.Errorf("window param of %s should be a positive number of points", f.Name)
+		}
+	case string:
+		if points, err := strconv.ParseFloat(window, 64); err == nil {
+			f.Args[idx] = points
+			return f.validateWindowArg(idx)
+		}
+		duration, err := time.ParseDuration(window)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("window param of %s should be a number of points or a duration e.g 5m", f.Name)
+		}
+	default:
+		return fmt.Errorf("window param of %s should be a number of points or a duration e.g 5m", f.Name)
+	}
+	return nil
+}
+
+// validatePercentileArg validates the percentile argument at idx, the percentile is between 0 and 100
+func (f *Function) validatePercentileArg(idx int) error {
+	if len(f.Args) <= idx {
+		return fmt.Errorf("percentile param missing in %s", f.Name)
+	}
+	percentile, ok := f.Args[idx].(float64)
+	if !ok {
+		str, isString := f.Args[idx].(string)
+		var err error
+		percentile, err = strconv.ParseFloat(str, 64)
+		if !isString || err != nil {
+			return fmt.Errorf("percentile param should be a number")
+		}
+		f.Args[idx] = percentile
+	}
+	if percentile < 0 || percentile > 100 {
+		return fmt.Errorf("percentile param should be between 0 and 100")
+	}
+	return nil
+}
+
+// Validate validates the name and the arguments of the function, the numbers sent as
+// strings are converted to float64
+func (f *Function) Validate() error {
+	if err := f.Name.Validate(); err != nil {
+		return fmt.Errorf("function name is invalid: %w", err)
+	}
+	if f.Name.IsMovingWindow() {
+		if err := f.validateWindowArg(0); err != nil {
+			return err
+		}
+		if f.Name == FunctionNameRollingPercentile {
+			if err := f.validatePercentileArg(1); err != nil {
+				return err
+			}
+		}
+	} else if f.Name == FunctionNameZScore {
+		// the z-score is computed over the whole series without a window
+		if len(f.Args) > 0 {
+			if err := f.validateWindowArg(0); err != nil {
+				return err
+			}
+		}
+	} else if f.Name.IsAggregateBy() {
+		if _, err := f.stringArgs(); err != nil {
+			return err
+		}
+	} else if f.Name == FunctionNameLabelReplace {
+		args, err := f.stringArgs()
+		if err != nil {
+			return err
+		}
+		if len(args) != 4 {
+			return fmt.Errorf("labelReplace takes the destination label, replacement, source label and regex params")
+		}
+		if _, err := LabelReplaceRegex(args[3]); err != nil {
+			return fmt.Errorf("regex param of labelReplace is invalid: %w", err)
+		}
+	} else if f.Name == FunctionNameLabelJoin {
+		args, err := f.stringArgs()
+		if err != nil {
+			return err
+		}
+		if len(args) < 3 {
+			return fmt.Errorf("labelJoin takes the destination label, separator and at least one source label params")
+		}
+	} else if f.Name == FunctionNameForecast {
+		if _, err := f.ForecastParams(); err != nil {
+			return err
+		}
+	} else if f.Name == FunctionNameOutliers {
+		if _, err := f.OutlierParams(); err != nil {
+			return err
+		}
+	} else if f.Name == FunctionNameTimeShift {
+		if len(f.Args) == 0 {
+			return fmt.Errorf("timeShiftBy param missing in query")
+		}
+		_, ok := f.Args[0].(float64)
+		if !ok {
+			// if string, attempt to convert to float
+			timeShiftBy, err := strconv.ParseFloat(f.Args[0].(string), 64)
+			if err != nil {
+				return fmt.Errorf("timeShiftBy param should be a number")
+			}
+			f.Args[0] = timeShiftBy
+		}
+	} else if f.Name == FunctionNameEWMA3 ||
+		f.Name == FunctionNameEWMA5 ||
+		f.Name == FunctionNameEWMA7 {
+		if len(f.Args) == 0 {
+			return fmt.Errorf("alpha param missing in query")
+		}
+		alpha, ok := f.Args[0].(float64)
+		if !ok {
+			// if string, attempt to convert to float
+			alpha, err := strconv.ParseFloat(f.Args[0].(string), 64)
+			if err != nil {
+				return fmt.Errorf("alpha param should be a float")
+			}
+			f.Args[0] = alpha
+		}
+		if alpha < 0 || alpha > 1 {
+			return fmt.Errorf("alpha param should be between 0 and 1")
+		}
+	} else if f.Name == FunctionNameCutOffMax ||
+		f.Name == FunctionNameCutOffMin ||
+		f.Name == FunctionNameClampMax ||
+		f.Name == FunctionNameClampMin {
+		if len(f.Args) == 0 {
+			return fmt.Errorf("threshold param missing in query")
+		}
+		_, ok := f.Args[0].(float64)
+		if !ok {
+			// if string, attempt to convert to float
+			threshold, err := strconv.ParseFloat(f.Args[0].(string), 64)
+			if err != nil {
+				return fmt.Errorf("threshold param should be a float")
+			}
+			f.Args[0] = threshold
+		}
+	}
+	return nil
+}
+
+type TopKDirection string
+
+const (
+	TopKDirectionTop    TopKDirection = "top"
+	TopKDirectionBottom TopKDirection = "bottom"
+)
+
+func (d TopKDirection) Validate() error {
+	switch d {
+	case TopKDirectionTop, TopKDirectionBottom:
+		return nil
+	default:
+		return fmt.Errorf("invalid top k direction: %s", d)
+	}
+}
+
+// TopK selects the K series with the highest or the lowest value of the reducer over the
+// window, the rest of the series are summed into the other series if Other is set
+type TopK struct {
+	Direction TopKDirection    `json:"direction"`
+	K         uint64           `json:"k"`
+	ReduceTo  ReduceToOperator `json:"reduceTo"`
+	Other     bool             `json:"other,omitempty"`
+}
+
+func (t *TopK) Validate() error {
+	if err := t.Direction.Validate(); err != nil {
+		return err
+	}
+	if t.K == 0 {
+		return fmt.Errorf("k should be greater than 0")
+	}
+	return t.ReduceTo.Validate()
+}
+
+// DefaultApdexThreshold is the threshold of the services without apdex settings, in seconds
+const DefaultApdexThreshold = 0.5
+
+// ApdexRatio is the value of the apdex aggregation
+type ApdexRatio string
+
+const (
+	// ApdexRatioScore is the apdex score, (satisfied + tolerating / 2) / total
+	ApdexRatioScore ApdexRatio = "score"
+	// ApdexRatioSatisfied is the ratio of the spans without errors faster than the threshold
+	ApdexRatioSatisfied ApdexRatio = "satisfied"
+	// ApdexRatioTolerating is the ratio of the spans without errors faster than four times the threshold
+	ApdexRatioTolerating ApdexRatio = "tolerating"
+	// ApdexRatioFrustrated is the ratio of the spans with errors or slower than four times the threshold
+	ApdexRatioFrustrated ApdexRatio = "frustrated"
+)
+
+func (a ApdexRatio) Validate() error {
+	switch a {
+	case ApdexRatioScore, ApdexRatioSatisfied, ApdexRatioTolerating, ApdexRatioFrustrated:
+		return nil
+	default:
+		return fmt.Errorf("invalid apdex ratio %s, should be one of score, satisfied, tolerating, frustrated", a)
+	}
+}
+
+// ApdexOptions are the options of the apdex aggregate operator. The threshold of the span is
+// the threshold of its service stored in the apdex settings unless Threshold overrides it
+type ApdexOptions struct {
+	Threshold float64    `json:"threshold,omitempty"`
+	Ratio     ApdexRatio `json:"ratio,omitempty"`
+	// ServiceThresholds are the stored thresholds by service, set by the query service
+	ServiceThresholds map[string]float64 `json:"-"`
+}
+
+func (a *ApdexOptions) Clone() *ApdexOptions {
+	if a == nil {
+		return nil
+	}
+	clone := *a
+	if a.ServiceThresholds != nil {
+		clone.ServiceThresholds = make(map[string]float64, len(a.ServiceThresholds))
+		for service, threshold := range a.ServiceThresholds {
+			clone.ServiceThresholds[service] = threshold
+		}
+	}
+	return &clone
+}
+
+// GetRatio returns the ratio of the options, the score by default
+func (a *ApdexOptions) GetRatio() ApdexRatio {
+	if a == nil || a.Ratio == "" {
+		return ApdexRatioScore
+	}
+	return a.Ratio
+}
+
+func (a *ApdexOptions) Validate() error {
+	if a.Threshold < 0 {
+		return fmt.Errorf("apdex threshold should be positive, got %v", a.Threshold)
+	}
+	if a.Ratio != "" {
+		return a.Ratio.Validate()
+	}
+	return nil
+}
+
+type JoinType string
+
+const (
+	JoinTypeInner JoinType = "inner"
+	JoinTypeLeft  JoinType = "left"
+	JoinTypeOuter JoinType = "outer"
+)
+
+func (j JoinType) Validate() error {
+	switch j {
+	case JoinTypeInner, JoinTypeLeft, JoinTypeOuter:
+		return nil
+	default:
+		return fmt.Errorf("invalid join type: %s", j)
+	}
+}
+
+// Join joins the series of the queries in the formula on the value of the Key label instead
+// of the label sets of the series, so that the queries of different data sources can be used
+// in the same formula. Rename maps the query name to the label of the query that is joined as
+// Key, e.g. serviceName of the traces query joined with service.name of the logs query.
+// The left side of the left join is the first query in the expression
+type Join struct {
+	Type   JoinType          `json:"type"`
+	Key    string            `json:"key"`
+	Rename map[string]string `json:"rename,omitempty"`
+}
+
+func (j *Join) Validate() error {
+	if err := j.Type.Validate(); err != nil {
+		return err
+	}
+	if j.Key == "" {
+		return fmt.Errorf("join key is required")
+	}
+	for queryName, label := range j.Rename {
+		if label == "" {
+			return fmt.Errorf("join label of query %s is empty", queryName)
+		}
+	}
+	return nil
+}
+
 type BuilderQuery struct {
 	QueryName            string            `json:"queryName"`
 	StepInterval         int64             `json:"stepInterval"`
@@ -783,10 +1519,19 @@ type BuilderQuery struct {
 	SelectColumns        []AttributeKey    `json:"selectColumns,omitempty"`
 	TimeAggregation      TimeAggregation   `json:"timeAggregation,omitempty"`
 	SpaceAggregation     SpaceAggregation  `json:"spaceAggregation,omitempty"`
+	Quantile             float64           `json:"quantile,omitempty"`
 	Functions            []Function        `json:"functions,omitempty"`
+	Buckets              []float64         `json:"buckets,omitempty"`
+	TopK                 *TopK             `json:"topK,omitempty"`
+	Join                 *Join             `json:"join,omitempty"`
+	FillMode             FillMode          `json:"fillMode,omitempty"`
+	Apdex                *ApdexOptions     `json:"apdex,omitempty"`
 	ShiftBy              int64
 	IsAnomaly            bool
 	QueriesUsedInFormula []string
+	// CompareOffset and CompareKind are set on the queries added for the compare option
+	CompareOffset string
+	CompareKind   CompareKind
 }
 
 func (b *BuilderQuery) Clone() *BuilderQuery {
@@ -814,21 +1559,65 @@ func (b *BuilderQuery) Clone() *BuilderQuery {
 		SelectColumns:        b.SelectColumns,
 		TimeAggregation:      b.TimeAggregation,
 		SpaceAggregation:     b.SpaceAggregation,
+		Quantile:             b.Quantile,
 		Functions:            b.Functions,
+		Buckets:              b.Buckets,
+		TopK:                 b.TopK,
+		Join:                 b.Join,
+		FillMode:             b.FillMode,
+		Apdex:                b.Apdex.Clone(),
 		ShiftBy:              b.ShiftBy,
 		IsAnomaly:            b.IsAnomaly,
 		QueriesUsedInFormula: b.QueriesUsedInFormula,
+		CompareOffset:        b.CompareOffset,
+		CompareKind:          b.CompareKind,
 	}
 }
 
-// CanDefaultZero returns true if the missing value can be substituted by zero
-// For example, for an aggregation window [Tx - Tx+1], with an aggregation operator `count`
-// The lack of data can always be interpreted as zero. No data for requests count = zero requests
-// This is true for all aggregations that have `count`ing involved.
+// FillMode is how the missing points of a series are filled
+type FillMode string
+
+const (
+	// FillModeNone doesn't fill the missing points
+	FillModeNone FillMode = "none"
+	// FillModeZero fills the missing points with zero
+	FillModeZero FillMode = "zero"
+	// FillModePrevious fills the missing points with the previous value of the series, for at most
+	// MaxFillPreviousSteps steps after it
+	FillModePrevious FillMode = "previous"
+	// FillModeLinear fills the missing points between two points with the linear interpolation
+	FillModeLinear FillMode = "linear"
+	// FillModeNull fills the missing points with NaN so that they are shown as gaps
+	FillModeNull FillMode = "null"
+)
+
+// MaxFillPreviousSteps is the number of steps the previous value of a series is carried forward for,
+// the series is missing afterwards
+const MaxFillPreviousSteps = 5
+
+func (f FillMode) Validate() error {
+	switch f {
+	case FillModeNone, FillModeZero, FillModePrevious, FillModeLinear, FillModeNull:
+		return nil
+	default:
+		return fmt.Errorf("invalid fill mode %s, should be one of none, zero, previous, linear, null", f)
+	}
+}
+
+// GapFillMode returns the fill mode of the query, or the default of the aggregation if it's not set.
+// For an aggregation window [Tx - Tx+1], with an aggregation operator `count`
+// the lack of data can always be interpreted as zero. No data for requests count = zero requests
+// This is true for all aggregations that have `count`ing involved, and for the rates.
+//
+// The gauges i.e the metrics aggregated with avg, min, max or latest in time keep their previous
+// value for up to MaxFillPreviousSteps steps, zero would be a wrong reading.
 //
 // The same can't be true for others, `sum` of no values doesn't necessarily mean zero.
 // We can't decide whether or not should it be zero.
-func (b *BuilderQuery) CanDefaultZero() bool {
+func (b *BuilderQuery) GapFillMode() FillMode {
+	if b.FillMode != "" {
+		return b.FillMode
+	}
 	switch b.DataSource {
 	case DataSourceMetrics:
 		if b.AggregateOperator.IsRateOperator() ||
@@ -837,16 +1626,36 @@ func (b *BuilderQuery) CanDefaultZero() bool {
 			b.AggregateOperator == AggregateOperatorCountDistinct ||
 			b.TimeAggregation == TimeAggregationCount ||
 			b.TimeAggregation == TimeAggregationCountDistinct {
-			return true
+			return FillModeZero
+		}
+		switch b.TimeAggregation {
+		case TimeAggregationAvg, TimeAggregationMin, TimeAggregationMax, TimeAggregationAnyLast:
+			return FillModePrevious
 		}
 	case DataSourceTraces, DataSourceLogs:
 		if b.AggregateOperator.IsRateOperator() ||
 			b.AggregateOperator == AggregateOperatorCount ||
 			b.AggregateOperator == AggregateOperatorCountDistinct {
-			return true
+			return FillModeZero
 		}
 	}
-	return false
+	return FillModeNone
+}
+
+// CanDefaultZero returns true if the missing value can be substituted by zero, see GapFillMode
+func (b *BuilderQuery) CanDefaultZero() bool {
+	return b.GapFillMode() == FillModeZero
+}
+
+// IsParameterisedPercentile returns true if the query uses the `percentile`
+// operator, for which the quantile is read from Quantile instead of the operator name
+func (b *BuilderQuery) IsParameterisedPercentile() bool {
+	switch b.DataSource {
+	case DataSourceMetrics:
+		return b.SpaceAggregation == SpaceAggregationPercentile
+	default:
+		return b.AggregateOperator == AggregateOperatorPercentile
+	}
 }
 
 func (b *BuilderQuery) Validate(panelType PanelType) error {
@@ -890,6 +1699,28 @@ func (b *BuilderQuery) Validate(panelType PanelType) error {
 		if b.AggregateAttribute == (AttributeKey{}) && b.AggregateOperator.RequireAttribute(b.DataSource) {
 			return fmt.Errorf("aggregate attribute is required")
 		}
+		if b.IsParameterisedPercentile() {
+			if b.Quantile <= 0 || b.Quantile >= 1 {
+				return fmt.Errorf("quantile should be between 0 and 1, got %v", b.Quantile)
+			}
+		}
+		if b.AggregateOperator == AggregateOperatorApdex && b.DataSource != DataSourceTraces {
+			return fmt.Errorf("apdex aggregate operator is only supported for traces")
+		}
+	}
+
+	if panelType == PanelTypeHeatmap {
+		if b.QueryName != b.Expression {
+			return fmt.Errorf("formulas are not supported for heatmap panel type")
+		}
+		if b.DataSource == DataSourceLogs && b.AggregateAttribute.Key == "" {
+			return fmt.Errorf("aggregate attribute is required for logs heatmap")
+		}
+		for idx := 1; idx < len(b.Buckets); idx++ {
+			if b.Buckets[idx] <= b.Buckets[idx-1] {
+				return fmt.Errorf("buckets should be in increasing order, got %v", b.Buckets)
+			}
+		}
 	}
 
 	if b.Filters != nil {
@@ -923,87 +1754,81 @@ func (b *BuilderQuery) Validate(panelType PanelType) error {
 		}
 	}
 
+	if b.Apdex != nil {
+		if err := b.Apdex.Validate(); err != nil {
+			return fmt.Errorf("apdex is invalid: %w", err)
+		}
+	}
+
+	if b.TopK != nil {
+		if err := b.TopK.Validate(); err != nil {
+			return fmt.Errorf("top k is invalid: %w", err)
+		}
+	}
+
+	if b.Join != nil {
+		if b.QueryName == b.Expression {
+			return fmt.Errorf("join is only supported for formulas")
+		}
+		if err := b.Join.Validate(); err != nil {
+			return fmt.Errorf("join is invalid: %w", err)
+		}
+	}
+
 	for _, selectColumn := range b.SelectColumns {
 		if err := selectColumn.Validate(); err != nil {
 			return fmt.Errorf("select column is invalid %w", err)
 		}
 	}
 
+	if b.FillMode != "" {
+		if err := b.FillMode.Validate(); err != nil {
+			return err
+		}
+	}
+
 	if b.Expression == "" {
 		return fmt.Errorf("expression is required")
 	}
 
-	if len(b.Functions) > 0 {
-		for _, function := range b.Functions {
-			if err := function.Name.Validate(); err != nil {
-				return fmt.Errorf("function name is invalid: %w", err)
-			}
-			if function.Name == FunctionNameTimeShift {
-				if len(function.Args) == 0 {
-					return fmt.Errorf("timeShiftBy param missing in query")
-				}
-				_, ok := function.Args[0].(float64)
-				if !ok {
-					// if string, attempt to convert to float
-					timeShiftBy, err := strconv.ParseFloat(function.Args[0].(string), 64)
-					if err != nil {
-						return fmt.Errorf("timeShiftBy param should be a number")
-					}
-					function.Args[0] = timeShiftBy
-				}
-			} else if function.Name == FunctionNameEWMA3 ||
-				function.Name == FunctionNameEWMA5 ||
-				function.Name == FunctionNameEWMA7 {
-				if len(function.Args) == 0 {
-					return fmt.Errorf("alpha param missing in query")
-				}
-				alpha, ok := function.Args[0].(float64)
-				if !ok {
-					// if string, attempt to convert to float
-					alpha, err := strconv.ParseFloat(function.Args[0].(string), 64)
-					if err != nil {
-						return fmt.Errorf("alpha param should be a float")
-					}
-					function.Args[0] = alpha
-				}
-				if alpha < 0 || alpha > 1 {
-					return fmt.Errorf("alpha param should be between 0 and 1")
-				}
-			} else if function.Name == FunctionNameCutOffMax ||
-				function.Name == FunctionNameCutOffMin ||
-				function.Name == FunctionNameClampMax ||
-				function.Name == FunctionNameClampMin {
-				if len(function.Args) == 0 {
-					return fmt.Errorf("threshold param missing in query")
-				}
-				_, ok := function.Args[0].(float64)
-				if !ok {
-					// if string, attempt to convert to float
-					threshold, err := strconv.ParseFloat(function.Args[0].(string), 64)
-					if err != nil {
-						return fmt.Errorf("threshold param should be a float")
-					}
-					function.Args[0] = threshold
-				}
-			}
+	for idx := range b.Functions {
+		if err := b.Functions[idx].Validate(); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// FilterSet is a list of filter items joined with the operator.
+// Groups are nested filter sets that are evaluated on their own, with their
+// own operator, and joined with the items of the parent. Not negates the group.
+// The items and groups of the top level filter set are always joined with AND,
+// OR and NOT are expressed with nested groups e.g (A AND B) OR (C AND D)
+// is a top level filter set with a single OR group of two AND groups.
 type FilterSet struct {
 	Operator string       `json:"op,omitempty"`
 	Items    []FilterItem `json:"items"`
+	Groups   []*FilterSet `json:"groups,omitempty"`
+	Not      bool         `json:"not,omitempty"`
 }
 
 func (f *FilterSet) Clone() *FilterSet {
 	if f == nil {
 		return nil
 	}
+	var groups []*FilterSet
+	if f.Groups != nil {
+		groups = make([]*FilterSet, 0, len(f.Groups))
+		for _, group := range f.Groups {
+			groups = append(groups, group.Clone())
+		}
+	}
 	return &FilterSet{
 		Operator: f.Operator,
 		Items:    f.Items,
+		Groups:   groups,
+		Not:      f.Not,
 	}
 }
 
@@ -1014,14 +1839,93 @@ func (f *FilterSet) Validate() error {
 	if f.Operator != "" && f.Operator != "AND" && f.Operator != "OR" {
 		return fmt.Errorf("operator must be AND or OR")
 	}
+	if len(f.Groups) > 0 && f.Operator == "OR" {
+		return fmt.Errorf("operator of the filters with groups must be AND, use a nested group for OR")
+	}
+	if f.Not {
+		return fmt.Errorf("not is only supported for nested groups")
+	}
+	return f.validateItemsAndGroups()
+}
+
+func (f *FilterSet) validateItemsAndGroups() error {
 	for _, item := range f.Items {
 		if err := item.Key.Validate(); err != nil {
 			return fmt.Errorf("filter item key is invalid: %w", err)
 		}
 	}
+	for _, group := range f.Groups {
+		if group == nil || (len(group.Items) == 0 && len(group.Groups) == 0) {
+			return fmt.Errorf("filter group is empty")
+		}
+		if group.Operator != "" && group.Operator != "AND" && group.Operator != "OR" {
+			return fmt.Errorf("operator of the filter group must be AND or OR")
+		}
+		if err := group.validateItemsAndGroups(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// LogicalOperator returns the operator joining the items and groups of the filter set
+func (f *FilterSet) LogicalOperator() string {
+	if strings.ToUpper(f.Operator) == "OR" {
+		return "OR"
+	}
+	return "AND"
+}
+
+// JoinConditions joins the conditions built for the items and groups of a nested
+// filter group with its operator. The result is wrapped in parentheses and negated
+// if required so that it can be joined with the conditions of the parent.
+func (f *FilterSet) JoinConditions(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	condition := "(" + strings.Join(conditions, " "+f.LogicalOperator()+" ") + ")"
+	if f.Not {
+		condition = "NOT " + condition
+	}
+	return condition
+}
+
+// ForEachItem calls fn with a pointer to every item of the filter set and its nested groups
+// The items can be modified in place, iteration stops at the first error
+func (f *FilterSet) ForEachItem(fn func(item *FilterItem) error) error {
+	if f == nil {
+		return nil
+	}
+	for idx := range f.Items {
+		if err := fn(&f.Items[idx]); err != nil {
+			return err
+		}
+	}
+	for _, group := range f.Groups {
+		if err := group.ForEachItem(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CacheKey returns the cache key of a nested filter group
+// The items of the top level filter set are part of the query cache key on their own
+func (f *FilterSet) CacheKey() string {
+	var parts []string
+	parts = append(parts, fmt.Sprintf("op:%s", f.LogicalOperator()))
+	if f.Not {
+		parts = append(parts, "not:true")
+	}
+	for _, item := range f.Items {
+		parts = append(parts, fmt.Sprintf("item:(%s)", item.CacheKey()))
+	}
+	for _, group := range f.Groups {
+		parts = append(parts, fmt.Sprintf("group:(%s)", group.CacheKey()))
+	}
+	return strings.Join(parts, ",")
+}
+
 // For serializing to and from db
 func (f *FilterSet) Scan(src interface{}) error {
 	if data, ok := src.([]byte); ok {
@@ -1138,6 +2042,27 @@ type QueryRangeResponse struct {
 	Result                []*Result `json:"result"`
 }
 
+// CacheMiss is the time range, in milliseconds, that is missing in the cache
+type CacheMiss struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// QueryExplanation is the dry run of a query of the query range request, the query is run
+// for each of the cache misses. The formulas evaluated by the query service have the
+// expression instead of the query
+type QueryExplanation struct {
+	QueryName  string      `json:"queryName"`
+	Query      string      `json:"query,omitempty"`
+	Expression string      `json:"expression,omitempty"`
+	Misses     []CacheMiss `json:"misses"`
+	Explain    []string    `json:"explain,omitempty"`
+}
+
+type QueryRangeExplainResponse struct {
+	Queries []*QueryExplanation `json:"queries"`
+}
+
 type TableColumn struct {
 	Name string `json:"name"`
 	// QueryName is the name of the query that this column belongs to
@@ -1145,27 +2070,174 @@ type TableColumn struct {
 	// IsValueColumn is true if this column is a value column
 	// i.e it is the column that contains the actual value that is being plotted
 	IsValueColumn bool `json:"isValueColumn"`
+	// Unit is the unit of the values of the column
+	Unit string `json:"unit,omitempty"`
 }
 
 type TableRow struct {
-	Data      map[string]interface{} `json:"data"`
-	QueryName string                 `json:"-"`
+	Data map[string]interface{} `json:"data"`
+	// Formatted are the values of the columns with a unit formatted for display
+	Formatted map[string]string `json:"formatted,omitempty"`
+	QueryName string            `json:"-"`
 }
 
 type Table struct {
 	Columns []*TableColumn `json:"columns"`
 	Rows    []*TableRow    `json:"rows"`
+	// TotalRows is the number of rows of the table before the pagination
+	TotalRows int `json:"totalRows,omitempty"`
+	// NextCursor is the cursor of the next page, empty for the last page
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// MaxTablePageSize is the maximum number of rows in a page of the table panel
+const MaxTablePageSize = 1000
+
+// TableOrderBy sorts the rows by the column, the column can be a group by label,
+// a query or a formula
+type TableOrderBy struct {
+	ColumnName string `json:"columnName"`
+	Order      string `json:"order"`
+}
+
+// TableOptions are the sorting, pagination and formatting of the rows of the table panel
+type TableOptions struct {
+	// OrderBy sorts the rows by the columns in order, the rows are sorted by the
+	// order by of the queries when empty
+	OrderBy []TableOrderBy `json:"orderBy,omitempty"`
+	// PageSize is the number of rows in a page, all the rows are returned when zero
+	PageSize int `json:"pageSize,omitempty"`
+	// Cursor is the NextCursor of the previous page
+	Cursor string `json:"cursor,omitempty"`
+	// Units are the units of the columns used to format the values
+	Units map[string]string `json:"units,omitempty"`
+}
+
+func (t *TableOptions) Clone() *TableOptions {
+	if t == nil {
+		return nil
+	}
+	var units map[string]string
+	if t.Units != nil {
+		units = make(map[string]string, len(t.Units))
+		for column, unit := range t.Units {
+			units[column] = unit
+		}
+	}
+	return &TableOptions{
+		OrderBy:  append([]TableOrderBy(nil), t.OrderBy...),
+		PageSize: t.PageSize,
+		Cursor:   t.Cursor,
+		Units:    units,
+	}
+}
+
+func (t *TableOptions) Validate() error {
+	for _, orderBy := range t.OrderBy {
+		if orderBy.ColumnName == "" {
+			return fmt.Errorf("order by column name is required")
+		}
+		if orderBy.Order != "asc" && orderBy.Order != "desc" {
+			return fmt.Errorf("invalid order %s for column %s, should be one of asc, desc", orderBy.Order, orderBy.ColumnName)
+		}
+	}
+	if t.PageSize < 0 || t.PageSize > MaxTablePageSize {
+		return fmt.Errorf("page size should be between 0 and %d", MaxTablePageSize)
+	}
+	if t.Cursor != "" {
+		if t.PageSize == 0 {
+			return fmt.Errorf("page size is required with the cursor")
+		}
+		if _, err := DecodeTableCursor(t.Cursor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TableCursor is the position of the last row of a page, the rows after it in the
+// sort order make the next page. Values are the values of the order by columns and
+// Key identifies the row when the values are equal
+type TableCursor struct {
+	Values []interface{} `json:"values"`
+	Key    string        `json:"key"`
+}
+
+func (c *TableCursor) Encode() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func DecodeTableCursor(cursor string) (*TableCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid table cursor: %w", err)
+	}
+	var tableCursor TableCursor
+	if err := json.Unmarshal(data, &tableCursor); err != nil {
+		return nil, fmt.Errorf("invalid table cursor: %w", err)
+	}
+	return &tableCursor, nil
 }
 
 type Result struct {
-	QueryName        string    `json:"queryName,omitempty"`
-	Series           []*Series `json:"series,omitempty"`
-	PredictedSeries  []*Series `json:"predictedSeries,omitempty"`
-	UpperBoundSeries []*Series `json:"upperBoundSeries,omitempty"`
-	LowerBoundSeries []*Series `json:"lowerBoundSeries,omitempty"`
-	AnomalyScores    []*Series `json:"anomalyScores,omitempty"`
-	List             []*Row    `json:"list,omitempty"`
-	Table            *Table    `json:"table,omitempty"`
+	QueryName        string           `json:"queryName,omitempty"`
+	Series           []*Series        `json:"series,omitempty"`
+	PredictedSeries  []*Series        `json:"predictedSeries,omitempty"`
+	UpperBoundSeries []*Series        `json:"upperBoundSeries,omitempty"`
+	LowerBoundSeries []*Series        `json:"lowerBoundSeries,omitempty"`
+	AnomalyScores    []*Series        `json:"anomalyScores,omitempty"`
+	List             []*Row           `json:"list,omitempty"`
+	Table            *Table           `json:"table,omitempty"`
+	Heatmap          []*HeatmapSeries `json:"heatmap,omitempty"`
+}
+
+// HeatmapSeries is the distribution of the values of a series over the buckets
+// for each step, Buckets are the upper bounds of the buckets in increasing order
+// and the counts of each point are in the same order as the buckets
+type HeatmapSeries struct {
+	Labels      map[string]string   `json:"labels"`
+	LabelsArray []map[string]string `json:"labelsArray"`
+	Buckets     HeatmapBuckets      `json:"buckets"`
+	Points      []HeatmapPoint      `json:"values"`
+}
+
+type HeatmapPoint struct {
+	Timestamp int64     `json:"timestamp"`
+	Counts    []float64 `json:"counts"`
+}
+
+// HeatmapBuckets are the upper bounds of the buckets, the last bucket can be +Inf
+// so the bounds are serialized as strings like the value of the Point
+type HeatmapBuckets []float64
+
+// MarshalJSON implements json.Marshaler.
+func (b HeatmapBuckets) MarshalJSON() ([]byte, error) {
+	bounds := make([]string, 0, len(b))
+	for _, bound := range b {
+		bounds = append(bounds, strconv.FormatFloat(bound, 'f', -1, 64))
+	}
+	return json.Marshal(bounds)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *HeatmapBuckets) UnmarshalJSON(data []byte) error {
+	var bounds []string
+	if err := json.Unmarshal(data, &bounds); err != nil {
+		return err
+	}
+	*b = make(HeatmapBuckets, 0, len(bounds))
+	for _, bound := range bounds {
+		value, err := strconv.ParseFloat(bound, 64)
+		if err != nil {
+			return err
+		}
+		*b = append(*b, value)
+	}
+	return nil
 }
 
 type Series struct {
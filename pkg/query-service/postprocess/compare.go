@@ -0,0 +1,159 @@
+package postprocess
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	promModel "github.com/prometheus/common/model"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+const (
+	// CompareOffsetLabel is the label with the offset of the series of the compare option
+	CompareOffsetLabel = "offset"
+	// CompareKindLabel is the label with the kind of the series of the compare option
+	CompareKindLabel = "compare"
+	// compareAverageOffset is the offset of the average of the shifted queries
+	compareAverageOffset = "avg"
+)
+
+// ExpandCompareQueries adds the queries of the compare option to the composite query.
+// For each enabled builder query A and offset 1w, it adds the query A_1w, which is A shifted by
+// the offset, and the formulas A_1w_delta = A - A_1w and A_1w_percent = (A - A_1w) / A_1w * 100.
+// With the average, A_avg is the average of the shifted queries with the same delta and percent formulas.
+// The shifted queries are regular builder queries, so they are cached like any other query
+func ExpandCompareQueries(params *v3.QueryRangeParamsV3) error {
+	if params.Compare == nil {
+		return nil
+	}
+	durations, err := params.Compare.Durations()
+	if err != nil {
+		return err
+	}
+
+	builderQueries := params.CompositeQuery.BuilderQueries
+	add := func(query *v3.BuilderQuery) error {
+		if _, ok := builderQueries[query.QueryName]; ok {
+			return fmt.Errorf("query %s of the compare option already exists", query.QueryName)
+		}
+		builderQueries[query.QueryName] = query
+		return nil
+	}
+	addChange := func(query *v3.BuilderQuery, shiftedName, offset string) error {
+		delta := compareFormula(query, shiftedName+"_delta", fmt.Sprintf("%s - %s", query.QueryName, shiftedName))
+		delta.CompareOffset, delta.CompareKind = offset, v3.CompareKindDelta
+		if err := add(delta); err != nil {
+			return err
+		}
+		percent := compareFormula(query, shiftedName+"_percent", fmt.Sprintf("(%s - %s) / %s * 100", query.QueryName, shiftedName, shiftedName))
+		percent.CompareOffset, percent.CompareKind = offset, v3.CompareKindPercent
+		return add(percent)
+	}
+
+	// the queries are expanded in the order of the names so the errors are deterministic
+	queryNames := make([]string, 0, len(builderQueries))
+	for name := range builderQueries {
+		queryNames = append(queryNames, name)
+	}
+	sort.Strings(queryNames)
+
+	for _, name := range queryNames {
+		query := builderQueries[name]
+		if query.Disabled || query.QueryName != query.Expression {
+			continue
+		}
+
+		shiftedNames := make([]string, 0, len(durations))
+		for _, duration := range durations {
+			offset := promModel.Duration(duration).String()
+			shifted := query.Clone()
+			shifted.QueryName = fmt.Sprintf("%s_%s", name, offset)
+			shifted.Expression = shifted.QueryName
+			shifted.Functions = shiftFunctions(query.Functions, duration)
+			shifted.CompareOffset, shifted.CompareKind = offset, v3.CompareKindShifted
+			if err := add(shifted); err != nil {
+				return err
+			}
+			if err := addChange(query, shifted.QueryName, offset); err != nil {
+				return err
+			}
+			shiftedNames = append(shiftedNames, shifted.QueryName)
+		}
+
+		if params.Compare.Average && len(shiftedNames) > 1 {
+			expression := fmt.Sprintf("(%s) / %d", strings.Join(shiftedNames, " + "), len(shiftedNames))
+			average := compareFormula(query, fmt.Sprintf("%s_%s", name, compareAverageOffset), expression)
+			average.CompareOffset, average.CompareKind = compareAverageOffset, v3.CompareKindShifted
+			if err := add(average); err != nil {
+				return err
+			}
+			if err := addChange(query, average.QueryName, compareAverageOffset); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compareFormula returns the formula of the compare option on the query
+func compareFormula(query *v3.BuilderQuery, name, expression string) *v3.BuilderQuery {
+	return &v3.BuilderQuery{
+		QueryName:    name,
+		Expression:   expression,
+		DataSource:   query.DataSource,
+		StepInterval: query.StepInterval,
+		ReduceTo:     query.ReduceTo,
+		FillMode:     query.FillMode,
+	}
+}
+
+// shiftFunctions returns the functions with the time shift of the query increased by the offset
+func shiftFunctions(functions []v3.Function, offset time.Duration) []v3.Function {
+	shiftBy := offset.Seconds()
+	shifted := make([]v3.Function, 0, len(functions)+1)
+	for _, function := range functions {
+		if function.Name == v3.FunctionNameTimeShift {
+			if value, ok := function.Args[0].(float64); ok {
+				shiftBy += value
+			}
+			continue
+		}
+		shifted = append(shifted, function)
+	}
+	timeShift := v3.Function{Name: v3.FunctionNameTimeShift, Args: []interface{}{shiftBy}}
+	return append([]v3.Function{timeShift}, shifted...)
+}
+
+// TagCompareSeries adds the offset and the kind labels to the series of the queries of the
+// compare option. The table panel has a column for each query, so the rows are not tagged
+func TagCompareSeries(results []*v3.Result, params *v3.QueryRangeParamsV3) {
+	if params.Compare == nil || params.CompositeQuery.PanelType == v3.PanelTypeTable {
+		return
+	}
+	for _, result := range results {
+		query, ok := params.CompositeQuery.BuilderQueries[result.QueryName]
+		if !ok || query.CompareKind == "" {
+			continue
+		}
+		for _, series := range result.Series {
+			// the labels of the formula series can be shared with the series of the queries
+			labels := make(map[string]string, len(series.Labels)+2)
+			for k, v := range series.Labels {
+				labels[k] = v
+			}
+			labels[CompareOffsetLabel] = query.CompareOffset
+			labels[CompareKindLabel] = string(query.CompareKind)
+			series.Labels = labels
+
+			labelsArray := make([]map[string]string, 0, len(series.LabelsArray)+2)
+			labelsArray = append(labelsArray, series.LabelsArray...)
+			labelsArray = append(labelsArray,
+				map[string]string{CompareOffsetLabel: query.CompareOffset},
+				map[string]string{CompareKindLabel: string(query.CompareKind)},
+			)
+			series.LabelsArray = labelsArray
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package postprocess
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+func TestExpandCompareQueries(t *testing.T) {
+	params := &v3.QueryRangeParamsV3{
+		CompositeQuery: &v3.CompositeQuery{
+			PanelType: v3.PanelTypeGraph,
+			QueryType: v3.QueryTypeBuilder,
+			BuilderQueries: map[string]*v3.BuilderQuery{
+				"A": {
+					QueryName:    "A",
+					Expression:   "A",
+					DataSource:   v3.DataSourceLogs,
+					StepInterval: 60,
+					Functions: []v3.Function{
+						{Name: v3.FunctionNameTimeShift, Args: []interface{}{float64(3600)}},
+						{Name: v3.FunctionNameClampMax, Args: []interface{}{float64(10)}},
+					},
+				},
+				"B":  {QueryName: "B", Expression: "B", Disabled: true},
+				"F1": {QueryName: "F1", Expression: "A * 2"},
+			},
+		},
+		Compare: &v3.CompareOptions{Offsets: []string{"1d", "1w"}, Average: true},
+	}
+
+	if err := ExpandCompareQueries(params); err != nil {
+		t.Fatalf("ExpandCompareQueries() error = %v", err)
+	}
+
+	expressions := map[string]string{}
+	for name, query := range params.CompositeQuery.BuilderQueries {
+		expressions[name] = query.Expression
+	}
+	expected := map[string]string{
+		"A":             "A",
+		"B":             "B",
+		"F1":            "A * 2",
+		"A_1d":          "A_1d",
+		"A_1d_delta":    "A - A_1d",
+		"A_1d_percent":  "(A - A_1d) / A_1d * 100",
+		"A_1w":          "A_1w",
+		"A_1w_delta":    "A - A_1w",
+		"A_1w_percent":  "(A - A_1w) / A_1w * 100",
+		"A_avg":         "(A_1d + A_1w) / 2",
+		"A_avg_delta":   "A - A_avg",
+		"A_avg_percent": "(A - A_avg) / A_avg * 100",
+	}
+	if !reflect.DeepEqual(expressions, expected) {
+		t.Errorf("ExpandCompareQueries() queries = %v, want %v", expressions, expected)
+	}
+
+	shifted := params.CompositeQuery.BuilderQueries["A_1w"]
+	expectedFunctions := []v3.Function{
+		{Name: v3.FunctionNameTimeShift, Args: []interface{}{float64(7*24*3600 + 3600)}},
+		{Name: v3.FunctionNameClampMax, Args: []interface{}{float64(10)}},
+	}
+	if !reflect.DeepEqual(shifted.Functions, expectedFunctions) {
+		t.Errorf("A_1w functions = %v, want %v", shifted.Functions, expectedFunctions)
+	}
+	if shifted.CompareOffset != "1w" || shifted.CompareKind != v3.CompareKindShifted {
+		t.Errorf("A_1w compare = %s %s, want 1w shifted", shifted.CompareOffset, shifted.CompareKind)
+	}
+	if len(params.CompositeQuery.BuilderQueries["A"].Functions) != 2 {
+		t.Errorf("the functions of A should not change")
+	}
+
+	// the queries are added once
+	if err := ExpandCompareQueries(params); err == nil {
+		t.Errorf("ExpandCompareQueries() expected error for the existing queries")
+	}
+}
+
+func TestPostProcessResultCompare(t *testing.T) {
+	params := &v3.QueryRangeParamsV3{
+		CompositeQuery: &v3.CompositeQuery{
+			PanelType: v3.PanelTypeGraph,
+			QueryType: v3.QueryTypeBuilder,
+			BuilderQueries: map[string]*v3.BuilderQuery{
+				"A": {QueryName: "A", Expression: "A", DataSource: v3.DataSourceTraces, StepInterval: 60},
+			},
+		},
+		Compare: &v3.CompareOptions{Offsets: []string{"1h"}},
+	}
+	if err := ExpandCompareQueries(params); err != nil {
+		t.Fatalf("ExpandCompareQueries() error = %v", err)
+	}
+
+	labels := map[string]string{"service_name": "frontend"}
+	results := []*v3.Result{
+		{
+			QueryName: "A",
+			Series: []*v3.Series{
+				{Labels: labels, LabelsArray: []map[string]string{labels}, Points: []v3.Point{{Timestamp: 7200000, Value: 30}}},
+			},
+		},
+		{
+			QueryName: "A_1h",
+			Series: []*v3.Series{
+				{Labels: labels, LabelsArray: []map[string]string{labels}, Points: []v3.Point{{Timestamp: 3600000, Value: 20}}},
+			},
+		},
+	}
+
+	got, err := PostProcessResult(results, params)
+	if err != nil {
+		t.Fatalf("PostProcessResult() error = %v", err)
+	}
+	sort.Slice(got, func(i, j int) bool {
+		return got[i].QueryName < got[j].QueryName
+	})
+
+	expected := []struct {
+		queryName string
+		labels    map[string]string
+		point     v3.Point
+	}{
+		{"A", map[string]string{"service_name": "frontend"}, v3.Point{Timestamp: 7200000, Value: 30}},
+		{"A_1h", map[string]string{"service_name": "frontend", "offset": "1h", "compare": "shifted"}, v3.Point{Timestamp: 7200000, Value: 20}},
+		{"A_1h_delta", map[string]string{"service_name": "frontend", "offset": "1h", "compare": "delta"}, v3.Point{Timestamp: 7200000, Value: 10}},
+		{"A_1h_percent", map[string]string{"service_name": "frontend", "offset": "1h", "compare": "percent"}, v3.Point{Timestamp: 7200000, Value: 50}},
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("PostProcessResult() got %d results, want %d", len(got), len(expected))
+	}
+	for idx, want := range expected {
+		result := got[idx]
+		if result.QueryName != want.queryName || len(result.Series) != 1 {
+			t.Errorf("result %d = %s with %d series, want %s with 1 series", idx, result.QueryName, len(result.Series), want.queryName)
+			continue
+		}
+		if !reflect.DeepEqual(result.Series[0].Labels, want.labels) {
+			t.Errorf("%s labels = %v, want %v", want.queryName, result.Series[0].Labels, want.labels)
+		}
+		if !reflect.DeepEqual(result.Series[0].Points, []v3.Point{want.point}) {
+			t.Errorf("%s points = %v, want %v", want.queryName, result.Series[0].Points, []v3.Point{want.point})
+		}
+	}
+}
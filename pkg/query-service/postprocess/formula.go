@@ -67,12 +67,14 @@ func joinAndCalculate(
 	results []*v3.Result,
 	uniqueLabelSet map[string]string,
 	expression *govaluate.EvaluableExpression,
-	canDefaultZero map[string]bool,
+	fills map[string]gapFill,
 ) (*v3.Series, error) {
 
 	uniqueTimestamps := make(map[int64]struct{})
 	// map[queryName]map[timestamp]value
 	seriesMap := make(map[string]map[int64]float64)
+	// map[queryName]points of the matching series, used to fill the missing values
+	pointsMap := make(map[string][]v3.Point)
 	for _, result := range results {
 		var matchingSeries *v3.Series
 		// We try to find a series that matches the label set from the current query result
@@ -86,6 +88,7 @@ func joinAndCalculate(
 		// Prepare the seriesMap for quick lookup during evaluation
 		// seriesMap[queryName][timestamp]value contains the value of the series with the given queryName at the given timestamp
 		if matchingSeries != nil {
+			pointsMap[result.QueryName] = matchingSeries.Points
 			for _, point := range matchingSeries.Points {
 				if _, ok := seriesMap[result.QueryName]; !ok {
 					seriesMap[result.QueryName] = make(map[int64]float64)
@@ -108,6 +111,7 @@ func joinAndCalculate(
 		return timestamps[i] < timestamps[j]
 	})
 
+	handlesMissing := handlesMissingValues(expression)
 	for _, timestamp := range timestamps {
 		values := make(map[string]interface{})
 		for queryName, series := range seriesMap {
@@ -115,11 +119,28 @@ func joinAndCalculate(
 				values[queryName] = series[timestamp]
 			}
 		}
+		values[TimestampVar] = float64(timestamp / 1000)
 
-		// If the value is not present in the values map, set it to 0
+		// If the value is not present in the values map, fill it with the fill mode of the query
 		for _, v := range expression.Vars() {
-			if _, ok := values[v]; !ok && canDefaultZero[v] {
-				values[v] = 0
+			if _, ok := values[v]; ok {
+				continue
+			}
+			points := pointsMap[v]
+			next := sort.Search(len(points), func(i int) bool {
+				return points[i].Timestamp > timestamp
+			})
+			if value, ok := fillValue(points, next, timestamp, fills[v]); ok && !math.IsNaN(value) {
+				values[v] = value
+			}
+		}
+
+		// The functions handling the missing values get NaN for them
+		if handlesMissing {
+			for _, v := range expression.Vars() {
+				if _, ok := values[v]; !ok {
+					values[v] = math.NaN()
+				}
 			}
 		}
 
@@ -167,9 +188,14 @@ func joinAndCalculate(
 func processResults(
 	results []*v3.Result,
 	expression *govaluate.EvaluableExpression,
-	canDefaultZero map[string]bool,
+	fills map[string]gapFill,
 ) (*v3.Result, error) {
 
+	expression, results, err := expandReducers(expression, results)
+	if err != nil {
+		return nil, err
+	}
+
 	queriesInExpression := make(map[string]struct{})
 	for _, v := range expression.Vars() {
 		queriesInExpression[v] = struct{}{}
@@ -178,7 +204,7 @@ func processResults(
 	newSeries := make([]*v3.Series, 0)
 
 	for _, labelSet := range uniqueLabelSets {
-		series, err := joinAndCalculate(results, labelSet, expression, canDefaultZero)
+		series, err := joinAndCalculate(results, labelSet, expression, fills)
 		if err != nil {
 			return nil, err
 		}
@@ -197,7 +223,7 @@ func processResults(
 	}, nil
 }
 
-var SupportedFunctions = []string{"exp", "log", "ln", "exp2", "log2", "exp10", "log10", "sqrt", "cbrt", "erf", "erfc", "lgamma", "tgamma", "sin", "cos", "tan", "asin", "acos", "atan", "degrees", "radians", "now", "toUnixTimestamp"}
+var SupportedFunctions = []string{"exp", "log", "ln", "exp2", "log2", "exp10", "log10", "sqrt", "cbrt", "erf", "erfc", "lgamma", "tgamma", "sin", "cos", "tan", "asin", "acos", "atan", "degrees", "radians", "now", "toUnixTimestamp", "if", "coalesce", "default", "min", "max", "timestamp", "sum", "avg", "count"}
 
 func EvalFuncs() map[string]govaluate.ExpressionFunction {
 	GoValuateFuncs := make(map[string]govaluate.ExpressionFunction)
@@ -290,6 +316,306 @@ func EvalFuncs() map[string]govaluate.ExpressionFunction {
 	GoValuateFuncs["now"] = func(args ...interface{}) (interface{}, error) {
 		return float64(time.Now().Unix()), nil
 	}
+	// Returns the second argument if the first argument is true or non-zero, otherwise the third argument.
+	GoValuateFuncs["if"] = func(args ...interface{}) (interface{}, error) {
+		switch condition := args[0].(type) {
+		case bool:
+			if condition {
+				return args[1], nil
+			}
+		case float64:
+			if condition != 0 && !math.IsNaN(condition) {
+				return args[1], nil
+			}
+		default:
+			return nil, fmt.Errorf("condition of if should be a comparison or a number, got %T", args[0])
+		}
+		return args[2], nil
+	}
+	// Returns the first argument that is not missing.
+	GoValuateFuncs["coalesce"] = func(args ...interface{}) (interface{}, error) {
+		for _, arg := range args {
+			if value, ok := arg.(float64); ok && !math.IsNaN(value) {
+				return value, nil
+			}
+		}
+		return math.NaN(), nil
+	}
+	// Returns the first argument, or the second argument if the first is missing.
+	GoValuateFuncs["default"] = func(args ...interface{}) (interface{}, error) {
+		if value, ok := args[0].(float64); ok && !math.IsNaN(value) {
+			return value, nil
+		}
+		return args[1], nil
+	}
+	// Returns the smallest of the arguments that are not missing.
+	GoValuateFuncs["min"] = func(args ...interface{}) (interface{}, error) {
+		return extremum(args, func(a, b float64) bool { return a < b }), nil
+	}
+	// Returns the largest of the arguments that are not missing.
+	GoValuateFuncs["max"] = func(args ...interface{}) (interface{}, error) {
+		return extremum(args, func(a, b float64) bool { return a > b }), nil
+	}
+	// timestamp() and the cross series reducers are replaced with variables before the evaluation.
+	for _, name := range []string{"timestamp", "sum", "avg", "count"} {
+		name := name
+		GoValuateFuncs[name] = func(args ...interface{}) (interface{}, error) {
+			return nil, fmt.Errorf("function %s can't be evaluated in this formula", name)
+		}
+	}
 
 	return GoValuateFuncs
 }
+
+// extremum returns the argument that is before the others in the order of less, the missing
+// arguments are skipped. It returns NaN if all the arguments are missing
+func extremum(args []interface{}, less func(a, b float64) bool) float64 {
+	result := math.NaN()
+	for _, arg := range args {
+		value, ok := arg.(float64)
+		if !ok || math.IsNaN(value) {
+			continue
+		}
+		if math.IsNaN(result) || less(value, result) {
+			result = value
+		}
+	}
+	return result
+}
+
+func floatArgs(values []float64) []interface{} {
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		args = append(args, value)
+	}
+	return args
+}
+
+// TimestampVar is the variable timestamp() is replaced with by ExpandFormula, the value is
+// the timestamp of the point in seconds
+const TimestampVar = "__timestamp"
+
+// formulaFunctionArgs is the minimum and maximum number of arguments of the functions, -1 for
+// any number of arguments
+var formulaFunctionArgs = map[string][2]int{
+	"now":       {0, 0},
+	"timestamp": {0, 0},
+	"if":        {3, 3},
+	"coalesce":  {1, -1},
+	"default":   {2, 2},
+	"min":       {1, -1},
+	"max":       {1, -1},
+	"sum":       {1, 1},
+	"avg":       {1, 1},
+	"count":     {1, 1},
+}
+
+// crossSeriesReducers reduce the points of all the series of a query at each timestamp, min and
+// max are reducers when they have a single argument
+var crossSeriesReducers = map[string]func(values []float64) float64{
+	"sum": func(values []float64) float64 {
+		var sum float64
+		for _, value := range values {
+			sum += value
+		}
+		return sum
+	},
+	"avg": func(values []float64) float64 {
+		var sum float64
+		for _, value := range values {
+			sum += value
+		}
+		return sum / float64(len(values))
+	},
+	"count": func(values []float64) float64 {
+		return float64(len(values))
+	},
+	"min": func(values []float64) float64 {
+		return extremum(floatArgs(values), func(a, b float64) bool { return a < b })
+	},
+	"max": func(values []float64) float64 {
+		return extremum(floatArgs(values), func(a, b float64) bool { return a > b })
+	},
+}
+
+// formulaCall is a function call in the tokens of a formula, the tokens of the call are
+// tokens[start:end] and args has the tokens of each argument
+type formulaCall struct {
+	name  string
+	start int
+	end   int
+	args  [][]govaluate.ExpressionToken
+}
+
+// formulaCalls returns the function calls in the tokens including the nested calls
+func formulaCalls(tokens []govaluate.ExpressionToken) []formulaCall {
+	calls := make([]formulaCall, 0)
+	for idx, token := range tokens {
+		if token.Kind != govaluate.FUNCTION || idx+1 >= len(tokens) || tokens[idx+1].Kind != govaluate.CLAUSE {
+			continue
+		}
+		name, _ := token.Meta.(string)
+		call := formulaCall{name: name, start: idx, args: make([][]govaluate.ExpressionToken, 0)}
+		depth := 0
+		argStart := idx + 2
+		for end := idx + 1; end < len(tokens); end++ {
+			switch tokens[end].Kind {
+			case govaluate.CLAUSE:
+				depth++
+			case govaluate.CLAUSE_CLOSE:
+				depth--
+			case govaluate.SEPARATOR:
+				if depth == 1 {
+					call.args = append(call.args, tokens[argStart:end])
+					argStart = end + 1
+				}
+			}
+			if depth == 0 {
+				if end > argStart || len(call.args) > 0 {
+					call.args = append(call.args, tokens[argStart:end])
+				}
+				call.end = end + 1
+				break
+			}
+		}
+		calls = append(calls, call)
+	}
+	return calls
+}
+
+// reducedQuery returns the query reduced across its series by the call e.g A of sum(A)
+func (c formulaCall) reducedQuery() (string, bool) {
+	if _, ok := crossSeriesReducers[c.name]; !ok || len(c.args) != 1 || len(c.args[0]) != 1 || c.args[0][0].Kind != govaluate.VARIABLE {
+		return "", false
+	}
+	queryName, ok := c.args[0][0].Value.(string)
+	return queryName, ok
+}
+
+// ValidateFormula validates the number of arguments of the functions of the formula, and that
+// the cross series reducers e.g sum(A) are applied to a query
+func ValidateFormula(expression *govaluate.EvaluableExpression) error {
+	for _, call := range formulaCalls(expression.Tokens()) {
+		limits, ok := formulaFunctionArgs[call.name]
+		if !ok {
+			// the math functions take one argument
+			limits = [2]int{1, 1}
+		}
+		if len(call.args) < limits[0] || (limits[1] >= 0 && len(call.args) > limits[1]) {
+			switch {
+			case limits[0] == limits[1]:
+				return fmt.Errorf("function %s takes %d arguments, got %d", call.name, limits[0], len(call.args))
+			default:
+				return fmt.Errorf("function %s takes at least %d arguments, got %d", call.name, limits[0], len(call.args))
+			}
+		}
+		if _, isReducer := crossSeriesReducers[call.name]; isReducer && len(call.args) == 1 {
+			if _, ok := call.reducedQuery(); !ok {
+				return fmt.Errorf("function %s of one argument reduces the series of a query, the argument should be a query name", call.name)
+			}
+		}
+	}
+	return nil
+}
+
+// ReducedQuery is a query reduced across its series by a cross series reducer e.g sum(A)
+type ReducedQuery struct {
+	Reducer   string
+	QueryName string
+}
+
+// ExpandFormula replaces timestamp() and the cross series reducers e.g sum(A) in the formula with
+// variables. It returns the formula with the reduced query of each reducer variable, the
+// reduced queries have a single series without labels that is joined with all the series
+func ExpandFormula(expression *govaluate.EvaluableExpression) (*govaluate.EvaluableExpression, map[string]ReducedQuery, error) {
+	tokens := expression.Tokens()
+	reduced := make(map[string]ReducedQuery)
+	expanded := make([]govaluate.ExpressionToken, 0, len(tokens))
+	calls := formulaCalls(tokens)
+	for idx := 0; idx < len(tokens); {
+		var replaced bool
+		for _, call := range calls {
+			if call.start != idx {
+				continue
+			}
+			variable := ""
+			if queryName, ok := call.reducedQuery(); ok {
+				variable = fmt.Sprintf("__%s_%s", call.name, queryName)
+				reduced[variable] = ReducedQuery{Reducer: call.name, QueryName: queryName}
+			} else if call.name == "timestamp" {
+				variable = TimestampVar
+			}
+			if variable != "" {
+				expanded = append(expanded, govaluate.ExpressionToken{Kind: govaluate.VARIABLE, Value: variable, Meta: variable})
+				idx = call.end
+				replaced = true
+			}
+			break
+		}
+		if !replaced {
+			expanded = append(expanded, tokens[idx])
+			idx++
+		}
+	}
+	if len(reduced) == 0 && len(expanded) == len(tokens) {
+		return expression, reduced, nil
+	}
+	result, err := govaluate.NewEvaluableExpressionFromTokens(expanded)
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, reduced, nil
+}
+
+// expandReducers expands the formula and adds the results of the queries reduced across
+// their series to the results
+func expandReducers(expression *govaluate.EvaluableExpression, results []*v3.Result) (*govaluate.EvaluableExpression, []*v3.Result, error) {
+	expression, reduced, err := ExpandFormula(expression)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(reduced) == 0 {
+		return expression, results, nil
+	}
+
+	expanded := make([]*v3.Result, len(results), len(results)+len(reduced))
+	copy(expanded, results)
+	for variable, reducedQuery := range reduced {
+		reducer := crossSeriesReducers[reducedQuery.Reducer]
+		values := make(map[int64][]float64)
+		for _, result := range results {
+			if result.QueryName != reducedQuery.QueryName {
+				continue
+			}
+			for _, series := range result.Series {
+				for _, point := range series.Points {
+					if !math.IsNaN(point.Value) {
+						values[point.Timestamp] = append(values[point.Timestamp], point.Value)
+					}
+				}
+			}
+		}
+		series := &v3.Series{Labels: map[string]string{}, Points: make([]v3.Point, 0, len(values))}
+		for timestamp, pointValues := range values {
+			series.Points = append(series.Points, v3.Point{Timestamp: timestamp, Value: reducer(pointValues)})
+		}
+		sort.Slice(series.Points, func(i, j int) bool { return series.Points[i].Timestamp < series.Points[j].Timestamp })
+		expanded = append(expanded, &v3.Result{QueryName: variable, Series: []*v3.Series{series}})
+	}
+	return expression, expanded, nil
+}
+
+// handlesMissingValues returns true if the formula has functions that handle the missing values
+// of the queries, the missing values are NaN for them instead of skipping the timestamp
+func handlesMissingValues(expression *govaluate.EvaluableExpression) bool {
+	for _, token := range expression.Tokens() {
+		if token.Kind != govaluate.FUNCTION {
+			continue
+		}
+		switch token.Meta {
+		case "coalesce", "default", "min", "max":
+			return true
+		}
+	}
+	return false
+}
@@ -353,11 +353,11 @@ func TestProcessResults(t *testing.T) {
 			if err != nil {
 				t.Errorf("Error parsing expression: %v", err)
 			}
-			canDefaultZero := map[string]bool{
-				"A": true,
-				"B": true,
+			fills := map[string]gapFill{
+				"A": {mode: v3.FillModeZero},
+				"B": {mode: v3.FillModeZero},
 			}
-			got, err := processResults(tt.results, expression, canDefaultZero)
+			got, err := processResults(tt.results, expression, fills)
 			if err != nil {
 				t.Errorf("Error processing results: %v", err)
 			}
@@ -517,11 +517,11 @@ func TestProcessResultsErrorRate(t *testing.T) {
 			if err != nil {
 				t.Errorf("Error parsing expression: %v", err)
 			}
-			canDefaultZero := map[string]bool{
-				"A": true,
-				"B": true,
+			fills := map[string]gapFill{
+				"A": {mode: v3.FillModeZero},
+				"B": {mode: v3.FillModeZero},
 			}
-			got, err := processResults(tt.results, expression, canDefaultZero)
+			got, err := processResults(tt.results, expression, fills)
 			if err != nil {
 				t.Errorf("Error processing results: %v", err)
 			}
@@ -1535,12 +1535,12 @@ func TestFormula(t *testing.T) {
 				t.Errorf("Error parsing expression: %v", err)
 				return
 			}
-			canDefaultZero := map[string]bool{
-				"A": true,
-				"B": true,
-				"C": true,
+			fills := map[string]gapFill{
+				"A": {mode: v3.FillModeZero},
+				"B": {mode: v3.FillModeZero},
+				"C": {mode: v3.FillModeZero},
 			}
-			got, err := processResults(tt.results, expression, canDefaultZero)
+			got, err := processResults(tt.results, expression, fills)
 			if err != nil {
 				t.Errorf("Error processing results: %v", err)
 				return
@@ -1628,11 +1628,11 @@ func TestProcessResultsNoDefaultZero(t *testing.T) {
 			if err != nil {
 				t.Errorf("Error parsing expression: %v", err)
 			}
-			canDefaultZero := map[string]bool{
-				"A": false,
-				"B": false,
+			fills := map[string]gapFill{
+				"A": {mode: v3.FillModeNone},
+				"B": {mode: v3.FillModeNone},
 			}
-			got, err := processResults(tt.results, expression, canDefaultZero)
+			got, err := processResults(tt.results, expression, fills)
 			if err != nil {
 				t.Errorf("Error processing results: %v", err)
 			}
@@ -1759,12 +1759,12 @@ func TestProcessResultsMixedQueries(t *testing.T) {
 			if err != nil {
 				t.Errorf("Error parsing expression: %v", err)
 			}
-			canDefaultZero := map[string]bool{
-				"A": true,
-				"B": true,
-				"C": true,
+			fills := map[string]gapFill{
+				"A": {mode: v3.FillModeZero},
+				"B": {mode: v3.FillModeZero},
+				"C": {mode: v3.FillModeZero},
 			}
-			got, err := processResults(tt.results, expression, canDefaultZero)
+			got, err := processResults(tt.results, expression, fills)
 			if err != nil {
 				t.Errorf("Error processing results: %v", err)
 			}
@@ -1785,3 +1785,200 @@ func TestProcessResultsMixedQueries(t *testing.T) {
 		})
 	}
 }
+
+func TestFormulaFunctions(t *testing.T) {
+	results := []*v3.Result{
+		{
+			QueryName: "A",
+			Series: []*v3.Series{
+				{
+					Labels: map[string]string{"service_name": "frontend"},
+					Points: []v3.Point{{Timestamp: 60000, Value: 10}, {Timestamp: 120000, Value: 20}},
+				},
+				{
+					Labels: map[string]string{"service_name": "redis"},
+					Points: []v3.Point{{Timestamp: 60000, Value: 30}},
+				},
+			},
+		},
+		{
+			QueryName: "B",
+			Series: []*v3.Series{
+				{
+					Labels: map[string]string{"service_name": "frontend"},
+					Points: []v3.Point{{Timestamp: 60000, Value: 5}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		expression string
+		want       map[string][]v3.Point
+	}{
+		{
+			expression: "default(B, 0) + A",
+			want: map[string][]v3.Point{
+				"frontend": {{Timestamp: 60000, Value: 15}, {Timestamp: 120000, Value: 20}},
+				"redis":    {{Timestamp: 60000, Value: 30}},
+			},
+		},
+		{
+			expression: "coalesce(B, A / 10)",
+			want: map[string][]v3.Point{
+				"frontend": {{Timestamp: 60000, Value: 5}, {Timestamp: 120000, Value: 2}},
+				"redis":    {{Timestamp: 60000, Value: 3}},
+			},
+		},
+		{
+			expression: "if(A > 15, 1, 0)",
+			want: map[string][]v3.Point{
+				"frontend": {{Timestamp: 60000, Value: 0}, {Timestamp: 120000, Value: 1}},
+				"redis":    {{Timestamp: 60000, Value: 1}},
+			},
+		},
+		{
+			expression: "max(A, B * 3)",
+			want: map[string][]v3.Point{
+				"frontend": {{Timestamp: 60000, Value: 15}, {Timestamp: 120000, Value: 20}},
+				"redis":    {{Timestamp: 60000, Value: 30}},
+			},
+		},
+		{
+			expression: "A * 100 / sum(A)",
+			want: map[string][]v3.Point{
+				"frontend": {{Timestamp: 60000, Value: 25}, {Timestamp: 120000, Value: 100}},
+				"redis":    {{Timestamp: 60000, Value: 75}},
+			},
+		},
+		{
+			expression: "max(A) - min(A)",
+			want: map[string][]v3.Point{
+				"": {{Timestamp: 60000, Value: 20}, {Timestamp: 120000, Value: 0}},
+			},
+		},
+		{
+			expression: "timestamp() + B",
+			want: map[string][]v3.Point{
+				"frontend": {{Timestamp: 60000, Value: 65}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expression, err := govaluate.NewEvaluableExpressionWithFunctions(tt.expression, EvalFuncs())
+			if err != nil {
+				t.Fatalf("Error parsing expression: %v", err)
+			}
+			if err := ValidateFormula(expression); err != nil {
+				t.Fatalf("ValidateFormula() error = %v", err)
+			}
+			got, err := processResults(results, expression, map[string]gapFill{})
+			if err != nil {
+				t.Fatalf("Error processing results: %v", err)
+			}
+			gotPoints := make(map[string][]v3.Point, len(got.Series))
+			for _, series := range got.Series {
+				gotPoints[series.Labels["service_name"]] = series.Points
+			}
+			if !reflect.DeepEqual(gotPoints, tt.want) {
+				t.Errorf("processResults() = %v, want %v", gotPoints, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFormula(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantErr    string
+	}{
+		{expression: "if(A > 0, A)", wantErr: "function if takes 3 arguments, got 2"},
+		{expression: "default(A)", wantErr: "function default takes 2 arguments, got 1"},
+		{expression: "coalesce()", wantErr: "function coalesce takes at least 1 arguments, got 0"},
+		{expression: "sqrt(A, B)", wantErr: "function sqrt takes 1 arguments, got 2"},
+		{expression: "sum(A + B)", wantErr: "function sum of one argument reduces the series of a query, the argument should be a query name"},
+		{expression: "timestamp(A)", wantErr: "function timestamp takes 0 arguments, got 1"},
+		{expression: "max(sum(A), B) + timestamp()"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			expression, err := govaluate.NewEvaluableExpressionWithFunctions(tt.expression, EvalFuncs())
+			if err != nil {
+				t.Fatalf("Error parsing expression: %v", err)
+			}
+			err = ValidateFormula(expression)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateFormula() error = %v", err)
+				}
+			} else if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidateFormula() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormulaFillModes(t *testing.T) {
+	results := []*v3.Result{
+		{
+			QueryName: "A",
+			Series: []*v3.Series{
+				{Points: []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 2}, {Timestamp: 3000, Value: 3}, {Timestamp: 4000, Value: 4}}},
+			},
+		},
+		{
+			QueryName: "B",
+			Series: []*v3.Series{
+				{Points: []v3.Point{{Timestamp: 2000, Value: 10}, {Timestamp: 4000, Value: 30}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		mode v3.FillMode
+		want []v3.Point
+	}{
+		{
+			mode: v3.FillModeNone,
+			want: []v3.Point{{Timestamp: 2000, Value: 12}, {Timestamp: 4000, Value: 34}},
+		},
+		{
+			mode: v3.FillModeZero,
+			want: []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 12}, {Timestamp: 3000, Value: 3}, {Timestamp: 4000, Value: 34}},
+		},
+		{
+			mode: v3.FillModePrevious,
+			want: []v3.Point{{Timestamp: 2000, Value: 12}, {Timestamp: 3000, Value: 13}, {Timestamp: 4000, Value: 34}},
+		},
+		{
+			mode: v3.FillModeLinear,
+			want: []v3.Point{{Timestamp: 2000, Value: 12}, {Timestamp: 3000, Value: 23}, {Timestamp: 4000, Value: 34}},
+		},
+		{
+			mode: v3.FillModeNull,
+			want: []v3.Point{{Timestamp: 2000, Value: 12}, {Timestamp: 4000, Value: 34}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			expression, err := govaluate.NewEvaluableExpressionWithFunctions("A + B", EvalFuncs())
+			if err != nil {
+				t.Fatalf("Error parsing expression: %v", err)
+			}
+			got, err := processResults(results, expression, map[string]gapFill{"A": {mode: v3.FillModeNone}, "B": {mode: tt.mode, step: 1}})
+			if err != nil {
+				t.Fatalf("Error processing results: %v", err)
+			}
+			if len(got.Series) != 1 {
+				t.Fatalf("processResults(): number of series - got = %v, want 1", len(got.Series))
+			}
+			if !reflect.DeepEqual(got.Series[0].Points, tt.want) {
+				t.Errorf("processResults() = %v, want %v", got.Series[0].Points, tt.want)
+			}
+		})
+	}
+}
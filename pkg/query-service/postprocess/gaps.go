@@ -1,6 +1,8 @@
 package postprocess
 
 import (
+	"math"
+
 	"github.com/SigNoz/govaluate"
 	"go.signoz.io/signoz/pkg/query-service/common"
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
@@ -19,40 +21,110 @@ func StepIntervalForFunction(params *v3.QueryRangeParamsV3, query string) int64
 	return q.StepInterval
 }
 
-func fillGap(series *v3.Series, start, end, step int64) *v3.Series {
+// gapFill is how the missing points of the series of a query are filled
+type gapFill struct {
+	mode v3.FillMode
+	// step is the step interval of the query in seconds, the previous value is carried forward
+	// for at most v3.MaxFillPreviousSteps steps. There is no limit without a step.
+	step int64
+}
+
+// queryGapFills returns the gap fill of each builder query of the params
+func queryGapFills(params *v3.QueryRangeParamsV3) map[string]gapFill {
+	fills := make(map[string]gapFill)
+	for _, query := range params.CompositeQuery.BuilderQueries {
+		fills[query.QueryName] = gapFill{mode: query.GapFillMode(), step: query.StepInterval}
+	}
+	return fills
+}
+
+// fillGap fills the missing points of the series at the intervals of step from start to end
+// with the mode. The previous value and the linear interpolation don't fill the points before
+// the first point of the series, and the linear interpolation the points after the last point
+func fillGap(series *v3.Series, start, end, step int64, mode v3.FillMode) *v3.Series {
 	v := make(map[int64]float64)
 	for _, point := range series.Points {
 		v[point.Timestamp] = point.Value
 	}
 
-	// For all the values from start to end, find the timestamps
-	// that don't have value and add zero point
-	start = start - (start % (step * 1000))
-	for i := start; i <= end; i += step * 1000 {
-		if _, ok := v[i]; !ok {
-			v[i] = 0
-		}
-	}
 	newSeries := &v3.Series{
 		Labels:      series.Labels,
 		LabelsArray: series.LabelsArray,
 		Points:      make([]v3.Point, 0),
 	}
+	// For all the values from start to end, find the timestamps
+	// that don't have value and add the point for the mode
+	start = start - (start % (step * 1000))
+	next := 0
 	for i := start; i <= end; i += step * 1000 {
-		newSeries.Points = append(newSeries.Points, v3.Point{Timestamp: i, Value: v[i]})
+		for next < len(series.Points) && series.Points[next].Timestamp <= i {
+			next++
+		}
+		if value, ok := v[i]; ok {
+			newSeries.Points = append(newSeries.Points, v3.Point{Timestamp: i, Value: value})
+			continue
+		}
+		// series.Points[next-1] is the last point before i and series.Points[next] the first after i
+		value, ok := fillValue(series.Points, next, i, gapFill{mode: mode, step: step})
+		if ok {
+			newSeries.Points = append(newSeries.Points, v3.Point{Timestamp: i, Value: value})
+		}
 	}
 	return newSeries
 }
 
+// fillValue returns the value of the missing point at the timestamp for the gap fill, next is
+// the index of the first point after the timestamp in the points sorted by the timestamp
+func fillValue(points []v3.Point, next int, timestamp int64, fill gapFill) (float64, bool) {
+	switch fill.mode {
+	case v3.FillModeZero:
+		return 0, true
+	case v3.FillModeNull:
+		return math.NaN(), true
+	case v3.FillModePrevious:
+		if next > 0 && (fill.step <= 0 || timestamp-points[next-1].Timestamp <= v3.MaxFillPreviousSteps*fill.step*1000) {
+			return points[next-1].Value, true
+		}
+	case v3.FillModeLinear:
+		if next > 0 && next < len(points) {
+			prev, after := points[next-1], points[next]
+			ratio := float64(timestamp-prev.Timestamp) / float64(after.Timestamp-prev.Timestamp)
+			return prev.Value + (after.Value-prev.Value)*ratio, true
+		}
+	}
+	return 0, false
+}
+
+// FillGaps fills the missing points of the series of the queries with the fill mode of the query.
+// The queries without a fill mode are filled with the default of their aggregation, or zero,
+// when the composite query has FillGaps
 // TODO(srikanthccv): can WITH FILL be perfect substitute for all cases https://clickhouse.com/docs/en/sql-reference/statements/select/order-by#order-by-expr-with-fill-modifier
 func FillGaps(results []*v3.Result, params *v3.QueryRangeParamsV3) {
 	if params.CompositeQuery.PanelType != v3.PanelTypeGraph {
 		return
 	}
+	builderQueries := params.CompositeQuery.BuilderQueries
 	for _, result := range results {
+		query, ok := builderQueries[result.QueryName]
+		if !ok {
+			continue
+		}
+		mode := query.FillMode
+		if mode == "" {
+			if !params.CompositeQuery.FillGaps {
+				continue
+			}
+			if mode = query.GapFillMode(); mode == v3.FillModeNone {
+				mode = v3.FillModeZero
+			}
+		}
+		if mode == v3.FillModeNone {
+			continue
+		}
+
 		// A `result` item in `results` contains the query result for individual query.
 		// If there are no series in the result, we add empty series and `fillGap` adds all zeros
-		if len(result.Series) == 0 {
+		if len(result.Series) == 0 && (mode == v3.FillModeZero || mode == v3.FillModeNull) {
 			result.Series = []*v3.Series{
 				{
 					Labels:      make(map[string]string),
@@ -61,13 +133,10 @@ func FillGaps(results []*v3.Result, params *v3.QueryRangeParamsV3) {
 			}
 		}
 
-		builderQueries := params.CompositeQuery.BuilderQueries
-		if builderQueries != nil {
-			// The values should be added at the intervals of `step`
-			step := StepIntervalForFunction(params, result.QueryName)
-			for idx := range result.Series {
-				result.Series[idx] = fillGap(result.Series[idx], params.Start, params.End, step)
-			}
+		// The values should be added at the intervals of `step`
+		step := StepIntervalForFunction(params, result.QueryName)
+		for idx := range result.Series {
+			result.Series[idx] = fillGap(result.Series[idx], params.Start, params.End, step, mode)
 		}
 	}
 }
@@ -1,6 +1,7 @@
 package postprocess
 
 import (
+	"math"
 	"testing"
 
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
@@ -44,6 +45,7 @@ func TestFillGaps(t *testing.T) {
 				End:   5000,
 				CompositeQuery: &v3.CompositeQuery{
 					PanelType: v3.PanelTypeGraph,
+					FillGaps:  true,
 					BuilderQueries: map[string]*v3.BuilderQuery{
 						"query1": {
 							QueryName:    "query1",
@@ -84,6 +86,7 @@ func TestFillGaps(t *testing.T) {
 				End:   5000,
 				CompositeQuery: &v3.CompositeQuery{
 					PanelType: v3.PanelTypeGraph,
+					FillGaps:  true,
 					BuilderQueries: map[string]*v3.BuilderQuery{
 						"query1": {
 							QueryName:    "query1",
@@ -124,6 +127,7 @@ func TestFillGaps(t *testing.T) {
 				End:   5000,
 				CompositeQuery: &v3.CompositeQuery{
 					PanelType: v3.PanelTypeGraph,
+					FillGaps:  true,
 					BuilderQueries: map[string]*v3.BuilderQuery{
 						"query1": {
 							QueryName:    "query1",
@@ -200,3 +204,87 @@ func TestFillGaps(t *testing.T) {
 		})
 	}
 }
+
+func TestFillGapsModes(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		name     string
+		mode     v3.FillMode
+		fillGaps bool
+		end      int64
+		points   []v3.Point
+		expected []v3.Point
+	}{
+		{
+			name:     "previous value",
+			mode:     v3.FillModePrevious,
+			points:   []v3.Point{{Timestamp: 2000, Value: 2}, {Timestamp: 4000, Value: 4}},
+			expected: []v3.Point{{Timestamp: 2000, Value: 2}, {Timestamp: 3000, Value: 2}, {Timestamp: 4000, Value: 4}, {Timestamp: 5000, Value: 4}},
+		},
+		{
+			name:   "previous value for at most the max steps",
+			mode:   v3.FillModePrevious,
+			end:    8000,
+			points: []v3.Point{{Timestamp: 1000, Value: 1}},
+			expected: []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 1}, {Timestamp: 3000, Value: 1},
+				{Timestamp: 4000, Value: 1}, {Timestamp: 5000, Value: 1}, {Timestamp: 6000, Value: 1}},
+		},
+		{
+			name:     "linear interpolation",
+			mode:     v3.FillModeLinear,
+			points:   []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 4000, Value: 7}},
+			expected: []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 3}, {Timestamp: 3000, Value: 5}, {Timestamp: 4000, Value: 7}},
+		},
+		{
+			name:     "null",
+			mode:     v3.FillModeNull,
+			points:   []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 3000, Value: 3}},
+			expected: []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: nan}, {Timestamp: 3000, Value: 3}, {Timestamp: 4000, Value: nan}, {Timestamp: 5000, Value: nan}},
+		},
+		{
+			name:     "none with fill gaps",
+			mode:     v3.FillModeNone,
+			fillGaps: true,
+			points:   []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 3000, Value: 3}},
+			expected: []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 3000, Value: 3}},
+		},
+		{
+			name:     "no fill mode without fill gaps",
+			points:   []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 3000, Value: 3}},
+			expected: []v3.Point{{Timestamp: 1000, Value: 1}, {Timestamp: 3000, Value: 3}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := []*v3.Result{{QueryName: "A", Series: []*v3.Series{{Points: tt.points}}}}
+			end := tt.end
+			if end == 0 {
+				end = 5000
+			}
+			params := &v3.QueryRangeParamsV3{
+				Start: 1000,
+				End:   end,
+				CompositeQuery: &v3.CompositeQuery{
+					PanelType: v3.PanelTypeGraph,
+					FillGaps:  tt.fillGaps,
+					BuilderQueries: map[string]*v3.BuilderQuery{
+						"A": {QueryName: "A", Expression: "A", StepInterval: 1, FillMode: tt.mode},
+					},
+				},
+			}
+			FillGaps(results, params)
+			got := results[0].Series[0].Points
+			if len(got) != len(tt.expected) {
+				t.Fatalf("expected %d points, got %d: %v", len(tt.expected), len(got), got)
+			}
+			for i, point := range got {
+				want := tt.expected[i]
+				if point.Timestamp != want.Timestamp ||
+					(point.Value != want.Value && !(math.IsNaN(point.Value) && math.IsNaN(want.Value))) {
+					t.Errorf("expected (%v, %v), got (%v, %v)", want.Timestamp, want.Value, point.Timestamp, point.Value)
+				}
+			}
+		})
+	}
+}
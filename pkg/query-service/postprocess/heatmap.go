@@ -0,0 +1,148 @@
+package postprocess
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+// heatmapLabelsKey returns a key which is same for the series that differ only by le
+func heatmapLabelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		if key != "le" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, key := range keys {
+		sb.WriteString(key)
+		sb.WriteString("=")
+		sb.WriteString(labels[key])
+		sb.WriteString(",")
+	}
+	return sb.String()
+}
+
+// toHeatmap groups the series of each bucket by the rest of the labels, the le label of the
+// series is the upper bound of the bucket. The buckets of the histogram metrics are cumulative
+// so the count of a bucket is the difference between it and the previous bucket
+func toHeatmap(series []*v3.Series, cumulative bool) ([]*v3.HeatmapSeries, error) {
+	type group struct {
+		labels      map[string]string
+		labelsArray []map[string]string
+		// counts by timestamp and upper bound
+		counts map[int64]map[float64]float64
+		bounds map[float64]struct{}
+	}
+
+	groups := make(map[string]*group)
+	var keys []string
+	for _, s := range series {
+		le, ok := s.Labels["le"]
+		if !ok {
+			return nil, fmt.Errorf("series %v doesn't have the le label", s.Labels)
+		}
+		bound, err := strconv.ParseFloat(le, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid le %s: %w", le, err)
+		}
+
+		key := heatmapLabelsKey(s.Labels)
+		g, ok := groups[key]
+		if !ok {
+			g = &group{
+				labels:      make(map[string]string),
+				labelsArray: make([]map[string]string, 0),
+				counts:      make(map[int64]map[float64]float64),
+				bounds:      make(map[float64]struct{}),
+			}
+			for name, value := range s.Labels {
+				if name != "le" {
+					g.labels[name] = value
+				}
+			}
+			for _, labels := range s.LabelsArray {
+				if _, ok := labels["le"]; !ok {
+					g.labelsArray = append(g.labelsArray, labels)
+				}
+			}
+			groups[key] = g
+			keys = append(keys, key)
+		}
+
+		g.bounds[bound] = struct{}{}
+		for _, point := range s.Points {
+			if _, ok := g.counts[point.Timestamp]; !ok {
+				g.counts[point.Timestamp] = make(map[float64]float64)
+			}
+			g.counts[point.Timestamp][bound] += point.Value
+		}
+	}
+
+	sort.Strings(keys)
+	heatmap := make([]*v3.HeatmapSeries, 0, len(keys))
+	for _, key := range keys {
+		g := groups[key]
+
+		bounds := make([]float64, 0, len(g.bounds))
+		for bound := range g.bounds {
+			bounds = append(bounds, bound)
+		}
+		sort.Float64s(bounds)
+
+		timestamps := make([]int64, 0, len(g.counts))
+		for ts := range g.counts {
+			timestamps = append(timestamps, ts)
+		}
+		sort.Slice(timestamps, func(i, j int) bool { return timestamps[i] < timestamps[j] })
+
+		points := make([]v3.HeatmapPoint, 0, len(timestamps))
+		for _, ts := range timestamps {
+			counts := make([]float64, len(bounds))
+			var previous float64
+			for idx, bound := range bounds {
+				value := g.counts[ts][bound]
+				if !cumulative {
+					counts[idx] = value
+					continue
+				}
+				// the missing bucket has the same cumulative count as the previous one, and as
+				// the buckets are not scraped at the same instant the difference can be negative
+				if value > previous {
+					counts[idx] = value - previous
+					previous = value
+				}
+			}
+			points = append(points, v3.HeatmapPoint{Timestamp: ts, Counts: counts})
+		}
+
+		heatmap = append(heatmap, &v3.HeatmapSeries{
+			Labels:      g.labels,
+			LabelsArray: g.labelsArray,
+			Buckets:     bounds,
+			Points:      points,
+		})
+	}
+	return heatmap, nil
+}
+
+// TransformToHeatmap converts the bucketed series of the builder query result to the
+// heatmap of the query, the series of the result are replaced by the heatmap
+func TransformToHeatmap(result *v3.Result, params *v3.QueryRangeParamsV3) error {
+	query := params.CompositeQuery.BuilderQueries[result.QueryName]
+	if query == nil {
+		return nil
+	}
+	heatmap, err := toHeatmap(result.Series, query.DataSource == v3.DataSourceMetrics)
+	if err != nil {
+		return fmt.Errorf("error converting query %s to heatmap: %w", result.QueryName, err)
+	}
+	result.Heatmap = heatmap
+	result.Series = nil
+	return nil
+}
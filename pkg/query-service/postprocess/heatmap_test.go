@@ -0,0 +1,122 @@
+package postprocess
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+func TestTransformToHeatmap(t *testing.T) {
+	createSeries := func(labels map[string]string, points []v3.Point) *v3.Series {
+		labelsArray := []map[string]string{}
+		for key, value := range labels {
+			labelsArray = append(labelsArray, map[string]string{key: value})
+		}
+		return &v3.Series{Labels: labels, LabelsArray: labelsArray, Points: points}
+	}
+
+	tests := []struct {
+		name       string
+		dataSource v3.DataSource
+		series     []*v3.Series
+		expected   []*v3.HeatmapSeries
+	}{
+		{
+			name:       "traces buckets grouped by service",
+			dataSource: v3.DataSourceTraces,
+			series: []*v3.Series{
+				createSeries(map[string]string{"serviceName": "api", "le": "1.048576e+06"}, []v3.Point{
+					{Timestamp: 2000, Value: 4},
+					{Timestamp: 1000, Value: 2},
+				}),
+				createSeries(map[string]string{"serviceName": "api", "le": "524288"}, []v3.Point{
+					{Timestamp: 1000, Value: 5},
+				}),
+				createSeries(map[string]string{"serviceName": "web", "le": "+Inf"}, []v3.Point{
+					{Timestamp: 1000, Value: 1},
+				}),
+			},
+			expected: []*v3.HeatmapSeries{
+				{
+					Labels:      map[string]string{"serviceName": "api"},
+					LabelsArray: []map[string]string{{"serviceName": "api"}},
+					Buckets:     v3.HeatmapBuckets{524288, 1048576},
+					Points: []v3.HeatmapPoint{
+						{Timestamp: 1000, Counts: []float64{5, 2}},
+						{Timestamp: 2000, Counts: []float64{0, 4}},
+					},
+				},
+				{
+					Labels:      map[string]string{"serviceName": "web"},
+					LabelsArray: []map[string]string{{"serviceName": "web"}},
+					Buckets:     v3.HeatmapBuckets{math.Inf(1)},
+					Points: []v3.HeatmapPoint{
+						{Timestamp: 1000, Counts: []float64{1}},
+					},
+				},
+			},
+		},
+		{
+			name:       "cumulative metric buckets",
+			dataSource: v3.DataSourceMetrics,
+			series: []*v3.Series{
+				createSeries(map[string]string{"le": "+Inf"}, []v3.Point{
+					{Timestamp: 1000, Value: 10},
+					{Timestamp: 2000, Value: 6},
+				}),
+				createSeries(map[string]string{"le": "100"}, []v3.Point{
+					{Timestamp: 1000, Value: 3},
+					{Timestamp: 2000, Value: 6},
+				}),
+				createSeries(map[string]string{"le": "250"}, []v3.Point{
+					{Timestamp: 1000, Value: 8},
+					// scraped before the +Inf bucket
+					{Timestamp: 2000, Value: 7},
+				}),
+			},
+			expected: []*v3.HeatmapSeries{
+				{
+					Labels:      map[string]string{},
+					LabelsArray: []map[string]string{},
+					Buckets:     v3.HeatmapBuckets{100, 250, math.Inf(1)},
+					Points: []v3.HeatmapPoint{
+						{Timestamp: 1000, Counts: []float64{3, 5, 2}},
+						{Timestamp: 2000, Counts: []float64{6, 1, 0}},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &v3.Result{QueryName: "A", Series: tt.series}
+			params := &v3.QueryRangeParamsV3{
+				CompositeQuery: &v3.CompositeQuery{
+					PanelType: v3.PanelTypeHeatmap,
+					BuilderQueries: map[string]*v3.BuilderQuery{
+						"A": {QueryName: "A", Expression: "A", DataSource: tt.dataSource},
+					},
+				},
+			}
+			err := TransformToHeatmap(result, params)
+			require.NoError(t, err)
+			assert.Nil(t, result.Series)
+			assert.Equal(t, tt.expected, result.Heatmap)
+		})
+	}
+
+	t.Run("series without le", func(t *testing.T) {
+		result := &v3.Result{QueryName: "A", Series: []*v3.Series{createSeries(map[string]string{"service": "api"}, nil)}}
+		params := &v3.QueryRangeParamsV3{
+			CompositeQuery: &v3.CompositeQuery{
+				BuilderQueries: map[string]*v3.BuilderQuery{"A": {QueryName: "A", Expression: "A", DataSource: v3.DataSourceLogs}},
+			},
+		}
+		err := TransformToHeatmap(result, params)
+		assert.EqualError(t, err, "error converting query A to heatmap: series map[service:api] doesn't have the le label")
+	})
+}
@@ -0,0 +1,165 @@
+package postprocess
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/SigNoz/govaluate"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+// projectOnJoinKey returns the series of the result keyed by the value of the join label, the
+// label is renamed to the join key. The series of a query grouped by more labels than the join
+// label are summed per timestamp, and the series without the join label are dropped
+func projectOnJoinKey(result *v3.Result, label, key string) (*v3.Result, map[string]struct{}) {
+	values := make(map[string]struct{})
+	// map[value]map[timestamp]value
+	points := make(map[string]map[int64]float64)
+	for _, series := range result.Series {
+		value, ok := series.Labels[label]
+		if !ok {
+			continue
+		}
+		if _, ok := points[value]; !ok {
+			points[value] = make(map[int64]float64)
+		}
+		values[value] = struct{}{}
+		for _, point := range series.Points {
+			points[value][point.Timestamp] += point.Value
+		}
+	}
+
+	projected := &v3.Result{QueryName: result.QueryName, Series: make([]*v3.Series, 0, len(points))}
+	for value, byTimestamp := range points {
+		series := &v3.Series{
+			Labels: map[string]string{key: value},
+			Points: make([]v3.Point, 0, len(byTimestamp)),
+		}
+		for ts, v := range byTimestamp {
+			series.Points = append(series.Points, v3.Point{Timestamp: ts, Value: v})
+		}
+		projected.Series = append(projected.Series, series)
+	}
+	return projected, values
+}
+
+// joinKeyValues returns the values of the join key in the result of the join, in sorted order
+func joinKeyValues(joinType v3.JoinType, variables []string, values map[string]map[string]struct{}) []string {
+	selected := make(map[string]struct{})
+	switch joinType {
+	case v3.JoinTypeInner:
+		for value := range values[variables[0]] {
+			inAll := true
+			for _, variable := range variables[1:] {
+				if _, ok := values[variable][value]; !ok {
+					inAll = false
+					break
+				}
+			}
+			if inAll {
+				selected[value] = struct{}{}
+			}
+		}
+	case v3.JoinTypeLeft:
+		selected = values[variables[0]]
+	case v3.JoinTypeOuter:
+		for _, variable := range variables {
+			for value := range values[variable] {
+				selected[value] = struct{}{}
+			}
+		}
+	}
+
+	keyValues := make([]string, 0, len(selected))
+	for value := range selected {
+		keyValues = append(keyValues, value)
+	}
+	sort.Strings(keyValues)
+	return keyValues
+}
+
+// processJoin evaluates the expression on the results joined on the join key
+// 1. Rename the join label of each query to the key and sum the series by the key
+// 2. Select the values of the key by the join type, inner join keeps the values present in all
+// the queries, left join the values of the first query and outer join the values of any query
+// 3. For each value, join the series on timestamp and calculate the new values. The queries
+// without a series for the value are zero for the left and outer joins
+func processJoin(
+	results []*v3.Result,
+	expression *govaluate.EvaluableExpression,
+	join *v3.Join,
+	fills map[string]gapFill,
+) (*v3.Result, error) {
+	expression, reduced, err := ExpandFormula(expression)
+	if err != nil {
+		return nil, err
+	}
+	if len(reduced) > 0 {
+		return nil, fmt.Errorf("cross series reducers are not supported in the formulas with join")
+	}
+
+	variables := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, v := range expression.Vars() {
+		if _, ok := seen[v]; !ok && v != TimestampVar {
+			seen[v] = struct{}{}
+			variables = append(variables, v)
+		}
+	}
+	if len(variables) == 0 {
+		return nil, fmt.Errorf("join requires at least one query in the expression")
+	}
+
+	projected := make([]*v3.Result, 0, len(variables))
+	values := make(map[string]map[string]struct{})
+	for _, result := range results {
+		if _, ok := seen[result.QueryName]; !ok {
+			continue
+		}
+		label := join.Key
+		if renamed, ok := join.Rename[result.QueryName]; ok {
+			label = renamed
+		}
+		var projectedResult *v3.Result
+		projectedResult, values[result.QueryName] = projectOnJoinKey(result, label, join.Key)
+		projected = append(projected, projectedResult)
+	}
+
+	newSeries := make([]*v3.Series, 0)
+	for _, value := range joinKeyValues(join.Type, variables, values) {
+		valueFills := make(map[string]gapFill, len(variables))
+		for _, v := range variables {
+			valueFills[v] = fills[v]
+			if _, ok := values[v][value]; !ok && join.Type != v3.JoinTypeInner {
+				valueFills[v] = gapFill{mode: v3.FillModeZero}
+			}
+		}
+
+		series, err := joinAndCalculate(projected, map[string]string{join.Key: value}, expression, valueFills)
+		if err != nil {
+			return nil, err
+		}
+		if len(series.Points) != 0 {
+			series.LabelsArray = []map[string]string{{join.Key: value}}
+			newSeries = append(newSeries, series)
+		}
+	}
+
+	return &v3.Result{
+		Series: newSeries,
+	}, nil
+}
+
+// ProcessJoinFormula evaluates the formula query with join on the results of the queries
+func ProcessJoinFormula(results []*v3.Result, query *v3.BuilderQuery, params *v3.QueryRangeParamsV3) (*v3.Result, error) {
+	expression, err := govaluate.NewEvaluableExpressionWithFunctions(query.Expression, EvalFuncs())
+	if err != nil {
+		return nil, err
+	}
+	result, err := processJoin(results, expression, query.Join, queryGapFills(params))
+	if err != nil {
+		return nil, err
+	}
+	result.QueryName = query.QueryName
+	return result, nil
+}
@@ -0,0 +1,122 @@
+package postprocess
+
+import (
+	"testing"
+
+	"github.com/SigNoz/govaluate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+func TestProcessJoin(t *testing.T) {
+	results := []*v3.Result{
+		{
+			// trace error counts
+			QueryName: "A",
+			Series: []*v3.Series{
+				{
+					Labels: map[string]string{"serviceName": "frontend", "operation": "GET /api"},
+					Points: []v3.Point{{Timestamp: 1, Value: 6}, {Timestamp: 2, Value: 20}},
+				},
+				{
+					Labels: map[string]string{"serviceName": "frontend", "operation": "POST /api"},
+					Points: []v3.Point{{Timestamp: 1, Value: 4}},
+				},
+				{
+					Labels: map[string]string{"serviceName": "redis"},
+					Points: []v3.Point{{Timestamp: 1, Value: 4}},
+				},
+				{
+					Labels: map[string]string{"operation": "GET /"},
+					Points: []v3.Point{{Timestamp: 1, Value: 100}},
+				},
+			},
+		},
+		{
+			// log error counts
+			QueryName: "B",
+			Series: []*v3.Series{
+				{
+					Labels: map[string]string{"service.name": "frontend"},
+					Points: []v3.Point{{Timestamp: 1, Value: 5}, {Timestamp: 2, Value: 10}},
+				},
+				{
+					Labels: map[string]string{"service.name": "driver"},
+					Points: []v3.Point{{Timestamp: 2, Value: 2}},
+				},
+			},
+		},
+	}
+
+	series := func(service string, points ...v3.Point) *v3.Series {
+		return &v3.Series{
+			Labels:      map[string]string{"service.name": service},
+			LabelsArray: []map[string]string{{"service.name": service}},
+			Points:      points,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		joinType v3.JoinType
+		expected []*v3.Series
+	}{
+		{
+			name:     "inner join",
+			joinType: v3.JoinTypeInner,
+			expected: []*v3.Series{
+				series("frontend", v3.Point{Timestamp: 1, Value: 15}, v3.Point{Timestamp: 2, Value: 30}),
+			},
+		},
+		{
+			name:     "left join",
+			joinType: v3.JoinTypeLeft,
+			expected: []*v3.Series{
+				series("frontend", v3.Point{Timestamp: 1, Value: 15}, v3.Point{Timestamp: 2, Value: 30}),
+				series("redis", v3.Point{Timestamp: 1, Value: 4}),
+			},
+		},
+		{
+			name:     "outer join",
+			joinType: v3.JoinTypeOuter,
+			expected: []*v3.Series{
+				series("driver", v3.Point{Timestamp: 2, Value: 2}),
+				series("frontend", v3.Point{Timestamp: 1, Value: 15}, v3.Point{Timestamp: 2, Value: 30}),
+				series("redis", v3.Point{Timestamp: 1, Value: 4}),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := govaluate.NewEvaluableExpressionWithFunctions("A + B", EvalFuncs())
+			require.NoError(t, err)
+			join := &v3.Join{Type: tt.joinType, Key: "service.name", Rename: map[string]string{"A": "serviceName"}}
+			result, err := processJoin(results, expression, join, map[string]gapFill{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result.Series)
+		})
+	}
+
+	t.Run("left side is the first query of the expression", func(t *testing.T) {
+		expression, err := govaluate.NewEvaluableExpressionWithFunctions("B - A", EvalFuncs())
+		require.NoError(t, err)
+		join := &v3.Join{Type: v3.JoinTypeLeft, Key: "service.name", Rename: map[string]string{"A": "serviceName"}}
+		result, err := processJoin(results, expression, join, map[string]gapFill{})
+		require.NoError(t, err)
+		assert.Equal(t, []*v3.Series{
+			series("driver", v3.Point{Timestamp: 2, Value: 2}),
+			series("frontend", v3.Point{Timestamp: 1, Value: -5}, v3.Point{Timestamp: 2, Value: -10}),
+		}, result.Series)
+	})
+
+	t.Run("inner join with a query without results", func(t *testing.T) {
+		expression, err := govaluate.NewEvaluableExpressionWithFunctions("A / B", EvalFuncs())
+		require.NoError(t, err)
+		join := &v3.Join{Type: v3.JoinTypeInner, Key: "service.name", Rename: map[string]string{"A": "serviceName"}}
+		result, err := processJoin(results[:1], expression, join, map[string]gapFill{"B": {mode: v3.FillModeZero}})
+		require.NoError(t, err)
+		assert.Empty(t, result.Series)
+	})
+}
@@ -41,11 +41,7 @@ func PostProcessResult(result []*v3.Result, queryRangeParams *v3.QueryRangeParam
 		tablePanelResultProcessor(result)
 	}
 
-	canDefaultZero := make(map[string]bool)
-
-	for _, query := range queryRangeParams.CompositeQuery.BuilderQueries {
-		canDefaultZero[query.QueryName] = query.CanDefaultZero()
-	}
+	fills := queryGapFills(queryRangeParams)
 
 	for _, query := range queryRangeParams.CompositeQuery.BuilderQueries {
 		// The way we distinguish between a formula and a query is by checking if the expression
@@ -58,7 +54,12 @@ func PostProcessResult(result []*v3.Result, queryRangeParams *v3.QueryRangeParam
 				zap.L().Error("error in expression", zap.Error(err))
 				return nil, err
 			}
-			formulaResult, err := processResults(result, expression, canDefaultZero)
+			var formulaResult *v3.Result
+			if query.Join != nil {
+				formulaResult, err = processJoin(result, expression, query.Join, fills)
+			} else {
+				formulaResult, err = processResults(result, expression, fills)
+			}
 			if err != nil {
 				zap.L().Error("error in expression", zap.Error(err))
 				return nil, err
@@ -69,6 +70,12 @@ func PostProcessResult(result []*v3.Result, queryRangeParams *v3.QueryRangeParam
 			result = append(result, formulaResult)
 		}
 	}
+	// top k is applied after the formulas so that the series left out are still
+	// used in the formulas
+	ApplyTopK(result, queryRangeParams)
+	// the offset labels are added after the formulas so the series of the queries still join
+	TagCompareSeries(result, queryRangeParams)
+
 	// we are done with the formula calculations, only send the results for enabled queries
 	removeDisabledQueries := func(result []*v3.Result) []*v3.Result {
 		var newResult []*v3.Result
@@ -83,9 +90,7 @@ func PostProcessResult(result []*v3.Result, queryRangeParams *v3.QueryRangeParam
 	if queryRangeParams.CompositeQuery.QueryType == v3.QueryTypeBuilder {
 		result = removeDisabledQueries(result)
 	}
-	if queryRangeParams.CompositeQuery.FillGaps {
-		FillGaps(result, queryRangeParams)
-	}
+	FillGaps(result, queryRangeParams)
 
 	if queryRangeParams.FormatForWeb &&
 		queryRangeParams.CompositeQuery.QueryType == v3.QueryTypeBuilder &&
@@ -96,18 +101,32 @@ func PostProcessResult(result []*v3.Result, queryRangeParams *v3.QueryRangeParam
 	return result, nil
 }
 
+// queryFunctions returns the functions of the builder, promql or clickhouse query with the name
+func queryFunctions(queryRangeParams *v3.QueryRangeParamsV3, queryName string) []v3.Function {
+	compositeQuery := queryRangeParams.CompositeQuery
+	switch compositeQuery.QueryType {
+	case v3.QueryTypeBuilder:
+		if query, ok := compositeQuery.BuilderQueries[queryName]; ok {
+			return query.Functions
+		}
+	case v3.QueryTypePromQL:
+		if query, ok := compositeQuery.PromQueries[queryName]; ok {
+			return query.Functions
+		}
+	case v3.QueryTypeClickHouseSQL:
+		if query, ok := compositeQuery.ClickHouseQueries[queryName]; ok {
+			return query.Functions
+		}
+	}
+	return nil
+}
+
 // ApplyFunctions applies functions for each query in the composite query
 // The functions can be more than one, and they are applied in the order they are defined
 func ApplyFunctions(results []*v3.Result, queryRangeParams *v3.QueryRangeParamsV3) {
 	for idx, result := range results {
-		builderQueries := queryRangeParams.CompositeQuery.BuilderQueries
-
-		if builderQueries != nil {
-			functions := builderQueries[result.QueryName].Functions
-
-			for _, function := range functions {
-				results[idx] = queryBuilder.ApplyFunction(function, result)
-			}
+		for _, function := range queryFunctions(queryRangeParams, result.QueryName) {
+			results[idx] = queryBuilder.ApplyFunction(function, result)
 		}
 	}
 }
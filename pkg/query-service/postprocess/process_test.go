@@ -220,3 +220,54 @@ func TestPostProcessResult(t *testing.T) {
 		})
 	}
 }
+
+func TestApplyFunctionsForPromQLAndClickHouseQueries(t *testing.T) {
+	results := func() []*v3.Result {
+		return []*v3.Result{
+			{
+				QueryName: "A",
+				Series: []*v3.Series{
+					{Labels: map[string]string{"cluster": "prod", "pod": "a"}, Points: []v3.Point{{Timestamp: 1, Value: 1}}},
+					{Labels: map[string]string{"cluster": "prod", "pod": "b"}, Points: []v3.Point{{Timestamp: 1, Value: 2}}},
+				},
+			},
+		}
+	}
+	functions := []v3.Function{{Name: v3.FunctionNameSumBy, Args: []interface{}{"cluster"}}}
+	want := []*v3.Series{
+		{
+			Labels:      map[string]string{"cluster": "prod"},
+			LabelsArray: []map[string]string{{"cluster": "prod"}},
+			Points:      []v3.Point{{Timestamp: 1, Value: 3}},
+		},
+	}
+
+	testCases := []struct {
+		name           string
+		compositeQuery *v3.CompositeQuery
+	}{
+		{
+			name: "promql",
+			compositeQuery: &v3.CompositeQuery{
+				QueryType:   v3.QueryTypePromQL,
+				PromQueries: map[string]*v3.PromQuery{"A": {Query: "up", Functions: functions}},
+			},
+		},
+		{
+			name: "clickhouse sql",
+			compositeQuery: &v3.CompositeQuery{
+				QueryType:         v3.QueryTypeClickHouseSQL,
+				ClickHouseQueries: map[string]*v3.ClickHouseQuery{"A": {Query: "SELECT 1", Functions: functions}},
+			},
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			got := results()
+			ApplyFunctions(got, &v3.QueryRangeParamsV3{CompositeQuery: tt.compositeQuery})
+			if !reflect.DeepEqual(got[0].Series, want) {
+				t.Errorf("ApplyFunctions() = %v, want %v", got[0].Series, want)
+			}
+		})
+	}
+}
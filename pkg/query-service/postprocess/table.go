@@ -7,7 +7,9 @@ import (
 	"strings"
 
 	"go.signoz.io/signoz/pkg/query-service/constants"
+	"go.signoz.io/signoz/pkg/query-service/formatter"
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.uber.org/zap"
 )
 
 func roundToTwoDecimal(number float64) float64 {
@@ -65,6 +67,8 @@ func TransformToTableForBuilderQueries(results []*v3.Result, params *v3.QueryRan
 
 	// Create a map to store unique rows
 	rowMap := make(map[string]*v3.TableRow)
+	// rowKeys are the keys of the rows, used to break the ties in the sort order of the table options
+	rowKeys := make(map[*v3.TableRow]string)
 
 	for _, result := range results {
 		for _, series := range result.Series {
@@ -93,6 +97,7 @@ func TransformToTableForBuilderQueries(results []*v3.Result, params *v3.QueryRan
 			if !ok {
 				row = &v3.TableRow{Data: rowData, QueryName: result.QueryName}
 				rowMap[rowKey] = row
+				rowKeys[row] = rowKey
 			}
 
 			// Add the value for this query
@@ -118,6 +123,10 @@ func TransformToTableForBuilderQueries(results []*v3.Result, params *v3.QueryRan
 	}
 	sort.Strings(queryNames)
 
+	if params.CompositeQuery.Table != nil {
+		return []*v3.Result{{Table: transformTable(columns, rows, rowKeys, params, queryNames)}}
+	}
+
 	// Sort rows based on OrderBy from BuilderQueries
 	sortRows(rows, params.CompositeQuery.BuilderQueries, queryNames)
 
@@ -142,6 +151,183 @@ func TransformToTableForBuilderQueries(results []*v3.Result, params *v3.QueryRan
 	return []*v3.Result{&tableResult}
 }
 
+// transformTable sorts the rows by the order by of the table options, or the order by of the
+// queries when empty, and returns the page of the rows after the cursor with the values formatted
+func transformTable(
+	columns []*v3.TableColumn,
+	rows []*v3.TableRow,
+	rowKeys map[*v3.TableRow]string,
+	params *v3.QueryRangeParamsV3,
+	queryNames []string,
+) *v3.Table {
+	options := params.CompositeQuery.Table
+
+	for _, row := range rows {
+		for _, col := range columns {
+			if !col.IsValueColumn {
+				continue
+			}
+			// NaN can't be encoded in the response and the cursor
+			if value, ok := row.Data[col.Name].(float64); row.Data[col.Name] == nil || (ok && math.IsNaN(value)) {
+				row.Data[col.Name] = "n/a"
+			}
+		}
+	}
+
+	orderBy := options.OrderBy
+	if len(orderBy) == 0 {
+		orderBy = tableOrderByFromQueries(params.CompositeQuery.BuilderQueries, queryNames)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return compareRows(rows[i].Data, rowKeys[rows[i]], rows[j].Data, rowKeys[rows[j]], orderBy) < 0
+	})
+
+	table := &v3.Table{
+		Columns:   columns,
+		Rows:      rows,
+		TotalRows: len(rows),
+	}
+	if options.PageSize > 0 {
+		table.Rows, table.NextCursor = paginateRows(rows, rowKeys, orderBy, options)
+	}
+	formatTable(table, options.Units, params.CompositeQuery.Unit)
+	return table
+}
+
+// tableOrderByFromQueries returns the order by of the queries in the order of the query names,
+// the rows sorted by it are in the same order as sortRows
+func tableOrderByFromQueries(builderQueries map[string]*v3.BuilderQuery, queryNames []string) []v3.TableOrderBy {
+	orderBy := make([]v3.TableOrderBy, 0)
+	for _, queryName := range queryNames {
+		orderByList := builderQueries[queryName].OrderBy
+		if len(orderByList) == 0 {
+			orderByList = []v3.OrderBy{{ColumnName: constants.SigNozOrderByValue, Order: "desc"}}
+		}
+		for _, item := range orderByList {
+			name := item.ColumnName
+			if name == constants.SigNozOrderByValue {
+				name = queryName
+			}
+			orderBy = append(orderBy, v3.TableOrderBy{ColumnName: name, Order: item.Order})
+		}
+	}
+	return orderBy
+}
+
+// compareValues orders the numbers before the strings and the strings before the bools
+func compareValues(a, b interface{}) int {
+	rank := func(v interface{}) int {
+		switch v.(type) {
+		case float64:
+			return 0
+		case string:
+			return 1
+		case bool:
+			return 2
+		}
+		return 3
+	}
+	if rank(a) != rank(b) {
+		return rank(a) - rank(b)
+	}
+	switch v := a.(type) {
+	case float64:
+		w := b.(float64)
+		if v < w {
+			return -1
+		} else if v > w {
+			return 1
+		}
+	case string:
+		return strings.Compare(v, b.(string))
+	case bool:
+		if v != b.(bool) {
+			if !v {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// compareRows compares the rows by the columns of the order by and by the row keys when the values are equal
+func compareRows(a map[string]interface{}, aKey string, b map[string]interface{}, bKey string, orderBy []v3.TableOrderBy) int {
+	for _, item := range orderBy {
+		cmp := compareValues(a[item.ColumnName], b[item.ColumnName])
+		if item.Order == "desc" {
+			cmp = -cmp
+		}
+		if cmp != 0 {
+			return cmp
+		}
+	}
+	return strings.Compare(aKey, bKey)
+}
+
+// paginateRows returns the page of the sorted rows after the cursor and the cursor of the next page.
+// The rows are found by the values of the cursor, so the page stays right when the rows before it change
+func paginateRows(rows []*v3.TableRow, rowKeys map[*v3.TableRow]string, orderBy []v3.TableOrderBy, options *v3.TableOptions) ([]*v3.TableRow, string) {
+	start := 0
+	if options.Cursor != "" {
+		cursor, err := v3.DecodeTableCursor(options.Cursor)
+		if err != nil {
+			// This shouldn't happen here, because it should have been caught earlier in validation
+			zap.L().Error("error in table cursor", zap.Error(err))
+			return []*v3.TableRow{}, ""
+		}
+		cursorData := make(map[string]interface{}, len(orderBy))
+		for idx, item := range orderBy {
+			if idx < len(cursor.Values) {
+				cursorData[item.ColumnName] = cursor.Values[idx]
+			}
+		}
+		start = sort.Search(len(rows), func(i int) bool {
+			return compareRows(rows[i].Data, rowKeys[rows[i]], cursorData, cursor.Key, orderBy) > 0
+		})
+	}
+
+	end := start + options.PageSize
+	if end >= len(rows) {
+		return rows[start:], ""
+	}
+
+	last := rows[end-1]
+	cursor := &v3.TableCursor{Key: rowKeys[last], Values: make([]interface{}, 0, len(orderBy))}
+	for _, item := range orderBy {
+		cursor.Values = append(cursor.Values, last.Data[item.ColumnName])
+	}
+	next, err := cursor.Encode()
+	if err != nil {
+		zap.L().Error("error in encoding table cursor", zap.Error(err))
+		return rows[start:end], ""
+	}
+	return rows[start:end], next
+}
+
+// formatTable sets the unit of the columns and formats the numeric values of the columns with a unit,
+// the value columns without a unit in units use the unit of the panel
+func formatTable(table *v3.Table, units map[string]string, panelUnit string) {
+	for _, col := range table.Columns {
+		col.Unit = units[col.Name]
+		if col.Unit == "" && col.IsValueColumn {
+			col.Unit = panelUnit
+		}
+	}
+	for _, row := range table.Rows {
+		for _, col := range table.Columns {
+			value, ok := row.Data[col.Name].(float64)
+			if col.Unit == "" || !ok {
+				continue
+			}
+			if row.Formatted == nil {
+				row.Formatted = make(map[string]string)
+			}
+			row.Formatted[col.Name] = formatter.FromUnit(col.Unit).Format(value, col.Unit)
+		}
+	}
+}
+
 func sortRows(rows []*v3.TableRow, builderQueries map[string]*v3.BuilderQuery, queryNames []string) {
 	// use reverse order of queryNames
 	for i := len(queryNames) - 1; i >= 0; i-- {
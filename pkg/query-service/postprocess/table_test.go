@@ -610,3 +610,139 @@ func TestTransformToTableForClickHouseQueriesSorting(t *testing.T) {
 		t.Errorf("TransformToTableForClickHouseQueries() sorting test failed. Got %v, want %v", string(got), string(exp))
 	}
 }
+
+func TestTransformToTableForBuilderQueriesWithOptions(t *testing.T) {
+	createResult := func(queryName string, values map[string]float64) *v3.Result {
+		result := &v3.Result{QueryName: queryName}
+		for service, value := range values {
+			result.Series = append(result.Series, &v3.Series{
+				Labels:      map[string]string{"service": service},
+				LabelsArray: []map[string]string{{"service": service}},
+				Points:      []v3.Point{{Timestamp: 0, Value: value}},
+			})
+		}
+		return result
+	}
+	newResults := func() []*v3.Result {
+		return []*v3.Result{
+			createResult("A", map[string]float64{"api": 10, "db": 20, "cache": 20, "web": 5, "queue": 1}),
+			createResult("F1", map[string]float64{"api": 100, "db": 200, "cache": 200, "web": 50}),
+		}
+	}
+
+	tests := []struct {
+		name     string
+		options  *v3.TableOptions
+		expected []string
+	}{
+		{
+			name: "order by formula and label",
+			options: &v3.TableOptions{
+				OrderBy:  []v3.TableOrderBy{{ColumnName: "F1", Order: "desc"}, {ColumnName: "service", Order: "asc"}},
+				PageSize: 2,
+			},
+			// queue has no F1 value, n/a is after the numbers in ascending order
+			expected: []string{"queue", "cache", "db", "api", "web"},
+		},
+		{
+			name: "order by label",
+			options: &v3.TableOptions{
+				OrderBy:  []v3.TableOrderBy{{ColumnName: "service", Order: "asc"}},
+				PageSize: 3,
+			},
+			expected: []string{"api", "cache", "db", "queue", "web"},
+		},
+		{
+			name:     "order by queries",
+			options:  &v3.TableOptions{PageSize: 2},
+			expected: []string{"cache", "db", "api", "web", "queue"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := &v3.QueryRangeParamsV3{
+				CompositeQuery: &v3.CompositeQuery{
+					PanelType: v3.PanelTypeTable,
+					QueryType: v3.QueryTypeBuilder,
+					BuilderQueries: map[string]*v3.BuilderQuery{
+						"A":  {QueryName: "A", Expression: "A"},
+						"F1": {QueryName: "F1", Expression: "A * 10"},
+					},
+					Table: tt.options,
+				},
+			}
+			if err := tt.options.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+
+			services := make([]string, 0)
+			for pages := 0; pages < len(tt.expected); pages++ {
+				table := TransformToTableForBuilderQueries(newResults(), params)[0].Table
+				if table.TotalRows != len(tt.expected) {
+					t.Errorf("TotalRows = %d, want %d", table.TotalRows, len(tt.expected))
+				}
+				if len(table.Rows) > tt.options.PageSize {
+					t.Errorf("got %d rows, want at most %d", len(table.Rows), tt.options.PageSize)
+				}
+				for _, row := range table.Rows {
+					services = append(services, row.Data["service"].(string))
+				}
+				if table.NextCursor == "" {
+					break
+				}
+				params.CompositeQuery.Table.Cursor = table.NextCursor
+			}
+			if !reflect.DeepEqual(services, tt.expected) {
+				t.Errorf("got rows %v, want %v", services, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTransformToTableForBuilderQueriesFormatting(t *testing.T) {
+	results := []*v3.Result{
+		{
+			QueryName: "A",
+			Series: []*v3.Series{
+				{
+					LabelsArray: []map[string]string{{"service": "api"}},
+					Points:      []v3.Point{{Timestamp: 0, Value: 1500}},
+				},
+			},
+		},
+		{
+			QueryName: "B",
+			Series: []*v3.Series{
+				{
+					LabelsArray: []map[string]string{{"service": "api"}},
+					Points:      []v3.Point{{Timestamp: 0, Value: 2048}},
+				},
+			},
+		},
+	}
+	params := &v3.QueryRangeParamsV3{
+		CompositeQuery: &v3.CompositeQuery{
+			PanelType: v3.PanelTypeTable,
+			QueryType: v3.QueryTypeBuilder,
+			Unit:      "ms",
+			BuilderQueries: map[string]*v3.BuilderQuery{
+				"A": {QueryName: "A", Expression: "A"},
+				"B": {QueryName: "B", Expression: "B"},
+			},
+			Table: &v3.TableOptions{Units: map[string]string{"B": "bytes"}},
+		},
+	}
+
+	table := TransformToTableForBuilderQueries(results, params)[0].Table
+	if len(table.Rows) != 1 {
+		t.Fatalf("got %d rows, want 1", len(table.Rows))
+	}
+	expected := map[string]string{"A": "1.50 s", "B": "2.0 KiB"}
+	if !reflect.DeepEqual(table.Rows[0].Formatted, expected) {
+		t.Errorf("Formatted = %v, want %v", table.Rows[0].Formatted, expected)
+	}
+	if table.Rows[0].Data["A"] != 1500.0 {
+		t.Errorf("Data[A] = %v, want 1500", table.Rows[0].Data["A"])
+	}
+}
@@ -0,0 +1,124 @@
+package postprocess
+
+import (
+	"math"
+	"sort"
+
+	"go.signoz.io/signoz/pkg/query-service/constants"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+// reduceSeries reduces the points of the series to a single value, the NaN and Inf
+// values are skipped and NaN is returned when there are no points left
+func reduceSeries(points []v3.Point, reduceTo v3.ReduceToOperator) float64 {
+	var valid []v3.Point
+	for _, point := range points {
+		if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+			continue
+		}
+		valid = append(valid, point)
+	}
+	if len(valid) == 0 {
+		return math.NaN()
+	}
+
+	reduced := valid[0].Value
+	last := valid[0].Timestamp
+	var sum float64
+	for _, point := range valid {
+		sum += point.Value
+		switch reduceTo {
+		case v3.ReduceToOperatorLast:
+			if point.Timestamp >= last {
+				reduced, last = point.Value, point.Timestamp
+			}
+		case v3.ReduceToOperatorMin:
+			reduced = math.Min(reduced, point.Value)
+		case v3.ReduceToOperatorMax:
+			reduced = math.Max(reduced, point.Value)
+		}
+	}
+
+	switch reduceTo {
+	case v3.ReduceToOperatorSum:
+		return sum
+	case v3.ReduceToOperatorAvg:
+		return sum / float64(len(valid))
+	}
+	return reduced
+}
+
+// otherSeries sums the points of the series with the same timestamp, the labels of the
+// series are set to constants.SigNozOtherSeriesValue
+func otherSeries(series []*v3.Series) *v3.Series {
+	labels := make(map[string]string)
+	values := make(map[int64]float64)
+	for _, s := range series {
+		for key := range s.Labels {
+			labels[key] = constants.SigNozOtherSeriesValue
+		}
+		for _, point := range s.Points {
+			if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+				continue
+			}
+			values[point.Timestamp] += point.Value
+		}
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	labelsArray := make([]map[string]string, 0, len(keys))
+	for _, key := range keys {
+		labelsArray = append(labelsArray, map[string]string{key: constants.SigNozOtherSeriesValue})
+	}
+
+	other := &v3.Series{Labels: labels, LabelsArray: labelsArray, Points: make([]v3.Point, 0, len(values))}
+	for ts, value := range values {
+		other.Points = append(other.Points, v3.Point{Timestamp: ts, Value: value})
+	}
+	other.SortPoints()
+	return other
+}
+
+func topK(series []*v3.Series, options *v3.TopK) []*v3.Series {
+	if len(series) <= int(options.K) {
+		return series
+	}
+
+	values := make(map[*v3.Series]float64, len(series))
+	for _, s := range series {
+		values[s] = reduceSeries(s.Points, options.ReduceTo)
+	}
+	sort.SliceStable(series, func(i, j int) bool {
+		vi, vj := values[series[i]], values[series[j]]
+		// the series without a value are always at the end
+		if math.IsNaN(vi) || math.IsNaN(vj) {
+			return !math.IsNaN(vi) && math.IsNaN(vj)
+		}
+		if options.Direction == v3.TopKDirectionBottom {
+			return vi < vj
+		}
+		return vi > vj
+	})
+
+	selected := append([]*v3.Series{}, series[:options.K]...)
+	if options.Other {
+		selected = append(selected, otherSeries(series[options.K:]))
+	}
+	return selected
+}
+
+// ApplyTopK keeps the top or bottom K series of each query by the value of the reducer over
+// the window, it is applied after the formulas so that they are evaluated with all the series
+func ApplyTopK(results []*v3.Result, queryRangeParams *v3.QueryRangeParamsV3) {
+	for _, result := range results {
+		query := queryRangeParams.CompositeQuery.BuilderQueries[result.QueryName]
+		if query == nil || query.TopK == nil {
+			continue
+		}
+		result.Series = topK(result.Series, query.TopK)
+	}
+}
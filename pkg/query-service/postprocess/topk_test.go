@@ -0,0 +1,115 @@
+package postprocess
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.signoz.io/signoz/pkg/query-service/constants"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+func TestApplyTopK(t *testing.T) {
+	createSeries := func(service string, values ...float64) *v3.Series {
+		points := make([]v3.Point, 0, len(values))
+		for idx, value := range values {
+			points = append(points, v3.Point{Timestamp: int64(idx+1) * 60000, Value: value})
+		}
+		return &v3.Series{
+			Labels:      map[string]string{"service_name": service},
+			LabelsArray: []map[string]string{{"service_name": service}},
+			Points:      points,
+		}
+	}
+	names := func(series []*v3.Series) []string {
+		var names []string
+		for _, s := range series {
+			names = append(names, s.Labels["service_name"])
+		}
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		topK     *v3.TopK
+		expected []string
+	}{
+		{
+			name:     "top 2 by max",
+			topK:     &v3.TopK{Direction: v3.TopKDirectionTop, K: 2, ReduceTo: v3.ReduceToOperatorMax},
+			expected: []string{"redis", "route"},
+		},
+		{
+			name:     "top 2 by last",
+			topK:     &v3.TopK{Direction: v3.TopKDirectionTop, K: 2, ReduceTo: v3.ReduceToOperatorLast},
+			expected: []string{"route", "frontend"},
+		},
+		{
+			name:     "bottom 2 by avg",
+			topK:     &v3.TopK{Direction: v3.TopKDirectionBottom, K: 2, ReduceTo: v3.ReduceToOperatorAvg},
+			expected: []string{"driver", "route"},
+		},
+		{
+			name:     "bottom 1 by sum, the series without values are never selected",
+			topK:     &v3.TopK{Direction: v3.TopKDirectionBottom, K: 1, ReduceTo: v3.ReduceToOperatorSum},
+			expected: []string{"driver"},
+		},
+		{
+			name:     "k more than the series",
+			topK:     &v3.TopK{Direction: v3.TopKDirectionTop, K: 10, ReduceTo: v3.ReduceToOperatorMax},
+			expected: []string{"frontend", "redis", "route", "driver", "empty"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := []*v3.Result{{
+				QueryName: "A",
+				Series: []*v3.Series{
+					createSeries("frontend", 10, 12, 11),
+					createSeries("redis", 2, 30, 1),
+					createSeries("route", 1, 2, 15),
+					createSeries("driver", 5, math.NaN(), 6),
+					createSeries("empty", math.NaN()),
+				},
+			}}
+			params := &v3.QueryRangeParamsV3{
+				CompositeQuery: &v3.CompositeQuery{
+					BuilderQueries: map[string]*v3.BuilderQuery{
+						"A": {QueryName: "A", Expression: "A", DataSource: v3.DataSourceLogs, TopK: tt.topK},
+					},
+				},
+			}
+			ApplyTopK(results, params)
+			assert.Equal(t, tt.expected, names(results[0].Series))
+		})
+	}
+
+	t.Run("other series", func(t *testing.T) {
+		results := []*v3.Result{{
+			QueryName: "A",
+			Series: []*v3.Series{
+				createSeries("frontend", 10, 12),
+				createSeries("redis", 2, 3),
+				createSeries("route", 1, math.NaN()),
+			},
+		}}
+		params := &v3.QueryRangeParamsV3{
+			CompositeQuery: &v3.CompositeQuery{
+				BuilderQueries: map[string]*v3.BuilderQuery{
+					"A": {QueryName: "A", Expression: "A", DataSource: v3.DataSourceTraces, TopK: &v3.TopK{
+						Direction: v3.TopKDirectionTop, K: 1, ReduceTo: v3.ReduceToOperatorAvg, Other: true,
+					}},
+				},
+			},
+		}
+		ApplyTopK(results, params)
+		assert.Len(t, results[0].Series, 2)
+		assert.Equal(t, "frontend", results[0].Series[0].Labels["service_name"])
+		assert.Equal(t, &v3.Series{
+			Labels:      map[string]string{"service_name": constants.SigNozOtherSeriesValue},
+			LabelsArray: []map[string]string{{"service_name": constants.SigNozOtherSeriesValue}},
+			Points:      []v3.Point{{Timestamp: 60000, Value: 3}, {Timestamp: 120000, Value: 3}},
+		}, results[0].Series[1])
+	})
+}
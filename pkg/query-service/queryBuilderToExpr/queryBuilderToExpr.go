@@ -47,43 +47,27 @@ func getTypeName(v v3.AttributeKeyType) string {
 }
 
 func Parse(filters *v3.FilterSet) (string, error) {
-	var res []string
+	var items []string
 	for _, v := range filters.Items {
-		if _, ok := logOperatorsToExpr[v.Operator]; !ok {
-			return "", fmt.Errorf("operator not supported")
+		filter, err := parseItem(v)
+		if err != nil {
+			return "", err
 		}
+		items = append(items, filter)
+	}
 
-		name := getName(v.Key)
-
-		var filter string
-
-		switch v.Operator {
-		// uncomment following lines when new version of expr is used
-		// case v3.FilterOperatorIn, v3.FilterOperatorNotIn:
-		// 	filter = fmt.Sprintf("%s %s list%s", name, logOperatorsToExpr[v.Operator], exprFormattedValue(v.Value))
-
-		case v3.FilterOperatorExists, v3.FilterOperatorNotExists:
-			filter = fmt.Sprintf("%s %s %s", exprFormattedValue(v.Key.Key), logOperatorsToExpr[v.Operator], getTypeName(v.Key.Type))
-
-		default:
-			filter = fmt.Sprintf("%s %s %s", name, logOperatorsToExpr[v.Operator], exprFormattedValue(v.Value))
-
-			if v.Operator == v3.FilterOperatorContains || v.Operator == v3.FilterOperatorNotContains {
-				// `contains` and `ncontains` should be case insensitive to match how they work when querying logs.
-				filter = fmt.Sprintf(
-					"lower(%s) %s lower(%s)",
-					name, logOperatorsToExpr[v.Operator], exprFormattedValue(v.Value),
-				)
-			}
-
-			// Avoid running operators on nil values
-			if v.Operator != v3.FilterOperatorEqual && v.Operator != v3.FilterOperatorNotEqual {
-				filter = fmt.Sprintf("%s != nil && %s", name, filter)
-			}
+	// the top level items are joined with the operator of the filter set, the groups are
+	// always joined with them using and, the same as the query builders do
+	var res []string
+	if len(items) > 0 {
+		q := strings.Join(items, " "+strings.ToLower(filters.Operator)+" ")
+		if len(items) > 1 && len(filters.Groups) > 0 && filters.LogicalOperator() == "OR" {
+			q = "(" + q + ")"
 		}
-
-		// check if the filter is a correct expression language
-		_, err := expr.Compile(filter)
+		res = append(res, q)
+	}
+	for _, group := range filters.Groups {
+		filter, err := parseGroup(group)
 		if err != nil {
 			return "", err
 		}
@@ -91,7 +75,7 @@ func Parse(filters *v3.FilterSet) (string, error) {
 	}
 
 	// check the final filter
-	q := strings.Join(res, " "+strings.ToLower(filters.Operator)+" ")
+	q := strings.Join(res, " and ")
 	_, err := expr.Compile(q)
 	if err != nil {
 		return "", err
@@ -100,6 +84,82 @@ func Parse(filters *v3.FilterSet) (string, error) {
 	return q, nil
 }
 
+func parseItemsAndGroups(filters *v3.FilterSet) ([]string, error) {
+	var res []string
+	for _, v := range filters.Items {
+		filter, err := parseItem(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, filter)
+	}
+
+	for _, group := range filters.Groups {
+		filter, err := parseGroup(group)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, filter)
+	}
+	return res, nil
+}
+
+// parseGroup converts a nested filter group to an expression wrapped in parentheses
+func parseGroup(group *v3.FilterSet) (string, error) {
+	res, err := parseItemsAndGroups(group)
+	if err != nil {
+		return "", err
+	}
+
+	filter := "(" + strings.Join(res, " "+strings.ToLower(group.LogicalOperator())+" ") + ")"
+	if group.Not {
+		filter = "not " + filter
+	}
+	return filter, nil
+}
+
+func parseItem(v v3.FilterItem) (string, error) {
+	if _, ok := logOperatorsToExpr[v.Operator]; !ok {
+		return "", fmt.Errorf("operator not supported")
+	}
+
+	name := getName(v.Key)
+
+	var filter string
+
+	switch v.Operator {
+	// uncomment following lines when new version of expr is used
+	// case v3.FilterOperatorIn, v3.FilterOperatorNotIn:
+	// 	filter = fmt.Sprintf("%s %s list%s", name, logOperatorsToExpr[v.Operator], exprFormattedValue(v.Value))
+
+	case v3.FilterOperatorExists, v3.FilterOperatorNotExists:
+		filter = fmt.Sprintf("%s %s %s", exprFormattedValue(v.Key.Key), logOperatorsToExpr[v.Operator], getTypeName(v.Key.Type))
+
+	default:
+		filter = fmt.Sprintf("%s %s %s", name, logOperatorsToExpr[v.Operator], exprFormattedValue(v.Value))
+
+		if v.Operator == v3.FilterOperatorContains || v.Operator == v3.FilterOperatorNotContains {
+			// `contains` and `ncontains` should be case insensitive to match how they work when querying logs.
+			filter = fmt.Sprintf(
+				"lower(%s) %s lower(%s)",
+				name, logOperatorsToExpr[v.Operator], exprFormattedValue(v.Value),
+			)
+		}
+
+		// Avoid running operators on nil values
+		if v.Operator != v3.FilterOperatorEqual && v.Operator != v3.FilterOperatorNotEqual {
+			filter = fmt.Sprintf("%s != nil && %s", name, filter)
+		}
+	}
+
+	// check if the filter is a correct expression language
+	_, err := expr.Compile(filter)
+	if err != nil {
+		return "", err
+	}
+	return filter, nil
+}
+
 func exprFormattedValue(v interface{}) string {
 	switch x := v.(type) {
 	case uint8, uint16, uint32, uint64, int, int8, int16, int32, int64:
@@ -146,6 +146,33 @@ var testCases = []struct {
 		Expr:        `attributes.key <= 10 and body not matches "[0-9]++" and "key" not in attributes`,
 		ExpectError: true,
 	},
+	{
+		Name: "nested groups",
+		Query: &v3.FilterSet{Operator: "AND", Groups: []*v3.FilterSet{
+			{Operator: "OR", Groups: []*v3.FilterSet{
+				{Operator: "AND", Items: []v3.FilterItem{
+					{Key: v3.AttributeKey{Key: "service", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeResource}, Value: "a", Operator: "="},
+					{Key: v3.AttributeKey{Key: "status", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}, Value: "500", Operator: "="},
+				}},
+				{Operator: "AND", Not: true, Items: []v3.FilterItem{
+					{Key: v3.AttributeKey{Key: "service", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeResource}, Value: "b", Operator: "="},
+				}},
+			}},
+		}},
+		Expr: `((resource["service"] == "a" and attributes["status"] == "500") or not (resource["service"] == "b"))`,
+	},
+	{
+		Name: "groups are joined with the items using and",
+		Query: &v3.FilterSet{Operator: "OR", Items: []v3.FilterItem{
+			{Key: v3.AttributeKey{Key: "service", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeResource}, Value: "a", Operator: "="},
+			{Key: v3.AttributeKey{Key: "service", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeResource}, Value: "b", Operator: "="},
+		}, Groups: []*v3.FilterSet{
+			{Operator: "AND", Not: true, Items: []v3.FilterItem{
+				{Key: v3.AttributeKey{Key: "status", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag}, Value: "500", Operator: "="},
+			}},
+		}},
+		Expr: `(resource["service"] == "a" or resource["service"] == "b") and not (attributes["status"] == "500")`,
+	},
 }
 
 func TestParse(t *testing.T) {
@@ -0,0 +1,210 @@
+package querycache
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"go.signoz.io/signoz/pkg/query-service/cache"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+// CachedRange is a time range covered by a cache entry
+type CachedRange struct {
+	Start  int64 `json:"start"` // in milliseconds
+	End    int64 `json:"end"`   // in milliseconds
+	Series int   `json:"series,omitempty"`
+	Rows   int   `json:"rows,omitempty"`
+}
+
+// KeyHash returns the short hash of the cache key used to refer to the cache entry
+func KeyHash(cacheKey string) string {
+	h := fnv.New64a()
+	h.Write([]byte(cacheKey))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// entryKind is the kind of the data stored in a cache entry
+type entryKind int
+
+const (
+	entryKindUnknown entryKind = iota
+	entryKindSeries
+	entryKindList
+)
+
+// cachedEntryKind returns the kind of the cached data, the entries not stored by the
+// query cache are of the unknown kind
+func cachedEntryKind(cachedData []byte) entryKind {
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(cachedData, &items); err != nil {
+		return entryKindUnknown
+	}
+	for _, item := range items {
+		if _, ok := item["rows"]; ok {
+			return entryKindList
+		}
+		if _, ok := item["data"]; !ok {
+			return entryKindUnknown
+		}
+	}
+	return entryKindSeries
+}
+
+// CachedRanges returns the time ranges covered by the cache entry ordered by the start time,
+// false is returned if the cache entry does not hold query results
+func (q *queryCache) CachedRanges(cacheKey string) ([]CachedRange, bool) {
+	if q.cache == nil {
+		return nil, false
+	}
+	cachedData, _, _ := q.cache.Retrieve(cacheKey, true)
+	if len(cachedData) == 0 {
+		return nil, false
+	}
+
+	ranges := make([]CachedRange, 0)
+	switch cachedEntryKind(cachedData) {
+	case entryKindSeries:
+		for _, data := range q.getCachedSeriesData(cacheKey) {
+			ranges = append(ranges, CachedRange{Start: data.Start, End: data.End, Series: len(data.Data)})
+		}
+	case entryKindList:
+		for _, data := range q.GetCachedListData(cacheKey) {
+			ranges = append(ranges, CachedRange{
+				Start: time.Unix(0, data.Start).UnixMilli(),
+				End:   time.Unix(0, data.End).UnixMilli(),
+				Rows:  len(data.Rows),
+			})
+		}
+	default:
+		return nil, false
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].Start < ranges[j].Start
+	})
+	return ranges, true
+}
+
+// InvalidateTimeRange removes the cached data of the cache entry within [start, end] milliseconds,
+// the cache entry is removed once it has no data left. It returns whether any data was removed.
+//
+// The entry is read, split and stored back without a lock, so a query merging its results into the
+// entry at the same time can store the invalidated range back. The recent data is the most likely to
+// be merged concurrently, by the dashboards being refreshed, so the whole entry is removed when the
+// invalidated range overlaps the flux interval
+func (q *queryCache) InvalidateTimeRange(cacheKey string, start, end int64) bool {
+	if q.cache == nil {
+		return false
+	}
+	cachedData, _, _ := q.cache.Retrieve(cacheKey, true)
+
+	var remaining interface{}
+	var removed bool
+	var empty bool
+	var ttl time.Duration
+	switch cachedEntryKind(cachedData) {
+	case entryKindSeries:
+		var seriesData []CachedSeriesData
+		if err := json.Unmarshal(cachedData, &seriesData); err != nil {
+			return false
+		}
+		seriesData, removed = invalidateSeriesData(seriesData, start, end)
+		remaining, empty = seriesData, len(seriesData) == 0
+	case entryKindList:
+		decoder := json.NewDecoder(bytes.NewReader(cachedData))
+		decoder.UseNumber()
+		var listData []CachedListData
+		if err := decoder.Decode(&listData); err != nil {
+			return false
+		}
+		// the list data ranges are in nanoseconds and the end millisecond is included
+		listData, removed = invalidateListData(listData, start*int64(time.Millisecond), (end+1)*int64(time.Millisecond)-1)
+		remaining, empty = listData, len(listData) == 0
+		ttl = ListDataTTL
+	default:
+		return false
+	}
+
+	if !removed {
+		return false
+	}
+	if empty || end >= time.Now().Add(-q.fluxInterval).UnixMilli() {
+		q.cache.Remove(cacheKey)
+		return true
+	}
+	remainingJSON, err := json.Marshal(remaining)
+	if err != nil {
+		q.cache.Remove(cacheKey)
+		return true
+	}
+	// the copies of the entry with the invalidated range are dropped as well
+	store := q.cache.Store
+	if replacer, ok := q.cache.(cache.Replacer); ok {
+		store = replacer.Replace
+	}
+	if err := store(cacheKey, remainingJSON, ttl); err != nil {
+		q.cache.Remove(cacheKey)
+	}
+	return true
+}
+
+// invalidateSeriesData splits the series data around [start, end] and drops the points within it
+func invalidateSeriesData(seriesData []CachedSeriesData, start, end int64) ([]CachedSeriesData, bool) {
+	remaining := make([]CachedSeriesData, 0, len(seriesData))
+	removed := false
+	for _, data := range seriesData {
+		if data.End < start || data.Start > end {
+			remaining = append(remaining, data)
+			continue
+		}
+		removed = true
+		if data.Start < start {
+			remaining = append(remaining, CachedSeriesData{Start: data.Start, End: start - 1, Data: seriesInRange(data.Data, data.Start, start-1)})
+		}
+		if data.End > end {
+			remaining = append(remaining, CachedSeriesData{Start: end + 1, End: data.End, Data: seriesInRange(data.Data, end+1, data.End)})
+		}
+	}
+	return remaining, removed
+}
+
+// seriesInRange returns the series with only the points with the timestamp in [start, end]
+func seriesInRange(series []*v3.Series, start, end int64) []*v3.Series {
+	filtered := make([]*v3.Series, 0, len(series))
+	for _, s := range series {
+		points := make([]v3.Point, 0, len(s.Points))
+		for _, point := range s.Points {
+			if point.Timestamp >= start && point.Timestamp <= end {
+				points = append(points, point)
+			}
+		}
+		if len(points) == 0 {
+			continue
+		}
+		filtered = append(filtered, &v3.Series{Labels: s.Labels, LabelsArray: s.LabelsArray, Points: points})
+	}
+	return filtered
+}
+
+// invalidateListData splits the list data around [start, end] and drops the rows within it
+func invalidateListData(listData []CachedListData, start, end int64) ([]CachedListData, bool) {
+	remaining := make([]CachedListData, 0, len(listData))
+	removed := false
+	for _, data := range listData {
+		if data.End < start || data.Start > end {
+			remaining = append(remaining, data)
+			continue
+		}
+		removed = true
+		if data.End > end {
+			remaining = append(remaining, CachedListData{Start: end + 1, End: data.End, Rows: rowsInRange(data.Rows, end+1, data.End)})
+		}
+		if data.Start < start {
+			remaining = append(remaining, CachedListData{Start: data.Start, End: start - 1, Rows: rowsInRange(data.Rows, data.Start, start-1)})
+		}
+	}
+	return remaining, removed
+}
@@ -0,0 +1,131 @@
+package querycache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.signoz.io/signoz/pkg/query-service/cache"
+	"go.signoz.io/signoz/pkg/query-service/cache/inmemory"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.signoz.io/signoz/pkg/query-service/querycache"
+)
+
+func TestCachedRanges(t *testing.T) {
+	mockCache := inmemory.New(&inmemory.Options{TTL: 5 * time.Minute, CleanupInterval: 10 * time.Minute})
+	q := querycache.NewQueryCache(querycache.WithCache(mockCache), querycache.WithFluxInterval(5*time.Minute))
+
+	q.MergeWithCachedSeriesData("series", []querycache.CachedSeriesData{
+		{Start: 5000, End: 6000, Data: []*v3.Series{{Labels: map[string]string{"a": "1"}}}},
+		{Start: 1000, End: 2000, Data: []*v3.Series{{Labels: map[string]string{"a": "1"}}, {Labels: map[string]string{"a": "2"}}}},
+	})
+	ranges, ok := q.CachedRanges("series")
+	require.True(t, ok)
+	assert.Equal(t, []querycache.CachedRange{
+		{Start: 1000, End: 2000, Series: 2},
+		{Start: 5000, End: 6000, Series: 1},
+	}, ranges)
+
+	q.MergeWithCachedListData("list", []querycache.CachedListData{
+		{Start: seconds(110), End: seconds(120), Rows: listRows(110, 120)},
+	})
+	ranges, ok = q.CachedRanges("list")
+	require.True(t, ok)
+	assert.Equal(t, []querycache.CachedRange{{Start: 110000, End: 120000, Rows: 11}}, ranges)
+
+	// the entries not stored by the query cache are skipped
+	require.NoError(t, mockCache.Store("async_query:1", []byte(`{"id":"1"}`), 0))
+	_, ok = q.CachedRanges("async_query:1")
+	assert.False(t, ok)
+	_, ok = q.CachedRanges("missing")
+	assert.False(t, ok)
+}
+
+func TestInvalidateTimeRange(t *testing.T) {
+	mockCache := inmemory.New(&inmemory.Options{TTL: 5 * time.Minute, CleanupInterval: 10 * time.Minute})
+	q := querycache.NewQueryCache(querycache.WithCache(mockCache), querycache.WithFluxInterval(5*time.Minute))
+
+	series := &v3.Series{Labels: map[string]string{"a": "1"}}
+	for ts := int64(1000); ts <= 5000; ts += 1000 {
+		series.Points = append(series.Points, v3.Point{Timestamp: ts, Value: float64(ts)})
+	}
+	q.MergeWithCachedSeriesData("series", []querycache.CachedSeriesData{
+		{Start: 1000, End: 5000, Data: []*v3.Series{series}},
+	})
+
+	// the range is split around the invalidated time range
+	assert.True(t, q.InvalidateTimeRange("series", 2000, 3000))
+	ranges, ok := q.CachedRanges("series")
+	require.True(t, ok)
+	assert.Equal(t, []querycache.CachedRange{
+		{Start: 1000, End: 1999, Series: 1},
+		{Start: 3001, End: 5000, Series: 1},
+	}, ranges)
+	assert.Equal(t, []querycache.MissInterval{{Start: 1999, End: 3001}}, q.FindMissingTimeRanges(1000, 5000, 1, "series"))
+
+	// nothing is invalidated outside the cached ranges
+	assert.False(t, q.InvalidateTimeRange("series", 10000, 20000))
+
+	// the cache entry is removed once it has no data left
+	assert.True(t, q.InvalidateTimeRange("series", 0, 10000))
+	_, ok = q.CachedRanges("series")
+	assert.False(t, ok)
+
+	q.MergeWithCachedListData("list", []querycache.CachedListData{
+		{Start: seconds(110), End: seconds(120), Rows: listRows(110, 120)},
+	})
+	assert.True(t, q.InvalidateTimeRange("list", 115000, 116000))
+	cached := q.GetCachedListData("list")
+	require.Len(t, cached, 2)
+	assert.Equal(t, rowTimestamps(listRows(110, 114)), rowTimestamps(cached[0].Rows))
+	assert.Equal(t, rowTimestamps(listRows(117, 120)), rowTimestamps(cached[1].Rows))
+	assert.Equal(t, seconds(116)+int64(time.Millisecond), cached[1].Start)
+}
+
+// replacingCache records the entries replaced instead of stored
+type replacingCache struct {
+	cache.Cache
+	replaced []string
+}
+
+func (c *replacingCache) Replace(cacheKey string, data []byte, ttl time.Duration) error {
+	c.replaced = append(c.replaced, cacheKey)
+	return c.Store(cacheKey, data, ttl)
+}
+
+func TestInvalidateTimeRangeReplacesEntry(t *testing.T) {
+	mockCache := &replacingCache{Cache: inmemory.New(&inmemory.Options{TTL: 5 * time.Minute, CleanupInterval: 10 * time.Minute})}
+	q := querycache.NewQueryCache(querycache.WithCache(mockCache), querycache.WithFluxInterval(5*time.Minute))
+
+	q.MergeWithCachedSeriesData("series", []querycache.CachedSeriesData{
+		{Start: 1000, End: 5000, Data: []*v3.Series{{Labels: map[string]string{"a": "1"}, Points: []v3.Point{{Timestamp: 1000}, {Timestamp: 5000}}}}},
+	})
+
+	// the trimmed entry replaces the entry so that the copies of the replicas are dropped
+	assert.True(t, q.InvalidateTimeRange("series", 2000, 3000))
+	assert.Equal(t, []string{"series"}, mockCache.replaced)
+}
+
+func TestInvalidateTimeRangeFluxInterval(t *testing.T) {
+	mockCache := inmemory.New(&inmemory.Options{TTL: 5 * time.Minute, CleanupInterval: 10 * time.Minute})
+	q := querycache.NewQueryCache(querycache.WithCache(mockCache), querycache.WithFluxInterval(5*time.Minute))
+
+	now := time.Now().UnixMilli()
+	start := now - time.Hour.Milliseconds()
+	series := &v3.Series{Labels: map[string]string{"a": "1"}, Points: []v3.Point{{Timestamp: start}, {Timestamp: now}}}
+	q.MergeWithCachedSeriesData("series", []querycache.CachedSeriesData{
+		{Start: start, End: now, Data: []*v3.Series{series}},
+	})
+
+	// the recent data can be merged back by the queries running at the same time, so the entry is removed
+	assert.True(t, q.InvalidateTimeRange("series", now-time.Minute.Milliseconds(), now))
+	_, ok := q.CachedRanges("series")
+	assert.False(t, ok)
+}
+
+func TestKeyHash(t *testing.T) {
+	assert.Equal(t, querycache.KeyHash("source=logs"), querycache.KeyHash("source=logs"))
+	assert.NotEqual(t, querycache.KeyHash("source=logs"), querycache.KeyHash("source=traces"))
+	assert.Len(t, querycache.KeyHash("source=logs"), 16)
+}
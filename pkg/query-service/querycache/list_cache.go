@@ -0,0 +1,242 @@
+package querycache
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	// ListDataTTL is the time for which the rows of a list query are kept in the cache
+	ListDataTTL = time.Hour
+	// MaxListRows is the number of the latest rows of a list query kept in the cache, every page
+	// of the query reads and rewrites all the cached rows so they are bounded
+	MaxListRows = 10000
+)
+
+// CachedListData holds all the rows of a list query with the timestamp in [Start, End],
+// ordered by the timestamp in descending order
+type CachedListData struct {
+	Start int64     `json:"start"` // in nanoseconds
+	End   int64     `json:"end"`   // in nanoseconds
+	Rows  []*v3.Row `json:"rows"`
+}
+
+// ListFetcher returns the latest rows of the list query with the timestamp in
+// [start, end], at most limit rows ordered by the timestamp in descending order
+type ListFetcher func(start, end int64, limit uint64) ([]*v3.Row, error)
+
+// GetCachedListData returns the cached list data of the cache key ordered by the start time
+func (q *queryCache) GetCachedListData(cacheKey string) []CachedListData {
+	if q.cache == nil || cacheKey == "" {
+		return nil
+	}
+	cachedData, _, _ := q.cache.Retrieve(cacheKey, true)
+	if len(cachedData) == 0 {
+		return nil
+	}
+	// the numbers are kept as is so that the rows of the cache are the same as the rows of the reader
+	decoder := json.NewDecoder(bytes.NewReader(cachedData))
+	decoder.UseNumber()
+	var cachedListData []CachedListData
+	if err := decoder.Decode(&cachedListData); err != nil {
+		return nil
+	}
+	sort.Slice(cachedListData, func(i, j int) bool {
+		return cachedListData[i].Start < cachedListData[j].Start
+	})
+	return cachedListData
+}
+
+// MergeWithCachedListData merges the new list data with the cached list data and stores it.
+// The rows within the flux interval are not cached as they might not be fully ingested
+func (q *queryCache) MergeWithCachedListData(cacheKey string, newData []CachedListData) []CachedListData {
+	if q.cache == nil || cacheKey == "" {
+		return newData
+	}
+
+	fluxEnd := time.Now().Add(-q.fluxInterval).UnixNano()
+	allData := q.GetCachedListData(cacheKey)
+	cachedCount := len(allData)
+	for _, data := range newData {
+		if data.End > fluxEnd {
+			data.End = fluxEnd
+			data.Rows = rowsInRange(data.Rows, data.Start, data.End)
+		}
+		if data.Start > data.End {
+			continue
+		}
+		allData = append(allData, data)
+	}
+	// the cached rows are not rewritten when nothing new is fetched
+	if len(allData) == cachedCount {
+		return allData
+	}
+
+	sort.Slice(allData, func(i, j int) bool {
+		return allData[i].Start < allData[j].Start
+	})
+
+	var mergedData []CachedListData
+	for _, data := range allData {
+		if len(mergedData) == 0 {
+			mergedData = append(mergedData, data)
+			continue
+		}
+		current := &mergedData[len(mergedData)-1]
+		// the ranges are inclusive, so the adjacent ranges are merged too
+		if data.Start > current.End+1 {
+			mergedData = append(mergedData, data)
+			continue
+		}
+		current.Rows = mergeRows(*current, data)
+		current.End = max(current.End, data.End)
+	}
+	mergedData = latestListData(mergedData, MaxListRows)
+
+	mergedDataJSON, err := json.Marshal(mergedData)
+	if err != nil {
+		zap.L().Error("error marshalling merged list data", zap.Error(err))
+		return mergedData
+	}
+	if err := q.cache.Store(cacheKey, mergedDataJSON, ListDataTTL); err != nil {
+		zap.L().Error("error storing merged list data", zap.Error(err))
+	}
+	return mergedData
+}
+
+// latestListData returns the list data with at most maxRows of the latest rows, the
+// ranges are narrowed to the timestamps of which all the rows are kept
+func latestListData(listData []CachedListData, maxRows int) []CachedListData {
+	latest := make([]CachedListData, 0, len(listData))
+	kept := 0
+	for idx := len(listData) - 1; idx >= 0 && kept < maxRows; idx-- {
+		data := listData[idx]
+		if kept+len(data.Rows) > maxRows {
+			rows := make([]*v3.Row, len(data.Rows))
+			copy(rows, data.Rows)
+			sort.SliceStable(rows, func(i, j int) bool {
+				return rows[i].Timestamp.After(rows[j].Timestamp)
+			})
+			// the rows with the timestamp of the first row left out are dropped too
+			data.Start = rows[maxRows-kept].Timestamp.UnixNano() + 1
+			data.Rows = rowsInRange(rows, data.Start, data.End)
+			if data.Start > data.End || len(data.Rows) == 0 {
+				break
+			}
+		}
+		kept += len(data.Rows)
+		latest = append(latest, data)
+	}
+	sort.Slice(latest, func(i, j int) bool {
+		return latest[i].Start < latest[j].Start
+	})
+	return latest
+}
+
+// mergeRows merges the rows of the overlapping list data, the rows of later are
+// used only outside the range of earlier since both have all the rows of their range
+func mergeRows(earlier, later CachedListData) []*v3.Row {
+	rows := make([]*v3.Row, 0, len(earlier.Rows)+len(later.Rows))
+	rows = append(rows, rowsInRange(later.Rows, earlier.End+1, later.End)...)
+	rows = append(rows, earlier.Rows...)
+	return rows
+}
+
+// rowsInRange returns the rows with the timestamp in [start, end]
+func rowsInRange(rows []*v3.Row, start, end int64) []*v3.Row {
+	filtered := make([]*v3.Row, 0, len(rows))
+	for _, row := range rows {
+		ts := row.Timestamp.UnixNano()
+		if ts >= start && ts <= end {
+			filtered = append(filtered, row)
+		}
+	}
+	return filtered
+}
+
+// FillList returns the latest rows with the timestamp in [start, end] for which keep is true,
+// at most limit rows ordered by the timestamp in descending order. The rows are taken from the
+// cached list data and fetched for the ranges missing in the cache, the fetched list data is
+// returned to be merged with the cached list data
+func FillList(cached []CachedListData, start, end int64, limit uint64, keep func(row *v3.Row) bool, fetch ListFetcher) ([]*v3.Row, []CachedListData, error) {
+	rows := make([]*v3.Row, 0)
+	var fetched []CachedListData
+
+	appendRows := func(candidates []*v3.Row) {
+		for _, row := range candidates {
+			if uint64(len(rows)) >= limit {
+				return
+			}
+			if keep == nil || keep(row) {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	// the rows are collected from the end to the start of the range
+	for end >= start && uint64(len(rows)) < limit {
+		if data := coveringListData(cached, end); data != nil {
+			appendRows(rowsInRange(data.Rows, max(start, data.Start), end))
+			end = data.Start - 1
+			continue
+		}
+
+		// fetch up to the next cached range
+		missStart := start
+		for _, data := range cached {
+			if data.End < end && data.End+1 > missStart {
+				missStart = data.End + 1
+			}
+		}
+		// one more row is fetched to know up to where the fetched rows are all the rows of the range
+		missLimit := limit - uint64(len(rows))
+		missRows, err := fetch(missStart, end, missLimit+1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if uint64(len(missRows)) <= missLimit {
+			// all the rows of the range are fetched
+			fetched = append(fetched, CachedListData{Start: missStart, End: end, Rows: missRows})
+			appendRows(missRows)
+			end = missStart - 1
+			continue
+		}
+
+		// the rows after the timestamp of the extra row are all the rows of their range,
+		// the rows with the same timestamp as the extra row might be left out by the limit
+		extra := missRows[missLimit].Timestamp.UnixNano()
+		missRows = missRows[:missLimit]
+		if extra >= end {
+			// all the rows have the same timestamp
+			appendRows(missRows)
+			break
+		}
+		complete := rowsInRange(missRows, extra+1, end)
+		fetched = append(fetched, CachedListData{Start: extra + 1, End: end, Rows: complete})
+		if keep == nil {
+			// the page is full with the fetched rows
+			appendRows(missRows)
+			break
+		}
+		appendRows(complete)
+		end = extra
+	}
+
+	return rows, fetched, nil
+}
+
+// coveringListData returns the list data with the range containing ts
+func coveringListData(listData []CachedListData, ts int64) *CachedListData {
+	for idx := range listData {
+		if listData[idx].Start <= ts && ts <= listData[idx].End {
+			return &listData[idx]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package querycache_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.signoz.io/signoz/pkg/query-service/cache"
+	"go.signoz.io/signoz/pkg/query-service/cache/inmemory"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.signoz.io/signoz/pkg/query-service/querycache"
+)
+
+// listRows returns a row every second in [start, end] seconds, latest first
+func listRows(start, end int64) []*v3.Row {
+	rows := make([]*v3.Row, 0)
+	for ts := end; ts >= start; ts-- {
+		rows = append(rows, &v3.Row{
+			Timestamp: time.Unix(ts, 0),
+			Data:      map[string]interface{}{"id": fmt.Sprintf("%d", ts)},
+		})
+	}
+	return rows
+}
+
+func rowTimestamps(rows []*v3.Row) []int64 {
+	timestamps := make([]int64, 0, len(rows))
+	for _, row := range rows {
+		timestamps = append(timestamps, row.Timestamp.Unix())
+	}
+	return timestamps
+}
+
+// listFetcher fetches from the rows and records the fetched ranges in seconds
+type listFetcher struct {
+	rows    []*v3.Row
+	fetched [][2]int64
+}
+
+func (f *listFetcher) fetch(start, end int64, limit uint64) ([]*v3.Row, error) {
+	f.fetched = append(f.fetched, [2]int64{(start + int64(time.Second) - 1) / int64(time.Second), end / int64(time.Second)})
+	rows := make([]*v3.Row, 0)
+	for _, row := range f.rows {
+		ts := row.Timestamp.UnixNano()
+		if ts >= start && ts <= end && uint64(len(rows)) < limit {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func seconds(s int64) int64 {
+	return s * int64(time.Second)
+}
+
+func TestFillList(t *testing.T) {
+	testCases := []struct {
+		name               string
+		cached             []querycache.CachedListData
+		start, end         int64
+		limit              uint64
+		keep               func(row *v3.Row) bool
+		expectedTimestamps []int64
+		expectedFetched    [][2]int64
+		expectedCached     [][2]int64
+	}{
+		{
+			name:               "nothing cached, page smaller than the range",
+			start:              100,
+			end:                120,
+			limit:              5,
+			expectedTimestamps: []int64{120, 119, 118, 117, 116},
+			expectedFetched:    [][2]int64{{100, 120}},
+			// the rows up to the timestamp of the extra row fetched
+			expectedCached: [][2]int64{{116, 120}},
+		},
+		{
+			name:               "nothing cached, range smaller than the page",
+			start:              100,
+			end:                102,
+			limit:              5,
+			expectedTimestamps: []int64{102, 101, 100},
+			expectedFetched:    [][2]int64{{100, 102}},
+			expectedCached:     [][2]int64{{100, 102}},
+		},
+		{
+			name: "page served from the cache",
+			cached: []querycache.CachedListData{
+				{Start: seconds(110), End: seconds(120), Rows: listRows(110, 120)},
+			},
+			start:              100,
+			end:                120,
+			limit:              5,
+			expectedTimestamps: []int64{120, 119, 118, 117, 116},
+		},
+		{
+			name: "rows after and before the cached range are fetched",
+			cached: []querycache.CachedListData{
+				{Start: seconds(110), End: seconds(115), Rows: listRows(110, 115)},
+			},
+			start:              100,
+			end:                117,
+			limit:              10,
+			expectedTimestamps: []int64{117, 116, 115, 114, 113, 112, 111, 110, 109, 108},
+			expectedFetched:    [][2]int64{{116, 117}, {100, 109}},
+			expectedCached:     [][2]int64{{116, 117}, {108, 109}},
+		},
+		{
+			name: "rows filtered by the cursor",
+			cached: []querycache.CachedListData{
+				{Start: seconds(110), End: seconds(120), Rows: listRows(110, 120)},
+			},
+			start: 100,
+			end:   120,
+			limit: 3,
+			keep: func(row *v3.Row) bool {
+				return row.Timestamp.Unix()%2 == 0
+			},
+			expectedTimestamps: []int64{120, 118, 116},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fetcher := &listFetcher{rows: listRows(0, 200)}
+			rows, fetched, err := querycache.FillList(tc.cached, seconds(tc.start), seconds(tc.end), tc.limit, tc.keep, fetcher.fetch)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedTimestamps, rowTimestamps(rows))
+			assert.Equal(t, tc.expectedFetched, fetcher.fetched)
+
+			var cachedRanges [][2]int64
+			for _, data := range fetched {
+				// the ranges start after the timestamp of the extra row fetched when the page is full
+				cachedRanges = append(cachedRanges, [2]int64{(data.Start + int64(time.Second) - 1) / int64(time.Second), data.End / int64(time.Second)})
+				assert.Equal(t, rowTimestamps(listRows((data.Start+int64(time.Second)-1)/int64(time.Second), data.End/int64(time.Second))), rowTimestamps(data.Rows))
+			}
+			assert.Equal(t, tc.expectedCached, cachedRanges)
+		})
+	}
+}
+
+func TestMergeWithCachedListData(t *testing.T) {
+	mockCache := inmemory.New(&inmemory.Options{TTL: 5 * time.Minute, CleanupInterval: 10 * time.Minute})
+	q := querycache.NewQueryCache(
+		querycache.WithCache(mockCache),
+		querycache.WithFluxInterval(5*time.Minute),
+	)
+
+	merged := q.MergeWithCachedListData("key", []querycache.CachedListData{
+		{Start: seconds(110), End: seconds(120), Rows: listRows(110, 120)},
+		{Start: seconds(130), End: seconds(140), Rows: listRows(130, 140)},
+	})
+	assert.Len(t, merged, 2)
+
+	// overlapping and adjacent ranges are merged
+	merged = q.MergeWithCachedListData("key", []querycache.CachedListData{
+		{Start: seconds(115), End: seconds(130) - 1, Rows: listRows(115, 129)},
+	})
+	require.Len(t, merged, 1)
+	assert.Equal(t, seconds(110), merged[0].Start)
+	assert.Equal(t, seconds(140), merged[0].End)
+	assert.Equal(t, rowTimestamps(listRows(110, 140)), rowTimestamps(merged[0].Rows))
+
+	cached := q.GetCachedListData("key")
+	require.Len(t, cached, 1)
+	assert.Equal(t, rowTimestamps(listRows(110, 140)), rowTimestamps(cached[0].Rows))
+	assert.Equal(t, "140", cached[0].Rows[0].Data["id"])
+
+	// the rows within the flux interval are not cached
+	now := time.Now().Unix()
+	merged = q.MergeWithCachedListData("recent", []querycache.CachedListData{
+		{Start: seconds(now - 600), End: seconds(now), Rows: listRows(now-600, now)},
+	})
+	require.Len(t, merged, 1)
+	assert.LessOrEqual(t, merged[0].End, time.Now().Add(-5*time.Minute).UnixNano())
+	assert.Len(t, merged[0].Rows, 301)
+}
+
+// ttlCache records the ttl of the stored entries
+type ttlCache struct {
+	cache.Cache
+	ttls map[string]time.Duration
+}
+
+func (c *ttlCache) Store(cacheKey string, data []byte, ttl time.Duration) error {
+	c.ttls[cacheKey] = ttl
+	return c.Cache.Store(cacheKey, data, ttl)
+}
+
+func TestMergeWithCachedListDataLimits(t *testing.T) {
+	mockCache := &ttlCache{Cache: inmemory.New(&inmemory.Options{TTL: 5 * time.Minute, CleanupInterval: 10 * time.Minute}), ttls: map[string]time.Duration{}}
+	q := querycache.NewQueryCache(
+		querycache.WithCache(mockCache),
+		querycache.WithFluxInterval(5*time.Minute),
+	)
+
+	maxRows := int64(querycache.MaxListRows)
+	q.MergeWithCachedListData("key", []querycache.CachedListData{
+		{Start: seconds(0), End: seconds(maxRows - 1), Rows: listRows(0, maxRows-1)},
+		{Start: seconds(maxRows + 100), End: seconds(maxRows + 199), Rows: listRows(maxRows+100, maxRows+199)},
+	})
+	assert.Equal(t, querycache.ListDataTTL, mockCache.ttls["key"])
+
+	// only the latest rows are kept
+	cached := q.GetCachedListData("key")
+	require.Len(t, cached, 2)
+	assert.Equal(t, seconds(99)+1, cached[0].Start)
+	assert.Equal(t, rowTimestamps(listRows(100, maxRows-1)), rowTimestamps(cached[0].Rows))
+	assert.Equal(t, rowTimestamps(listRows(maxRows+100, maxRows+199)), rowTimestamps(cached[1].Rows))
+
+	// the entry is not rewritten when nothing new is fetched
+	delete(mockCache.ttls, "key")
+	q.MergeWithCachedListData("key", nil)
+	assert.NotContains(t, mockCache.ttls, "key")
+}
@@ -0,0 +1,139 @@
+package querylimits
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"go.signoz.io/signoz/pkg/query-service/model"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// Limits are the limits on the queries run for a user, the zero value of a limit is no limit
+type Limits struct {
+	// MaxTimeRange is the maximum time range of the query range request
+	MaxTimeRange time.Duration `yaml:"max_time_range,omitempty"`
+	// MaxRowsToRead is the max_rows_to_read setting of the clickhouse queries
+	MaxRowsToRead uint64 `yaml:"max_rows_to_read,omitempty"`
+	// MaxExecutionTime is the max_execution_time setting of the clickhouse queries
+	MaxExecutionTime time.Duration `yaml:"max_execution_time,omitempty"`
+	// MaxSeries is the maximum number of series in the result of a query
+	MaxSeries int `yaml:"max_series,omitempty"`
+	// RequireFiltersForList requires the logs and traces list queries to have a filter
+	RequireFiltersForList bool `yaml:"require_filters_for_list,omitempty"`
+}
+
+// Config is the limits of each role, the roles without limits use the default limits
+type Config struct {
+	Default Limits            `yaml:"default"`
+	Roles   map[string]Limits `yaml:"roles,omitempty"`
+}
+
+// LoadFromYAMLConfig loads the query limits from the given YAML config bytes
+func LoadFromYAMLConfig(yamlConfig []byte) (*Config, error) {
+	var config Config
+	err := yaml.Unmarshal(yamlConfig, &config)
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// LoadFromYAMLConfigFile loads the query limits from the given YAML config file
+func LoadFromYAMLConfigFile(configFile string) (*Config, error) {
+	bytes, err := os.ReadFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	return LoadFromYAMLConfig(bytes)
+}
+
+// ForRole returns the limits of the role e.g ADMIN, EDITOR or VIEWER
+func (c *Config) ForRole(role string) Limits {
+	if limits, ok := c.Roles[role]; ok {
+		return limits
+	}
+	return c.Default
+}
+
+// Error is the error of a query that exceeds a limit, Limit is the name of the limit in the config
+type Error struct {
+	Limit     string `json:"limit"`
+	QueryName string `json:"queryName,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func limitError(limit, queryName, format string, args ...interface{}) error {
+	return model.QueryLimitError(&Error{Limit: limit, QueryName: queryName, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the query range params against the limits before the queries are run
+func (l Limits) Validate(params *v3.QueryRangeParamsV3) error {
+	if l.MaxTimeRange > 0 {
+		timeRange := time.Duration(params.End-params.Start) * time.Millisecond
+		if timeRange > l.MaxTimeRange {
+			return limitError("max_time_range", "", "the time range %s exceeds the limit of %s, reduce the time range", timeRange, l.MaxTimeRange)
+		}
+	}
+
+	if l.RequireFiltersForList && params.CompositeQuery != nil &&
+		params.CompositeQuery.QueryType == v3.QueryTypeBuilder &&
+		(params.CompositeQuery.PanelType == v3.PanelTypeList || params.CompositeQuery.PanelType == v3.PanelTypeTrace) {
+		for queryName, query := range params.CompositeQuery.BuilderQueries {
+			if query.DataSource == v3.DataSourceMetrics {
+				continue
+			}
+			if query.Filters == nil || (len(query.Filters.Items) == 0 && len(query.Filters.Groups) == 0) {
+				return limitError("require_filters_for_list", queryName, "query %s requires at least one filter, add a filter such as service.name", queryName)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateResults checks the number of series in the results against the limits
+func (l Limits) ValidateResults(results []*v3.Result) error {
+	if l.MaxSeries == 0 {
+		return nil
+	}
+	for _, result := range results {
+		if len(result.Series) > l.MaxSeries {
+			return limitError("max_series", result.QueryName, "query %s returned %d series which exceeds the limit of %d, add filters or reduce the group by", result.QueryName, len(result.Series), l.MaxSeries)
+		}
+	}
+	return nil
+}
+
+// ClickHouseSettings returns the clickhouse settings of the limits
+func (l Limits) ClickHouseSettings() map[string]string {
+	settings := make(map[string]string)
+	if l.MaxRowsToRead > 0 {
+		settings["max_rows_to_read"] = strconv.FormatUint(l.MaxRowsToRead, 10)
+	}
+	if l.MaxExecutionTime > 0 {
+		settings["max_execution_time"] = strconv.FormatInt(int64(math.Ceil(l.MaxExecutionTime.Seconds())), 10)
+	}
+	return settings
+}
+
+type contextKey struct{}
+
+// NewContext returns a context with the limits of the user
+func NewContext(ctx context.Context, limits Limits) context.Context {
+	return context.WithValue(ctx, contextKey{}, limits)
+}
+
+// FromContext returns the limits of the user, the queries run without a user e.g rules
+// don't have limits
+func FromContext(ctx context.Context) (Limits, bool) {
+	limits, ok := ctx.Value(contextKey{}).(Limits)
+	return limits, ok
+}
@@ -0,0 +1,122 @@
+package querylimits
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.signoz.io/signoz/pkg/query-service/model"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+)
+
+func TestLoadFromYAMLConfig(t *testing.T) {
+	config, err := LoadFromYAMLConfig([]byte(`
+default:
+  max_time_range: 168h
+  max_rows_to_read: 1000000000
+  max_execution_time: 90s
+  max_series: 500
+  require_filters_for_list: true
+roles:
+  ADMIN:
+    max_time_range: 720h
+`))
+	require.NoError(t, err)
+
+	assert.Equal(t, Limits{
+		MaxTimeRange:          168 * time.Hour,
+		MaxRowsToRead:         1000000000,
+		MaxExecutionTime:      90 * time.Second,
+		MaxSeries:             500,
+		RequireFiltersForList: true,
+	}, config.ForRole("VIEWER"))
+	assert.Equal(t, Limits{MaxTimeRange: 720 * time.Hour}, config.ForRole("ADMIN"))
+	assert.Equal(t, map[string]string{
+		"max_rows_to_read":   "1000000000",
+		"max_execution_time": "90",
+	}, config.ForRole("EDITOR").ClickHouseSettings())
+}
+
+func TestValidate(t *testing.T) {
+	listParams := func(filters *v3.FilterSet) *v3.QueryRangeParamsV3 {
+		return &v3.QueryRangeParamsV3{
+			Start: 1675115596722,
+			End:   1675115596722 + 60*60*1000,
+			CompositeQuery: &v3.CompositeQuery{
+				QueryType: v3.QueryTypeBuilder,
+				PanelType: v3.PanelTypeList,
+				BuilderQueries: map[string]*v3.BuilderQuery{
+					"A": {QueryName: "A", Expression: "A", DataSource: v3.DataSourceLogs, Filters: filters},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		limits    Limits
+		params    *v3.QueryRangeParamsV3
+		wantLimit string
+	}{
+		{
+			name:   "no limits",
+			limits: Limits{},
+			params: listParams(nil),
+		},
+		{
+			name:      "time range exceeds the limit",
+			limits:    Limits{MaxTimeRange: 30 * time.Minute},
+			params:    listParams(nil),
+			wantLimit: "max_time_range",
+		},
+		{
+			name:      "list query without filters",
+			limits:    Limits{RequireFiltersForList: true},
+			params:    listParams(&v3.FilterSet{Operator: "AND"}),
+			wantLimit: "require_filters_for_list",
+		},
+		{
+			name:   "list query with filters",
+			limits: Limits{RequireFiltersForList: true, MaxTimeRange: time.Hour},
+			params: listParams(&v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{
+				{Key: v3.AttributeKey{Key: "service.name"}, Operator: v3.FilterOperatorEqual, Value: "api"},
+			}}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.limits.Validate(tt.params)
+			if tt.wantLimit == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var apiErr *model.ApiError
+			require.True(t, errors.As(err, &apiErr))
+			assert.Equal(t, model.ErrorQueryLimitExceeded, apiErr.Typ)
+			var limitErr *Error
+			require.True(t, errors.As(apiErr.Err, &limitErr))
+			assert.Equal(t, tt.wantLimit, limitErr.Limit)
+		})
+	}
+}
+
+func TestValidateResults(t *testing.T) {
+	results := []*v3.Result{{QueryName: "A", Series: []*v3.Series{{}, {}, {}}}}
+
+	assert.NoError(t, Limits{}.ValidateResults(results))
+	assert.NoError(t, Limits{MaxSeries: 3}.ValidateResults(results))
+	assert.EqualError(t, Limits{MaxSeries: 2}.ValidateResults(results), "query A returned 3 series which exceeds the limit of 2, add filters or reduce the group by")
+}
+
+func TestContext(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	limits, ok := FromContext(NewContext(context.Background(), Limits{MaxSeries: 10}))
+	assert.True(t, ok)
+	assert.Equal(t, 10, limits.MaxSeries)
+}
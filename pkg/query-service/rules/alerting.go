@@ -30,6 +30,7 @@ const (
 	RuleTypeThreshold = "threshold_rule"
 	RuleTypeProm      = "promql_rule"
 	RuleTypeAnomaly   = "anomaly_rule"
+	RuleTypeBurnRate  = "burn_rate_rule"
 )
 
 type RuleHealth string
@@ -118,6 +119,9 @@ type RuleCondition struct {
 	SelectedQuery     string             `json:"selectedQueryName,omitempty"`
 	RequireMinPoints  bool               `yaml:"requireMinPoints,omitempty" json:"requireMinPoints,omitempty"`
 	RequiredNumPoints int                `yaml:"requiredNumPoints,omitempty" json:"requiredNumPoints,omitempty"`
+	SLOId             string             `yaml:"sloId,omitempty" json:"sloId,omitempty"`
+	LongWindow        Duration           `yaml:"longWindow,omitempty" json:"longWindow,omitempty"`
+	ShortWindow       Duration           `yaml:"shortWindow,omitempty" json:"shortWindow,omitempty"`
 }
 
 func (rc *RuleCondition) GetSelectedQueryName() string {
@@ -154,6 +158,10 @@ func (rc *RuleCondition) GetSelectedQueryName() string {
 		for k := range queryNames {
 			keys = append(keys, k)
 		}
+		// the burn rate rules run the queries of the SLO and have none of their own
+		if len(keys) == 0 {
+			return ""
+		}
 		sort.Strings(keys)
 		return keys[len(keys)-1]
 	}
@@ -163,6 +171,11 @@ func (rc *RuleCondition) GetSelectedQueryName() string {
 
 func (rc *RuleCondition) IsValid() bool {
 
+	// the burn rate rules run the queries of the SLO
+	if rc.SLOId != "" {
+		return rc.Target != nil && rc.CompareOp != ""
+	}
+
 	if rc.CompositeQuery == nil {
 		return false
 	}
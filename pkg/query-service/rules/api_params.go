@@ -4,10 +4,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 	"unicode/utf8"
 
+	"github.com/SigNoz/govaluate"
 	"github.com/pkg/errors"
+	"go.signoz.io/signoz/pkg/query-service/app/queryBuilder"
 	"go.signoz.io/signoz/pkg/query-service/model"
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
 	"go.uber.org/multierr"
@@ -131,7 +134,24 @@ func parseIntoRule(initRule PostableRule, content []byte, kind RuleDataKind) (*P
 		rule.Frequency = Duration(1 * time.Minute)
 	}
 
-	if rule.RuleCondition != nil {
+	if rule.RuleType == RuleTypeBurnRate && rule.RuleCondition != nil {
+		// the burn rates are evaluated over the windows of the rule, so the
+		// alert fires as soon as both of them are above the target
+		if rule.RuleCondition.LongWindow == 0 {
+			rule.RuleCondition.LongWindow = Duration(1 * time.Hour)
+		}
+		if rule.RuleCondition.ShortWindow == 0 {
+			rule.RuleCondition.ShortWindow = Duration(5 * time.Minute)
+		}
+		if rule.RuleCondition.CompareOp == "" {
+			rule.RuleCondition.CompareOp = ValueIsAbove
+		}
+		if rule.RuleCondition.MatchType == "" {
+			rule.RuleCondition.MatchType = AtleastOnce
+		}
+	}
+
+	if rule.RuleCondition != nil && rule.RuleCondition.CompositeQuery != nil {
 		if rule.RuleCondition.CompositeQuery.QueryType == v3.QueryTypeBuilder {
 			if rule.RuleType == "" {
 				rule.RuleType = RuleTypeThreshold
@@ -209,6 +229,36 @@ func isAllQueriesDisabled(compositeQuery *v3.CompositeQuery) bool {
 	return true
 }
 
+// formulaOnlyFunctionErrors returns an error for each formula of the composite query using a
+// function that is only evaluated by the query service
+func formulaOnlyFunctionErrors(compositeQuery *v3.CompositeQuery) []error {
+	if compositeQuery == nil || compositeQuery.QueryType != v3.QueryTypeBuilder {
+		return nil
+	}
+	names := make([]string, 0, len(compositeQuery.BuilderQueries))
+	for name := range compositeQuery.BuilderQueries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		query := compositeQuery.BuilderQueries[name]
+		if query.QueryName == query.Expression {
+			continue
+		}
+		expression, err := govaluate.NewEvaluableExpressionWithFunctions(query.Expression, queryBuilder.EvalFuncs)
+		if err != nil {
+			// the invalid expressions are reported by the validation of the query
+			continue
+		}
+		if function, ok := queryBuilder.FormulaOnlyFunction(expression); ok {
+			errs = append(errs, errors.Errorf("function %s of formula %s is only supported by the v4 rules", function, name))
+		}
+	}
+	return errs
+}
+
 func (r *PostableRule) Validate() error {
 
 	var errs []error
@@ -216,6 +266,19 @@ func (r *PostableRule) Validate() error {
 	if r.RuleCondition == nil {
 		// will get panic if we try to access CompositeQuery, so return here
 		return errors.Errorf("rule condition is required")
+	} else if r.RuleType == RuleTypeBurnRate {
+		// the queries of the burn rate rules are the queries of the SLO
+		if r.RuleCondition.SLOId == "" {
+			errs = append(errs, errors.Errorf("rule condition missing the slo id"))
+		}
+		if r.RuleCondition.Target == nil {
+			errs = append(errs, errors.Errorf("rule condition missing the burn rate threshold"))
+		}
+		if r.RuleCondition.ShortWindow <= 0 || r.RuleCondition.LongWindow <= 0 {
+			errs = append(errs, errors.Errorf("rule condition windows must be positive"))
+		} else if r.RuleCondition.ShortWindow >= r.RuleCondition.LongWindow {
+			errs = append(errs, errors.Errorf("rule condition short window must be less than the long window"))
+		}
 	} else {
 		if r.RuleCondition.CompositeQuery == nil {
 			errs = append(errs, errors.Errorf("composite metric query is required"))
@@ -226,6 +289,11 @@ func (r *PostableRule) Validate() error {
 		errs = append(errs, errors.Errorf("all queries are disabled in rule condition"))
 	}
 
+	// the formulas of the rules before v4 are evaluated in clickhouse
+	if r.Version != "v4" {
+		errs = append(errs, formulaOnlyFunctionErrors(r.RuleCondition.CompositeQuery)...)
+	}
+
 	if r.RuleType == RuleTypeThreshold {
 		if r.RuleCondition.Target == nil {
 			errs = append(errs, errors.Errorf("rule condition missing the threshold"))
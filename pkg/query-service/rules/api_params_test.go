@@ -1,6 +1,7 @@
 package rules
 
 import (
+	"strings"
 	"testing"
 
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
@@ -84,3 +85,37 @@ func TestIsAllQueriesDisabled(t *testing.T) {
 		}
 	}
 }
+
+func TestValidateFormulaOnlyFunctions(t *testing.T) {
+	target := 1.0
+	for _, version := range []string{"v3", "v4"} {
+		rule := &PostableRule{
+			AlertName: "formula rule",
+			RuleType:  RuleTypeThreshold,
+			Version:   version,
+			RuleCondition: &RuleCondition{
+				CompositeQuery: &v3.CompositeQuery{
+					QueryType: v3.QueryTypeBuilder,
+					BuilderQueries: map[string]*v3.BuilderQuery{
+						"A":  {QueryName: "A", Expression: "A", DataSource: v3.DataSourceMetrics},
+						"F1": {QueryName: "F1", Expression: "coalesce(A, 0)"},
+					},
+				},
+				Target:    &target,
+				CompareOp: ValueIsAbove,
+				MatchType: AtleastOnce,
+			},
+		}
+
+		err := rule.Validate()
+		if version == "v4" {
+			if err != nil {
+				t.Errorf("expected no error for the %s rule, got %s", version, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), "function coalesce of formula F1 is only supported by the v4 rules") {
+			t.Errorf("expected the formula only function error for the %s rule, got %v", version, err)
+		}
+	}
+}
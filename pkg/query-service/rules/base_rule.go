@@ -2,6 +2,7 @@ package rules
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"net/url"
@@ -9,10 +10,13 @@ import (
 	"time"
 
 	"go.signoz.io/signoz/pkg/query-service/converter"
+	"go.signoz.io/signoz/pkg/query-service/formatter"
 	"go.signoz.io/signoz/pkg/query-service/interfaces"
 	"go.signoz.io/signoz/pkg/query-service/model"
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
 	qslabels "go.signoz.io/signoz/pkg/query-service/utils/labels"
+	"go.signoz.io/signoz/pkg/query-service/utils/times"
+	"go.signoz.io/signoz/pkg/query-service/utils/timestamp"
 	"go.uber.org/zap"
 )
 
@@ -655,6 +659,167 @@ func (r *BaseRule) RecordRuleStateHistory(ctx context.Context, prevState, curren
 	return nil
 }
 
+// newAlert returns the pending alert of the sample, the labels and the annotations of the rule
+// are expanded with the labels and the value of the sample. The result labels are the labels of
+// the sample the alert is for, the links are added to the annotations as they are
+func (r *BaseRule) newAlert(ctx context.Context, ts time.Time, smpl Sample, resultLabels qslabels.Labels, links ...qslabels.Label) *Alert {
+	valueFormatter := formatter.FromUnit(r.Unit())
+
+	l := make(map[string]string, len(smpl.Metric))
+	for _, lbl := range smpl.Metric {
+		l[lbl.Name] = lbl.Value
+	}
+
+	value := valueFormatter.Format(smpl.V, r.Unit())
+	threshold := valueFormatter.Format(r.targetVal(), r.Unit())
+	zap.L().Debug("Alert template data for rule", zap.String("name", r.Name()), zap.String("formatter", valueFormatter.Name()), zap.String("value", value), zap.String("threshold", threshold))
+
+	tmplData := AlertTemplateData(l, value, threshold)
+	// Inject some convenience variables that are easier to remember for users
+	// who are not used to Go's templating system.
+	defs := "{{$labels := .Labels}}{{$value := .Value}}{{$threshold := .Threshold}}"
+
+	// utility function to apply go template on labels and annotations
+	expand := func(text string) string {
+
+		tmpl := NewTemplateExpander(
+			ctx,
+			defs+text,
+			"__alert_"+r.Name(),
+			tmplData,
+			times.Time(timestamp.FromTime(ts)),
+			nil,
+		)
+		result, err := tmpl.Expand()
+		if err != nil {
+			result = fmt.Sprintf("<error expanding template: %s>", err)
+			zap.L().Error("Expanding alert template failed", zap.Error(err), zap.Any("data", tmplData))
+		}
+		return result
+	}
+
+	lb := qslabels.NewBuilder(resultLabels)
+
+	for name, value := range r.labels.Map() {
+		lb.Set(name, expand(value))
+	}
+
+	lb.Set(qslabels.AlertNameLabel, r.Name())
+	lb.Set(qslabels.AlertRuleIdLabel, r.ID())
+	lb.Set(qslabels.RuleSourceLabel, r.GeneratorURL())
+
+	annotations := make(qslabels.Labels, 0, len(r.annotations.Map()))
+	for name, value := range r.annotations.Map() {
+		annotations = append(annotations, qslabels.Label{Name: name, Value: expand(value)})
+	}
+	annotations = append(annotations, links...)
+	if smpl.IsMissing {
+		lb.Set(qslabels.AlertNameLabel, "[No data] "+r.Name())
+	}
+
+	return &Alert{
+		Labels:            lb.Labels(),
+		QueryResultLables: resultLabels,
+		Annotations:       annotations,
+		ActiveAt:          ts,
+		State:             model.StatePending,
+		Value:             smpl.V,
+		GeneratorURL:      r.GeneratorURL(),
+		Receivers:         r.preferredChannels,
+		Missing:           smpl.IsMissing,
+	}
+}
+
+// updateActiveAlerts adds the alerts of the evaluation at ts, by the hash of their labels, to the
+// active alerts. The pending alerts held long enough start firing and the active alerts that are
+// not in the evaluation are resolved, the state changes are recorded in the rule state history.
+// It returns the number of the active alerts, the rule mutex should be held by the caller
+func (r *BaseRule) updateActiveAlerts(ctx context.Context, ts time.Time, prevState model.AlertState, alerts map[uint64]*Alert) int {
+	zap.L().Info("number of alerts found", zap.String("name", r.Name()), zap.Int("count", len(alerts)))
+
+	// alerts[h] is ready, add or update active list now
+	for h, a := range alerts {
+		// Check whether we already have alerting state for the identifying label set.
+		// Update the last value and annotations if so, create a new alert entry otherwise.
+		if alert, ok := r.Active[h]; ok && alert.State != model.StateInactive {
+
+			alert.Value = a.Value
+			alert.Annotations = a.Annotations
+			alert.Receivers = r.preferredChannels
+			continue
+		}
+
+		r.Active[h] = a
+	}
+
+	itemsToAdd := []model.RuleStateHistory{}
+
+	// Check if any pending alerts should be removed or fire now. Write out alert timeseries.
+	for fp, a := range r.Active {
+		labelsJSON, err := json.Marshal(a.QueryResultLables)
+		if err != nil {
+			zap.L().Error("error marshaling labels", zap.Error(err), zap.Any("labels", a.Labels))
+		}
+		if _, ok := alerts[fp]; !ok {
+			// If the alert was previously firing, keep it around for a given
+			// retention time so it is reported as resolved to the AlertManager.
+			if a.State == model.StatePending || (!a.ResolvedAt.IsZero() && ts.Sub(a.ResolvedAt) > ResolvedRetention) {
+				delete(r.Active, fp)
+			}
+			if a.State != model.StateInactive {
+				a.State = model.StateInactive
+				a.ResolvedAt = ts
+				itemsToAdd = append(itemsToAdd, model.RuleStateHistory{
+					RuleID:       r.ID(),
+					RuleName:     r.Name(),
+					State:        model.StateInactive,
+					StateChanged: true,
+					UnixMilli:    ts.UnixMilli(),
+					Labels:       model.LabelsString(labelsJSON),
+					Fingerprint:  a.QueryResultLables.Hash(),
+					Value:        a.Value,
+				})
+			}
+			continue
+		}
+
+		if a.State == model.StatePending && ts.Sub(a.ActiveAt) >= r.holdDuration {
+			a.State = model.StateFiring
+			a.FiredAt = ts
+			state := model.StateFiring
+			if a.Missing {
+				state = model.StateNoData
+			}
+			itemsToAdd = append(itemsToAdd, model.RuleStateHistory{
+				RuleID:       r.ID(),
+				RuleName:     r.Name(),
+				State:        state,
+				StateChanged: true,
+				UnixMilli:    ts.UnixMilli(),
+				Labels:       model.LabelsString(labelsJSON),
+				Fingerprint:  a.QueryResultLables.Hash(),
+				Value:        a.Value,
+			})
+		}
+	}
+
+	currentState := r.State()
+
+	overallStateChanged := currentState != prevState
+	for idx, item := range itemsToAdd {
+		item.OverallStateChanged = overallStateChanged
+		item.OverallState = currentState
+		itemsToAdd[idx] = item
+	}
+
+	r.RecordRuleStateHistory(ctx, prevState, currentState, itemsToAdd)
+
+	r.health = HealthGood
+	r.lastError = nil
+
+	return len(r.Active)
+}
+
 func (r *BaseRule) PopulateTemporality(ctx context.Context, qp *v3.QueryRangeParamsV3) error {
 
 	missingTemporality := make([]string, 0)
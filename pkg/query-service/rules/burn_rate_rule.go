@@ -0,0 +1,151 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+
+	querierV2 "go.signoz.io/signoz/pkg/query-service/app/querier/v2"
+	"go.signoz.io/signoz/pkg/query-service/app/queryBuilder"
+	"go.signoz.io/signoz/pkg/query-service/interfaces"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.signoz.io/signoz/pkg/query-service/utils/labels"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	sloNameLabel = "slo"
+	sloIdLabel   = "slo_id"
+)
+
+// BurnRateRule alerts on the burn rate of the error budget of a SLO. The burn rate is
+// evaluated over a long and a short window and the value of the rule is the lower of
+// the two, so the rule fires when the budget is spent fast over both windows and
+// resolves as soon as the short window recovers
+type BurnRateRule struct {
+	*BaseRule
+
+	ruleDB    RuleDB
+	evaluator *SLOEvaluator
+}
+
+func NewBurnRateRule(
+	id string,
+	p *PostableRule,
+	featureFlags interfaces.FeatureLookup,
+	reader interfaces.Reader,
+	ruleDB RuleDB,
+	useLogsNewSchema bool,
+	opts ...RuleOption,
+) (*BurnRateRule, error) {
+
+	zap.L().Info("creating new BurnRateRule", zap.String("id", id), zap.Any("opts", opts))
+
+	baseRule, err := NewBaseRule(id, p, reader, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	t := BurnRateRule{
+		BaseRule: baseRule,
+		ruleDB:   ruleDB,
+	}
+
+	querierOptsV2 := querierV2.QuerierOptions{
+		Reader:           reader,
+		Cache:            nil,
+		KeyGenerator:     queryBuilder.NewKeyGenerator(),
+		FeatureLookup:    featureFlags,
+		UseLogsNewSchema: useLogsNewSchema,
+	}
+
+	t.evaluator = NewSLOEvaluator(querierV2.NewQuerier(querierOptsV2), reader, t.PopulateTemporality)
+	t.reader = reader
+	return &t, nil
+}
+
+func (r *BurnRateRule) Type() RuleType {
+	return RuleTypeBurnRate
+}
+
+func (r *BurnRateRule) buildAndRunQuery(ctx context.Context, ts time.Time) (Vector, error) {
+
+	slo, err := r.ruleDB.GetSLOByID(ctx, r.ruleCondition.SLOId)
+	if err != nil {
+		return nil, fmt.Errorf("error while fetching the slo %s: %w", r.ruleCondition.SLOId, err)
+	}
+
+	if r.EvalDelay() > 0 {
+		ts = ts.Add(-r.EvalDelay())
+	}
+
+	longBurnRate, err := r.evaluator.BurnRate(ctx, slo, time.Duration(r.ruleCondition.LongWindow), ts)
+	if err != nil {
+		return nil, err
+	}
+	shortBurnRate, err := r.evaluator.BurnRate(ctx, slo, time.Duration(r.ruleCondition.ShortWindow), ts)
+	if err != nil {
+		return nil, err
+	}
+	zap.L().Debug("burn rates", zap.String("ruleid", r.ID()), zap.String("slo", slo.Name), zap.Float64("long", longBurnRate), zap.Float64("short", shortBurnRate))
+
+	series := v3.Series{
+		Labels: map[string]string{
+			sloNameLabel: slo.Name,
+			sloIdLabel:   strconv.FormatInt(slo.Id, 10),
+		},
+		Points: []v3.Point{{Timestamp: ts.UnixMilli(), Value: math.Min(longBurnRate, shortBurnRate)}},
+	}
+
+	var resultVector Vector
+	if smpl, shouldAlert := r.ShouldAlert(series); shouldAlert {
+		resultVector = append(resultVector, smpl)
+	}
+	return resultVector, nil
+}
+
+func (r *BurnRateRule) Eval(ctx context.Context, ts time.Time) (interface{}, error) {
+
+	prevState := r.State()
+
+	res, err := r.buildAndRunQuery(ctx, ts)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	var alerts = make(map[uint64]*Alert, len(res))
+	for _, smpl := range res {
+		alert := r.newAlert(ctx, ts, smpl, labels.NewBuilder(smpl.Metric).Labels())
+		alerts[alert.Labels.Hash()] = alert
+	}
+
+	return r.updateActiveAlerts(ctx, ts, prevState, alerts), nil
+}
+
+func (r *BurnRateRule) String() string {
+
+	ar := PostableRule{
+		AlertName:         r.name,
+		RuleCondition:     r.ruleCondition,
+		EvalWindow:        Duration(r.evalWindow),
+		Labels:            r.labels.Map(),
+		Annotations:       r.annotations.Map(),
+		PreferredChannels: r.preferredChannels,
+	}
+
+	byt, err := yaml.Marshal(ar)
+	if err != nil {
+		return fmt.Sprintf("error marshaling alerting rule: %s", err.Error())
+	}
+
+	return string(byt)
+}
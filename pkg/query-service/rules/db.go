@@ -56,6 +56,21 @@ type RuleDB interface {
 	// GetAllPlannedMaintenance fetches the maintenance definitions from db
 	GetAllPlannedMaintenance(ctx context.Context) ([]PlannedMaintenance, error)
 
+	// CreateSLO stores a given SLO in db
+	CreateSLO(ctx context.Context, slo SLO) (int64, error)
+
+	// DeleteSLO deletes the given SLO in the db
+	DeleteSLO(ctx context.Context, id string) error
+
+	// GetSLOByID fetches the SLO definition from db by id
+	GetSLOByID(ctx context.Context, id string) (*SLO, error)
+
+	// EditSLO updates the given SLO in the db
+	EditSLO(ctx context.Context, slo SLO, id string) error
+
+	// GetAllSLOs fetches the SLO definitions from db
+	GetAllSLOs(ctx context.Context) ([]SLO, error)
+
 	// used for internal telemetry
 	GetAlertsInfo(ctx context.Context) (*model.AlertsInfo, error)
 }
@@ -313,6 +328,83 @@ func (r *ruleDB) EditPlannedMaintenance(ctx context.Context, maintenance Planned
 	return "", nil
 }
 
+func (r *ruleDB) GetAllSLOs(ctx context.Context) ([]SLO, error) {
+	slos := []SLO{}
+
+	query := "SELECT id, name, description, objective, time_window, indicator, created_at, created_by, updated_at, updated_by FROM slos"
+
+	err := r.Select(&slos, query)
+
+	if err != nil {
+		zap.L().Error("Error in processing sql query", zap.Error(err))
+		return nil, err
+	}
+
+	return slos, nil
+}
+
+func (r *ruleDB) GetSLOByID(ctx context.Context, id string) (*SLO, error) {
+	slo := &SLO{}
+
+	query := "SELECT id, name, description, objective, time_window, indicator, created_at, created_by, updated_at, updated_by FROM slos WHERE id=$1"
+	err := r.Get(slo, query, id)
+
+	if err != nil {
+		zap.L().Error("Error in processing sql query", zap.Error(err))
+		return nil, err
+	}
+
+	return slo, nil
+}
+
+func (r *ruleDB) CreateSLO(ctx context.Context, slo SLO) (int64, error) {
+
+	email, _ := auth.GetEmailFromJwt(ctx)
+	slo.CreatedBy = email
+	slo.CreatedAt = time.Now()
+	slo.UpdatedBy = email
+	slo.UpdatedAt = time.Now()
+
+	query := "INSERT INTO slos (name, description, objective, time_window, indicator, created_at, created_by, updated_at, updated_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)"
+
+	result, err := r.Exec(query, slo.Name, slo.Description, slo.Objective, slo.Window, slo.Indicator, slo.CreatedAt, slo.CreatedBy, slo.UpdatedAt, slo.UpdatedBy)
+
+	if err != nil {
+		zap.L().Error("Error in processing sql query", zap.Error(err))
+		return 0, err
+	}
+
+	return result.LastInsertId()
+}
+
+func (r *ruleDB) DeleteSLO(ctx context.Context, id string) error {
+	query := "DELETE FROM slos WHERE id=$1"
+	_, err := r.Exec(query, id)
+
+	if err != nil {
+		zap.L().Error("Error in processing sql query", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *ruleDB) EditSLO(ctx context.Context, slo SLO, id string) error {
+	email, _ := auth.GetEmailFromJwt(ctx)
+	slo.UpdatedBy = email
+	slo.UpdatedAt = time.Now()
+
+	query := "UPDATE slos SET name=$1, description=$2, objective=$3, time_window=$4, indicator=$5, updated_at=$6, updated_by=$7 WHERE id=$8"
+	_, err := r.Exec(query, slo.Name, slo.Description, slo.Objective, slo.Window, slo.Indicator, slo.UpdatedAt, slo.UpdatedBy, id)
+
+	if err != nil {
+		zap.L().Error("Error in processing sql query", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
 func getChannelType(receiver *am.Receiver) string {
 
 	if receiver.EmailConfigs != nil {
@@ -570,13 +662,16 @@ func (r *ruleDB) GetAlertsInfo(ctx context.Context) (*model.AlertsInfo, error) {
 				}
 			}
 
-			for _, query := range rule.RuleCondition.CompositeQuery.BuilderQueries {
-				if rule.RuleCondition.CompositeQuery.QueryType == v3.QueryTypeBuilder {
-					if query.Filters != nil {
-						for _, item := range query.Filters.Items {
-							if slices.Contains([]string{"contains", "ncontains", "like", "nlike"}, string(item.Operator)) {
-								if item.Key.Key != "body" {
-									alertsInfo.AlertsWithLogsContainsOp += 1
+			// the burn rate rules have no composite query
+			if rule.RuleCondition != nil && rule.RuleCondition.CompositeQuery != nil {
+				for _, query := range rule.RuleCondition.CompositeQuery.BuilderQueries {
+					if rule.RuleCondition.CompositeQuery.QueryType == v3.QueryTypeBuilder {
+						if query.Filters != nil {
+							for _, item := range query.Filters.Items {
+								if slices.Contains([]string{"contains", "ncontains", "like", "nlike"}, string(item.Operator)) {
+									if item.Key.Key != "body" {
+										alertsInfo.AlertsWithLogsContainsOp += 1
+									}
 								}
 							}
 						}
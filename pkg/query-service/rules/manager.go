@@ -2,6 +2,7 @@ package rules
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"sort"
@@ -172,8 +173,30 @@ func defaultPrepareTaskFunc(opts PrepareTaskOptions) (Task, error) {
 		// create promql rule task for evalution
 		task = newTask(TaskTypeProm, opts.TaskName, taskNamesuffix, time.Duration(opts.Rule.Frequency), rules, opts.ManagerOpts, opts.NotifyFunc, opts.RuleDB)
 
+	} else if opts.Rule.RuleType == RuleTypeBurnRate {
+
+		// create burn rate rule
+		br, err := NewBurnRateRule(
+			ruleId,
+			opts.Rule,
+			opts.FF,
+			opts.Reader,
+			opts.RuleDB,
+			opts.UseLogsNewSchema,
+			WithEvalDelay(opts.ManagerOpts.EvalDelay),
+		)
+
+		if err != nil {
+			return task, err
+		}
+
+		rules = append(rules, br)
+
+		// create ch rule task for evalution
+		task = newTask(TaskTypeCh, opts.TaskName, taskNamesuffix, time.Duration(opts.Rule.Frequency), rules, opts.ManagerOpts, opts.NotifyFunc, opts.RuleDB)
+
 	} else {
-		return nil, fmt.Errorf("unsupported rule type %s. Supported types: %s, %s", opts.Rule.RuleType, RuleTypeProm, RuleTypeThreshold)
+		return nil, fmt.Errorf("unsupported rule type %s. Supported types: %s, %s, %s", opts.Rule.RuleType, RuleTypeProm, RuleTypeThreshold, RuleTypeBurnRate)
 	}
 
 	return task, nil
@@ -318,6 +341,9 @@ func (m *Manager) EditRule(ctx context.Context, ruleStr string, id string) error
 	if err != nil {
 		return err
 	}
+	if err := m.validateSLO(ctx, parsedRule); err != nil {
+		return err
+	}
 
 	taskName, _, err := m.ruleDB.EditRuleTx(ctx, ruleStr, id)
 	if err != nil {
@@ -334,6 +360,21 @@ func (m *Manager) EditRule(ctx context.Context, ruleStr string, id string) error
 	return nil
 }
 
+// validateSLO checks that the SLO of the burn rate rule exists, the burn rates of
+// the rule are evaluated with the queries of the SLO
+func (m *Manager) validateSLO(ctx context.Context, rule *PostableRule) error {
+	if rule.RuleType != RuleTypeBurnRate || rule.RuleCondition == nil {
+		return nil
+	}
+	if _, err := m.ruleDB.GetSLOByID(ctx, rule.RuleCondition.SLOId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("slo %s of the burn rate rule doesn't exist", rule.RuleCondition.SLOId)
+		}
+		return fmt.Errorf("error while fetching the slo %s: %w", rule.RuleCondition.SLOId, err)
+	}
+	return nil
+}
+
 func (m *Manager) editTask(rule *PostableRule, taskName string) error {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
@@ -433,6 +474,9 @@ func (m *Manager) CreateRule(ctx context.Context, ruleStr string) (*GettableRule
 	if err != nil {
 		return nil, err
 	}
+	if err := m.validateSLO(ctx, parsedRule); err != nil {
+		return nil, err
+	}
 
 	lastInsertId, tx, err := m.ruleDB.CreateRuleTx(ctx, ruleStr)
 	taskName := prepareTaskName(lastInsertId)
@@ -653,6 +697,27 @@ func (m *Manager) ListRuleStates(ctx context.Context) (*GettableRules, error) {
 	return &GettableRules{Rules: resp}, nil
 }
 
+// RulesUsingSLO returns the ids of the burn rate rules of the SLO
+func (m *Manager) RulesUsingSLO(ctx context.Context, sloId string) ([]string, error) {
+	storedRules, err := m.ruleDB.GetStoredRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ruleIds := make([]string, 0)
+	for _, s := range storedRules {
+		rule := &PostableRule{}
+		if err := json.Unmarshal([]byte(s.Data), rule); err != nil {
+			zap.L().Error("failed to unmarshal rule from db", zap.Int("id", s.Id), zap.Error(err))
+			continue
+		}
+		if rule.RuleCondition != nil && rule.RuleCondition.SLOId == sloId {
+			ruleIds = append(ruleIds, fmt.Sprintf("%d", s.Id))
+		}
+	}
+	return ruleIds, nil
+}
+
 func (m *Manager) GetRule(ctx context.Context, id string) (*GettableRule, error) {
 	s, err := m.ruleDB.GetStoredRule(ctx, id)
 	if err != nil {
@@ -739,6 +804,9 @@ func (m *Manager) PatchRule(ctx context.Context, ruleStr string, ruleId string)
 	if err != nil {
 		return nil, err
 	}
+	if err := m.validateSLO(ctx, patchedRule); err != nil {
+		return nil, err
+	}
 
 	// deploy or un-deploy task according to patched (new) rule state
 	if err := m.syncRuleStateWithTask(taskName, patchedRule); err != nil {
@@ -790,6 +858,9 @@ func (m *Manager) TestNotification(ctx context.Context, ruleStr string) (int, *m
 	if err != nil {
 		return 0, newApiErrorBadData(err)
 	}
+	if err := m.validateSLO(ctx, parsedRule); err != nil {
+		return 0, newApiErrorBadData(err)
+	}
 
 	var alertname = parsedRule.AlertName
 	if alertname == "" {
@@ -843,6 +914,24 @@ func (m *Manager) TestNotification(ctx context.Context, ruleStr string) (int, *m
 			zap.L().Error("failed to prepare a new promql rule for test", zap.String("name", rule.Name()), zap.Error(err))
 			return 0, newApiErrorBadData(err)
 		}
+	} else if parsedRule.RuleType == RuleTypeBurnRate {
+
+		// create burn rate rule
+		rule, err = NewBurnRateRule(
+			alertname,
+			parsedRule,
+			m.featureFlags,
+			m.reader,
+			m.ruleDB,
+			m.opts.UseLogsNewSchema,
+			WithSendAlways(),
+			WithSendUnmatched(),
+		)
+
+		if err != nil {
+			zap.L().Error("failed to prepare a new burn rate rule for test", zap.String("name", rule.Name()), zap.Error(err))
+			return 0, newApiErrorBadData(err)
+		}
 	} else {
 		return 0, newApiErrorBadData(fmt.Errorf("failed to derive ruletype with given information"))
 	}
@@ -0,0 +1,295 @@
+package rules
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+	promModel "github.com/prometheus/common/model"
+	"go.signoz.io/signoz/pkg/query-service/common"
+	"go.signoz.io/signoz/pkg/query-service/interfaces"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+	"go.uber.org/multierr"
+)
+
+var (
+	ErrMissingObjective = errors.New("objective must be between 0 and 100 (exclusive)")
+	ErrMissingWindow    = errors.New("missing window")
+	ErrMissingIndicator = errors.New("missing good and total queries")
+)
+
+const (
+	sloGoodQueryName  = "good"
+	sloTotalQueryName = "total"
+)
+
+// DefaultBurnRateWindows are the windows of the burn rates reported in the status of a SLO
+var DefaultBurnRateWindows = []time.Duration{
+	5 * time.Minute,
+	30 * time.Minute,
+	1 * time.Hour,
+	6 * time.Hour,
+	24 * time.Hour,
+	72 * time.Hour,
+}
+
+// SLO is a service level objective. The indicator is the ratio of the good events to
+// the total events, both counted by builder queries, and the objective is the percent of
+// the events that should be good over the window e.g. 99.9 over 30d
+type SLO struct {
+	Id          int64         `json:"id" db:"id"`
+	Name        string        `json:"name" db:"name"`
+	Description string        `json:"description" db:"description"`
+	Objective   float64       `json:"objective" db:"objective"`
+	Window      string        `json:"window" db:"time_window"`
+	Indicator   *SLOIndicator `json:"indicator" db:"indicator"`
+	CreatedAt   time.Time     `json:"createdAt" db:"created_at"`
+	CreatedBy   string        `json:"createdBy" db:"created_by"`
+	UpdatedAt   time.Time     `json:"updatedAt" db:"updated_at"`
+	UpdatedBy   string        `json:"updatedBy" db:"updated_by"`
+}
+
+// SLOIndicator has the queries counting the good and the total events of the SLO
+type SLOIndicator struct {
+	Good  *v3.BuilderQuery `json:"good"`
+	Total *v3.BuilderQuery `json:"total"`
+}
+
+func (i *SLOIndicator) Scan(src interface{}) error {
+	if data, ok := src.([]byte); ok {
+		return json.Unmarshal(data, i)
+	}
+	if data, ok := src.(string); ok {
+		return json.Unmarshal([]byte(data), i)
+	}
+	return nil
+}
+
+func (i *SLOIndicator) Value() (driver.Value, error) {
+	return json.Marshal(i)
+}
+
+// WindowDuration returns the window of the SLO, which can be in days e.g. 30d
+func (s *SLO) WindowDuration() (time.Duration, error) {
+	window, err := promModel.ParseDuration(s.Window)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window %s: %w", s.Window, err)
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("window must be positive, got %s", s.Window)
+	}
+	return time.Duration(window), nil
+}
+
+// ErrorBudget returns the ratio of the events that are allowed to be bad
+func (s *SLO) ErrorBudget() float64 {
+	return 1 - s.Objective/100
+}
+
+func (s *SLO) Validate() error {
+	var errs []error
+
+	if s.Name == "" {
+		errs = append(errs, ErrMissingName)
+	}
+	if s.Objective <= 0 || s.Objective >= 100 {
+		errs = append(errs, ErrMissingObjective)
+	}
+	if s.Window == "" {
+		errs = append(errs, ErrMissingWindow)
+	} else if _, err := s.WindowDuration(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if s.Indicator == nil || s.Indicator.Good == nil || s.Indicator.Total == nil {
+		errs = append(errs, ErrMissingIndicator)
+	} else {
+		for name, query := range map[string]*v3.BuilderQuery{sloGoodQueryName: s.Indicator.Good, sloTotalQueryName: s.Indicator.Total} {
+			query = sloQuery(query, name, 60)
+			if err := query.Validate(v3.PanelTypeGraph); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s query: %w", name, err))
+			}
+		}
+	}
+
+	return multierr.Combine(errs...)
+}
+
+// BurnRate is the rate at which the error budget is spent over the window, 1 spends
+// exactly the error budget over the window of the SLO
+type BurnRate struct {
+	Window string  `json:"window"`
+	Value  float64 `json:"value"`
+}
+
+// SLOStatus is the state of the SLO at the time of the evaluation.
+// The SLI and the error budgets are in percent
+type SLOStatus struct {
+	SLOId                int64      `json:"sloId"`
+	Objective            float64    `json:"objective"`
+	Window               string     `json:"window"`
+	Good                 float64    `json:"good"`
+	Total                float64    `json:"total"`
+	SLI                  float64    `json:"sli"`
+	ErrorBudget          float64    `json:"errorBudget"`
+	ErrorBudgetRemaining float64    `json:"errorBudgetRemaining"`
+	BurnRates            []BurnRate `json:"burnRates"`
+	EvaluatedAt          time.Time  `json:"evaluatedAt"`
+}
+
+// sliRatio returns the ratio of the good events, without events nothing is bad
+func sliRatio(good, total float64) float64 {
+	if total <= 0 {
+		return 1
+	}
+	return math.Max(0, math.Min(1, good/total))
+}
+
+// burnRate returns the ratio of the bad events to the error budget
+func burnRate(good, total, errorBudget float64) float64 {
+	return (1 - sliRatio(good, total)) / errorBudget
+}
+
+// SLOEvaluator computes the indicator, the error budget and the burn rates of SLOs
+type SLOEvaluator struct {
+	querier interfaces.Querier
+	reader  interfaces.Reader
+	// populateTemporality sets the temporality of the metrics queries
+	populateTemporality func(ctx context.Context, qp *v3.QueryRangeParamsV3) error
+}
+
+func NewSLOEvaluator(querier interfaces.Querier, reader interfaces.Reader, populateTemporality func(ctx context.Context, qp *v3.QueryRangeParamsV3) error) *SLOEvaluator {
+	return &SLOEvaluator{
+		querier:             querier,
+		reader:              reader,
+		populateTemporality: populateTemporality,
+	}
+}
+
+// sloQuery returns a copy of the query of the SLO with the given name and step
+func sloQuery(query *v3.BuilderQuery, name string, step int64) *v3.BuilderQuery {
+	query = query.Clone()
+	query.QueryName = name
+	query.Expression = name
+	query.StepInterval = step
+	query.Disabled = false
+	return query
+}
+
+// perSecond returns whether the points of the query are the rates per second of the events
+// instead of their counts. The metrics queries are aggregated over the time by the time aggregation
+func perSecond(query *v3.BuilderQuery) bool {
+	if query.DataSource == v3.DataSourceMetrics {
+		return query.TimeAggregation == v3.TimeAggregationRate
+	}
+	return query.AggregateOperator.IsRateOperator()
+}
+
+// Counts returns the number of good and total events of the SLO over the window ending at ts
+func (e *SLOEvaluator) Counts(ctx context.Context, slo *SLO, window time.Duration, ts time.Time) (float64, float64, error) {
+	end := ts.UnixMilli()
+	start := ts.Add(-window).UnixMilli()
+	// round to minute otherwise we could potentially miss data
+	start = start - (start % (60 * 1000))
+	end = end - (end % (60 * 1000))
+	step := int64(math.Max(float64(common.MinAllowedStepInterval(start, end)), 60))
+
+	params := &v3.QueryRangeParamsV3{
+		Start: start,
+		End:   end,
+		Step:  step,
+		CompositeQuery: &v3.CompositeQuery{
+			QueryType: v3.QueryTypeBuilder,
+			PanelType: v3.PanelTypeGraph,
+			BuilderQueries: map[string]*v3.BuilderQuery{
+				sloGoodQueryName:  sloQuery(slo.Indicator.Good, sloGoodQueryName, step),
+				sloTotalQueryName: sloQuery(slo.Indicator.Total, sloTotalQueryName, step),
+			},
+		},
+		Variables: make(map[string]interface{}),
+	}
+	if e.populateTemporality != nil {
+		if err := e.populateTemporality(ctx, params); err != nil {
+			return 0, 0, fmt.Errorf("internal error while setting temporality")
+		}
+	}
+	if _, _, err := enrichBuilderQueries(ctx, e.reader, params); err != nil {
+		return 0, 0, fmt.Errorf("error while enriching the queries of the SLO %s: %w", slo.Name, err)
+	}
+
+	results, errQueriesByName, err := e.querier.QueryRange(ctx, params)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error while running the queries of the SLO %s: %w %v", slo.Name, err, errQueriesByName)
+	}
+
+	counts := make(map[string]float64, 2)
+	for _, result := range results {
+		// the rates are per second, the count of a step is the rate over the step
+		scale := 1.0
+		if query, ok := params.CompositeQuery.BuilderQueries[result.QueryName]; ok && perSecond(query) {
+			scale = float64(query.StepInterval)
+		}
+		for _, series := range result.Series {
+			for _, point := range series.Points {
+				if math.IsNaN(point.Value) || math.IsInf(point.Value, 0) {
+					continue
+				}
+				counts[result.QueryName] += point.Value * scale
+			}
+		}
+	}
+	return counts[sloGoodQueryName], counts[sloTotalQueryName], nil
+}
+
+// BurnRate returns the burn rate of the error budget of the SLO over the window ending at ts
+func (e *SLOEvaluator) BurnRate(ctx context.Context, slo *SLO, window time.Duration, ts time.Time) (float64, error) {
+	good, total, err := e.Counts(ctx, slo, window, ts)
+	if err != nil {
+		return 0, err
+	}
+	return burnRate(good, total, slo.ErrorBudget()), nil
+}
+
+// Status returns the indicator and the error budget of the SLO over its window
+// and the burn rates over the given windows, ending at ts
+func (e *SLOEvaluator) Status(ctx context.Context, slo *SLO, windows []time.Duration, ts time.Time) (*SLOStatus, error) {
+	sloWindow, err := slo.WindowDuration()
+	if err != nil {
+		return nil, err
+	}
+	good, total, err := e.Counts(ctx, slo, sloWindow, ts)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &SLOStatus{
+		SLOId:       slo.Id,
+		Objective:   slo.Objective,
+		Window:      slo.Window,
+		Good:        good,
+		Total:       total,
+		SLI:         sliRatio(good, total) * 100,
+		ErrorBudget: slo.ErrorBudget() * 100,
+		// the burn rate over the window of the SLO is the spent ratio of the error budget,
+		// the remaining error budget is negative when the SLO is breached
+		ErrorBudgetRemaining: (1 - burnRate(good, total, slo.ErrorBudget())) * 100,
+		BurnRates:            make([]BurnRate, 0, len(windows)),
+		EvaluatedAt:          ts,
+	}
+
+	for _, window := range windows {
+		value, err := e.BurnRate(ctx, slo, window, ts)
+		if err != nil {
+			return nil, err
+		}
+		status.BurnRates = append(status.BurnRates, BurnRate{
+			Window: promModel.Duration(window).String(),
+			Value:  value,
+		})
+	}
+	return status, nil
+}
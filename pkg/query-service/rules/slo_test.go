@@ -0,0 +1,301 @@
+package rules
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.signoz.io/signoz/pkg/query-service/app/clickhouseReader"
+	"go.signoz.io/signoz/pkg/query-service/featureManager"
+	"go.signoz.io/signoz/pkg/query-service/interfaces"
+	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
+
+	cmock "github.com/srikanthccv/ClickHouse-go-mock"
+)
+
+// sloQuerier returns the good and the total counts of the window of the query range
+type sloQuerier struct {
+	counts map[time.Duration]map[string]float64
+	params []*v3.QueryRangeParamsV3
+}
+
+func (q *sloQuerier) QueryRange(ctx context.Context, params *v3.QueryRangeParamsV3) ([]*v3.Result, map[string]error, error) {
+	q.params = append(q.params, params)
+	window := time.Duration(params.End-params.Start) * time.Millisecond
+	results := make([]*v3.Result, 0)
+	for name := range params.CompositeQuery.BuilderQueries {
+		count := q.counts[window][name]
+		// the count is split over two points and two series
+		results = append(results, &v3.Result{
+			QueryName: name,
+			Series: []*v3.Series{
+				{Points: []v3.Point{{Timestamp: params.Start, Value: count / 4}, {Timestamp: params.End, Value: count / 4}}},
+				{Points: []v3.Point{{Timestamp: params.Start, Value: count / 2}, {Timestamp: params.End, Value: math.NaN()}}},
+			},
+		})
+	}
+	return results, nil, nil
+}
+
+func (q *sloQuerier) Explain(ctx context.Context, params *v3.QueryRangeParamsV3, withExplain bool) ([]*v3.QueryExplanation, error) {
+	return nil, nil
+}
+
+func (q *sloQuerier) QueriesExecuted() []string {
+	return nil
+}
+
+func (q *sloQuerier) TimeRanges() [][]int {
+	return nil
+}
+
+// sloReader has the keys of the span attributes
+type sloReader struct {
+	interfaces.Reader
+}
+
+func (r *sloReader) GetSpanAttributeKeys(ctx context.Context) (map[string]v3.AttributeKey, error) {
+	return map[string]v3.AttributeKey{
+		"responseStatusCode": {Key: "responseStatusCode", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag, IsColumn: true},
+	}, nil
+}
+
+type sloRuleDB struct {
+	RuleDB
+	slo *SLO
+}
+
+func (db *sloRuleDB) GetSLOByID(ctx context.Context, id string) (*SLO, error) {
+	if db.slo == nil {
+		return nil, sql.ErrNoRows
+	}
+	return db.slo, nil
+}
+
+func testSLO() *SLO {
+	return &SLO{
+		Id:        1,
+		Name:      "checkout availability",
+		Objective: 99,
+		Window:    "30d",
+		Indicator: &SLOIndicator{
+			Good: &v3.BuilderQuery{
+				DataSource:        v3.DataSourceTraces,
+				AggregateOperator: v3.AggregateOperatorCount,
+				Filters: &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{
+					{Key: v3.AttributeKey{Key: "hasError", Type: v3.AttributeKeyTypeTag, DataType: v3.AttributeKeyDataTypeBool, IsColumn: true}, Operator: v3.FilterOperatorEqual, Value: false},
+				}},
+			},
+			Total: &v3.BuilderQuery{
+				DataSource:        v3.DataSourceTraces,
+				AggregateOperator: v3.AggregateOperatorCount,
+			},
+		},
+	}
+}
+
+func TestSLOValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		update  func(slo *SLO)
+		wantErr bool
+	}{
+		{name: "valid", update: func(slo *SLO) {}},
+		{name: "missing name", update: func(slo *SLO) { slo.Name = "" }, wantErr: true},
+		{name: "objective of 100", update: func(slo *SLO) { slo.Objective = 100 }, wantErr: true},
+		{name: "invalid window", update: func(slo *SLO) { slo.Window = "a month" }, wantErr: true},
+		{name: "missing good query", update: func(slo *SLO) { slo.Indicator.Good = nil }, wantErr: true},
+		{name: "invalid total query", update: func(slo *SLO) { slo.Indicator.Total.DataSource = "" }, wantErr: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			slo := testSLO()
+			c.update(slo)
+			err := slo.Validate()
+			if c.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestSLOEvaluatorStatus(t *testing.T) {
+	querier := &sloQuerier{counts: map[time.Duration]map[string]float64{
+		30 * 24 * time.Hour: {"good": 99600, "total": 100000},
+		time.Hour:           {"good": 980, "total": 1000},
+		5 * time.Minute:     {"good": 0, "total": 0},
+	}}
+	evaluator := NewSLOEvaluator(querier, &sloReader{}, nil)
+
+	now := time.Now()
+	status, err := evaluator.Status(context.Background(), testSLO(), []time.Duration{time.Hour, 5 * time.Minute}, now)
+	assert.NoError(t, err)
+
+	assert.Equal(t, float64(99600), status.Good)
+	assert.Equal(t, float64(100000), status.Total)
+	assert.InDelta(t, 99.6, status.SLI, 1e-9)
+	assert.InDelta(t, 1, status.ErrorBudget, 1e-9)
+	// 0.4% of the events are bad out of the 1% allowed
+	assert.InDelta(t, 60, status.ErrorBudgetRemaining, 1e-9)
+
+	assert.Len(t, status.BurnRates, 2)
+	assert.Equal(t, "1h", status.BurnRates[0].Window)
+	assert.InDelta(t, 2, status.BurnRates[0].Value, 1e-9)
+	// without events nothing is spent
+	assert.Equal(t, "5m", status.BurnRates[1].Window)
+	assert.InDelta(t, 0, status.BurnRates[1].Value, 1e-9)
+}
+
+func TestSLOEvaluatorCounts(t *testing.T) {
+	slo := testSLO()
+	// the type of the key is set from the span attributes
+	slo.Indicator.Good.Filters = &v3.FilterSet{Operator: "AND", Items: []v3.FilterItem{
+		{Key: v3.AttributeKey{Key: "responseStatusCode"}, Operator: v3.FilterOperatorLessThan, Value: "500"},
+	}}
+	// the rate per second of the total events
+	slo.Indicator.Total.AggregateOperator = v3.AggregateOperatorRate
+
+	querier := &sloQuerier{counts: map[time.Duration]map[string]float64{
+		time.Hour: {"good": 990, "total": 2},
+	}}
+	evaluator := NewSLOEvaluator(querier, &sloReader{}, nil)
+
+	good, total, err := evaluator.Counts(context.Background(), slo, time.Hour, time.Now())
+	assert.NoError(t, err)
+	assert.Len(t, querier.params, 1)
+
+	queries := querier.params[0].CompositeQuery.BuilderQueries
+	assert.Equal(t, v3.AttributeKey{Key: "responseStatusCode", DataType: v3.AttributeKeyDataTypeString, Type: v3.AttributeKeyTypeTag, IsColumn: true}, queries["good"].Filters.Items[0].Key)
+	assert.Equal(t, float64(990), good)
+	// the rates are counted over the step
+	assert.Equal(t, float64(2*queries["total"].StepInterval), total)
+}
+
+func TestValidateSLOOfBurnRateRule(t *testing.T) {
+	rule, err := ParsePostableRule([]byte(`{
+		"alert": "checkout burn rate",
+		"ruleType": "burn_rate_rule",
+		"condition": {"sloId": "1", "target": 14.4}
+	}`))
+	assert.NoError(t, err)
+
+	m := &Manager{ruleDB: &sloRuleDB{slo: testSLO()}}
+	assert.NoError(t, m.validateSLO(context.Background(), rule))
+
+	m = &Manager{ruleDB: &sloRuleDB{}}
+	assert.ErrorContains(t, m.validateSLO(context.Background(), rule), "slo 1 of the burn rate rule doesn't exist")
+}
+
+func TestParseBurnRateRule(t *testing.T) {
+	rule, err := ParsePostableRule([]byte(`{
+		"alert": "checkout burn rate",
+		"ruleType": "burn_rate_rule",
+		"condition": {"sloId": "1", "target": 14.4}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, RuleType(RuleTypeBurnRate), rule.RuleType)
+	assert.Equal(t, Duration(time.Hour), rule.RuleCondition.LongWindow)
+	assert.Equal(t, Duration(5*time.Minute), rule.RuleCondition.ShortWindow)
+	assert.Equal(t, ValueIsAbove, rule.RuleCondition.CompareOp)
+	assert.Equal(t, AtleastOnce, rule.RuleCondition.MatchType)
+	// the burn rate rules have no queries of their own
+	assert.Equal(t, "", rule.RuleCondition.GetSelectedQueryName())
+
+	_, err = ParsePostableRule([]byte(`{
+		"alert": "checkout burn rate",
+		"ruleType": "burn_rate_rule",
+		"condition": {"target": 14.4, "longWindow": "5m", "shortWindow": "1h"}
+	}`))
+	assert.ErrorContains(t, err, "missing the slo id")
+	assert.ErrorContains(t, err, "short window must be less than the long window")
+}
+
+func TestBurnRateRuleEval(t *testing.T) {
+	cases := []struct {
+		name      string
+		longGood  float64
+		shortGood float64
+		alerts    int
+	}{
+		// 20% of the events are bad, 20x the error budget of 1%
+		{name: "both windows burning", longGood: 800, shortGood: 80, alerts: 1},
+		{name: "short window recovered", longGood: 800, shortGood: 100, alerts: 0},
+		{name: "long window below the target", longGood: 950, shortGood: 80, alerts: 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			target := 14.4
+			postableRule := PostableRule{
+				AlertName:  "Burn rate test",
+				AlertType:  AlertTypeTraces,
+				RuleType:   RuleTypeBurnRate,
+				EvalWindow: Duration(5 * time.Minute),
+				Frequency:  Duration(1 * time.Minute),
+				RuleCondition: &RuleCondition{
+					SLOId:       "1",
+					Target:      &target,
+					CompareOp:   ValueIsAbove,
+					MatchType:   AtleastOnce,
+					LongWindow:  Duration(time.Hour),
+					ShortWindow: Duration(5 * time.Minute),
+				},
+			}
+			fm := featureManager.StartManager()
+			mock, err := cmock.NewClickHouseWithQueryMatcher(nil, &queryMatcherAny{})
+			if err != nil {
+				t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			options := clickhouseReader.NewOptions("", 0, 0, 0, "", "archiveNamespace")
+			reader := clickhouseReader.NewReaderFromClickhouseConnection(mock, options, nil, "", fm, "", true)
+
+			rule, err := NewBurnRateRule("70", &postableRule, fm, reader, &sloRuleDB{slo: testSLO()}, true)
+			assert.NoError(t, err)
+			rule.evaluator = NewSLOEvaluator(&sloQuerier{counts: map[time.Duration]map[string]float64{
+				time.Hour:       {"good": c.longGood, "total": 1000},
+				5 * time.Minute: {"good": c.shortGood, "total": 100},
+			}}, &sloReader{}, nil)
+
+			retVal, err := rule.Eval(context.Background(), time.Now())
+			assert.NoError(t, err)
+			assert.Equal(t, c.alerts, retVal.(int))
+			for _, item := range rule.Active {
+				assert.Equal(t, "checkout availability", item.Labels.Get("slo"))
+				assert.Equal(t, "1", item.Labels.Get("slo_id"))
+				assert.InDelta(t, 20, item.Value, 1e-9)
+			}
+		})
+	}
+}
+
+type storedRulesDB struct {
+	RuleDB
+	rules []StoredRule
+}
+
+func (db *storedRulesDB) GetStoredRules(ctx context.Context) ([]StoredRule, error) {
+	return db.rules, nil
+}
+
+func TestRulesUsingSLO(t *testing.T) {
+	m := &Manager{ruleDB: &storedRulesDB{rules: []StoredRule{
+		{Id: 1, Data: `{"alert": "fast burn", "ruleType": "burn_rate_rule", "condition": {"sloId": "1", "target": 14.4}}`},
+		{Id: 2, Data: `{"alert": "slow burn", "ruleType": "burn_rate_rule", "condition": {"sloId": "2", "target": 6}}`},
+		{Id: 3, Data: `{"alert": "latency", "ruleType": "threshold_rule", "condition": {"target": 500}}`},
+		{Id: 4, Data: `{"alert": "fast burn again", "ruleType": "burn_rate_rule", "condition": {"sloId": "1", "target": 14.4}}`},
+	}}}
+
+	ruleIds, err := m.RulesUsingSLO(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "4"}, ruleIds)
+
+	ruleIds, err = m.RulesUsingSLO(context.Background(), "3")
+	assert.NoError(t, err)
+	assert.Empty(t, ruleIds)
+}
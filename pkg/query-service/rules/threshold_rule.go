@@ -3,7 +3,6 @@ package rules
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"math"
 	"text/template"
@@ -13,6 +12,7 @@ import (
 
 	"go.signoz.io/signoz/pkg/query-service/common"
 	"go.signoz.io/signoz/pkg/query-service/contextlinks"
+	"go.signoz.io/signoz/pkg/query-service/dao"
 	"go.signoz.io/signoz/pkg/query-service/model"
 	"go.signoz.io/signoz/pkg/query-service/postprocess"
 
@@ -24,12 +24,9 @@ import (
 	v3 "go.signoz.io/signoz/pkg/query-service/model/v3"
 	"go.signoz.io/signoz/pkg/query-service/utils/labels"
 	querytemplate "go.signoz.io/signoz/pkg/query-service/utils/queryTemplate"
-	"go.signoz.io/signoz/pkg/query-service/utils/times"
-	"go.signoz.io/signoz/pkg/query-service/utils/timestamp"
 
 	logsv3 "go.signoz.io/signoz/pkg/query-service/app/logs/v3"
 	tracesV3 "go.signoz.io/signoz/pkg/query-service/app/traces/v3"
-	"go.signoz.io/signoz/pkg/query-service/formatter"
 
 	yaml "gopkg.in/yaml.v2"
 )
@@ -194,14 +191,9 @@ func (r *ThresholdRule) prepareLinksToLogs(ts time.Time, lbls labels.Labels) str
 		return ""
 	}
 
-	queryFilter := []v3.FilterItem{}
-	if q.Filters != nil {
-		queryFilter = q.Filters.Items
-	}
-
-	filterItems := contextlinks.PrepareFilters(lbls.Map(), queryFilter, q.GroupBy, r.logsKeys)
+	filters := contextlinks.PrepareFilters(lbls.Map(), q.Filters, q.GroupBy, r.logsKeys)
 
-	return contextlinks.PrepareLinksToLogs(start, end, filterItems)
+	return contextlinks.PrepareLinksToLogs(start, end, filters)
 }
 
 func (r *ThresholdRule) prepareLinksToTraces(ts time.Time, lbls labels.Labels) string {
@@ -228,20 +220,69 @@ func (r *ThresholdRule) prepareLinksToTraces(ts time.Time, lbls labels.Labels) s
 		return ""
 	}
 
-	queryFilter := []v3.FilterItem{}
-	if q.Filters != nil {
-		queryFilter = q.Filters.Items
-	}
-
-	filterItems := contextlinks.PrepareFilters(lbls.Map(), queryFilter, q.GroupBy, r.spansKeys)
+	filters := contextlinks.PrepareFilters(lbls.Map(), q.Filters, q.GroupBy, r.spansKeys)
 
-	return contextlinks.PrepareLinksToTraces(start, end, filterItems)
+	return contextlinks.PrepareLinksToTraces(start, end, filters)
 }
 
 func (r *ThresholdRule) GetSelectedQuery() string {
 	return r.ruleCondition.GetSelectedQueryName()
 }
 
+// enrichBuilderQueries sets the types of the keys of the logs and traces builder queries and the
+// apdex thresholds of the services. The keys of the logs and the spans are returned, they are
+// nil when the params don't need them
+func enrichBuilderQueries(ctx context.Context, reader interfaces.Reader, params *v3.QueryRangeParamsV3) (map[string]v3.AttributeKey, map[string]v3.AttributeKey, error) {
+	if params.CompositeQuery.QueryType != v3.QueryTypeBuilder {
+		return nil, nil, nil
+	}
+	hasLogsQuery := false
+	hasTracesQuery := false
+	for _, query := range params.CompositeQuery.BuilderQueries {
+		if query.DataSource == v3.DataSourceLogs {
+			hasLogsQuery = true
+		}
+		if query.DataSource == v3.DataSourceTraces {
+			hasTracesQuery = true
+		}
+	}
+
+	var logsKeys, spansKeys map[string]v3.AttributeKey
+	if hasLogsQuery {
+		// check if any enrichment is required for logs if yes then enrich them
+		if logsv3.EnrichmentRequired(params) {
+			logsFields, err := reader.GetLogFields(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			logsKeys = model.GetLogFieldsV3(ctx, params, logsFields)
+			logsv3.Enrich(params, logsKeys)
+		}
+	}
+
+	if hasTracesQuery {
+		var err error
+		spansKeys, err = reader.GetSpanAttributeKeys(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		tracesV3.Enrich(params, spansKeys)
+
+		if tracesV3.ApdexRequired(params) {
+			apdexSettings, apiErr := dao.DB().GetAllApdexSettings(ctx)
+			if apiErr != nil {
+				return nil, nil, apiErr.Err
+			}
+			thresholds := make(map[string]float64, len(apdexSettings))
+			for _, settings := range apdexSettings {
+				thresholds[settings.ServiceName] = settings.Threshold
+			}
+			tracesV3.EnrichApdex(params, thresholds)
+		}
+	}
+	return logsKeys, spansKeys, nil
+}
+
 func (r *ThresholdRule) buildAndRunQuery(ctx context.Context, ts time.Time) (Vector, error) {
 
 	params, err := r.prepareQueryRange(ts)
@@ -253,39 +294,15 @@ func (r *ThresholdRule) buildAndRunQuery(ctx context.Context, ts time.Time) (Vec
 		return nil, fmt.Errorf("internal error while setting temporality")
 	}
 
-	if params.CompositeQuery.QueryType == v3.QueryTypeBuilder {
-		hasLogsQuery := false
-		hasTracesQuery := false
-		for _, query := range params.CompositeQuery.BuilderQueries {
-			if query.DataSource == v3.DataSourceLogs {
-				hasLogsQuery = true
-			}
-			if query.DataSource == v3.DataSourceTraces {
-				hasTracesQuery = true
-			}
-		}
-
-		if hasLogsQuery {
-			// check if any enrichment is required for logs if yes then enrich them
-			if logsv3.EnrichmentRequired(params) {
-				logsFields, err := r.reader.GetLogFields(ctx)
-				if err != nil {
-					return nil, err
-				}
-				logsKeys := model.GetLogFieldsV3(ctx, params, logsFields)
-				r.logsKeys = logsKeys
-				logsv3.Enrich(params, logsKeys)
-			}
-		}
-
-		if hasTracesQuery {
-			spanKeys, err := r.reader.GetSpanAttributeKeys(ctx)
-			if err != nil {
-				return nil, err
-			}
-			r.spansKeys = spanKeys
-			tracesV3.Enrich(params, spanKeys)
-		}
+	logsKeys, spansKeys, err := enrichBuilderQueries(ctx, r.reader, params)
+	if err != nil {
+		return nil, err
+	}
+	if logsKeys != nil {
+		r.logsKeys = logsKeys
+	}
+	if spansKeys != nil {
+		r.spansKeys = spansKeys
 	}
 
 	var results []*v3.Result
@@ -340,7 +357,13 @@ func (r *ThresholdRule) buildAndRunQuery(ctx context.Context, ts time.Time) (Vec
 		return resultVector, nil
 	}
 
-	for _, series := range queryResult.Series {
+	// the condition of the query with the outliers function applies to the outlier scores
+	seriesList := queryResult.Series
+	if hasOutliersFunction(params, selectedQuery) {
+		seriesList = queryResult.AnomalyScores
+	}
+
+	for _, series := range seriesList {
 		smpl, shouldAlert := r.ShouldAlert(*series)
 		if shouldAlert {
 			resultVector = append(resultVector, smpl)
@@ -349,11 +372,27 @@ func (r *ThresholdRule) buildAndRunQuery(ctx context.Context, ts time.Time) (Vec
 	return resultVector, nil
 }
 
+// hasOutliersFunction returns true if the builder query has the outliers function
+func hasOutliersFunction(params *v3.QueryRangeParamsV3, queryName string) bool {
+	if params.CompositeQuery.QueryType != v3.QueryTypeBuilder {
+		return false
+	}
+	query, ok := params.CompositeQuery.BuilderQueries[queryName]
+	if !ok {
+		return false
+	}
+	for _, function := range query.Functions {
+		if function.Name == v3.FunctionNameOutliers {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *ThresholdRule) Eval(ctx context.Context, ts time.Time) (interface{}, error) {
 
 	prevState := r.State()
 
-	valueFormatter := formatter.FromUnit(r.Unit())
 	res, err := r.buildAndRunQuery(ctx, ts)
 
 	if err != nil {
@@ -363,185 +402,41 @@ func (r *ThresholdRule) Eval(ctx context.Context, ts time.Time) (interface{}, er
 	r.mtx.Lock()
 	defer r.mtx.Unlock()
 
-	resultFPs := map[uint64]struct{}{}
 	var alerts = make(map[uint64]*Alert, len(res))
 
 	for _, smpl := range res {
-		l := make(map[string]string, len(smpl.Metric))
-		for _, lbl := range smpl.Metric {
-			l[lbl.Name] = lbl.Value
-		}
-
-		value := valueFormatter.Format(smpl.V, r.Unit())
-		threshold := valueFormatter.Format(r.targetVal(), r.Unit())
-		zap.L().Debug("Alert template data for rule", zap.String("name", r.Name()), zap.String("formatter", valueFormatter.Name()), zap.String("value", value), zap.String("threshold", threshold))
-
-		tmplData := AlertTemplateData(l, value, threshold)
-		// Inject some convenience variables that are easier to remember for users
-		// who are not used to Go's templating system.
-		defs := "{{$labels := .Labels}}{{$value := .Value}}{{$threshold := .Threshold}}"
-
-		// utility function to apply go template on labels and annotations
-		expand := func(text string) string {
-
-			tmpl := NewTemplateExpander(
-				ctx,
-				defs+text,
-				"__alert_"+r.Name(),
-				tmplData,
-				times.Time(timestamp.FromTime(ts)),
-				nil,
-			)
-			result, err := tmpl.Expand()
-			if err != nil {
-				result = fmt.Sprintf("<error expanding template: %s>", err)
-				zap.L().Error("Expanding alert template failed", zap.Error(err), zap.Any("data", tmplData))
-			}
-			return result
-		}
-
-		lb := labels.NewBuilder(smpl.Metric).Del(labels.MetricNameLabel).Del(labels.TemporalityLabel)
-		resultLabels := labels.NewBuilder(smpl.Metric).Del(labels.MetricNameLabel).Del(labels.TemporalityLabel).Labels()
-
-		for name, value := range r.labels.Map() {
-			lb.Set(name, expand(value))
-		}
-
-		lb.Set(labels.AlertNameLabel, r.Name())
-		lb.Set(labels.AlertRuleIdLabel, r.ID())
-		lb.Set(labels.RuleSourceLabel, r.GeneratorURL())
-
-		annotations := make(labels.Labels, 0, len(r.annotations.Map()))
-		for name, value := range r.annotations.Map() {
-			annotations = append(annotations, labels.Label{Name: name, Value: expand(value)})
-		}
-		if smpl.IsMissing {
-			lb.Set(labels.AlertNameLabel, "[No data] "+r.Name())
-		}
-
 		// Links with timestamps should go in annotations since labels
 		// is used alert grouping, and we want to group alerts with the same
 		// label set, but different timestamps, together.
+		var links []labels.Label
 		if r.typ == AlertTypeTraces {
 			link := r.prepareLinksToTraces(ts, smpl.Metric)
 			if link != "" && r.hostFromSource() != "" {
 				zap.L().Info("adding traces link to annotations", zap.String("link", fmt.Sprintf("%s/traces-explorer?%s", r.hostFromSource(), link)))
-				annotations = append(annotations, labels.Label{Name: "related_traces", Value: fmt.Sprintf("%s/traces-explorer?%s", r.hostFromSource(), link)})
+				links = append(links, labels.Label{Name: "related_traces", Value: fmt.Sprintf("%s/traces-explorer?%s", r.hostFromSource(), link)})
 			}
 		} else if r.typ == AlertTypeLogs {
 			link := r.prepareLinksToLogs(ts, smpl.Metric)
 			if link != "" && r.hostFromSource() != "" {
 				zap.L().Info("adding logs link to annotations", zap.String("link", fmt.Sprintf("%s/logs/logs-explorer?%s", r.hostFromSource(), link)))
-				annotations = append(annotations, labels.Label{Name: "related_logs", Value: fmt.Sprintf("%s/logs/logs-explorer?%s", r.hostFromSource(), link)})
+				links = append(links, labels.Label{Name: "related_logs", Value: fmt.Sprintf("%s/logs/logs-explorer?%s", r.hostFromSource(), link)})
 			}
 		}
 
-		lbs := lb.Labels()
-		h := lbs.Hash()
-		resultFPs[h] = struct{}{}
+		resultLabels := labels.NewBuilder(smpl.Metric).Del(labels.MetricNameLabel).Del(labels.TemporalityLabel).Labels()
+		alert := r.newAlert(ctx, ts, smpl, resultLabels, links...)
 
+		h := alert.Labels.Hash()
 		if _, ok := alerts[h]; ok {
 			zap.L().Error("the alert query returns duplicate records", zap.String("ruleid", r.ID()), zap.Any("alert", alerts[h]))
 			err = fmt.Errorf("duplicate alert found, vector contains metrics with the same labelset after applying alert labels")
 			return nil, err
 		}
 
-		alerts[h] = &Alert{
-			Labels:            lbs,
-			QueryResultLables: resultLabels,
-			Annotations:       annotations,
-			ActiveAt:          ts,
-			State:             model.StatePending,
-			Value:             smpl.V,
-			GeneratorURL:      r.GeneratorURL(),
-			Receivers:         r.preferredChannels,
-			Missing:           smpl.IsMissing,
-		}
-	}
-
-	zap.L().Info("number of alerts found", zap.String("name", r.Name()), zap.Int("count", len(alerts)))
-
-	// alerts[h] is ready, add or update active list now
-	for h, a := range alerts {
-		// Check whether we already have alerting state for the identifying label set.
-		// Update the last value and annotations if so, create a new alert entry otherwise.
-		if alert, ok := r.Active[h]; ok && alert.State != model.StateInactive {
-
-			alert.Value = a.Value
-			alert.Annotations = a.Annotations
-			alert.Receivers = r.preferredChannels
-			continue
-		}
-
-		r.Active[h] = a
+		alerts[h] = alert
 	}
 
-	itemsToAdd := []model.RuleStateHistory{}
-
-	// Check if any pending alerts should be removed or fire now. Write out alert timeseries.
-	for fp, a := range r.Active {
-		labelsJSON, err := json.Marshal(a.QueryResultLables)
-		if err != nil {
-			zap.L().Error("error marshaling labels", zap.Error(err), zap.Any("labels", a.Labels))
-		}
-		if _, ok := resultFPs[fp]; !ok {
-			// If the alert was previously firing, keep it around for a given
-			// retention time so it is reported as resolved to the AlertManager.
-			if a.State == model.StatePending || (!a.ResolvedAt.IsZero() && ts.Sub(a.ResolvedAt) > ResolvedRetention) {
-				delete(r.Active, fp)
-			}
-			if a.State != model.StateInactive {
-				a.State = model.StateInactive
-				a.ResolvedAt = ts
-				itemsToAdd = append(itemsToAdd, model.RuleStateHistory{
-					RuleID:       r.ID(),
-					RuleName:     r.Name(),
-					State:        model.StateInactive,
-					StateChanged: true,
-					UnixMilli:    ts.UnixMilli(),
-					Labels:       model.LabelsString(labelsJSON),
-					Fingerprint:  a.QueryResultLables.Hash(),
-					Value:        a.Value,
-				})
-			}
-			continue
-		}
-
-		if a.State == model.StatePending && ts.Sub(a.ActiveAt) >= r.holdDuration {
-			a.State = model.StateFiring
-			a.FiredAt = ts
-			state := model.StateFiring
-			if a.Missing {
-				state = model.StateNoData
-			}
-			itemsToAdd = append(itemsToAdd, model.RuleStateHistory{
-				RuleID:       r.ID(),
-				RuleName:     r.Name(),
-				State:        state,
-				StateChanged: true,
-				UnixMilli:    ts.UnixMilli(),
-				Labels:       model.LabelsString(labelsJSON),
-				Fingerprint:  a.QueryResultLables.Hash(),
-				Value:        a.Value,
-			})
-		}
-	}
-
-	currentState := r.State()
-
-	overallStateChanged := currentState != prevState
-	for idx, item := range itemsToAdd {
-		item.OverallStateChanged = overallStateChanged
-		item.OverallState = currentState
-		itemsToAdd[idx] = item
-	}
-
-	r.RecordRuleStateHistory(ctx, prevState, currentState, itemsToAdd)
-
-	r.health = HealthGood
-	r.lastError = err
-
-	return len(r.Active), nil
+	return r.updateActiveAlerts(ctx, ts, prevState, alerts), nil
 }
 
 func (r *ThresholdRule) String() string {
@@ -1602,3 +1602,79 @@ func TestThresholdRuleLogsLink(t *testing.T) {
 		}
 	}
 }
+
+func TestThresholdRuleOutliers(t *testing.T) {
+	postableRule := PostableRule{
+		AlertName:  "Outliers test",
+		AlertType:  AlertTypeMetric,
+		RuleType:   RuleTypeThreshold,
+		EvalWindow: Duration(5 * time.Minute),
+		Frequency:  Duration(1 * time.Minute),
+		RuleCondition: &RuleCondition{
+			CompositeQuery: &v3.CompositeQuery{
+				QueryType: v3.QueryTypeBuilder,
+				BuilderQueries: map[string]*v3.BuilderQuery{
+					"A": {
+						QueryName:    "A",
+						StepInterval: 60,
+						AggregateAttribute: v3.AttributeKey{
+							Key: "signoz_latency",
+						},
+						AggregateOperator: v3.AggregateOperatorAvg,
+						DataSource:        v3.DataSourceMetrics,
+						Expression:        "A",
+						GroupBy:           []v3.AttributeKey{{Key: "pod"}},
+						Functions: []v3.Function{
+							{Name: v3.FunctionNameOutliers, NamedArgs: map[string]interface{}{"algorithm": "mad", "tolerance": float64(3)}},
+						},
+					},
+				},
+			},
+			CompareOp: ValueIsAbove,
+			MatchType: AtleastOnce,
+		},
+	}
+	fm := featureManager.StartManager()
+	mock, err := cmock.NewClickHouseWithQueryMatcher(nil, &queryMatcherAny{})
+	if err != nil {
+		t.Errorf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	cols := make([]cmock.ColumnType, 0)
+	cols = append(cols, cmock.ColumnType{Name: "value", Type: "Float64"})
+	cols = append(cols, cmock.ColumnType{Name: "pod", Type: "String"})
+	cols = append(cols, cmock.ColumnType{Name: "timestamp", Type: "String"})
+
+	now := time.Now()
+	values := [][]interface{}{
+		{float64(100), "pod-a", now},
+		{float64(102), "pod-b", now},
+		{float64(98), "pod-c", now},
+		{float64(101), "pod-d", now},
+		{float64(400), "pod-e", now},
+	}
+	mock.ExpectQuery("SELECT any").WillReturnRows(cmock.NewRows(cols, values))
+
+	// the target is the outlier score
+	target := float64(3)
+	postableRule.RuleCondition.Target = &target
+
+	options := clickhouseReader.NewOptions("", 0, 0, 0, "", "archiveNamespace")
+	reader := clickhouseReader.NewReaderFromClickhouseConnection(mock, options, nil, "", fm, "", true)
+
+	rule, err := NewThresholdRule("69", &postableRule, fm, reader, true)
+	assert.NoError(t, err)
+	rule.TemporalityMap = map[string]map[v3.Temporality]bool{
+		"signoz_latency": {
+			v3.Unspecified: true,
+		},
+	}
+
+	retVal, err := rule.Eval(context.Background(), now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, retVal.(int))
+	for _, item := range rule.Active {
+		assert.Equal(t, "pod-e", item.Labels.Get("pod"))
+		assert.Equal(t, "true", item.Labels.Get("outlier"))
+	}
+}
@@ -219,6 +219,25 @@ func ClickHouseFormattedValue(v interface{}) string {
 	}
 }
 
+// HeatmapBucket returns the expression for the upper bound of the heatmap bucket the value
+// falls into, without the explicit buckets the bounds are the powers of two
+// and the values <= 0 are in the 0 bucket
+func HeatmapBucket(value string, buckets []float64) string {
+	if len(buckets) == 0 {
+		return fmt.Sprintf("if(%s <= 0, 0, exp2(ceil(log2(%s))))", value, value)
+	}
+	bounds := make([]string, 0, len(buckets)+1)
+	for _, bound := range buckets {
+		if math.IsInf(bound, 1) {
+			continue
+		}
+		bounds = append(bounds, strconv.FormatFloat(bound, 'f', -1, 64))
+	}
+	// the values greater than the last bound are in the +Inf bucket
+	bounds = append(bounds, "inf")
+	return fmt.Sprintf("arrayFirst(b -> %s <= b, [%s])", value, strings.Join(bounds, ","))
+}
+
 func getPointerValue(v interface{}) interface{} {
 	switch x := v.(type) {
 	case *uint8: